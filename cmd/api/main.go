@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
@@ -9,79 +12,171 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/go-chi/chi/v5"
 	"github.com/joho/godotenv"
 	"github.com/vaultpass/vaultpass-go/internal/config"
+	"github.com/vaultpass/vaultpass-go/internal/crypto"
 	"github.com/vaultpass/vaultpass-go/internal/handler"
 	"github.com/vaultpass/vaultpass-go/internal/middleware"
 	"github.com/vaultpass/vaultpass-go/internal/repository"
+	"github.com/vaultpass/vaultpass-go/internal/server"
 	"github.com/vaultpass/vaultpass-go/internal/service"
 )
 
 func main() {
-	if err := godotenv.Load(); err != nil {
+	calibrate := flag.Duration("calibrate-argon2", 0, "print Argon2id parameters tuned so one hash takes about this long on this machine, then exit")
+	flag.Parse()
+	if err := godotenv.Load(); err != nil && *calibrate == 0 {
 		slog.Warn("no .env file found, using environment variables")
 	}
+	if *calibrate > 0 {
+		runCalibration(*calibrate, config.LoadArgon2Limits())
+		return
+	}
 
 	cfg := config.Load()
+	slog.SetDefault(newLogger(os.Stderr, cfg))
+
+	genOpts := []service.GeneratorOption{
+		service.WithLengthBounds(crypto.LengthBounds{
+			Min: cfg.PasswordMinLength,
+			Max: cfg.PasswordMaxLength,
+		}),
+		service.WithDefaults(cfg.GenerateDefaults),
+	}
+	if cfg.GenerateRecovery {
+		recovery := service.NewRecoveryCache(cfg.GenerateRecoveryTTL)
+		defer recovery.Close()
+		genOpts = append(genOpts, service.WithRecovery(recovery))
+	}
+	if len(cfg.GenerateDenylist) > 0 {
+		// Already validated by config.Load.
+		denylist, _ := crypto.NewDenylist(cfg.GenerateDenylist)
+		genOpts = append(genOpts, service.WithDenylist(denylist))
+	}
+	authOpts := []service.AuthOption{
+		service.WithHashParams(cfg.Argon2Params),
+		service.WithHashLimits(cfg.Argon2Limits),
+	}
+	if cfg.CommonPasswordCheck {
+		authOpts = append(authOpts, service.WithCommonPasswordCheck(crypto.NewCommonPasswords(cfg.CommonPasswords)))
+	}
+	if cfg.BreachCheck {
+		breaches := crypto.NewBreachChecker(
+			crypto.WithBreachURL(cfg.BreachCheckURL),
+			crypto.WithBreachTimeout(cfg.BreachCheckTimeout),
+		)
+		genOpts = append(genOpts, service.WithBreachChecker(breaches))
+		if cfg.BreachCheckEnforce {
+			authOpts = append(authOpts, service.WithRegistrationBreachCheck(breaches))
+		}
+	}
+	genService := service.NewGeneratorService(genOpts...)
+	limiters := &middleware.RateLimiterSet{}
+	genHandler := handler.NewGeneratorHandler(genService, handler.WithRateLimitVisitors(limiters.Visitors))
 
-	genService := service.NewGeneratorService()
-	genHandler := handler.NewGeneratorHandler(genService)
+	hashTiming, err := checkHashTiming(cfg)
+	if err != nil {
+		slog.Error("argon2 self-test failed", "error", err)
+		os.Exit(1)
+	}
 
-	r := chi.NewRouter()
-	r.Use(middleware.Logger)
+	tokens, err := newTokenManager(cfg)
+	if err != nil {
+		slog.Error("failed to load JWT signing key", "error", err)
+		os.Exit(1)
+	}
 
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("ok"))
-	})
+	deps := server.Deps{
+		Config:       cfg,
+		Tokens:       tokens,
+		Health:       handler.NewHealthHandler(hashTiming),
+		Generator:    genHandler,
+		RateLimiters: limiters,
+	}
+	if tokens.Asymmetric() {
+		deps.Keys = handler.NewKeysHandler(tokens)
+	}
 
-	r.Post("/api/v1/generate", genHandler.HandleGenerate)
+	// Stops background jobs once the server has shut down.
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
 
-	// Initialize DB and auth routes if database is available.
-	db, err := repository.NewDB(cfg.DatabaseDSN)
+	// Initialize storage and auth routes if the database is available.
+	stores, err := newStores(cfg)
 	if err != nil {
 		slog.Warn("database connection failed — auth routes disabled", "error", err)
 	} else {
-		userRepo := repository.NewUserRepository(db)
-		authService := service.NewAuthService(userRepo, cfg.JWTSecret, cfg.JWTExpiry)
-		authHandler := handler.NewAuthHandler(authService)
-
-		vaultRepo := repository.NewVaultRepository(db)
-		vaultService := service.NewVaultService(vaultRepo)
-		vaultHandler := handler.NewVaultHandler(vaultService)
-
-		r.Group(func(r chi.Router) {
-			r.Use(middleware.RateLimit(5, 10))
-			r.Post("/api/v1/auth/register", authHandler.HandleRegister)
-			r.Post("/api/v1/auth/login", authHandler.HandleLogin)
-		})
-
-		r.Group(func(r chi.Router) {
-			r.Use(middleware.JWTAuth(cfg.JWTSecret))
-			r.Get("/api/v1/auth/me", authHandler.HandleMe)
-
-			r.Get("/api/v1/vault", vaultHandler.HandleListEntries)
-			r.Post("/api/v1/vault", vaultHandler.HandleCreateEntry)
-			r.Put("/api/v1/vault/{entry_id}", vaultHandler.HandleUpdateEntry)
-			r.Delete("/api/v1/vault/{entry_id}", vaultHandler.HandleDeleteEntry)
-			r.Post("/api/v1/vault/sync", vaultHandler.HandleSync)
-		})
+		if cfg.InviteOnly {
+			authOpts = append(authOpts, service.WithInviteOnly(stores.invites))
+		}
+		if cfg.AdminToken != "" {
+			deps.Invites = handler.NewInviteHandler(service.NewInviteService(stores.invites))
+			deps.Admin = handler.NewAdminHandler(
+				service.NewAccountExportService(stores.users, stores.vaults, stores.vault),
+				service.NewUserListService(stores.users),
+			)
+		}
+		authService := service.NewAuthService(stores.users, stores.audit, stores.sessions, tokens, authOpts...)
+		deps.Sessions = authService
+		authHandlerOpts := []handler.AuthHandlerOption{handler.WithMaxAuthBody(cfg.MaxBodyAuth)}
+		if !cfg.RegistrationOpen {
+			authHandlerOpts = append(authHandlerOpts, handler.WithRegistrationClosed())
+		}
+		deps.Auth = handler.NewAuthHandler(authService, authHandlerOpts...)
+		vaultOpts := []service.VaultOption{service.WithUserStore(stores.users), service.WithSyncTxTimeout(cfg.SyncTxTimeout)}
+		if cfg.VaultHardWipe {
+			vaultOpts = append(vaultOpts, service.WithHardWipe())
+		}
+		if cfg.VaultMaxEntries > 0 || cfg.VaultMaxEntryBytes > 0 {
+			vaultOpts = append(vaultOpts, service.WithEntryLimits(cfg.VaultMaxEntries, int(cfg.VaultMaxEntryBytes)))
+		}
+		vaultService := service.NewVaultService(stores.vault, stores.vaults, vaultOpts...)
+		deps.Vault = handler.NewVaultHandler(vaultService,
+			handler.WithMaxSyncEntries(cfg.MaxSyncEntries),
+			handler.WithMaxVaultBody(cfg.MaxBodyVault),
+		)
+		go vaultService.RunExpiryPurge(purgeCtx, cfg.ExpiryPurgeInterval)
 	}
 
-	srv := &http.Server{
-		Addr:    ":" + cfg.Port,
-		Handler: r,
-	}
+	// Cancelled if shutdown times out, so long-running handlers can stop cleanly.
+	// Handlers that hijack connections register them in streams to be closed on shutdown.
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	streams := server.NewStreams()
+
+	srv := server.New(cfg, server.NewRouter(deps),
+		server.WithBaseContext(baseCtx),
+		server.WithStreams(streams),
+	)
 
 	go func() {
-		slog.Info("server starting", "port", cfg.Port, "env", cfg.Env)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		tlsEnabled := cfg.TLSCertFile != ""
+		slog.Info("server starting", "port", cfg.Port, "env", cfg.Env, "tls", tlsEnabled)
+		var err error
+		if tlsEnabled {
+			err = srv.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			slog.Error("server error", "error", err)
 			os.Exit(1)
 		}
 	}()
 
+	// Plain HTTP clients are sent to the TLS server when a redirect port is set.
+	var redirect *http.Server
+	if cfg.HTTPRedirectPort != "" {
+		redirect = server.NewRedirect(cfg)
+		go func() {
+			slog.Info("https redirect starting", "port", cfg.HTTPRedirectPort)
+			if err := redirect.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				slog.Error("https redirect error", "error", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
@@ -90,10 +185,157 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
+	if redirect != nil {
+		if err := redirect.Shutdown(ctx); err != nil {
+			redirect.Close()
+		}
+	}
+	if err := server.Shutdown(ctx, srv, cancelRequests); err != nil {
 		slog.Error("server forced shutdown", "error", err)
 		os.Exit(1)
 	}
 
 	slog.Info("server stopped")
 }
+
+// newTokenManager builds the token manager from config, loading the RSA signing
+// key from disk in RS256 mode.
+func newTokenManager(cfg config.Config) (*crypto.TokenManager, error) {
+	tc := crypto.TokenConfig{
+		Secret:            cfg.JWTSecret,
+		OldSecrets:        cfg.JWTOldSecrets,
+		Expiry:            cfg.JWTExpiry,
+		RememberExpiry:    cfg.JWTExpiryRemember,
+		IdleTimeout:       cfg.SessionIdleTimeout,
+		Issuer:            cfg.JWTIssuer,
+		Audience:          cfg.JWTAudience,
+		AcceptedIssuers:   cfg.JWTAcceptedIssuers,
+		AcceptedAudiences: cfg.JWTAcceptedAudiences,
+		SigningMethod:     cfg.JWTSigningMethod,
+		KeyID:             cfg.JWTKeyID,
+		Leeway:            cfg.JWTClockSkew,
+	}
+
+	if cfg.JWTSigningMethod == crypto.SigningRS256 {
+		pemData, err := os.ReadFile(cfg.JWTPrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		key, err := crypto.ParseRSAPrivateKey(pemData)
+		if err != nil {
+			return nil, err
+		}
+		tc.PrivateKey = key
+	}
+
+	return crypto.NewTokenManager(tc), nil
+}
+
+// runCalibration prints Argon2id parameters tuned to target, within the
+// configured verify limits, as the settings that apply them, along with the
+// time one hash takes with them.
+func runCalibration(target time.Duration, limits crypto.HashLimits) {
+	params, err := crypto.CalibrateWithin(target, limits)
+	if err != nil {
+		slog.Error("argon2 calibration failed", "error", err)
+		os.Exit(1)
+	}
+	timing, err := crypto.CheckHashTiming(params, target)
+	if err != nil {
+		slog.Error("argon2 calibration failed", "error", err)
+		os.Exit(1)
+	}
+	fmt.Printf("ARGON2_MEMORY=%d\nARGON2_ITERATIONS=%d\nARGON2_PARALLELISM=%d\n# one hash took %v, target %v\n",
+		params.Memory, params.Iterations, params.Parallelism, timing.Duration.Round(time.Millisecond), target)
+}
+
+// checkHashTiming times one password hash with the configured parameters.
+// A slow result is a warning in development and fatal in production.
+func checkHashTiming(cfg config.Config) (crypto.HashTiming, error) {
+	timing, err := crypto.CheckHashTiming(cfg.Argon2Params, cfg.Argon2SlowThreshold)
+	if err != nil {
+		return timing, err
+	}
+
+	if timing.Slow {
+		attrs := []any{"duration", timing.Duration, "threshold", timing.Threshold}
+		if cfg.Env == "production" {
+			slog.Error("argon2 parameters exceed the configured hashing threshold", attrs...)
+			os.Exit(1)
+		}
+		slog.Warn("argon2 parameters exceed the configured hashing threshold — logins will be slow", attrs...)
+	} else {
+		slog.Info("argon2 self-test passed", "duration", timing.Duration)
+	}
+
+	return timing, nil
+}
+
+// newLogger builds the application logger writing to w, as JSON or text per
+// LOG_FORMAT and dropping records below LOG_LEVEL.
+func newLogger(w io.Writer, cfg config.Config) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: cfg.LogLevel}
+	if cfg.LogFormat == "json" {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// stores groups the persistence backends used by the services.
+type stores struct {
+	users    repository.UserStore
+	vaults   repository.CollectionStore
+	vault    repository.VaultStore
+	audit    repository.AuditStore
+	sessions repository.SessionStore
+	invites  repository.InviteStore
+}
+
+// newStores builds MySQL-backed stores, or in-memory ones when DB_DRIVER=memory.
+func newStores(cfg config.Config) (stores, error) {
+	if cfg.DBDriver == "memory" {
+		var memoryVaultOpts []repository.MemoryVaultOption
+		if cfg.VaultEraseOnDelete {
+			memoryVaultOpts = append(memoryVaultOpts, repository.WithMemoryEraseOnDelete())
+		}
+		slog.Warn("using in-memory storage — all data is lost on restart")
+		return stores{
+			users:    repository.NewMemoryUserRepository(),
+			vaults:   repository.NewMemoryCollectionRepository(),
+			vault:    repository.NewMemoryVaultRepository(memoryVaultOpts...),
+			audit:    repository.NewMemoryAuditRepository(),
+			sessions: repository.NewMemorySessionRepository(),
+			invites:  repository.NewMemoryInviteRepository(),
+		}, nil
+	}
+
+	db, err := repository.NewDB(cfg.DatabaseDSN, repository.WithPingRetry(cfg.DBConnectAttempts, cfg.DBConnectInterval))
+	if err != nil {
+		return stores{}, err
+	}
+
+	var vaultOpts []repository.VaultOption
+	if cfg.StorageCompression {
+		vaultOpts = append(vaultOpts, repository.WithCompression())
+	}
+	if cfg.StorageKey != nil {
+		aead, err := repository.NewBlobCipher(cfg.StorageKey)
+		if err != nil {
+			return stores{}, err
+		}
+		vaultOpts = append(vaultOpts, repository.WithEncryption(aead))
+	}
+	if cfg.VaultEraseOnDelete {
+		vaultOpts = append(vaultOpts, repository.WithEraseOnDelete())
+	}
+
+	slow := repository.NewSlowQueryLog(cfg.SlowQueryThreshold)
+	return stores{
+		users:    slow.Users(repository.NewUserRepository(db)),
+		vaults:   slow.Collections(repository.NewCollectionRepository(db)),
+		vault:    slow.Vault(repository.NewVaultRepository(db, vaultOpts...)),
+		audit:    slow.Audit(repository.NewAuditRepository(db)),
+		sessions: slow.Sessions(repository.NewSessionRepository(db)),
+		invites:  slow.Invites(repository.NewInviteRepository(db)),
+	}, nil
+}
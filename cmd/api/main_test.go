@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/vaultpass/vaultpass-go/internal/config"
+)
+
+func TestNewLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newLogger(&buf, config.Config{LogLevel: slog.LevelWarn, LogFormat: "json"})
+
+	logger.Info("dropped")
+	logger.Warn("kept", "key", "value")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected only the warning to be logged, got %q", buf.String())
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("expected a JSON record, got %q: %v", lines[0], err)
+	}
+	if record["msg"] != "kept" || record["level"] != "WARN" || record["key"] != "value" {
+		t.Errorf("unexpected record: %v", record)
+	}
+}
+
+func TestNewLogger_Text(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newLogger(&buf, config.Config{LogLevel: slog.LevelDebug, LogFormat: "text"})
+
+	logger.Debug("details", "key", "value")
+
+	out := buf.String()
+	if !strings.Contains(out, "level=DEBUG") || !strings.Contains(out, "msg=details") || !strings.Contains(out, "key=value") {
+		t.Errorf("expected a text record at debug level, got %q", out)
+	}
+	if json.Valid([]byte(strings.TrimSpace(out))) {
+		t.Errorf("expected text output, got JSON %q", out)
+	}
+}
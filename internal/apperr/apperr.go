@@ -0,0 +1,36 @@
+// Package apperr defines errors that carry the HTTP status and machine-readable
+// code they should be answered with, so services decide how a failure is
+// reported and handlers render it in one place.
+package apperr
+
+import "errors"
+
+// Error is a client-facing failure. Its message is safe to show to callers.
+type Error struct {
+	Status  int    // HTTP status code
+	Code    string // stable identifier such as "vault_not_found"
+	Message string
+	Err     error // wrapped cause, or nil
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// New returns an Error suitable for a package-level sentinel.
+func New(status int, code, message string) error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// Wrap gives err a status and code, keeping its message. errors.Is still
+// matches err through the result.
+func Wrap(status int, code string, err error) error {
+	return &Error{Status: status, Code: code, Message: err.Error(), Err: err}
+}
+
+// As returns the first Error in err's chain, if any.
+func As(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
@@ -0,0 +1,35 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestAs(t *testing.T) {
+	errGone := New(http.StatusNotFound, "gone", "it is gone")
+	e, ok := As(fmt.Errorf("lookup: %w", errGone))
+	if !ok || e.Status != http.StatusNotFound || e.Code != "gone" || e.Message != "it is gone" {
+		t.Errorf("As() = %+v, %v", e, ok)
+	}
+
+	if _, ok := As(errors.New("plain")); ok {
+		t.Error("expected a plain error not to be an Error")
+	}
+}
+
+func TestWrap(t *testing.T) {
+	cause := errors.New("account is deactivated")
+	err := Wrap(http.StatusForbidden, "account_deactivated", cause)
+
+	if !errors.Is(err, cause) {
+		t.Error("expected the wrapped cause to match errors.Is")
+	}
+	if err.Error() != cause.Error() {
+		t.Errorf("Error() = %q, want the cause's message", err.Error())
+	}
+	if e, _ := As(err); e.Status != http.StatusForbidden {
+		t.Errorf("Status = %d, want 403", e.Status)
+	}
+}
@@ -1,39 +1,707 @@
 package config
 
 import (
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"log/slog"
+	"math"
+	"net"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/crypto"
+)
+
+// minAdminTokenLength keeps ADMIN_TOKEN out of reach of guessing.
+const minAdminTokenLength = 32
+
+// defaultJWTSecret is the development JWT_SECRET. It is public, so tokens
+// signed with it can be forged.
+const defaultJWTSecret = "dev-secret-change-in-production"
+
+// minJWTSecretLength is the shortest HS256 secret accepted when
+// JWT_SECRET_ENFORCE is fail.
+const minJWTSecretLength = 32
+
+var (
+	errJWTSecretDefault = errors.New("JWT_SECRET is the insecure default")
+	errJWTSecretShort   = errors.New("JWT_SECRET is shorter than 32 bytes")
 )
 
 type Config struct {
-	Port        string
-	Env         string
-	DatabaseDSN string
-	JWTSecret   string
-	JWTExpiry   time.Duration
+	Port                 string
+	Env                  string
+	LogLevel             slog.Level
+	LogFormat            string // "text" or "json"
+	LogSampleRate        int    // log 1 in N successful requests
+	DBDriver             string
+	DatabaseDSN          string
+	DBConnectAttempts    int
+	DBConnectInterval    time.Duration
+	SlowQueryThreshold   time.Duration
+	JWTSecret            string
+	JWTSecretEnforce     string // "off", "warn" or "fail"
+	JWTOldSecrets        []string
+	JWTExpiry            time.Duration
+	JWTExpiryRemember    time.Duration // lifetime of "remember me" tokens
+	SessionIdleTimeout   time.Duration // longest a session may go unused; zero disables it
+	JWTClockSkew         time.Duration
+	JWTIssuer            string
+	JWTAudience          string
+	JWTAcceptedIssuers   []string
+	JWTAcceptedAudiences []string
+	JWTSigningMethod     string
+	JWTPrivateKeyFile    string
+	JWTKeyID             string
+	StorageCompression   bool
+	StorageKey           []byte
+	Argon2SlowThreshold  time.Duration
+	Argon2Params         crypto.HashParams // cost of new password hashes
+	Argon2Limits         crypto.HashLimits // bounds on the parameters of stored hashes
+	PasswordMinLength    int
+	PasswordMaxLength    int
+	GenerateDefaults     crypto.GeneratorOptions
+	GenerateDenylist     []string // words generated passwords must not contain
+	ReadHeaderTimeout    time.Duration
+	ReadTimeout          time.Duration
+	WriteTimeout         time.Duration
+	IdleTimeout          time.Duration
+	RequestTimeout       time.Duration // handler deadline, except for sync, import and export; zero disables it
+	ClientTimeoutMax     time.Duration // cap on X-Request-Timeout, on every route; zero ignores the header
+	StrictJSON           bool          // reject request bodies with unknown fields
+	TLSCertFile          string
+	TLSKeyFile           string
+	TLSMinVersion        uint16 // a crypto/tls version constant
+	HTTPRedirectPort     string // plain HTTP port redirecting to HTTPS; empty disables it
+	MaxInFlight          int
+	MaxSyncEntries       int
+	SyncTxTimeout        time.Duration // how long a sync upload may hold its transaction open; zero disables it
+	MaxBodyAuth          int64
+	MaxBodyVault         int64
+	VaultMaxEntries      int   // live entries per user across all vaults; 0 means no limit
+	VaultMaxEntryBytes   int64 // decoded blob size per entry; 0 means no limit
+	GenerateRecovery     bool
+	GenerateRecoveryTTL  time.Duration
+	ExpiryPurgeInterval  time.Duration
+	VaultHardWipe        bool
+	VaultEraseOnDelete   bool
+	RegistrationOpen     bool
+	InviteOnly           bool
+	AdminToken           string
+	CommonPasswordCheck  bool     // reject registrations using a common password
+	CommonPasswords      []string // added to the built-in common password list
+	BreachCheck          bool
+	BreachCheckEnforce   bool
+	BreachCheckURL       string
+	BreachCheckTimeout   time.Duration
+
+	// Per route group rate limits and CORS origins.
+	GenerateRoutes RoutePolicy
+	AuthRoutes     RoutePolicy
+	VaultRoutes    RoutePolicy
+
+	// CheckEmailRoutes is AuthRoutes with a much stricter rate limit, which
+	// can't be disabled, for the email availability check.
+	CheckEmailRoutes RoutePolicy
+
+	// RateLimitExempt lists client networks that no route group rate-limits.
+	RateLimitExempt []*net.IPNet
+
+	// AllowedHosts lists the Host header values the server answers to, with
+	// "*.example.com" matching any subdomain. Empty allows every host.
+	AllowedHosts []string
+}
+
+// RoutePolicy configures the rate limit and CORS origins for one group of routes.
+// A zero RateLimitRPS disables rate limiting; no origins disables CORS.
+// A non-zero RateLimitWindow swaps the token bucket for a fixed-window counter
+// allowing RateLimitRPS*RateLimitWindow requests per window. A non-zero
+// UserRateLimitRPS limits authenticated callers per user instead of per IP.
+type RoutePolicy struct {
+	RateLimitRPS       float64
+	RateLimitBurst     int
+	RateLimitWindow    time.Duration
+	UserRateLimitRPS   float64
+	UserRateLimitBurst int
+	CORSOrigins        []string
+
+	// CORSAllowCredentials sends Access-Control-Allow-Credentials; it can't be
+	// combined with "*" origins. CORSExposeHeaders are readable by scripts in
+	// addition to ETag, Retry-After and X-Request-ID.
+	CORSAllowCredentials bool
+	CORSExposeHeaders    []string
 }
 
 func Load() Config {
 	cfg := Config{
 		Port:        getEnv("PORT", "8080"),
 		Env:         getEnv("ENV", "development"),
+		DBDriver:    getEnv("DB_DRIVER", "mysql"),
 		DatabaseDSN: getEnv("DATABASE_DSN", "root:password@tcp(127.0.0.1:3306)/vaultpass?parseTime=true"),
-		JWTSecret:   getEnv("JWT_SECRET", "dev-secret-change-in-production"),
+		JWTSecret:   getEnv("JWT_SECRET", defaultJWTSecret),
 		JWTExpiry:   24 * time.Hour,
+		JWTIssuer:   getEnv("JWT_ISSUER", "vaultpass"),
+		JWTAudience: getEnv("JWT_AUDIENCE", "vaultpass-api"),
+
+		JWTSigningMethod:  getEnv("JWT_SIGNING_METHOD", "HS256"),
+		JWTPrivateKeyFile: getEnv("JWT_PRIVATE_KEY_FILE", ""),
+		JWTKeyID:          getEnv("JWT_KEY_ID", ""),
+
+		TLSCertFile:      getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:       getEnv("TLS_KEY_FILE", ""),
+		HTTPRedirectPort: getEnv("HTTP_REDIRECT_PORT", ""),
+
+		StorageCompression: getEnv("STORAGE_COMPRESSION", "false") == "true",
+		GenerateRecovery:   getEnv("GENERATE_RECOVERY", "false") == "true",
+		RegistrationOpen:   getEnv("REGISTRATION_OPEN", "true") == "true",
+		InviteOnly:         getEnv("REGISTRATION_INVITE_ONLY", "false") == "true",
+		AdminToken:         getEnv("ADMIN_TOKEN", ""),
+		BreachCheck:        getEnv("BREACH_CHECK", "false") == "true",
+		BreachCheckEnforce: getEnv("BREACH_CHECK_ENFORCE", "false") == "true",
+		BreachCheckURL:     getEnv("BREACH_CHECK_URL", crypto.DefaultBreachRangeURL),
+	}
+	cfg.LogLevel = getLogLevel()
+	cfg.LogFormat = getLogFormat(cfg.Env)
+	cfg.LogSampleRate = getEnvInt("LOG_SAMPLE_RATE", 1)
+	if cfg.LogSampleRate < 1 {
+		slog.Error("LOG_SAMPLE_RATE must be at least 1", "value", cfg.LogSampleRate)
+		os.Exit(1)
+	}
+	cfg.StorageKey = getEnvKey("STORAGE_KEY")
+	cfg.DBConnectAttempts = getEnvInt("DB_CONNECT_ATTEMPTS", 5)
+	cfg.DBConnectInterval = getEnvDuration("DB_CONNECT_INTERVAL", time.Second)
+	cfg.SlowQueryThreshold = getEnvDuration("SLOW_QUERY_THRESHOLD", 250*time.Millisecond)
+	cfg.Argon2SlowThreshold = getEnvDuration("ARGON2_SLOW_THRESHOLD", 500*time.Millisecond)
+	cfg.Argon2Limits = LoadArgon2Limits()
+	cfg.Argon2Params = getArgon2Params(cfg.Argon2Limits)
+	cfg.JWTClockSkew = getEnvDuration("JWT_CLOCK_SKEW", crypto.DefaultLeeway)
+	cfg.JWTExpiryRemember = getEnvDuration("JWT_EXPIRY_REMEMBER", 30*24*time.Hour)
+	if cfg.JWTExpiryRemember < cfg.JWTExpiry {
+		slog.Error("JWT_EXPIRY_REMEMBER must not be shorter than the 24h token lifetime", "value", cfg.JWTExpiryRemember)
+		os.Exit(1)
+	}
+	cfg.SessionIdleTimeout = getEnvDuration("SESSION_IDLE_TIMEOUT", 0)
+	if cfg.SessionIdleTimeout > 0 && cfg.SessionIdleTimeout < time.Second {
+		slog.Error("SESSION_IDLE_TIMEOUT must be at least 1s", "value", cfg.SessionIdleTimeout)
+		os.Exit(1)
+	}
+	cfg.ReadHeaderTimeout = getEnvDuration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second)
+	cfg.ReadTimeout = getEnvDuration("HTTP_READ_TIMEOUT", 15*time.Second)
+	cfg.WriteTimeout = getEnvDuration("HTTP_WRITE_TIMEOUT", 30*time.Second)
+	cfg.IdleTimeout = getEnvDuration("HTTP_IDLE_TIMEOUT", 60*time.Second)
+	cfg.RequestTimeout = getEnvOptionalDuration("HTTP_REQUEST_TIMEOUT", 20*time.Second)
+	cfg.ClientTimeoutMax = getEnvOptionalDuration("HTTP_CLIENT_TIMEOUT_MAX", 60*time.Second)
+	cfg.StrictJSON = getEnv("STRICT_JSON", "false") == "true"
+	cfg.TLSMinVersion = getTLSMinVersion()
+	cfg.MaxInFlight = getEnvInt("MAX_IN_FLIGHT", 100)
+	cfg.MaxSyncEntries = getEnvInt("MAX_SYNC_ENTRIES", 1000)
+	cfg.SyncTxTimeout = getEnvOptionalDuration("SYNC_TX_TIMEOUT", 10*time.Second)
+	cfg.MaxBodyAuth = getEnvBytes("MAX_BODY_AUTH", 1<<20)
+	cfg.MaxBodyVault = getEnvBytes("MAX_BODY_VAULT", 10<<20)
+	cfg.VaultMaxEntries = getEnvInt("VAULT_MAX_ENTRIES", 0)
+	cfg.VaultMaxEntryBytes = getEnvBytes("VAULT_MAX_ENTRY_BYTES", 0)
+	cfg.GenerateRecoveryTTL = getEnvDuration("GENERATE_RECOVERY_TTL", 5*time.Minute)
+	cfg.ExpiryPurgeInterval = getEnvDuration("VAULT_EXPIRY_PURGE_INTERVAL", time.Hour)
+	cfg.VaultHardWipe = getVaultWipeMode() == "hard"
+	cfg.VaultEraseOnDelete = getEnv("VAULT_ERASE_ON_DELETE", "false") == "true"
+	cfg.BreachCheckTimeout = getEnvDuration("BREACH_CHECK_TIMEOUT", crypto.DefaultBreachTimeout)
+	cfg.PasswordMinLength = getEnvInt("PASSWORD_MIN_LENGTH", 8)
+	cfg.PasswordMaxLength = getEnvInt("PASSWORD_MAX_LENGTH", 128)
+	cfg.GenerateDefaults = getGenerateDefaults(cfg.PasswordMinLength, cfg.PasswordMaxLength)
+	cfg.GenerateDenylist = getEnvList("GENERATE_DENYLIST", nil)
+	cfg.CommonPasswordCheck = getEnv("COMMON_PASSWORD_CHECK", "true") == "true"
+	cfg.CommonPasswords = getEnvList("COMMON_PASSWORDS", nil)
+	cfg.GenerateRoutes = getRoutePolicy("GENERATE", 0, 0)
+	cfg.GenerateRoutes.UserRateLimitRPS = getEnvFloat("GENERATE_USER_RATE_LIMIT_RPS", 0)
+	cfg.GenerateRoutes.UserRateLimitBurst = getEnvInt("GENERATE_USER_RATE_LIMIT_BURST", 0)
+	cfg.AuthRoutes = getRoutePolicy("AUTH", 5, 10)
+	cfg.VaultRoutes = getRoutePolicy("VAULT", 0, 0)
+	if window := getRateLimitWindow(); window > 0 {
+		cfg.GenerateRoutes.RateLimitWindow = window
+		cfg.AuthRoutes.RateLimitWindow = window
+		cfg.VaultRoutes.RateLimitWindow = window
+	}
+	cfg.CheckEmailRoutes = cfg.AuthRoutes
+	cfg.CheckEmailRoutes.RateLimitRPS = getEnvFloat("CHECK_EMAIL_RATE_LIMIT_RPS", 0.1)
+	cfg.CheckEmailRoutes.RateLimitBurst = getEnvInt("CHECK_EMAIL_RATE_LIMIT_BURST", 3)
+	cfg.RateLimitExempt = getEnvCIDRs("RATE_LIMIT_EXEMPT_CIDRS")
+	cfg.AllowedHosts = getEnvList("ALLOWED_HOSTS", nil)
+	cfg.JWTSecretEnforce = getJWTSecretEnforce(cfg.Env)
+	cfg.JWTOldSecrets = getEnvList("JWT_SECRET_OLD", nil)
+	cfg.JWTAcceptedIssuers = getEnvList("JWT_ACCEPTED_ISSUERS", []string{cfg.JWTIssuer})
+	cfg.JWTAcceptedAudiences = getEnvList("JWT_ACCEPTED_AUDIENCES", []string{cfg.JWTAudience})
+
+	if cfg.DBDriver != "mysql" && cfg.DBDriver != "memory" {
+		slog.Error("DB_DRIVER must be mysql or memory", "value", cfg.DBDriver)
+		os.Exit(1)
+	}
+
+	for _, host := range cfg.AllowedHosts {
+		if strings.Contains(strings.TrimPrefix(host, "*."), "*") {
+			slog.Error("ALLOWED_HOSTS wildcards must be a leading *. label", "value", host)
+			os.Exit(1)
+		}
+	}
+
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		slog.Error("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+		os.Exit(1)
+	}
+	if cfg.HTTPRedirectPort != "" && (cfg.TLSCertFile == "" || cfg.HTTPRedirectPort == cfg.Port) {
+		slog.Error("HTTP_REDIRECT_PORT requires TLS_CERT_FILE and must differ from PORT", "value", cfg.HTTPRedirectPort)
+		os.Exit(1)
+	}
+
+	if cfg.DBConnectAttempts < 1 {
+		slog.Error("DB_CONNECT_ATTEMPTS must be at least 1", "value", cfg.DBConnectAttempts)
+		os.Exit(1)
+	}
+
+	bounds := crypto.LengthBounds{Min: cfg.PasswordMinLength, Max: cfg.PasswordMaxLength}
+	if err := bounds.Validate(); err != nil {
+		slog.Error("invalid PASSWORD_MIN_LENGTH/PASSWORD_MAX_LENGTH", "error", err, "min", bounds.Min, "max", bounds.Max)
+		os.Exit(1)
+	}
+
+	if l := cfg.GenerateDefaults.Length; l < bounds.Min || l > bounds.Max {
+		slog.Error("GENERATE_DEFAULT_LENGTH must be within PASSWORD_MIN_LENGTH and PASSWORD_MAX_LENGTH",
+			"value", l, "min", bounds.Min, "max", bounds.Max)
+		os.Exit(1)
+	}
+
+	if d := cfg.GenerateDefaults; !d.Uppercase && !d.Lowercase && !d.Numbers && !d.Symbols {
+		slog.Error("GENERATE_DEFAULT_CLASSES and GENERATE_DEFAULT_<CLASS> must leave at least one character type enabled")
+		os.Exit(1)
+	}
+
+	if _, err := crypto.NewDenylist(cfg.GenerateDenylist); err != nil {
+		slog.Error("GENERATE_DENYLIST words must be at least 3 characters", "error", err)
+		os.Exit(1)
+	}
+
+	if gr := cfg.GenerateRoutes; gr.UserRateLimitRPS < 0 || (gr.UserRateLimitRPS > 0 && gr.UserRateLimitBurst < 1) {
+		slog.Error("GENERATE_USER_RATE_LIMIT_RPS must be >= 0 and GENERATE_USER_RATE_LIMIT_BURST at least 1 when limiting",
+			"rps", gr.UserRateLimitRPS, "burst", gr.UserRateLimitBurst)
+		os.Exit(1)
+	}
+
+	if ce := cfg.CheckEmailRoutes; ce.RateLimitRPS <= 0 || ce.RateLimitBurst < 1 {
+		slog.Error("CHECK_EMAIL_RATE_LIMIT_RPS must be positive and CHECK_EMAIL_RATE_LIMIT_BURST at least 1",
+			"rps", ce.RateLimitRPS, "burst", ce.RateLimitBurst)
+		os.Exit(1)
 	}
 
-	if cfg.Env == "production" && cfg.JWTSecret == "dev-secret-change-in-production" {
-		slog.Error("JWT_SECRET must be set in production environment")
+	if cfg.MaxSyncEntries < 1 {
+		slog.Error("MAX_SYNC_ENTRIES must be positive", "value", cfg.MaxSyncEntries)
+		os.Exit(1)
+	}
+
+	if cfg.VaultMaxEntries < 0 {
+		slog.Error("VAULT_MAX_ENTRIES must not be negative", "value", cfg.VaultMaxEntries)
+		os.Exit(1)
+	}
+
+	if cfg.GenerateRecoveryTTL <= 0 || cfg.GenerateRecoveryTTL > 15*time.Minute {
+		slog.Error("GENERATE_RECOVERY_TTL must be between 0 and 15m", "value", cfg.GenerateRecoveryTTL)
+		os.Exit(1)
+	}
+
+	if cfg.InviteOnly && (!cfg.RegistrationOpen || cfg.AdminToken == "") {
+		slog.Error("REGISTRATION_INVITE_ONLY requires REGISTRATION_OPEN=true and an ADMIN_TOKEN to mint codes")
+		os.Exit(1)
+	}
+	if cfg.AdminToken != "" && len(cfg.AdminToken) < minAdminTokenLength {
+		slog.Error("ADMIN_TOKEN is too short", "min_length", minAdminTokenLength)
 		os.Exit(1)
 	}
 
+	if cfg.BreachCheckEnforce && !cfg.BreachCheck {
+		slog.Error("BREACH_CHECK_ENFORCE requires BREACH_CHECK=true")
+		os.Exit(1)
+	}
+
+	if cfg.JWTSigningMethod != "HS256" && cfg.JWTSigningMethod != "RS256" {
+		slog.Error("JWT_SIGNING_METHOD must be HS256 or RS256", "value", cfg.JWTSigningMethod)
+		os.Exit(1)
+	}
+	if cfg.JWTSigningMethod == "RS256" && cfg.JWTPrivateKeyFile == "" {
+		slog.Error("JWT_PRIVATE_KEY_FILE is required when JWT_SIGNING_METHOD is RS256")
+		os.Exit(1)
+	}
+
+	if cfg.JWTSigningMethod == "HS256" {
+		fatal, err := checkJWTSecret(cfg.JWTSecretEnforce, cfg.JWTSecret)
+		if fatal {
+			slog.Error("refusing to start with a weak JWT_SECRET", "error", err, "enforce", cfg.JWTSecretEnforce)
+			os.Exit(1)
+		}
+		if err != nil {
+			slog.Warn("weak JWT_SECRET: tokens may be forged", "error", err, "enforce", cfg.JWTSecretEnforce)
+		}
+	}
+
 	return cfg
 }
 
+// getRoutePolicy reads <PREFIX>_RATE_LIMIT_RPS, <PREFIX>_RATE_LIMIT_BURST and
+// the <PREFIX>_CORS_* settings, exiting if the limit is malformed or
+// credentials are allowed for any origin.
+func getRoutePolicy(prefix string, rps float64, burst int) RoutePolicy {
+	p := RoutePolicy{
+		RateLimitRPS:         getEnvFloat(prefix+"_RATE_LIMIT_RPS", rps),
+		RateLimitBurst:       getEnvInt(prefix+"_RATE_LIMIT_BURST", burst),
+		CORSOrigins:          getEnvList(prefix+"_CORS_ORIGINS", nil),
+		CORSAllowCredentials: getEnv(prefix+"_CORS_ALLOW_CREDENTIALS", "false") == "true",
+		CORSExposeHeaders:    getEnvList(prefix+"_CORS_EXPOSE_HEADERS", nil),
+	}
+	if p.RateLimitRPS < 0 || (p.RateLimitRPS > 0 && p.RateLimitBurst < 1) {
+		slog.Error(prefix+"_RATE_LIMIT_RPS must be >= 0 and "+prefix+"_RATE_LIMIT_BURST at least 1 when limiting",
+			"rps", p.RateLimitRPS, "burst", p.RateLimitBurst)
+		os.Exit(1)
+	}
+	if p.CORSAllowCredentials && slices.Contains(p.CORSOrigins, "*") {
+		slog.Error(prefix + "_CORS_ALLOW_CREDENTIALS requires " + prefix + "_CORS_ORIGINS to list specific origins, not *")
+		os.Exit(1)
+	}
+	return p
+}
+
+// getRateLimitWindow reads RATE_LIMIT_ALGORITHM and, for fixed_window,
+// RATE_LIMIT_WINDOW. It returns zero for the default token bucket.
+func getRateLimitWindow() time.Duration {
+	switch algo := getEnv("RATE_LIMIT_ALGORITHM", "token_bucket"); algo {
+	case "token_bucket":
+		return 0
+	case "fixed_window":
+		return getEnvDuration("RATE_LIMIT_WINDOW", time.Minute)
+	default:
+		slog.Error("RATE_LIMIT_ALGORITHM must be token_bucket or fixed_window", "value", algo)
+		os.Exit(1)
+		return 0
+	}
+}
+
+// getTLSMinVersion reads TLS_MIN_VERSION, exiting unless it is 1.2 or 1.3.
+func getTLSMinVersion() uint16 {
+	switch v := getEnv("TLS_MIN_VERSION", "1.2"); v {
+	case "1.2":
+		return tls.VersionTLS12
+	case "1.3":
+		return tls.VersionTLS13
+	default:
+		slog.Error("TLS_MIN_VERSION must be 1.2 or 1.3", "value", v)
+		os.Exit(1)
+		return 0
+	}
+}
+
+// getLogLevel reads LOG_LEVEL (debug, info, warn or error, optionally with an
+// offset such as "info+2"), exiting if it is malformed.
+func getLogLevel() slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(getEnv("LOG_LEVEL", "info"))); err != nil {
+		slog.Error("LOG_LEVEL must be debug, info, warn or error", "error", err)
+		os.Exit(1)
+	}
+	return level
+}
+
+// getLogFormat reads LOG_FORMAT, which defaults to json in production and text
+// elsewhere, exiting unless it is text or json.
+func getLogFormat(env string) string {
+	fallback := "text"
+	if env == "production" {
+		fallback = "json"
+	}
+	format := strings.ToLower(getEnv("LOG_FORMAT", fallback))
+	if format != "text" && format != "json" {
+		slog.Error("LOG_FORMAT must be text or json", "value", format)
+		os.Exit(1)
+	}
+	return format
+}
+
+// getGenerateDefaults reads the generator defaults. The length falls back to
+// 16 fitted into the configured bounds, and GENERATE_DEFAULT_CLASSES lists the
+// character classes enabled by default. GENERATE_DEFAULT_UPPERCASE, _LOWERCASE,
+// _NUMBERS and _SYMBOLS, when set to true or false, override single classes.
+func getGenerateDefaults(minLength, maxLength int) crypto.GeneratorOptions {
+	defaults := crypto.DefaultOptions()
+	defaults.Length = getEnvInt("GENERATE_DEFAULT_LENGTH", min(max(defaults.Length, minLength), maxLength))
+
+	classes := getEnvList("GENERATE_DEFAULT_CLASSES", []string{"uppercase", "lowercase", "numbers", "symbols"})
+	defaults.Uppercase, defaults.Lowercase, defaults.Numbers, defaults.Symbols = false, false, false, false
+	for _, class := range classes {
+		switch class {
+		case "uppercase":
+			defaults.Uppercase = true
+		case "lowercase":
+			defaults.Lowercase = true
+		case "numbers":
+			defaults.Numbers = true
+		case "symbols":
+			defaults.Symbols = true
+		default:
+			slog.Error("GENERATE_DEFAULT_CLASSES entries must be uppercase, lowercase, numbers or symbols", "value", class)
+			os.Exit(1)
+		}
+	}
+
+	for key, class := range map[string]*bool{
+		"GENERATE_DEFAULT_UPPERCASE": &defaults.Uppercase,
+		"GENERATE_DEFAULT_LOWERCASE": &defaults.Lowercase,
+		"GENERATE_DEFAULT_NUMBERS":   &defaults.Numbers,
+		"GENERATE_DEFAULT_SYMBOLS":   &defaults.Symbols,
+	} {
+		switch v := os.Getenv(key); v {
+		case "":
+		case "true", "false":
+			*class = v == "true"
+		default:
+			slog.Error(key+" must be true or false", "value", v)
+			os.Exit(1)
+		}
+	}
+	return defaults
+}
+
+// LoadArgon2Limits reads the bounds on the Argon2id cost a stored hash may
+// demand at login, starting from crypto.DefaultHashLimits, and exits if any is
+// not positive. It is exported for -calibrate-argon2, which runs without the
+// rest of the config.
+func LoadArgon2Limits() crypto.HashLimits {
+	l := crypto.DefaultHashLimits()
+	memory := getEnvInt("ARGON2_MAX_MEMORY", int(l.MaxMemory))
+	iterations := getEnvInt("ARGON2_MAX_ITERATIONS", int(l.MaxIterations))
+	parallelism := getEnvInt("ARGON2_MAX_PARALLELISM", int(l.MaxParallelism))
+	if memory <= 0 || uint64(memory) > math.MaxUint32 || iterations <= 0 || uint64(iterations) > math.MaxUint32 ||
+		parallelism <= 0 || parallelism > math.MaxUint8 {
+		slog.Error("ARGON2_MAX_MEMORY, ARGON2_MAX_ITERATIONS and ARGON2_MAX_PARALLELISM must be positive and in range",
+			"memory", memory, "iterations", iterations, "parallelism", parallelism)
+		os.Exit(1)
+	}
+	l.MaxMemory, l.MaxIterations, l.MaxParallelism = uint32(memory), uint32(iterations), uint8(parallelism)
+	return l
+}
+
+// getArgon2Params reads the Argon2id cost of new password hashes, starting
+// from crypto.DefaultHashParams. It exits if the parameters fall outside
+// limits, since hashes made with them would not verify.
+func getArgon2Params(limits crypto.HashLimits) crypto.HashParams {
+	p := crypto.DefaultHashParams()
+	memory := getEnvInt("ARGON2_MEMORY", int(p.Memory))
+	iterations := getEnvInt("ARGON2_ITERATIONS", int(p.Iterations))
+	parallelism := getEnvInt("ARGON2_PARALLELISM", int(p.Parallelism))
+	if memory <= 0 || uint64(memory) > math.MaxUint32 || iterations <= 0 || uint64(iterations) > math.MaxUint32 ||
+		parallelism <= 0 || parallelism > math.MaxUint8 {
+		slog.Error("ARGON2_MEMORY, ARGON2_ITERATIONS and ARGON2_PARALLELISM must be positive and in range",
+			"memory", memory, "iterations", iterations, "parallelism", parallelism)
+		os.Exit(1)
+	}
+	p.Memory, p.Iterations, p.Parallelism = uint32(memory), uint32(iterations), uint8(parallelism)
+
+	if err := checkArgon2Params(p, limits); err != nil {
+		slog.Error("invalid Argon2 parameters", "error", err)
+		os.Exit(1)
+	}
+	return p
+}
+
+// checkArgon2Params reports whether p can be used for new hashes that verify
+// under limits. Argon2 needs at least 8 KiB of memory per lane.
+func checkArgon2Params(p crypto.HashParams, limits crypto.HashLimits) error {
+	if p.Memory < 8*uint32(p.Parallelism) {
+		return fmt.Errorf("ARGON2_MEMORY must be at least 8 KiB per lane, %d KiB for parallelism %d", 8*uint32(p.Parallelism), p.Parallelism)
+	}
+	return limits.Check(p)
+}
+
+// getVaultWipeMode reads VAULT_WIPE_MODE, exiting unless it is soft or hard.
+func getVaultWipeMode() string {
+	mode := getEnv("VAULT_WIPE_MODE", "soft")
+	if mode != "soft" && mode != "hard" {
+		slog.Error("VAULT_WIPE_MODE must be soft or hard", "value", mode)
+		os.Exit(1)
+	}
+	return mode
+}
+
+// getJWTSecretEnforce reads JWT_SECRET_ENFORCE, exiting unless it is off, warn
+// or fail. It defaults to warn, and production always uses fail.
+func getJWTSecretEnforce(env string) string {
+	mode := getEnv("JWT_SECRET_ENFORCE", "warn")
+	if mode != "off" && mode != "warn" && mode != "fail" {
+		slog.Error("JWT_SECRET_ENFORCE must be off, warn or fail", "value", mode)
+		os.Exit(1)
+	}
+	if env == "production" {
+		return "fail"
+	}
+	return mode
+}
+
+// checkJWTSecret applies a JWT_SECRET_ENFORCE mode to an HS256 secret. err
+// says why the secret is weak, or is nil when it is acceptable or mode is off;
+// fatal reports whether mode refuses it.
+func checkJWTSecret(mode, secret string) (fatal bool, err error) {
+	if mode == "off" {
+		return false, nil
+	}
+	switch {
+	case secret == defaultJWTSecret:
+		err = errJWTSecretDefault
+	case len(secret) < minJWTSecretLength:
+		err = errJWTSecretShort
+	}
+	return err != nil && mode == "fail", err
+}
+
+// getEnvCIDRs reads a comma-separated list of IPv4/IPv6 CIDRs, exiting if one
+// is malformed. A bare IP is treated as a single-address network.
+func getEnvCIDRs(key string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, item := range getEnvList(key, nil) {
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				slog.Error(key+" must be a comma-separated list of IPs or CIDRs", "value", item)
+				os.Exit(1)
+			}
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(item)
+		if err != nil {
+			slog.Error(key+" must be a comma-separated list of IPs or CIDRs", "value", item)
+			os.Exit(1)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// getEnvKey reads a base64-encoded 32-byte key, exiting if it is malformed.
+func getEnvKey(key string) []byte {
+	v := os.Getenv(key)
+	if v == "" {
+		return nil
+	}
+	b, err := base64.StdEncoding.DecodeString(v)
+	if err != nil || len(b) != 32 {
+		slog.Error(key + " must be 32 bytes, base64-encoded")
+		os.Exit(1)
+	}
+	return b
+}
+
+// getEnvDuration reads a Go duration string such as "500ms", exiting if it is malformed.
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		slog.Error(key+" must be a positive duration", "value", v)
+		os.Exit(1)
+	}
+	return d
+}
+
+// getEnvOptionalDuration is getEnvDuration for settings where zero turns the
+// feature off. It still exits on negative or malformed values.
+func getEnvOptionalDuration(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		slog.Error(key+" must be a duration, or 0 to disable it", "value", v)
+		os.Exit(1)
+	}
+	return d
+}
+
+// getEnvBytes reads a positive byte size, either a plain number of bytes or a
+// number with a KB, MB or GB suffix (powers of 1024), exiting if it is malformed.
+func getEnvBytes(key string, fallback int64) int64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	num, unit := strings.ToUpper(strings.TrimSpace(v)), int64(1)
+	for suffix, size := range map[string]int64{"KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30} {
+		if strings.HasSuffix(num, suffix) {
+			num, unit = strings.TrimSpace(strings.TrimSuffix(num, suffix)), size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n <= 0 || n > math.MaxInt64/unit {
+		slog.Error(key+" must be a positive size in bytes, KB, MB or GB", "value", v)
+		os.Exit(1)
+	}
+	return n * unit
+}
+
+// getEnvInt reads an integer, exiting if it is malformed.
+func getEnvInt(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		slog.Error(key+" must be an integer", "value", v)
+		os.Exit(1)
+	}
+	return n
+}
+
+// getEnvFloat reads a decimal number, exiting if it is malformed.
+func getEnvFloat(key string, fallback float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		slog.Error(key+" must be a number", "value", v)
+		os.Exit(1)
+	}
+	return f
+}
+
 func getEnv(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
 	}
 	return fallback
 }
+
+// getEnvList reads a comma-separated list, ignoring empty items.
+func getEnvList(key string, fallback []string) []string {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		return fallback
+	}
+	return items
+}
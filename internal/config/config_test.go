@@ -0,0 +1,130 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/crypto"
+)
+
+func TestCheckJWTSecret(t *testing.T) {
+	strong := strings.Repeat("k", minJWTSecretLength)
+	short := strong[1:]
+
+	tests := []struct {
+		mode, name, secret string
+		wantFatal          bool
+		wantErr            error
+	}{
+		{"off", "default", defaultJWTSecret, false, nil},
+		{"off", "short", short, false, nil},
+		{"warn", "default", defaultJWTSecret, false, errJWTSecretDefault},
+		{"warn", "short", short, false, errJWTSecretShort},
+		{"warn", "strong", strong, false, nil},
+		{"fail", "default", defaultJWTSecret, true, errJWTSecretDefault},
+		{"fail", "short", short, true, errJWTSecretShort},
+		{"fail", "strong", strong, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode+"/"+tt.name, func(t *testing.T) {
+			fatal, err := checkJWTSecret(tt.mode, tt.secret)
+			if fatal != tt.wantFatal || !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkJWTSecret(%q) = %v, %v; want %v, %v", tt.mode, fatal, err, tt.wantFatal, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetJWTSecretEnforce(t *testing.T) {
+	t.Setenv("JWT_SECRET_ENFORCE", "")
+	if got := getJWTSecretEnforce("development"); got != "warn" {
+		t.Errorf("expected warn by default, got %q", got)
+	}
+
+	t.Setenv("JWT_SECRET_ENFORCE", "fail")
+	if got := getJWTSecretEnforce("staging"); got != "fail" {
+		t.Errorf("expected fail outside production when set, got %q", got)
+	}
+
+	t.Setenv("JWT_SECRET_ENFORCE", "off")
+	if got := getJWTSecretEnforce("production"); got != "fail" {
+		t.Errorf("expected production to always fail, got %q", got)
+	}
+}
+
+func TestGetGenerateDefaults_ClassOverrides(t *testing.T) {
+	t.Setenv("GENERATE_DEFAULT_CLASSES", "uppercase,lowercase,numbers")
+	t.Setenv("GENERATE_DEFAULT_SYMBOLS", "true")
+	t.Setenv("GENERATE_DEFAULT_NUMBERS", "false")
+
+	d := getGenerateDefaults(8, 128)
+	if !d.Uppercase || !d.Lowercase || d.Numbers || !d.Symbols {
+		t.Errorf("expected the per-class settings to override the list, got %+v", d)
+	}
+
+	t.Setenv("GENERATE_DEFAULT_SYMBOLS", "")
+	t.Setenv("GENERATE_DEFAULT_NUMBERS", "")
+	if d := getGenerateDefaults(8, 128); !d.Numbers || d.Symbols {
+		t.Errorf("expected the class list alone when no override is set, got %+v", d)
+	}
+}
+
+func TestGetEnvOptionalDuration(t *testing.T) {
+	t.Setenv("TEST_TIMEOUT", "")
+	if got := getEnvOptionalDuration("TEST_TIMEOUT", 5*time.Second); got != 5*time.Second {
+		t.Errorf("expected the fallback when unset, got %v", got)
+	}
+
+	for v, want := range map[string]time.Duration{"0": 0, "0s": 0, "750ms": 750 * time.Millisecond} {
+		t.Setenv("TEST_TIMEOUT", v)
+		if got := getEnvOptionalDuration("TEST_TIMEOUT", 5*time.Second); got != want {
+			t.Errorf("%q: expected %v, got %v", v, want, got)
+		}
+	}
+}
+
+func TestCheckArgon2Params(t *testing.T) {
+	limits := crypto.DefaultHashLimits()
+	with := func(memory, iterations uint32, parallelism uint8) crypto.HashParams {
+		p := crypto.DefaultHashParams()
+		p.Memory, p.Iterations, p.Parallelism = memory, iterations, parallelism
+		return p
+	}
+
+	if err := checkArgon2Params(crypto.DefaultHashParams(), limits); err != nil {
+		t.Errorf("defaults: unexpected error %v", err)
+	}
+	if err := checkArgon2Params(with(128*1024, 4, 4), limits); err != nil {
+		t.Errorf("calibrated: unexpected error %v", err)
+	}
+	for name, p := range map[string]crypto.HashParams{
+		"too little memory per lane": with(16, 3, 4),
+		"memory over the limit":      with(limits.MaxMemory+1, 3, 2),
+		"iterations over the limit":  with(64*1024, limits.MaxIterations+1, 2),
+		"parallelism over the limit": with(64*1024, 3, limits.MaxParallelism+1),
+	} {
+		if err := checkArgon2Params(p, limits); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLoadArgon2Limits(t *testing.T) {
+	t.Setenv("ARGON2_MAX_MEMORY", "")
+	if got := LoadArgon2Limits(); got != crypto.DefaultHashLimits() {
+		t.Errorf("unset: expected the default limits, got %+v", got)
+	}
+
+	t.Setenv("ARGON2_MAX_MEMORY", "262144")
+	t.Setenv("ARGON2_MAX_ITERATIONS", "8")
+	t.Setenv("ARGON2_MAX_PARALLELISM", "4")
+	got := LoadArgon2Limits()
+	if got.MaxMemory != 256*1024 || got.MaxIterations != 8 || got.MaxParallelism != 4 {
+		t.Errorf("expected the configured limits, got %+v", got)
+	}
+	if got.MinSaltLength != crypto.DefaultHashLimits().MinSaltLength {
+		t.Errorf("expected unconfigured limits to keep their defaults, got %+v", got)
+	}
+}
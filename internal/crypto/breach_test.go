@@ -0,0 +1,93 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// roundTripFunc stubs an http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// SHA-1("password") = 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+const (
+	passwordPrefix = "5BAA6"
+	passwordSuffix = "1E4C9B93F3F0682250B6CF8331B7EE68FD8"
+)
+
+func stubBreachChecker(t *testing.T, body string, status int, gotPath *string) *BreachChecker {
+	t.Helper()
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if gotPath != nil {
+			*gotPath = r.URL.Path
+		}
+		return &http.Response{
+			StatusCode: status,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	})}
+	return NewBreachChecker(WithBreachClient(client), WithBreachURL("https://breach.test/range/"))
+}
+
+func TestBreachChecker_Found(t *testing.T) {
+	var path string
+	body := "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n" + passwordSuffix + ":9545824\r\n"
+	checker := stubBreachChecker(t, body, http.StatusOK, &path)
+
+	count, err := checker.Count(context.Background(), "password")
+	if err != nil {
+		t.Fatalf("Count() unexpected error: %v", err)
+	}
+	if count != 9545824 {
+		t.Errorf("Count() = %d, want 9545824", count)
+	}
+	// Only the five-character prefix may leave the process.
+	if path != "/range/"+passwordPrefix {
+		t.Errorf("request path = %q, want %q", path, "/range/"+passwordPrefix)
+	}
+}
+
+func TestBreachChecker_NotFound(t *testing.T) {
+	body := "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n00D4F6E8FA6EECAD2A3AA415EEC418D38EC:0\r\n"
+	checker := stubBreachChecker(t, body, http.StatusOK, nil)
+
+	count, err := checker.Count(context.Background(), "password")
+	if err != nil {
+		t.Fatalf("Count() unexpected error: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Count() = %d, want 0", count)
+	}
+}
+
+func TestBreachChecker_Timeout(t *testing.T) {
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		<-r.Context().Done()
+		return nil, r.Context().Err()
+	})}
+	checker := NewBreachChecker(WithBreachClient(client), WithBreachTimeout(20*time.Millisecond))
+
+	start := time.Now()
+	_, err := checker.Count(context.Background(), "password")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Count() error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Count() took %v, want it bounded by the timeout", elapsed)
+	}
+}
+
+func TestBreachChecker_UnexpectedStatus(t *testing.T) {
+	checker := stubBreachChecker(t, "", http.StatusServiceUnavailable, nil)
+
+	if _, err := checker.Count(context.Background(), "password"); err == nil {
+		t.Fatal("Count() expected an error for a 503 response")
+	}
+}
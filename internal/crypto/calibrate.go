@@ -0,0 +1,56 @@
+package crypto
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrCalibrationTarget = errors.New("calibration target must be positive")
+
+// Calibrate tunes Argon2id parameters so that one hash on this machine takes
+// about target. It starts from DefaultHashParams and never goes below them,
+// first doubling memory, then raising iterations to close the remaining gap.
+// The result stays within DefaultHashLimits so hashes made with it still verify.
+// Calibration runs several hashes and can take a few multiples of target.
+func Calibrate(target time.Duration) (HashParams, error) {
+	return CalibrateWithin(target, DefaultHashLimits())
+}
+
+// CalibrateWithin is Calibrate with caller-supplied maxima for memory and iterations.
+func CalibrateWithin(target time.Duration, limits HashLimits) (HashParams, error) {
+	if target <= 0 {
+		return HashParams{}, ErrCalibrationTarget
+	}
+
+	params := DefaultHashParams()
+	elapsed, err := timeHash(params)
+	if err != nil {
+		return HashParams{}, err
+	}
+
+	// Hash time grows roughly linearly with memory, so doubling stops once the
+	// next step would overshoot.
+	for elapsed*2 <= target && params.Memory*2 <= limits.MaxMemory {
+		params.Memory *= 2
+		if elapsed, err = timeHash(params); err != nil {
+			return HashParams{}, err
+		}
+	}
+
+	// Iterations also scale linearly; use them for the finer adjustment.
+	if elapsed < target {
+		scaled := uint32(float64(params.Iterations) * float64(target) / float64(elapsed))
+		params.Iterations = max(params.Iterations, min(scaled, limits.MaxIterations))
+	}
+
+	return params, nil
+}
+
+// timeHash measures a single hash with params.
+func timeHash(params HashParams) (time.Duration, error) {
+	start := time.Now()
+	if _, err := HashPasswordWith("vaultpass-calibration", params); err != nil {
+		return 0, err
+	}
+	return max(time.Since(start), time.Microsecond), nil
+}
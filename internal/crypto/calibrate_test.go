@@ -0,0 +1,66 @@
+package crypto
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCalibrate_ReachesTarget(t *testing.T) {
+	base, err := timeHash(DefaultHashParams())
+	if err != nil {
+		t.Fatalf("timeHash() unexpected error: %v", err)
+	}
+	target := 4 * base
+
+	params, err := Calibrate(target)
+	if err != nil {
+		t.Fatalf("Calibrate() unexpected error: %v", err)
+	}
+	if err := DefaultHashLimits().Check(params); err != nil {
+		t.Fatalf("calibrated params outside the verify limits: %v", err)
+	}
+	def := DefaultHashParams()
+	if params.Memory < def.Memory || params.Iterations < def.Iterations {
+		t.Errorf("calibrated params %+v weaker than the defaults", params)
+	}
+
+	// Timing on shared CI runners is noisy, so only check the ballpark.
+	got, err := timeHash(params)
+	if err != nil {
+		t.Fatalf("timeHash() unexpected error: %v", err)
+	}
+	if got < target/3 || got > target*3 {
+		t.Errorf("calibrated hash took %v, want roughly %v (params %+v)", got, target, params)
+	}
+}
+
+func TestCalibrate_NeverBelowDefaults(t *testing.T) {
+	params, err := Calibrate(time.Nanosecond)
+	if err != nil {
+		t.Fatalf("Calibrate() unexpected error: %v", err)
+	}
+	if params != DefaultHashParams() {
+		t.Errorf("expected the defaults for a tiny target, got %+v", params)
+	}
+}
+
+func TestCalibrateWithin_CapsAtLimits(t *testing.T) {
+	limits := DefaultHashLimits()
+	limits.MaxMemory = DefaultHashParams().Memory
+	limits.MaxIterations = 5
+
+	params, err := CalibrateWithin(time.Hour, limits)
+	if err != nil {
+		t.Fatalf("CalibrateWithin() unexpected error: %v", err)
+	}
+	if params.Memory != limits.MaxMemory || params.Iterations != limits.MaxIterations {
+		t.Errorf("expected params capped at m=%d,t=%d, got %+v", limits.MaxMemory, limits.MaxIterations, params)
+	}
+}
+
+func TestCalibrate_RejectsNonPositiveTarget(t *testing.T) {
+	if _, err := Calibrate(0); !errors.Is(err, ErrCalibrationTarget) {
+		t.Errorf("expected ErrCalibrationTarget, got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package crypto
+
+import (
+	_ "embed"
+	"strings"
+)
+
+// commonPasswordList is the built-in list of commonly used passwords.
+//
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+// CommonPasswords is a set of passwords too common to accept. Lookups ignore
+// case and surrounding whitespace. A nil CommonPasswords contains nothing.
+type CommonPasswords struct {
+	set map[string]struct{}
+}
+
+// NewCommonPasswords builds a set from the built-in list plus extra, ignoring
+// blanks.
+func NewCommonPasswords(extra []string) *CommonPasswords {
+	c := &CommonPasswords{set: make(map[string]struct{})}
+	for line := range strings.Lines(commonPasswordList) {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		c.add(line)
+	}
+	for _, p := range extra {
+		c.add(p)
+	}
+	return c
+}
+
+func (c *CommonPasswords) add(password string) {
+	if p := normalizeCommon(password); p != "" {
+		c.set[p] = struct{}{}
+	}
+}
+
+// Contains reports whether password is in the set.
+func (c *CommonPasswords) Contains(password string) bool {
+	if c == nil {
+		return false
+	}
+	_, ok := c.set[normalizeCommon(password)]
+	return ok
+}
+
+// Len returns the number of passwords in the set.
+func (c *CommonPasswords) Len() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.set)
+}
+
+// normalizeCommon folds password to the form stored in the set.
+func normalizeCommon(password string) string {
+	return strings.ToLower(strings.TrimSpace(password))
+}
@@ -0,0 +1,28 @@
+package crypto
+
+import "testing"
+
+func TestCommonPasswords_Contains(t *testing.T) {
+	c := NewCommonPasswords([]string{"  HunterTwo ", ""})
+
+	for _, p := range []string{"password", "PASSWORD123", " qwerty123\t", "huntertwo"} {
+		if !c.Contains(p) {
+			t.Errorf("expected %q to be common", p)
+		}
+	}
+	for _, p := range []string{"v7#Lq9!zRt2@wX", "", "# Commonly used passwords, one per line. Matching ignores case and"} {
+		if c.Contains(p) {
+			t.Errorf("expected %q not to be common", p)
+		}
+	}
+}
+
+func TestCommonPasswords_Nil(t *testing.T) {
+	var c *CommonPasswords
+	if c.Contains("password") || c.Len() != 0 {
+		t.Error("expected a nil set to contain nothing")
+	}
+	if NewCommonPasswords(nil).Len() < 100 {
+		t.Error("expected the built-in list to be loaded")
+	}
+}
@@ -0,0 +1,70 @@
+package crypto
+
+import (
+	"errors"
+	"strings"
+)
+
+// MaxDenylistAttempts caps how many passwords are generated in search of one
+// free of denylisted words, so a list that matches nearly every output can't
+// loop forever.
+const MaxDenylistAttempts = 20
+
+// MinDenylistWordLength is the shortest word a Denylist accepts. Shorter words
+// would match most outputs by chance.
+const MinDenylistWordLength = 3
+
+var (
+	ErrDenylistWordTooShort = errors.New("denylisted words must be at least 3 characters")
+	ErrDenylistExhausted    = errors.New("could not generate a password free of denylisted words; try a longer length or other character types")
+)
+
+// Denylist holds words a generated password must not contain. Matching is a
+// case-insensitive substring search. A nil Denylist matches nothing.
+type Denylist struct {
+	words []string
+}
+
+// NewDenylist builds a Denylist from words, ignoring blanks and duplicates.
+func NewDenylist(words []string) (*Denylist, error) {
+	d := &Denylist{}
+	seen := make(map[string]bool, len(words))
+	for _, w := range words {
+		w = strings.ToLower(strings.TrimSpace(w))
+		if w == "" || seen[w] {
+			continue
+		}
+		if len(w) < MinDenylistWordLength {
+			return nil, ErrDenylistWordTooShort
+		}
+		seen[w] = true
+		d.words = append(d.words, w)
+	}
+	return d, nil
+}
+
+// Contains reports whether password contains any denylisted word.
+func (d *Denylist) Contains(password string) bool {
+	if d == nil || len(d.words) == 0 {
+		return false
+	}
+	password = strings.ToLower(password)
+	for _, w := range d.words {
+		if strings.Contains(password, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// avoidDenylisted calls generate until it returns a password free of words
+// in d, giving up with ErrDenylistExhausted after MaxDenylistAttempts.
+func avoidDenylisted(d *Denylist, generate func() (string, error)) (string, error) {
+	for range MaxDenylistAttempts {
+		password, err := generate()
+		if err != nil || !d.Contains(password) {
+			return password, err
+		}
+	}
+	return "", ErrDenylistExhausted
+}
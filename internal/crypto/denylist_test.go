@@ -0,0 +1,88 @@
+package crypto
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNewDenylist(t *testing.T) {
+	d, err := NewDenylist([]string{" Bad ", "", "bad", "worse"})
+	if err != nil {
+		t.Fatalf("NewDenylist() unexpected error: %v", err)
+	}
+	if len(d.words) != 2 {
+		t.Errorf("expected blanks and duplicates dropped, got %q", d.words)
+	}
+	for pw, want := range map[string]bool{"xxBADxx": true, "aworsez": true, "fine": false} {
+		if got := d.Contains(pw); got != want {
+			t.Errorf("Contains(%q) = %v, want %v", pw, got, want)
+		}
+	}
+
+	if _, err := NewDenylist([]string{"ok!", "no"}); err != ErrDenylistWordTooShort {
+		t.Errorf("expected ErrDenylistWordTooShort, got %v", err)
+	}
+	if (*Denylist)(nil).Contains("anything") {
+		t.Error("expected a nil denylist to match nothing")
+	}
+}
+
+func TestGeneratePronounceable_RegeneratesUntilClean(t *testing.T) {
+	opts := GeneratorOptions{Length: 12}
+
+	// Draw the sequence the seed produces without a denylist.
+	src := seededReader(7)
+	var plain []string
+	for range 5 {
+		pw, _, err := GeneratePronounceableWith(opts, src)
+		if err != nil {
+			t.Fatalf("GeneratePronounceableWith() unexpected error: %v", err)
+		}
+		plain = append(plain, pw)
+	}
+
+	// Ban a word from the first password; the same seed must skip ahead to the
+	// first later password without it.
+	word := plain[0][:4]
+	want := ""
+	for _, pw := range plain[1:] {
+		if !strings.Contains(pw, word) {
+			want = pw
+			break
+		}
+	}
+	if want == "" {
+		t.Fatalf("no clean password in %q; pick another seed", plain)
+	}
+
+	opts.Denylist, _ = NewDenylist([]string{strings.ToUpper(word)})
+	got, entropy, err := GeneratePronounceableWith(opts, seededReader(7))
+	if err != nil {
+		t.Fatalf("GeneratePronounceableWith() with denylist unexpected error: %v", err)
+	}
+	if got != want {
+		t.Errorf("expected regeneration to yield %q, got %q", want, got)
+	}
+	if entropy <= 0 {
+		t.Errorf("expected the clean password's entropy, got %v", entropy)
+	}
+}
+
+func TestGenerate_DenylistExhausted(t *testing.T) {
+	// Digits only, length 8: every password contains some 3-digit run, so a
+	// list of all of them can never be satisfied.
+	var words []string
+	for i := range 1000 {
+		words = append(words, fmt.Sprintf("%03d", i))
+	}
+	denylist, err := NewDenylist(words)
+	if err != nil {
+		t.Fatalf("NewDenylist() unexpected error: %v", err)
+	}
+
+	_, err = GenerateWith(GeneratorOptions{Length: 8, Numbers: true, Denylist: denylist}, seededReader(1))
+	if err != ErrDenylistExhausted {
+		t.Errorf("expected ErrDenylistExhausted, got %v", err)
+	}
+}
@@ -3,7 +3,11 @@ package crypto
 import (
 	"crypto/rand"
 	"errors"
+	"io"
+	"math"
 	"math/big"
+	"strings"
+	"unicode/utf8"
 )
 
 const (
@@ -12,17 +16,46 @@ const (
 	numberChars    = "0123456789"
 	symbolChars    = "!@#$%^&*()_+-=[]{}|;:,.<>?"
 
+	// mobileSymbolChars are symbols on the first symbol page of both the iOS and
+	// Android keyboards, so typing one takes a single layer switch.
+	mobileSymbolChars = "-.@!?&$"
+
+	// MinLength and MaxLength are the default length bounds.
 	MinLength = 8
 	MaxLength = 128
+
+	// LowestMinLength and HighestMaxLength are the outer limits for configured bounds.
+	LowestMinLength  = 4
+	HighestMaxLength = 1024
 )
 
 var (
-	ErrLengthTooShort    = errors.New("password length must be at least 8")
-	ErrLengthTooLong     = errors.New("password length must be at most 128")
-	ErrNoCharacterTypes  = errors.New("at least one character type must be selected")
+	ErrLengthTooShort     = errors.New("password length is below the minimum")
+	ErrLengthTooLong      = errors.New("password length is above the maximum")
+	ErrNoCharacterTypes   = errors.New("at least one character type must be selected")
 	ErrLengthInsufficient = errors.New("password length must be at least equal to the number of selected character types")
+	ErrInvalidBounds      = errors.New("length bounds must satisfy 4 <= min <= max <= 1024")
 )
 
+// LengthBounds limits the accepted password length.
+type LengthBounds struct {
+	Min int
+	Max int
+}
+
+// DefaultLengthBounds returns the 8-128 default bounds.
+func DefaultLengthBounds() LengthBounds {
+	return LengthBounds{Min: MinLength, Max: MaxLength}
+}
+
+// Validate reports whether the bounds are usable.
+func (b LengthBounds) Validate() error {
+	if b.Min < LowestMinLength || b.Max > HighestMaxLength || b.Min > b.Max {
+		return ErrInvalidBounds
+	}
+	return nil
+}
+
 // GeneratorOptions configures the password generator.
 type GeneratorOptions struct {
 	Length    int
@@ -30,6 +63,21 @@ type GeneratorOptions struct {
 	Lowercase bool
 	Numbers   bool
 	Symbols   bool
+
+	// MobileFriendly draws symbols from a small set that is easy to reach on
+	// phone keyboards. The smaller pool lowers entropy per character.
+	MobileFriendly bool
+
+	// Bounds overrides the accepted length range; the zero value means DefaultLengthBounds.
+	Bounds LengthBounds
+
+	// Denylist, if set, rejects passwords containing any of its words; they are
+	// regenerated up to MaxDenylistAttempts times.
+	Denylist *Denylist
+
+	// Policy, if set, must match the password; it is regenerated up to
+	// MaxPolicyAttempts times.
+	Policy *Policy
 }
 
 // DefaultOptions returns sensible defaults: 16 characters with all types enabled.
@@ -45,33 +93,28 @@ func DefaultOptions() GeneratorOptions {
 
 // Generate creates a cryptographically secure random password based on the given options.
 func Generate(opts GeneratorOptions) (string, error) {
-	if opts.Length < MinLength {
-		return "", ErrLengthTooShort
-	}
-	if opts.Length > MaxLength {
-		return "", ErrLengthTooLong
-	}
-
-	// Build the character pool and collect required sets.
-	var pool string
-	var requiredSets []string
+	return GenerateWith(opts, rand.Reader)
+}
 
-	if opts.Uppercase {
-		pool += uppercaseChars
-		requiredSets = append(requiredSets, uppercaseChars)
-	}
-	if opts.Lowercase {
-		pool += lowercaseChars
-		requiredSets = append(requiredSets, lowercaseChars)
-	}
-	if opts.Numbers {
-		pool += numberChars
-		requiredSets = append(requiredSets, numberChars)
-	}
-	if opts.Symbols {
-		pool += symbolChars
-		requiredSets = append(requiredSets, symbolChars)
+// GenerateWith creates a password using src as the source of randomness.
+// Production code should use Generate; this exists so tests can inject a
+// deterministic reader and get reproducible output.
+func GenerateWith(opts GeneratorOptions, src io.Reader) (string, error) {
+	if err := checkLength(opts); err != nil {
+		return "", err
 	}
+	return satisfyPolicy(opts.Policy, func() (string, error) {
+		return avoidDenylisted(opts.Denylist, func() (string, error) {
+			return generateOnce(opts, src)
+		})
+	})
+}
+
+// generateOnce draws a single password for opts, which must have a valid length.
+func generateOnce(opts GeneratorOptions, src io.Reader) (string, error) {
+	// Build the character pool from the required sets.
+	requiredSets := charsets(opts)
+	pool := strings.Join(requiredSets, "")
 
 	if len(requiredSets) == 0 {
 		return "", ErrNoCharacterTypes
@@ -84,7 +127,7 @@ func Generate(opts GeneratorOptions) (string, error) {
 
 	// Guarantee at least one character from each selected type.
 	for i, charset := range requiredSets {
-		ch, err := randChar(charset)
+		ch, err := randChar(src, charset)
 		if err != nil {
 			return "", err
 		}
@@ -93,7 +136,7 @@ func Generate(opts GeneratorOptions) (string, error) {
 
 	// Fill the remaining positions from the full pool.
 	for i := len(requiredSets); i < opts.Length; i++ {
-		ch, err := randChar(pool)
+		ch, err := randChar(src, pool)
 		if err != nil {
 			return "", err
 		}
@@ -101,26 +144,112 @@ func Generate(opts GeneratorOptions) (string, error) {
 	}
 
 	// Securely shuffle using Fisher-Yates with crypto/rand.
-	if err := secureShuffle(result); err != nil {
+	if err := secureShuffle(src, result); err != nil {
 		return "", err
 	}
 
 	return string(result), nil
 }
 
-// randChar picks a random character from charset using crypto/rand.
-func randChar(charset string) (byte, error) {
-	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+// Entropy returns the approximate entropy in bits of a Generate password with opts.
+func Entropy(opts GeneratorOptions) float64 {
+	return float64(opts.Length) * EntropyPerChar(opts)
+}
+
+// EntropyPerChar returns the entropy in bits of one character drawn from the
+// pool opts selects, or 0 if no character types are selected.
+func EntropyPerChar(opts GeneratorOptions) float64 {
+	pool := len(strings.Join(charsets(opts), ""))
+	if pool == 0 {
+		return 0
+	}
+	return math.Log2(float64(pool))
+}
+
+// charsets returns the character sets opts enables, in a fixed order.
+func charsets(opts GeneratorOptions) []string {
+	var sets []string
+	if opts.Uppercase {
+		sets = append(sets, uppercaseChars)
+	}
+	if opts.Lowercase {
+		sets = append(sets, lowercaseChars)
+	}
+	if opts.Numbers {
+		sets = append(sets, numberChars)
+	}
+	if opts.Symbols {
+		sets = append(sets, symbols(opts))
+	}
+	return sets
+}
+
+// symbols returns the symbol set opts draws from.
+func symbols(opts GeneratorOptions) string {
+	if opts.MobileFriendly {
+		return mobileSymbolChars
+	}
+	return symbolChars
+}
+
+// EstimateEntropy returns the approximate entropy in bits of an arbitrary password,
+// assuming each character is drawn from the union of the character classes it uses.
+// Characters outside the generator's sets count towards the symbol pool.
+func EstimateEntropy(password string) float64 {
+	var upper, lower, number, symbol bool
+	for _, r := range password {
+		switch {
+		case strings.ContainsRune(uppercaseChars, r):
+			upper = true
+		case strings.ContainsRune(lowercaseChars, r):
+			lower = true
+		case strings.ContainsRune(numberChars, r):
+			number = true
+		default:
+			symbol = true
+		}
+	}
+	return Entropy(GeneratorOptions{
+		Length:    utf8.RuneCountInString(password),
+		Uppercase: upper,
+		Lowercase: lower,
+		Numbers:   number,
+		Symbols:   symbol,
+	})
+}
+
+// checkLength validates opts.Length against opts.Bounds, or the defaults if unset.
+func checkLength(opts GeneratorOptions) error {
+	bounds := opts.Bounds
+	if bounds == (LengthBounds{}) {
+		bounds = DefaultLengthBounds()
+	}
+	if err := bounds.Validate(); err != nil {
+		return err
+	}
+
+	if opts.Length < bounds.Min {
+		return ErrLengthTooShort
+	}
+	if opts.Length > bounds.Max {
+		return ErrLengthTooLong
+	}
+	return nil
+}
+
+// randChar picks a random character from charset using src.
+func randChar(src io.Reader, charset string) (byte, error) {
+	n, err := rand.Int(src, big.NewInt(int64(len(charset))))
 	if err != nil {
 		return 0, err
 	}
 	return charset[n.Int64()], nil
 }
 
-// secureShuffle performs a Fisher-Yates shuffle using crypto/rand.
-func secureShuffle(data []byte) error {
+// secureShuffle performs a Fisher-Yates shuffle using src.
+func secureShuffle(src io.Reader, data []byte) error {
 	for i := len(data) - 1; i > 0; i-- {
-		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		j, err := rand.Int(src, big.NewInt(int64(i+1)))
 		if err != nil {
 			return err
 		}
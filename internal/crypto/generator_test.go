@@ -1,6 +1,10 @@
 package crypto
 
 import (
+	"crypto/rand"
+	"errors"
+	"math"
+	mathrand "math/rand/v2"
 	"strings"
 	"testing"
 )
@@ -169,6 +173,11 @@ func TestGenerateSingleTypeContainsOnlyThatType(t *testing.T) {
 			opts:    GeneratorOptions{Length: 32, Symbols: true},
 			charset: symbolChars,
 		},
+		{
+			name:    "mobile-friendly symbols only",
+			opts:    GeneratorOptions{Length: 32, Symbols: true, MobileFriendly: true},
+			charset: mobileSymbolChars,
+		},
 	}
 
 	for _, tt := range tests {
@@ -186,6 +195,36 @@ func TestGenerateSingleTypeContainsOnlyThatType(t *testing.T) {
 	}
 }
 
+func TestGenerateMobileFriendly(t *testing.T) {
+	opts := GeneratorOptions{Length: 24, Uppercase: true, Lowercase: true, Numbers: true, Symbols: true, MobileFriendly: true}
+	allowed := uppercaseChars + lowercaseChars + numberChars + mobileSymbolChars
+
+	for range 50 {
+		password, err := Generate(opts)
+		if err != nil {
+			t.Fatalf("Generate() unexpected error: %v", err)
+		}
+		if len(password) != opts.Length {
+			t.Fatalf("expected length %d, got %d", opts.Length, len(password))
+		}
+		if strings.Trim(password, allowed) != "" {
+			t.Fatalf("password %q contains characters outside the mobile-friendly set", password)
+		}
+		if !strings.ContainsAny(password, mobileSymbolChars) {
+			t.Fatalf("password %q has no symbol", password)
+		}
+	}
+
+	full := opts
+	full.MobileFriendly = false
+	if got, want := Entropy(opts), float64(opts.Length)*math.Log2(69); math.Abs(got-want) > 1e-9 {
+		t.Errorf("Entropy() = %v, want %v for a 69-character pool", got, want)
+	}
+	if Entropy(opts) >= Entropy(full) {
+		t.Errorf("mobile-friendly entropy %v should be below the full pool's %v", Entropy(opts), Entropy(full))
+	}
+}
+
 func TestGenerateProducesUniquePasswords(t *testing.T) {
 	opts := DefaultOptions()
 	seen := make(map[string]bool)
@@ -201,3 +240,93 @@ func TestGenerateProducesUniquePasswords(t *testing.T) {
 		seen[password] = true
 	}
 }
+
+func seededReader(seed byte) *mathrand.ChaCha8 {
+	var key [32]byte
+	key[0] = seed
+	return mathrand.NewChaCha8(key)
+}
+
+func TestGenerateWithSameSeedIsReproducible(t *testing.T) {
+	opts := DefaultOptions()
+
+	first, err := GenerateWith(opts, seededReader(1))
+	if err != nil {
+		t.Fatalf("GenerateWith() unexpected error: %v", err)
+	}
+	second, err := GenerateWith(opts, seededReader(1))
+	if err != nil {
+		t.Fatalf("GenerateWith() unexpected error: %v", err)
+	}
+	if first != second {
+		t.Errorf("same seed produced different passwords: %q vs %q", first, second)
+	}
+
+	other, err := GenerateWith(opts, seededReader(2))
+	if err != nil {
+		t.Fatalf("GenerateWith() unexpected error: %v", err)
+	}
+	if first == other {
+		t.Errorf("different seeds produced the same password: %q", first)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("entropy unavailable") }
+
+func TestGenerateUsesCryptoRand(t *testing.T) {
+	original := rand.Reader
+	rand.Reader = failingReader{}
+	defer func() { rand.Reader = original }()
+
+	if _, err := Generate(DefaultOptions()); err == nil {
+		t.Fatal("Generate() should read from crypto/rand.Reader")
+	}
+}
+
+func TestGenerate_CustomBounds(t *testing.T) {
+	bounds := LengthBounds{Min: 12, Max: 256}
+
+	for _, length := range []int{12, 200, 256} {
+		pw, err := Generate(GeneratorOptions{Length: length, Lowercase: true, Bounds: bounds})
+		if err != nil {
+			t.Fatalf("Generate(%d) unexpected error: %v", length, err)
+		}
+		if len(pw) != length {
+			t.Errorf("expected length %d, got %d", length, len(pw))
+		}
+	}
+
+	if _, err := Generate(GeneratorOptions{Length: 10, Lowercase: true, Bounds: bounds}); err != ErrLengthTooShort {
+		t.Errorf("expected ErrLengthTooShort below custom min, got %v", err)
+	}
+	if _, err := Generate(GeneratorOptions{Length: 257, Lowercase: true, Bounds: bounds}); err != ErrLengthTooLong {
+		t.Errorf("expected ErrLengthTooLong above custom max, got %v", err)
+	}
+}
+
+func TestLengthBounds_Validate(t *testing.T) {
+	tests := []struct {
+		bounds LengthBounds
+		valid  bool
+	}{
+		{DefaultLengthBounds(), true},
+		{LengthBounds{Min: 12, Max: 256}, true},
+		{LengthBounds{Min: 16, Max: 16}, true},
+		{LengthBounds{Min: 3, Max: 128}, false},
+		{LengthBounds{Min: 8, Max: 2048}, false},
+		{LengthBounds{Min: 64, Max: 32}, false},
+	}
+
+	for _, tt := range tests {
+		err := tt.bounds.Validate()
+		if (err == nil) != tt.valid {
+			t.Errorf("Validate(%+v) = %v, want valid=%v", tt.bounds, err, tt.valid)
+		}
+	}
+
+	if _, err := Generate(GeneratorOptions{Length: 16, Lowercase: true, Bounds: LengthBounds{Min: 64, Max: 32}}); err != ErrInvalidBounds {
+		t.Errorf("expected ErrInvalidBounds, got %v", err)
+	}
+}
@@ -7,13 +7,16 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"golang.org/x/crypto/argon2"
 )
 
 var (
-	ErrInvalidHashFormat = errors.New("invalid encoded hash format")
-	ErrIncompatibleVersion = errors.New("incompatible argon2 version")
+	ErrInvalidHashFormat    = errors.New("invalid encoded hash format")
+	ErrIncompatibleVersion  = errors.New("incompatible argon2 version")
+	ErrUnsupportedVariant   = errors.New("unsupported argon2 variant")
+	ErrHashParamsOutOfRange = errors.New("argon2 parameters outside the allowed range")
 )
 
 // HashParams configures the Argon2id hashing parameters.
@@ -36,11 +39,54 @@ func DefaultHashParams() HashParams {
 	}
 }
 
+// HashLimits bounds the Argon2id parameters accepted from a stored hash.
+// Verification cost is chosen by whoever wrote the hash, so a crafted hash
+// could otherwise demand gigabytes of memory or hours of work.
+type HashLimits struct {
+	MaxMemory      uint32 // KiB
+	MaxIterations  uint32
+	MaxParallelism uint8
+	MinSaltLength  uint32
+	MaxSaltLength  uint32
+	MinKeyLength   uint32
+	MaxKeyLength   uint32
+}
+
+// DefaultHashLimits returns limits comfortably above DefaultHashParams.
+func DefaultHashLimits() HashLimits {
+	return HashLimits{
+		MaxMemory:      1024 * 1024, // 1 GiB
+		MaxIterations:  16,
+		MaxParallelism: 16,
+		MinSaltLength:  8,
+		MaxSaltLength:  64,
+		MinKeyLength:   16,
+		MaxKeyLength:   64,
+	}
+}
+
+// Check reports ErrHashParamsOutOfRange if any parameter falls outside the limits.
+func (l HashLimits) Check(p HashParams) error {
+	switch {
+	case p.Memory == 0 || p.Memory > l.MaxMemory,
+		p.Iterations == 0 || p.Iterations > l.MaxIterations,
+		p.Parallelism == 0 || p.Parallelism > l.MaxParallelism,
+		p.SaltLength < l.MinSaltLength || p.SaltLength > l.MaxSaltLength,
+		p.KeyLength < l.MinKeyLength || p.KeyLength > l.MaxKeyLength:
+		return fmt.Errorf("%w: m=%d,t=%d,p=%d, salt %d bytes, key %d bytes", ErrHashParamsOutOfRange,
+			p.Memory, p.Iterations, p.Parallelism, p.SaltLength, p.KeyLength)
+	}
+	return nil
+}
+
 // HashPassword hashes a password using Argon2id with default parameters.
 // Returns the hash encoded in PHC string format.
 func HashPassword(password string) (string, error) {
-	params := DefaultHashParams()
+	return HashPasswordWith(password, DefaultHashParams())
+}
 
+// HashPasswordWith hashes a password using Argon2id with the given parameters.
+func HashPasswordWith(password string, params HashParams) (string, error) {
 	salt := make([]byte, params.SaltLength)
 	if _, err := rand.Read(salt); err != nil {
 		return "", fmt.Errorf("generating salt: %w", err)
@@ -61,13 +107,45 @@ func HashPassword(password string) (string, error) {
 	return encoded, nil
 }
 
+// HashTiming reports how long a single hash took with a parameter set.
+type HashTiming struct {
+	Duration  time.Duration
+	Threshold time.Duration
+	Slow      bool
+}
+
+// CheckHashTiming times one hash with params and flags it as slow when it
+// exceeds threshold. It is run at startup to catch pathological configs.
+func CheckHashTiming(params HashParams, threshold time.Duration) (HashTiming, error) {
+	start := time.Now()
+	if _, err := HashPasswordWith("vaultpass-self-test", params); err != nil {
+		return HashTiming{}, err
+	}
+	elapsed := time.Since(start)
+
+	return HashTiming{
+		Duration:  elapsed,
+		Threshold: threshold,
+		Slow:      elapsed > threshold,
+	}, nil
+}
+
 // VerifyPassword checks whether a password matches the given Argon2id encoded hash.
-// Uses constant-time comparison to prevent timing attacks.
+// Uses constant-time comparison to prevent timing attacks. Hashes whose parameters
+// exceed DefaultHashLimits are rejected before any hashing work is done.
 func VerifyPassword(password, encodedHash string) (bool, error) {
+	return VerifyPasswordWith(password, encodedHash, DefaultHashLimits())
+}
+
+// VerifyPasswordWith is VerifyPassword with caller-supplied parameter limits.
+func VerifyPasswordWith(password, encodedHash string, limits HashLimits) (bool, error) {
 	params, salt, hash, err := decodeHash(encodedHash)
 	if err != nil {
 		return false, err
 	}
+	if err := limits.Check(params); err != nil {
+		return false, err
+	}
 
 	candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
 
@@ -85,7 +163,11 @@ func decodeHash(encodedHash string) (HashParams, []byte, []byte, error) {
 		return HashParams{}, nil, nil, ErrInvalidHashFormat
 	}
 
-	if parts[1] != "argon2id" {
+	switch parts[1] {
+	case "argon2id":
+	case "argon2i", "argon2d":
+		return HashParams{}, nil, nil, ErrUnsupportedVariant
+	default:
 		return HashParams{}, nil, nil, ErrInvalidHashFormat
 	}
 
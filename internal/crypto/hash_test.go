@@ -1,8 +1,10 @@
 package crypto
 
 import (
+	"errors"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestHashPassword(t *testing.T) {
@@ -86,3 +88,111 @@ func TestVerifyPasswordInvalidHash(t *testing.T) {
 		t.Error("VerifyPassword() expected error for invalid hash format")
 	}
 }
+
+func TestCheckHashTiming_ReportsDuration(t *testing.T) {
+	timing, err := CheckHashTiming(DefaultHashParams(), time.Minute)
+	if err != nil {
+		t.Fatalf("CheckHashTiming() unexpected error: %v", err)
+	}
+	if timing.Duration <= 0 {
+		t.Errorf("expected positive duration, got %v", timing.Duration)
+	}
+	if timing.Threshold != time.Minute {
+		t.Errorf("expected threshold to be echoed, got %v", timing.Threshold)
+	}
+	if timing.Slow {
+		t.Error("default params should not exceed a one-minute threshold")
+	}
+}
+
+func TestCheckHashTiming_SlowParams(t *testing.T) {
+	// Far more iterations than anyone should configure, against a tight budget.
+	params := DefaultHashParams()
+	params.Memory = 8 * 1024
+	params.Iterations = 50
+
+	timing, err := CheckHashTiming(params, time.Millisecond)
+	if err != nil {
+		t.Fatalf("CheckHashTiming() unexpected error: %v", err)
+	}
+	if !timing.Slow {
+		t.Errorf("expected slow params to trip the threshold, took %v", timing.Duration)
+	}
+}
+
+func TestHashPasswordWith_EncodesParams(t *testing.T) {
+	params := DefaultHashParams()
+	params.Memory = 8 * 1024
+	params.Iterations = 1
+
+	hash, err := HashPasswordWith("pw", params)
+	if err != nil {
+		t.Fatalf("HashPasswordWith() unexpected error: %v", err)
+	}
+	if !strings.Contains(hash, "m=8192,t=1,p=2") {
+		t.Errorf("expected params in encoded hash, got %q", hash)
+	}
+	if ok, _ := VerifyPassword("pw", hash); !ok {
+		t.Error("expected hash to verify")
+	}
+}
+
+func TestVerifyPassword_RejectsExcessiveMemory(t *testing.T) {
+	// A crafted hash demanding 64 GiB must be refused before argon2 runs.
+	crafted := "$argon2id$v=19$m=67108864,t=3,p=2$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaGhhc2hoYXNoaGFzaGhhc2g"
+
+	ok, err := VerifyPassword("password", crafted)
+	if !errors.Is(err, ErrHashParamsOutOfRange) {
+		t.Fatalf("expected ErrHashParamsOutOfRange, got %v", err)
+	}
+	if ok {
+		t.Error("out-of-range hash must never verify")
+	}
+}
+
+func TestVerifyPasswordWith_Limits(t *testing.T) {
+	params := DefaultHashParams()
+	params.Memory = 8 * 1024
+	params.Iterations = 2
+
+	hash, err := HashPasswordWith("pw", params)
+	if err != nil {
+		t.Fatalf("HashPasswordWith() unexpected error: %v", err)
+	}
+	if ok, err := VerifyPassword("pw", hash); err != nil || !ok {
+		t.Fatalf("expected normal hash to verify, got %v (err %v)", ok, err)
+	}
+
+	tests := map[string]func(*HashLimits){
+		"memory":      func(l *HashLimits) { l.MaxMemory = 4 * 1024 },
+		"iterations":  func(l *HashLimits) { l.MaxIterations = 1 },
+		"parallelism": func(l *HashLimits) { l.MaxParallelism = 1 },
+		"salt":        func(l *HashLimits) { l.MaxSaltLength = 8 },
+		"key":         func(l *HashLimits) { l.MinKeyLength = 48 },
+	}
+	for name, tighten := range tests {
+		t.Run(name, func(t *testing.T) {
+			limits := DefaultHashLimits()
+			tighten(&limits)
+			if _, err := VerifyPasswordWith("pw", hash, limits); !errors.Is(err, ErrHashParamsOutOfRange) {
+				t.Errorf("expected ErrHashParamsOutOfRange, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyPassword_ZeroParallelism(t *testing.T) {
+	crafted := "$argon2id$v=19$m=65536,t=3,p=0$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaGhhc2hoYXNoaGFzaGhhc2g"
+
+	if _, err := VerifyPassword("password", crafted); !errors.Is(err, ErrHashParamsOutOfRange) {
+		t.Errorf("expected ErrHashParamsOutOfRange, got %v", err)
+	}
+}
+
+func TestVerifyPassword_UnsupportedVariant(t *testing.T) {
+	hash := "$argon2i$v=19$m=65536,t=3,p=2$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaGhhc2hoYXNoaGFzaGhhc2g"
+
+	if _, err := VerifyPassword("password", hash); !errors.Is(err, ErrUnsupportedVariant) {
+		t.Errorf("expected ErrUnsupportedVariant, got %v", err)
+	}
+}
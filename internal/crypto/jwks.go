@@ -0,0 +1,65 @@
+package crypto
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWK is the public portion of an RSA signing key in JSON Web Key form (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS is a JSON Web Key Set document.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public verification keys as a key set. It returns false when
+// tokens are signed with a shared secret, which must never be published.
+func (m *TokenManager) JWKS() (JWKS, bool) {
+	if !m.Asymmetric() || m.cfg.PrivateKey == nil {
+		return JWKS{}, false
+	}
+	return JWKS{Keys: []JWK{publicJWK(&m.cfg.PrivateKey.PublicKey, m.cfg.KeyID)}}, true
+}
+
+// ParseRSAPrivateKey parses a PEM-encoded PKCS#1 or PKCS#8 RSA private key.
+func ParseRSAPrivateKey(pemData []byte) (*rsa.PrivateKey, error) {
+	return jwt.ParseRSAPrivateKeyFromPEM(pemData)
+}
+
+// publicJWK encodes an RSA public key. Only the modulus and exponent are included.
+func publicJWK(pub *rsa.PublicKey, kid string) JWK {
+	return JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: SigningRS256,
+		Kid: kid,
+		N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
+
+// thumbprint computes the RFC 7638 SHA-256 thumbprint of an RSA public key.
+func thumbprint(pub *rsa.PublicKey) string {
+	jwk := publicJWK(pub, "")
+	// Members must appear in lexicographic order with no whitespace.
+	canonical, _ := json.Marshal(struct {
+		E   string `json:"e"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+	}{jwk.E, jwk.Kty, jwk.N})
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,128 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newRS256Manager(t *testing.T) (*TokenManager, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() unexpected error: %v", err)
+	}
+	return NewTokenManager(TokenConfig{
+		Expiry:        time.Hour,
+		SigningMethod: SigningRS256,
+		PrivateKey:    key,
+		KeyID:         "key-2026",
+	}), key
+}
+
+func TestJWKSRendersPublicKey(t *testing.T) {
+	m, key := newRS256Manager(t)
+
+	jwks, ok := m.JWKS()
+	if !ok {
+		t.Fatal("JWKS() expected key set in RS256 mode")
+	}
+
+	raw, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatalf("json.Marshal() unexpected error: %v", err)
+	}
+
+	var doc struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("JWKS is not valid JSON: %v", err)
+	}
+	if len(doc.Keys) != 1 {
+		t.Fatalf("expected 1 key, got %d", len(doc.Keys))
+	}
+
+	k := doc.Keys[0]
+	if k["kid"] != "key-2026" || k["kty"] != "RSA" || k["alg"] != "RS256" || k["use"] != "sig" {
+		t.Errorf("unexpected key metadata: %v", k)
+	}
+	if _, present := k["d"]; present || strings.Contains(string(raw), `"p"`) {
+		t.Fatal("JWKS must not contain private key material")
+	}
+
+	n, err := base64.RawURLEncoding.DecodeString(k["n"])
+	if err != nil || new(big.Int).SetBytes(n).Cmp(key.N) != 0 {
+		t.Error("modulus does not match the signing key")
+	}
+	if k["e"] != "AQAB" {
+		t.Errorf("exponent = %q, want AQAB", k["e"])
+	}
+}
+
+func TestRS256TokenCarriesKidAndValidates(t *testing.T) {
+	m, _ := newRS256Manager(t)
+
+	token, err := m.Generate(42, "session-1")
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+	if err != nil {
+		t.Fatalf("ParseUnverified() unexpected error: %v", err)
+	}
+	if parsed.Header["kid"] != "key-2026" {
+		t.Errorf("token kid = %v, want key-2026", parsed.Header["kid"])
+	}
+	if parsed.Method.Alg() != "RS256" {
+		t.Errorf("token alg = %s, want RS256", parsed.Method.Alg())
+	}
+
+	claims, err := m.Validate(token)
+	if err != nil {
+		t.Fatalf("Validate() unexpected error: %v", err)
+	}
+	if claims.UserID != 42 {
+		t.Errorf("Validate() UserID = %d, want 42", claims.UserID)
+	}
+}
+
+func TestRS256RejectsHS256Tokens(t *testing.T) {
+	m, _ := newRS256Manager(t)
+
+	hsToken, err := GenerateToken(42, "test-secret", time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateToken() unexpected error: %v", err)
+	}
+	if _, err := m.Validate(hsToken); err == nil {
+		t.Error("Validate() expected error for HS256 token in RS256 mode")
+	}
+}
+
+func TestJWKSDisabledForSymmetricSigning(t *testing.T) {
+	m := NewTokenManager(TokenConfig{Secret: "test-secret"})
+	if _, ok := m.JWKS(); ok {
+		t.Error("JWKS() must not be available in HS256 mode")
+	}
+}
+
+func TestDefaultKeyIDIsThumbprint(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() unexpected error: %v", err)
+	}
+	m := NewTokenManager(TokenConfig{SigningMethod: SigningRS256, PrivateKey: key})
+
+	jwks, _ := m.JWKS()
+	if jwks.Keys[0].Kid == "" || jwks.Keys[0].Kid != thumbprint(&key.PublicKey) {
+		t.Errorf("expected kid to default to the key thumbprint, got %q", jwks.Keys[0].Kid)
+	}
+}
@@ -1,47 +1,174 @@
 package crypto
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
 	"errors"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const (
+	DefaultIssuer   = "vaultpass"
+	DefaultAudience = "vaultpass-api"
+
+	SigningHS256 = "HS256"
+	SigningRS256 = "RS256"
+
+	// DefaultLeeway is how far apart the issuer's and verifier's clocks may be
+	// before exp, nbf and iat checks reject a token.
+	DefaultLeeway = 30 * time.Second
+)
+
 var (
-	ErrInvalidToken = errors.New("invalid or expired token")
+	ErrInvalidToken      = errors.New("invalid or expired token")
+	ErrMissingSigningKey = errors.New("RS256 signing requires a private key")
 )
 
 // Claims represents the JWT claims for VaultPass authentication.
+// The registered ID (jti) claim carries the server-side session ID, if any.
+// TokenEpoch is the user's token epoch at issue time; bumping the epoch
+// invalidates every token issued before. MaxIdle, in seconds, is how long the
+// token's session may go unused before it is rejected; zero means no limit.
 type Claims struct {
 	jwt.RegisteredClaims
-	UserID int64 `json:"user_id"`
+	UserID     int64 `json:"user_id"`
+	TokenEpoch int64 `json:"token_epoch"`
+	MaxIdle    int64 `json:"max_idle,omitempty"`
 }
 
-// GenerateToken creates a signed JWT token for the given user.
-func GenerateToken(userID int64, secret string, expiry time.Duration) (string, error) {
+// TokenConfig configures how tokens are issued and which tokens are accepted.
+// Issued tokens carry Issuer and Audience; validation accepts any of
+// AcceptedIssuers and AcceptedAudiences, which default to Issuer and Audience.
+//
+// SigningMethod selects HS256 (default, using Secret) or RS256 (using PrivateKey).
+// In HS256 mode, tokens are signed with Secret but also accepted if signed with
+// any of OldSecrets, so a secret can be rotated without logging everyone out.
+// In RS256 mode KeyID is placed in the token header and published via JWKS; it
+// defaults to the key's RFC 7638 thumbprint.
+//
+// Leeway tolerates clock skew when checking exp and nbf; zero means DefaultLeeway.
+// RememberExpiry is the lifetime of tokens issued for "remember me" logins;
+// zero means Expiry. IdleTimeout, if set, is carried as MaxIdle by tokens bound
+// to a session; tokens without one have no server-side activity to check.
+type TokenConfig struct {
+	Secret            string
+	OldSecrets        []string
+	Expiry            time.Duration
+	RememberExpiry    time.Duration
+	IdleTimeout       time.Duration
+	Issuer            string
+	Audience          string
+	AcceptedIssuers   []string
+	AcceptedAudiences []string
+	SigningMethod     string
+	PrivateKey        *rsa.PrivateKey
+	KeyID             string
+	Leeway            time.Duration
+}
+
+// TokenManager issues and validates JWTs according to a TokenConfig.
+type TokenManager struct {
+	cfg TokenConfig
+}
+
+// NewTokenManager creates a TokenManager, filling unset issuer and audience
+// fields with the VaultPass defaults.
+func NewTokenManager(cfg TokenConfig) *TokenManager {
+	if cfg.Issuer == "" {
+		cfg.Issuer = DefaultIssuer
+	}
+	if cfg.Audience == "" {
+		cfg.Audience = DefaultAudience
+	}
+	if len(cfg.AcceptedIssuers) == 0 {
+		cfg.AcceptedIssuers = []string{cfg.Issuer}
+	}
+	if len(cfg.AcceptedAudiences) == 0 {
+		cfg.AcceptedAudiences = []string{cfg.Audience}
+	}
+	if cfg.SigningMethod == "" {
+		cfg.SigningMethod = SigningHS256
+	}
+	if cfg.Leeway == 0 {
+		cfg.Leeway = DefaultLeeway
+	}
+	if cfg.RememberExpiry == 0 {
+		cfg.RememberExpiry = cfg.Expiry
+	}
+	if cfg.SigningMethod == SigningRS256 && cfg.PrivateKey != nil && cfg.KeyID == "" {
+		cfg.KeyID = thumbprint(&cfg.PrivateKey.PublicKey)
+	}
+	return &TokenManager{cfg: cfg}
+}
+
+// Asymmetric reports whether tokens are signed with an RSA key pair.
+func (m *TokenManager) Asymmetric() bool {
+	return m.cfg.SigningMethod == SigningRS256
+}
+
+// Expiry returns the lifetime of issued tokens.
+func (m *TokenManager) Expiry() time.Duration {
+	return m.cfg.Expiry
+}
+
+// RememberExpiry returns the lifetime of tokens issued for "remember me" logins.
+func (m *TokenManager) RememberExpiry() time.Duration {
+	return m.cfg.RememberExpiry
+}
+
+// Generate creates a signed JWT for the given user, bound to sessionID if non-empty.
+func (m *TokenManager) Generate(userID int64, sessionID string) (string, error) {
+	return m.GenerateWithEpoch(userID, sessionID, 0)
+}
+
+// GenerateWithEpoch is Generate for a user whose token epoch is epoch.
+func (m *TokenManager) GenerateWithEpoch(userID int64, sessionID string, epoch int64) (string, error) {
+	return m.GenerateWithExpiry(userID, sessionID, epoch, m.cfg.Expiry)
+}
+
+// GenerateWithExpiry is GenerateWithEpoch for a token valid for expiry rather
+// than the configured lifetime.
+func (m *TokenManager) GenerateWithExpiry(userID int64, sessionID string, epoch int64, expiry time.Duration) (string, error) {
 	now := time.Now()
 	claims := Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    "vaultpass",
-			Audience:  jwt.ClaimStrings{"vaultpass-api"},
+			ID:        sessionID,
+			Issuer:    m.cfg.Issuer,
+			Audience:  jwt.ClaimStrings{m.cfg.Audience},
 			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
+			NotBefore: jwt.NewNumericDate(now),
 			IssuedAt:  jwt.NewNumericDate(now),
 		},
-		UserID: userID,
+		UserID:     userID,
+		TokenEpoch: epoch,
+	}
+	if sessionID != "" && m.cfg.IdleTimeout > 0 {
+		claims.MaxIdle = int64(m.cfg.IdleTimeout.Seconds())
+	}
+
+	if m.Asymmetric() {
+		if m.cfg.PrivateKey == nil {
+			return "", ErrMissingSigningKey
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = m.cfg.KeyID
+		return token.SignedString(m.cfg.PrivateKey)
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return token.SignedString([]byte(m.cfg.Secret))
 }
 
-// ValidateToken parses and validates a JWT token string, returning the claims if valid.
-func ValidateToken(tokenString, secret string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrInvalidToken
-		}
-		return []byte(secret), nil
-	}, jwt.WithIssuer("vaultpass"), jwt.WithAudience("vaultpass-api"))
+// Validate parses and validates a JWT token string, returning the claims if valid.
+func (m *TokenManager) Validate(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.keyFunc,
+		jwt.WithAudience(m.cfg.AcceptedAudiences...),
+		jwt.WithLeeway(m.cfg.Leeway),
+	)
 	if err != nil {
 		return nil, ErrInvalidToken
 	}
@@ -51,5 +178,60 @@ func ValidateToken(tokenString, secret string) (*Claims, error) {
 		return nil, ErrInvalidToken
 	}
 
+	if !slices.Contains(m.cfg.AcceptedIssuers, claims.Issuer) {
+		return nil, ErrInvalidToken
+	}
+
 	return claims, nil
 }
+
+// keyFunc returns the verification key for a token, rejecting tokens signed with
+// a different algorithm family or an unknown key ID.
+func (m *TokenManager) keyFunc(t *jwt.Token) (interface{}, error) {
+	if m.Asymmetric() {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, ErrInvalidToken
+		}
+		if kid, _ := t.Header["kid"].(string); kid != m.cfg.KeyID || m.cfg.PrivateKey == nil {
+			return nil, ErrInvalidToken
+		}
+		return &m.cfg.PrivateKey.PublicKey, nil
+	}
+
+	if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, ErrInvalidToken
+	}
+	if len(m.cfg.OldSecrets) == 0 {
+		return []byte(m.cfg.Secret), nil
+	}
+	keys := jwt.VerificationKeySet{Keys: []jwt.VerificationKey{[]byte(m.cfg.Secret)}}
+	for _, secret := range m.cfg.OldSecrets {
+		keys.Keys = append(keys.Keys, []byte(secret))
+	}
+	return keys, nil
+}
+
+// GenerateToken creates a signed JWT token for the given user using the default issuer and audience.
+func GenerateToken(userID int64, secret string, expiry time.Duration) (string, error) {
+	return GenerateSessionToken(userID, "", secret, expiry)
+}
+
+// GenerateSessionToken creates a signed JWT token bound to a server-side session.
+func GenerateSessionToken(userID int64, sessionID, secret string, expiry time.Duration) (string, error) {
+	return NewTokenManager(TokenConfig{Secret: secret, Expiry: expiry}).Generate(userID, sessionID)
+}
+
+// ValidateToken parses and validates a JWT token string using the default issuer and audience.
+// Tokens signed with any of oldSecrets are accepted as well as those signed with secret.
+func ValidateToken(tokenString, secret string, oldSecrets ...string) (*Claims, error) {
+	return NewTokenManager(TokenConfig{Secret: secret, OldSecrets: oldSecrets}).Validate(tokenString)
+}
+
+// NewSessionID returns a random 128-bit session identifier encoded as hex.
+func NewSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
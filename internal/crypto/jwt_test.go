@@ -55,13 +55,12 @@ func TestValidateTokenWrongSecret(t *testing.T) {
 }
 
 func TestValidateTokenExpired(t *testing.T) {
-	token, err := GenerateToken(42, "test-secret", time.Millisecond)
+	// Expired by more than DefaultLeeway.
+	token, err := GenerateToken(42, "test-secret", -DefaultLeeway-time.Second)
 	if err != nil {
 		t.Fatalf("GenerateToken() unexpected error: %v", err)
 	}
 
-	time.Sleep(10 * time.Millisecond)
-
 	_, err = ValidateToken(token, "test-secret")
 	if err == nil {
 		t.Error("ValidateToken() expected error for expired token")
@@ -117,3 +116,219 @@ func TestValidateTokenWrongAudience(t *testing.T) {
 		t.Error("ValidateToken() expected error for wrong audience")
 	}
 }
+
+func TestGenerateSessionTokenCarriesSessionID(t *testing.T) {
+	sessionID, err := NewSessionID()
+	if err != nil {
+		t.Fatalf("NewSessionID() unexpected error: %v", err)
+	}
+	if len(sessionID) != 32 {
+		t.Fatalf("NewSessionID() length = %d, want 32", len(sessionID))
+	}
+
+	token, err := GenerateSessionToken(42, sessionID, "test-secret", time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateSessionToken() unexpected error: %v", err)
+	}
+
+	claims, err := ValidateToken(token, "test-secret")
+	if err != nil {
+		t.Fatalf("ValidateToken() unexpected error: %v", err)
+	}
+	if claims.ID != sessionID {
+		t.Errorf("ValidateToken() ID = %q, want %q", claims.ID, sessionID)
+	}
+}
+
+func TestTokenManagerIdleTimeoutOnSessionTokens(t *testing.T) {
+	m := NewTokenManager(TokenConfig{Secret: "test-secret", Expiry: time.Hour, IdleTimeout: 15 * time.Minute})
+
+	for sessionID, want := range map[string]int64{"sess-1": 900, "": 0} {
+		token, err := m.GenerateWithEpoch(42, sessionID, 0)
+		if err != nil {
+			t.Fatalf("GenerateWithEpoch() unexpected error: %v", err)
+		}
+		claims, err := m.Validate(token)
+		if err != nil {
+			t.Fatalf("Validate() unexpected error: %v", err)
+		}
+		if claims.MaxIdle != want {
+			t.Errorf("session %q: MaxIdle = %d, want %d", sessionID, claims.MaxIdle, want)
+		}
+	}
+}
+
+func signTestToken(t *testing.T, secret, issuer, audience string) string {
+	t.Helper()
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Audience:  jwt.ClaimStrings{audience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+		UserID: 42,
+	}
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("SignedString() unexpected error: %v", err)
+	}
+	return tokenString
+}
+
+func TestTokenManagerAcceptsConfiguredAudiences(t *testing.T) {
+	m := NewTokenManager(TokenConfig{
+		Secret:            "test-secret",
+		Expiry:            time.Hour,
+		AcceptedIssuers:   []string{"vaultpass", "vaultpass-sso"},
+		AcceptedAudiences: []string{"vaultpass-api", "vaultpass-admin", "vaultpass-sync"},
+	})
+
+	for _, aud := range []string{"vaultpass-api", "vaultpass-admin", "vaultpass-sync"} {
+		t.Run(aud, func(t *testing.T) {
+			claims, err := m.Validate(signTestToken(t, "test-secret", "vaultpass-sso", aud))
+			if err != nil {
+				t.Fatalf("Validate() unexpected error for audience %q: %v", aud, err)
+			}
+			if claims.UserID != 42 {
+				t.Errorf("Validate() UserID = %d, want 42", claims.UserID)
+			}
+		})
+	}
+}
+
+func TestTokenManagerRejectsDisallowedAudienceAndIssuer(t *testing.T) {
+	m := NewTokenManager(TokenConfig{
+		Secret:            "test-secret",
+		AcceptedIssuers:   []string{"vaultpass"},
+		AcceptedAudiences: []string{"vaultpass-api", "vaultpass-admin"},
+	})
+
+	if _, err := m.Validate(signTestToken(t, "test-secret", "vaultpass", "billing-api")); err == nil {
+		t.Error("Validate() expected error for disallowed audience")
+	}
+	if _, err := m.Validate(signTestToken(t, "test-secret", "other-issuer", "vaultpass-api")); err == nil {
+		t.Error("Validate() expected error for disallowed issuer")
+	}
+}
+
+func TestTokenManagerGenerateUsesConfiguredClaims(t *testing.T) {
+	m := NewTokenManager(TokenConfig{
+		Secret:   "test-secret",
+		Expiry:   time.Hour,
+		Issuer:   "vaultpass-eu",
+		Audience: "vaultpass-sync",
+	})
+
+	token, err := m.Generate(7, "")
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	claims, err := m.Validate(token)
+	if err != nil {
+		t.Fatalf("Validate() unexpected error: %v", err)
+	}
+	if claims.Issuer != "vaultpass-eu" {
+		t.Errorf("Issuer = %q, want %q", claims.Issuer, "vaultpass-eu")
+	}
+	if len(claims.Audience) != 1 || claims.Audience[0] != "vaultpass-sync" {
+		t.Errorf("Audience = %v, want [vaultpass-sync]", claims.Audience)
+	}
+
+	// Tokens minted with a custom issuer are not accepted by the defaults.
+	if _, err := ValidateToken(token, "test-secret"); err == nil {
+		t.Error("ValidateToken() expected error for non-default issuer")
+	}
+}
+
+func TestTokenManagerSecretRotation(t *testing.T) {
+	oldManager := NewTokenManager(TokenConfig{Secret: "old-secret", Expiry: time.Hour})
+	oldToken, err := oldManager.Generate(1, "")
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	rotated := NewTokenManager(TokenConfig{Secret: "new-secret", OldSecrets: []string{"old-secret"}, Expiry: time.Hour})
+	newToken, err := rotated.Generate(2, "")
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	// New tokens are signed with the primary secret only.
+	if _, err := ValidateToken(newToken, "new-secret"); err != nil {
+		t.Errorf("token signed with the primary secret should validate: %v", err)
+	}
+	if claims, err := rotated.Validate(oldToken); err != nil || claims.UserID != 1 {
+		t.Errorf("token signed with an old secret should validate during overlap, got %v", err)
+	}
+	if _, err := ValidateToken(oldToken, "new-secret", "old-secret"); err != nil {
+		t.Errorf("ValidateToken() should accept old secrets: %v", err)
+	}
+
+	retired := NewTokenManager(TokenConfig{Secret: "new-secret", Expiry: time.Hour})
+	if _, err := retired.Validate(oldToken); err != ErrInvalidToken {
+		t.Errorf("token signed with a removed secret should fail, got %v", err)
+	}
+}
+
+func TestGenerateTokenSetsNotBefore(t *testing.T) {
+	token, err := GenerateToken(42, "test-secret", time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateToken() unexpected error: %v", err)
+	}
+	claims, err := ValidateToken(token, "test-secret")
+	if err != nil {
+		t.Fatalf("ValidateToken() unexpected error: %v", err)
+	}
+	if claims.NotBefore == nil || !claims.NotBefore.Equal(claims.IssuedAt.Time) {
+		t.Errorf("expected nbf equal to iat, got nbf=%v iat=%v", claims.NotBefore, claims.IssuedAt)
+	}
+}
+
+func TestValidateTokenClockSkewLeeway(t *testing.T) {
+	const secret = "test-secret"
+	sign := func(notBefore, expiresAt time.Time) string {
+		t.Helper()
+		claims := Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    DefaultIssuer,
+				Audience:  jwt.ClaimStrings{DefaultAudience},
+				NotBefore: jwt.NewNumericDate(notBefore),
+				ExpiresAt: jwt.NewNumericDate(expiresAt),
+			},
+			UserID: 42,
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatalf("SignedString() unexpected error: %v", err)
+		}
+		return token
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		nbf, exp  time.Time
+		wantValid bool
+	}{
+		{"issuer clock ahead within leeway", now.Add(10 * time.Second), now.Add(time.Hour), true},
+		{"issuer clock ahead beyond leeway", now.Add(2 * time.Minute), now.Add(time.Hour), false},
+		{"expired within leeway", now.Add(-time.Hour), now.Add(-10 * time.Second), true},
+		{"expired beyond leeway", now.Add(-time.Hour), now.Add(-2 * time.Minute), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateToken(sign(tt.nbf, tt.exp), secret)
+			if (err == nil) != tt.wantValid {
+				t.Errorf("expected valid=%v with the default leeway, got %v", tt.wantValid, err)
+			}
+		})
+	}
+
+	// A tighter configured leeway rejects the token the default accepts.
+	strict := NewTokenManager(TokenConfig{Secret: secret, Leeway: time.Second})
+	if _, err := strict.Validate(sign(now.Add(10*time.Second), now.Add(time.Hour))); err != ErrInvalidToken {
+		t.Errorf("expected a 1s leeway to reject a token not valid for 10s, got %v", err)
+	}
+}
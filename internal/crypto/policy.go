@@ -0,0 +1,75 @@
+package crypto
+
+import (
+	"errors"
+	"regexp"
+	"regexp/syntax"
+)
+
+// MaxPolicyAttempts caps how many passwords are generated in search of one
+// matching a Policy, so an unsatisfiable pattern fails instead of looping.
+const MaxPolicyAttempts = 100
+
+// MaxPolicyLength is the longest pattern NewPolicy accepts.
+const MaxPolicyLength = 256
+
+// maxPolicyInstructions bounds the compiled program. Go's regexp matches in
+// linear time, so this guards memory and per-match cost rather than
+// backtracking; counted repeats like (a{100}){100} are rejected here.
+const maxPolicyInstructions = 2000
+
+var (
+	ErrPolicyInvalid     = errors.New("policy_regex is not a valid regular expression")
+	ErrPolicyTooComplex  = errors.New("policy_regex is too long or complex")
+	ErrPolicyUnsatisfied = errors.New("could not generate a password matching policy_regex; check the pattern against the length and character types")
+)
+
+// Policy is a regular expression a generated password must match. Matching is
+// unanchored, so use ^ and $ to constrain the whole password. A nil Policy
+// matches everything.
+type Policy struct {
+	re *regexp.Regexp
+}
+
+// NewPolicy compiles pattern with RE2 syntax, rejecting patterns longer than
+// MaxPolicyLength or that compile to an oversized program.
+func NewPolicy(pattern string) (*Policy, error) {
+	if len(pattern) > MaxPolicyLength {
+		return nil, ErrPolicyTooComplex
+	}
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		var serr *syntax.Error
+		if errors.As(err, &serr) && (serr.Code == syntax.ErrLarge || serr.Code == syntax.ErrInvalidRepeatSize) {
+			return nil, ErrPolicyTooComplex
+		}
+		return nil, ErrPolicyInvalid
+	}
+	prog, err := syntax.Compile(parsed.Simplify())
+	if err != nil || len(prog.Inst) > maxPolicyInstructions {
+		return nil, ErrPolicyTooComplex
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, ErrPolicyInvalid
+	}
+	return &Policy{re: re}, nil
+}
+
+// Matches reports whether password satisfies p.
+func (p *Policy) Matches(password string) bool {
+	return p == nil || p.re.MatchString(password)
+}
+
+// satisfyPolicy calls generate until it returns a password matching p, giving
+// up with ErrPolicyUnsatisfied after MaxPolicyAttempts.
+func satisfyPolicy(p *Policy, generate func() (string, error)) (string, error) {
+	for range MaxPolicyAttempts {
+		password, err := generate()
+		if err != nil || p.Matches(password) {
+			return password, err
+		}
+	}
+	return "", ErrPolicyUnsatisfied
+}
@@ -0,0 +1,75 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewPolicy(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    error
+	}{
+		{`[0-9]`, nil},
+		{`^[A-Z].*[!@#]$`, nil},
+		{`[0-9`, ErrPolicyInvalid},
+		{`(?<=a)b`, ErrPolicyInvalid},
+		{strings.Repeat("a", MaxPolicyLength+1), ErrPolicyTooComplex},
+		{`(a{100}){100}`, ErrPolicyTooComplex},
+		{`((a{50}){50}){50}`, ErrPolicyTooComplex},
+	}
+	for _, tt := range tests {
+		if _, err := NewPolicy(tt.pattern); err != tt.want {
+			t.Errorf("NewPolicy(%q) error = %v, want %v", tt.pattern, err, tt.want)
+		}
+	}
+
+	if !(*Policy)(nil).Matches("anything") {
+		t.Error("expected a nil policy to match everything")
+	}
+}
+
+func TestGenerate_PolicyRegenerates(t *testing.T) {
+	// The generator guarantees one digit when numbers are enabled, but not two.
+	policy, err := NewPolicy(`[0-9].*[0-9]`)
+	if err != nil {
+		t.Fatalf("NewPolicy() unexpected error: %v", err)
+	}
+	opts := GeneratorOptions{Length: 8, Uppercase: true, Lowercase: true, Numbers: true}
+
+	src := seededReader(3)
+	misses := 0
+	for range 20 {
+		pw, _ := GenerateWith(opts, src)
+		if !policy.Matches(pw) {
+			misses++
+		}
+	}
+	if misses == 0 {
+		t.Fatal("every unconstrained password already matches; pick another seed or policy")
+	}
+
+	opts.Policy = policy
+	src = seededReader(3)
+	for range 20 {
+		pw, err := GenerateWith(opts, src)
+		if err != nil {
+			t.Fatalf("GenerateWith() unexpected error: %v", err)
+		}
+		if !policy.Matches(pw) {
+			t.Fatalf("expected %q to contain two digits", pw)
+		}
+	}
+}
+
+func TestGenerate_PolicyUnsatisfiable(t *testing.T) {
+	policy, err := NewPolicy(`^.{40}$`)
+	if err != nil {
+		t.Fatalf("NewPolicy() unexpected error: %v", err)
+	}
+
+	_, err = GenerateWith(GeneratorOptions{Length: 16, Lowercase: true, Policy: policy}, seededReader(1))
+	if err != ErrPolicyUnsatisfied {
+		t.Errorf("expected ErrPolicyUnsatisfied, got %v", err)
+	}
+}
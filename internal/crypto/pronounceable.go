@@ -0,0 +1,85 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"io"
+	"math"
+	"unicode"
+)
+
+const (
+	// Letters that read unambiguously aloud; q, x and y are left out.
+	pronounceableConsonants = "bcdfghjklmnprstvwz"
+	pronounceableVowels     = "aeiou"
+)
+
+// GeneratePronounceable creates a password of alternating consonants and vowels
+// that is easy to read aloud. Numbers and Symbols each append one character of
+// that type; Uppercase capitalizes the first letter. Length is validated against
+// the same bounds as Generate. It returns the password and its entropy in bits.
+func GeneratePronounceable(opts GeneratorOptions) (string, float64, error) {
+	return GeneratePronounceableWith(opts, rand.Reader)
+}
+
+// GeneratePronounceableWith is GeneratePronounceable using src as the source of randomness.
+func GeneratePronounceableWith(opts GeneratorOptions, src io.Reader) (string, float64, error) {
+	if err := checkLength(opts); err != nil {
+		return "", 0, err
+	}
+	var entropy float64
+	password, err := satisfyPolicy(opts.Policy, func() (string, error) {
+		return avoidDenylisted(opts.Denylist, func() (string, error) {
+			password, bits, err := generatePronounceableOnce(opts, src)
+			entropy = bits
+			return password, err
+		})
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	return password, entropy, nil
+}
+
+// generatePronounceableOnce draws a single pronounceable password for opts,
+// which must have a valid length.
+func generatePronounceableOnce(opts GeneratorOptions, src io.Reader) (string, float64, error) {
+	var suffixSets []string
+	if opts.Numbers {
+		suffixSets = append(suffixSets, numberChars)
+	}
+	if opts.Symbols {
+		suffixSets = append(suffixSets, symbols(opts))
+	}
+
+	letters := opts.Length - len(suffixSets)
+	result := make([]byte, 0, opts.Length)
+	var entropy float64
+
+	for i := range letters {
+		charset := pronounceableConsonants
+		if i%2 == 1 {
+			charset = pronounceableVowels
+		}
+		ch, err := randChar(src, charset)
+		if err != nil {
+			return "", 0, err
+		}
+		result = append(result, ch)
+		entropy += math.Log2(float64(len(charset)))
+	}
+
+	for _, charset := range suffixSets {
+		ch, err := randChar(src, charset)
+		if err != nil {
+			return "", 0, err
+		}
+		result = append(result, ch)
+		entropy += math.Log2(float64(len(charset)))
+	}
+
+	if opts.Uppercase {
+		result[0] = byte(unicode.ToUpper(rune(result[0])))
+	}
+
+	return string(result), entropy, nil
+}
@@ -0,0 +1,72 @@
+package crypto
+
+import (
+	"regexp"
+	"testing"
+)
+
+var pronounceablePattern = regexp.MustCompile(`^([bcdfghjklmnprstvwz][aeiou])*[bcdfghjklmnprstvwz]?$`)
+
+func TestGeneratePronounceable_Pattern(t *testing.T) {
+	for _, length := range []int{MinLength, 15, 32} {
+		pw, entropy, err := GeneratePronounceable(GeneratorOptions{Length: length})
+		if err != nil {
+			t.Fatalf("GeneratePronounceable(%d) unexpected error: %v", length, err)
+		}
+		if len(pw) != length {
+			t.Errorf("expected length %d, got %d (%q)", length, len(pw), pw)
+		}
+		if !pronounceablePattern.MatchString(pw) {
+			t.Errorf("%q does not alternate consonants and vowels", pw)
+		}
+		if entropy <= 0 {
+			t.Errorf("expected positive entropy, got %v", entropy)
+		}
+	}
+}
+
+func TestGeneratePronounceable_InjectsPolicyCharacters(t *testing.T) {
+	pattern := regexp.MustCompile(`^[BCDFGHJKLMNPRSTVWZ][aeiou]([bcdfghjklmnprstvwz][aeiou])*[bcdfghjklmnprstvwz]?[0-9][!@#$%^&*()_+\-=\[\]{}|;:,.<>?]$`)
+
+	pw, _, err := GeneratePronounceable(GeneratorOptions{Length: 12, Uppercase: true, Numbers: true, Symbols: true})
+	if err != nil {
+		t.Fatalf("GeneratePronounceable() unexpected error: %v", err)
+	}
+	if len(pw) != 12 {
+		t.Errorf("expected length 12, got %d", len(pw))
+	}
+	if !pattern.MatchString(pw) {
+		t.Errorf("%q does not match capitalized syllables plus digit and symbol", pw)
+	}
+}
+
+func TestGeneratePronounceable_LowerEntropyThanRandom(t *testing.T) {
+	opts := GeneratorOptions{Length: 16, Uppercase: true, Lowercase: true, Numbers: true, Symbols: true}
+
+	_, entropy, err := GeneratePronounceable(opts)
+	if err != nil {
+		t.Fatalf("GeneratePronounceable() unexpected error: %v", err)
+	}
+	if random := Entropy(opts); entropy >= random {
+		t.Errorf("expected pronounceable entropy %.1f below random %.1f", entropy, random)
+	}
+}
+
+func TestGeneratePronounceable_Bounds(t *testing.T) {
+	if _, _, err := GeneratePronounceable(GeneratorOptions{Length: 4}); err != ErrLengthTooShort {
+		t.Errorf("expected ErrLengthTooShort, got %v", err)
+	}
+	if _, _, err := GeneratePronounceable(GeneratorOptions{Length: 500}); err != ErrLengthTooLong {
+		t.Errorf("expected ErrLengthTooLong, got %v", err)
+	}
+}
+
+func TestGeneratePronounceableWith_SameSeedIsReproducible(t *testing.T) {
+	opts := GeneratorOptions{Length: 14, Numbers: true}
+
+	a, _, _ := GeneratePronounceableWith(opts, seededReader(7))
+	b, _, _ := GeneratePronounceableWith(opts, seededReader(7))
+	if a != b {
+		t.Errorf("expected identical output for the same seed, got %q and %q", a, b)
+	}
+}
@@ -0,0 +1,152 @@
+package crypto
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Findings reported by AnalyzeStrength, each naming one reason a password is
+// weaker than its length and character mix alone suggest.
+const (
+	FindingTooShort    = "too_short"    // fewer than RecommendedLength characters
+	FindingNoUppercase = "no_uppercase" // no A-Z
+	FindingNoLowercase = "no_lowercase" // no a-z
+	FindingNoNumbers   = "no_numbers"   // no 0-9
+	FindingNoSymbols   = "no_symbols"   // nothing outside letters and digits
+	FindingSequence    = "sequence"     // a run like "abc", "123" or "cba"
+	FindingRepeated    = "repeated"     // the same character three times in a row
+	FindingCommonWord  = "common_word"  // a well-known password or word, also in l33t spelling
+)
+
+// RecommendedLength is the shortest password AnalyzeStrength accepts without
+// reporting FindingTooShort.
+const RecommendedLength = 12
+
+// minPatternRun is how long a sequence or repeat must be to count.
+const minPatternRun = 3
+
+// commonWords are fragments found in most cracking wordlists. Matching is
+// case-insensitive and after undoing common l33t substitutions.
+var commonWords = []string{
+	"password", "passwort", "qwerty", "asdf", "zxcv", "letmein", "welcome",
+	"admin", "login", "iloveyou", "monkey", "dragon", "master", "shadow",
+	"sunshine", "princess", "football", "baseball", "superman", "trustno",
+	"secret", "changeme",
+}
+
+// leetReplacer maps common l33t substitutions back to letters.
+var leetReplacer = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s", "!", "i")
+
+// StrengthReport is the structured outcome of AnalyzeStrength.
+type StrengthReport struct {
+	EntropyBits float64
+	// Score runs from 0 (trivial) to 4 (strong).
+	Score    int
+	Findings []string
+}
+
+// AnalyzeStrength estimates password's entropy and lists the findings that
+// explain a low score. The score starts from the entropy estimate and loses a
+// point for each sequence, repeat or common word found.
+func AnalyzeStrength(password string) StrengthReport {
+	report := StrengthReport{EntropyBits: EstimateEntropy(password), Findings: []string{}}
+
+	if utf8.RuneCountInString(password) < RecommendedLength {
+		report.Findings = append(report.Findings, FindingTooShort)
+	}
+	if !strings.ContainsAny(password, uppercaseChars) {
+		report.Findings = append(report.Findings, FindingNoUppercase)
+	}
+	if !strings.ContainsAny(password, lowercaseChars) {
+		report.Findings = append(report.Findings, FindingNoLowercase)
+	}
+	if !strings.ContainsAny(password, numberChars) {
+		report.Findings = append(report.Findings, FindingNoNumbers)
+	}
+	if strings.Trim(password, uppercaseChars+lowercaseChars+numberChars) == "" {
+		report.Findings = append(report.Findings, FindingNoSymbols)
+	}
+
+	score := entropyScore(report.EntropyBits)
+	for _, p := range []struct {
+		finding string
+		found   bool
+	}{
+		{FindingSequence, hasSequence(password)},
+		{FindingRepeated, hasRepeat(password)},
+		{FindingCommonWord, hasCommonWord(password)},
+	} {
+		if p.found {
+			report.Findings = append(report.Findings, p.finding)
+			score--
+		}
+	}
+	report.Score = max(score, 0)
+	return report
+}
+
+// entropyScore buckets an entropy estimate into a 0-4 score.
+func entropyScore(bits float64) int {
+	switch {
+	case bits < 28:
+		return 0
+	case bits < 36:
+		return 1
+	case bits < 60:
+		return 2
+	case bits < 80:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// hasSequence reports whether password has minPatternRun or more consecutive
+// characters that step by one in either direction, such as "abc" or "321".
+func hasSequence(password string) bool {
+	runes := []rune(strings.ToLower(password))
+	up, down := 1, 1
+	for i := 1; i < len(runes); i++ {
+		switch runes[i] - runes[i-1] {
+		case 1:
+			up, down = up+1, 1
+		case -1:
+			up, down = 1, down+1
+		default:
+			up, down = 1, 1
+		}
+		if up >= minPatternRun || down >= minPatternRun {
+			return true
+		}
+	}
+	return false
+}
+
+// hasRepeat reports whether password repeats one character minPatternRun or more times in a row.
+func hasRepeat(password string) bool {
+	runes := []rune(password)
+	run := 1
+	for i := 1; i < len(runes); i++ {
+		if runes[i] == runes[i-1] {
+			run++
+		} else {
+			run = 1
+		}
+		if run >= minPatternRun {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCommonWord reports whether password contains one of commonWords.
+func hasCommonWord(password string) bool {
+	lower := strings.ToLower(password)
+	unleet := leetReplacer.Replace(lower)
+	for _, w := range commonWords {
+		if strings.Contains(lower, w) || strings.Contains(unleet, w) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package crypto
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAnalyzeStrength_Findings(t *testing.T) {
+	tests := []struct {
+		password string
+		want     []string
+	}{
+		{"aaaa1234", []string{FindingTooShort, FindingNoUppercase, FindingNoSymbols, FindingSequence, FindingRepeated}},
+		{"zyx-Q7w!Lm2#pR", []string{FindingSequence}},
+		{"P@ssw0rd-2024!", []string{FindingCommonWord}},
+		{"Kt7#vR2m!Qp9zW", []string{}},
+		{"ABCDEFGHIJKL", []string{FindingNoLowercase, FindingNoNumbers, FindingNoSymbols, FindingSequence}},
+	}
+
+	for _, tt := range tests {
+		got := AnalyzeStrength(tt.password).Findings
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("AnalyzeStrength(%q).Findings = %q, want %q", tt.password, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeStrength_PatternsLowerScore(t *testing.T) {
+	clean := AnalyzeStrength("Kt7#vR2m!Qp9zW")
+	if clean.Score != 4 {
+		t.Errorf("expected a clean 14-character mixed password to score 4, got %d (%.1f bits)", clean.Score, clean.EntropyBits)
+	}
+
+	// Same length and character mix, but with a sequence and a repeat.
+	patterned := AnalyzeStrength("Kt7#abc!Qp999W")
+	if patterned.Score != clean.Score-2 {
+		t.Errorf("expected two points off for two patterns, got %d vs %d", patterned.Score, clean.Score)
+	}
+
+	if weak := AnalyzeStrength("aaaa1234"); weak.Score != 0 {
+		t.Errorf("expected a score of 0, got %d", weak.Score)
+	}
+}
@@ -0,0 +1,74 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+)
+
+// Token encodings supported by GenerateRandomToken.
+const (
+	EncodingHex       = "hex"
+	EncodingBase32    = "base32"
+	EncodingBase64URL = "base64url"
+
+	MinTokenBytes     = 16
+	MaxTokenBytes     = 512
+	DefaultTokenBytes = 32
+)
+
+var (
+	ErrTokenBytes      = errors.New("token bytes must be between 16 and 512")
+	ErrUnknownEncoding = errors.New("unknown token encoding")
+)
+
+// base32NoPad is standard base32 without '=' padding, so tokens are URL-safe.
+var base32NoPad = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateRandomToken returns n random bytes from crypto/rand in the given
+// encoding. Unlike Generate, the output is not constrained by a character policy.
+func GenerateRandomToken(encoding string, n int) (string, error) {
+	return GenerateRandomTokenWith(encoding, n, rand.Reader)
+}
+
+// GenerateRandomTokenWith is GenerateRandomToken using src as the source of randomness.
+func GenerateRandomTokenWith(encoding string, n int, src io.Reader) (string, error) {
+	if n < MinTokenBytes || n > MaxTokenBytes {
+		return "", ErrTokenBytes
+	}
+
+	var encode func([]byte) string
+	switch encoding {
+	case EncodingHex:
+		encode = hex.EncodeToString
+	case EncodingBase32:
+		encode = base32NoPad.EncodeToString
+	case EncodingBase64URL:
+		encode = base64.RawURLEncoding.EncodeToString
+	default:
+		return "", ErrUnknownEncoding
+	}
+
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(src, buf); err != nil {
+		return "", err
+	}
+	return encode(buf), nil
+}
+
+// NewUUID returns a random RFC 9562 version 4 UUID in its 36-character
+// canonical form.
+func NewUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 9562 variant
+
+	h := hex.EncodeToString(b[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
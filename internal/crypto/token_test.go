@@ -0,0 +1,80 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"regexp"
+	"testing"
+)
+
+func TestGenerateRandomToken_Encodings(t *testing.T) {
+	tests := []struct {
+		encoding string
+		pattern  *regexp.Regexp
+		decode   func(string) ([]byte, error)
+	}{
+		{EncodingHex, regexp.MustCompile(`^[0-9a-f]+$`), hex.DecodeString},
+		{EncodingBase32, regexp.MustCompile(`^[A-Z2-7]+$`), base32NoPad.DecodeString},
+		{EncodingBase64URL, regexp.MustCompile(`^[A-Za-z0-9_-]+$`), base64.RawURLEncoding.DecodeString},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoding, func(t *testing.T) {
+			for _, n := range []int{MinTokenBytes, 33, MaxTokenBytes} {
+				token, err := GenerateRandomToken(tt.encoding, n)
+				if err != nil {
+					t.Fatalf("GenerateRandomToken(%d) unexpected error: %v", n, err)
+				}
+				if !tt.pattern.MatchString(token) {
+					t.Errorf("token %q is not unpadded URL-safe %s", token, tt.encoding)
+				}
+				raw, err := tt.decode(token)
+				if err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				if len(raw) != n {
+					t.Errorf("decoded length = %d, want %d", len(raw), n)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateRandomToken_Errors(t *testing.T) {
+	if _, err := GenerateRandomToken(EncodingHex, MinTokenBytes-1); err != ErrTokenBytes {
+		t.Errorf("expected ErrTokenBytes below minimum, got %v", err)
+	}
+	if _, err := GenerateRandomToken(EncodingHex, MaxTokenBytes+1); err != ErrTokenBytes {
+		t.Errorf("expected ErrTokenBytes above maximum, got %v", err)
+	}
+	if _, err := GenerateRandomToken("base58", 32); err != ErrUnknownEncoding {
+		t.Errorf("expected ErrUnknownEncoding, got %v", err)
+	}
+}
+
+func TestGenerateRandomTokenWith_SameSeedIsReproducible(t *testing.T) {
+	a, _ := GenerateRandomTokenWith(EncodingHex, 32, seededReader(3))
+	b, _ := GenerateRandomTokenWith(EncodingHex, 32, seededReader(3))
+	if a != b {
+		t.Errorf("expected identical output for the same seed, got %q and %q", a, b)
+	}
+}
+
+func TestNewUUID(t *testing.T) {
+	uuidV4 := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	seen := make(map[string]bool)
+	for range 100 {
+		id, err := NewUUID()
+		if err != nil {
+			t.Fatalf("NewUUID() unexpected error: %v", err)
+		}
+		if !uuidV4.MatchString(id) {
+			t.Fatalf("NewUUID() = %q, not a canonical version 4 UUID", id)
+		}
+		if seen[id] {
+			t.Fatalf("NewUUID() repeated %q", id)
+		}
+		seen[id] = true
+	}
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vaultpass/vaultpass-go/internal/middleware"
+	"github.com/vaultpass/vaultpass-go/internal/model"
+	"github.com/vaultpass/vaultpass-go/internal/service"
+)
+
+// AdminHandler handles admin requests about user accounts.
+type AdminHandler struct {
+	exports *service.AccountExportService
+	users   *service.UserListService
+}
+
+// NewAdminHandler creates a new AdminHandler.
+func NewAdminHandler(exports *service.AccountExportService, users *service.UserListService) *AdminHandler {
+	return &AdminHandler{exports: exports, users: users}
+}
+
+// HandleListUsers handles GET /api/v1/admin/users requests, returning a page
+// of accounts oldest first. Auth hashes are never included.
+func (h *AdminHandler) HandleListUsers(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryInt(r, "limit")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse("invalid limit"))
+		return
+	}
+
+	resp, err := h.users.List(r.Context(), model.UserListQuery{Limit: limit, Cursor: r.URL.Query().Get("cursor")})
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// HandleExportUser handles GET /api/v1/admin/users/{id}/export requests. Every
+// export is logged so access to account data can be audited.
+func (h *AdminHandler) HandleExportUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || userID < 1 {
+		writeJSON(w, http.StatusBadRequest, errorResponse("invalid user id"))
+		return
+	}
+
+	export, err := h.exports.Export(r.Context(), userID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	slog.Info("admin exported user account",
+		"user_id", userID,
+		"vaults", len(export.Vaults),
+		"request_id", middleware.RequestIDFromContext(r.Context()),
+		"remote_addr", r.RemoteAddr,
+	)
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, export)
+}
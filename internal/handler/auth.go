@@ -3,46 +3,82 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"net"
 	"net/http"
+	"strconv"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/vaultpass/vaultpass-go/internal/middleware"
 	"github.com/vaultpass/vaultpass-go/internal/model"
 	"github.com/vaultpass/vaultpass-go/internal/service"
 )
 
+// errRegistrationClosed is returned to signups while registration is closed.
+var errRegistrationClosed = errors.New("registration is closed on this server")
+
+// DefaultMaxAuthBody is the default request body limit for register, login and reactivate.
+const DefaultMaxAuthBody int64 = 1 << 20 // 1MB
+
+// maxKDFProfileBody caps a key derivation profile upload, which is a handful of small fields.
+const maxKDFProfileBody = 4 << 10 // 4KB
+
 // AuthHandler handles HTTP requests for authentication.
 type AuthHandler struct {
-	service *service.AuthService
+	service            *service.AuthService
+	registrationClosed bool
+	maxBody            int64
+}
+
+// AuthHandlerOption configures an AuthHandler.
+type AuthHandlerOption func(*AuthHandler)
+
+// WithRegistrationClosed rejects new signups with 403. Existing accounts can
+// still log in.
+func WithRegistrationClosed() AuthHandlerOption {
+	return func(h *AuthHandler) {
+		h.registrationClosed = true
+	}
+}
+
+// WithMaxAuthBody overrides the DefaultMaxAuthBody limit. n must be positive.
+func WithMaxAuthBody(n int64) AuthHandlerOption {
+	return func(h *AuthHandler) {
+		h.maxBody = n
+	}
 }
 
 // NewAuthHandler creates a new AuthHandler.
-func NewAuthHandler(svc *service.AuthService) *AuthHandler {
-	return &AuthHandler{service: svc}
+func NewAuthHandler(svc *service.AuthService, opts ...AuthHandlerOption) *AuthHandler {
+	h := &AuthHandler{service: svc, maxBody: DefaultMaxAuthBody}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // HandleRegister handles POST /api/v1/auth/register requests.
 func (h *AuthHandler) HandleRegister(w http.ResponseWriter, r *http.Request) {
-	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1MB
+	if h.registrationClosed {
+		writeJSON(w, http.StatusForbidden, errorResponse(errRegistrationClosed.Error()))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxBody)
 
 	var req model.CreateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		if err.Error() == "http: request body too large" {
-			writeJSON(w, http.StatusRequestEntityTooLarge, errorResponse("request body too large"))
-			return
-		}
-		writeJSON(w, http.StatusBadRequest, errorResponse("invalid request body"))
+	if err := newDecoder(r).Decode(&req); err != nil {
+		writeDecodeError(w, err)
 		return
 	}
 
-	resp, err := h.service.Register(r.Context(), req)
+	resp, err := h.service.Register(r.Context(), req, clientInfo(r))
 	if err != nil {
+		var verr *model.ValidationError
 		switch {
-		case errors.Is(err, service.ErrEmailRequired), errors.Is(err, service.ErrPasswordRequired):
-			writeJSON(w, http.StatusBadRequest, errorResponse(err.Error()))
-		case errors.Is(err, service.ErrEmailTaken):
-			writeJSON(w, http.StatusConflict, errorResponse(err.Error()))
+		case errors.As(err, &verr):
+			writeJSON(w, http.StatusBadRequest, validationErrorResponse(verr))
 		default:
-			writeJSON(w, http.StatusInternalServerError, errorResponse("internal server error"))
+			writeAppError(w, err)
 		}
 		return
 	}
@@ -50,27 +86,72 @@ func (h *AuthHandler) HandleRegister(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusCreated, resp)
 }
 
+// HandleCheckEmail handles POST /api/v1/auth/check-email requests. The route
+// has its own strict rate limit, and the service pads every answer to the same
+// minimum time, so it can't be used to enumerate accounts quickly.
+func (h *AuthHandler) HandleCheckEmail(w http.ResponseWriter, r *http.Request) {
+	if h.registrationClosed {
+		writeJSON(w, http.StatusForbidden, errorResponse(errRegistrationClosed.Error()))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxBody)
+
+	var req model.CheckEmailRequest
+	if err := newDecoder(r).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	resp, err := h.service.CheckEmail(r.Context(), req)
+	if err != nil {
+		var verr *model.ValidationError
+		if errors.As(err, &verr) {
+			writeJSON(w, http.StatusBadRequest, validationErrorResponse(verr))
+			return
+		}
+		writeInternalError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, resp)
+}
+
 // HandleLogin handles POST /api/v1/auth/login requests.
 func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
-	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1MB
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxBody)
 
 	var req model.LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		if err.Error() == "http: request body too large" {
-			writeJSON(w, http.StatusRequestEntityTooLarge, errorResponse("request body too large"))
-			return
-		}
-		writeJSON(w, http.StatusBadRequest, errorResponse("invalid request body"))
+	if err := newDecoder(r).Decode(&req); err != nil {
+		writeDecodeError(w, err)
 		return
 	}
 
-	resp, err := h.service.Login(r.Context(), req)
+	resp, err := h.service.Login(r.Context(), req, clientInfo(r))
 	if err != nil {
-		if errors.Is(err, service.ErrInvalidCredentials) {
-			writeJSON(w, http.StatusUnauthorized, errorResponse(err.Error()))
-			return
-		}
-		writeJSON(w, http.StatusInternalServerError, errorResponse("internal server error"))
+		writeAppError(w, err)
+		return
+	}
+
+	// Correct credentials: don't let earlier typos keep throttling this client.
+	middleware.ResetRateLimit(r.Context())
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// HandleReactivate handles POST /api/v1/auth/reactivate requests.
+func (h *AuthHandler) HandleReactivate(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxBody)
+
+	var req model.LoginRequest
+	if err := newDecoder(r).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	resp, err := h.service.Reactivate(r.Context(), req, clientInfo(r))
+	if err != nil {
+		writeAppError(w, err)
 		return
 	}
 
@@ -87,9 +168,189 @@ func (h *AuthHandler) HandleMe(w http.ResponseWriter, r *http.Request) {
 
 	resp, err := h.service.GetUser(r.Context(), userID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, errorResponse("internal server error"))
+		writeInternalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// HandleLoginHistory handles GET /api/v1/auth/login-history requests.
+func (h *AuthHandler) HandleLoginHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse("unauthorized"))
+		return
+	}
+
+	limit, err := queryInt(r, "limit")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse("invalid limit"))
+		return
+	}
+	offset, err := queryInt(r, "offset")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse("invalid offset"))
+		return
+	}
+
+	q := model.LoginHistoryQuery{Limit: limit, Offset: offset, Cursor: r.URL.Query().Get("cursor")}
+	if v := r.URL.Query().Get("success"); v != "" {
+		success, err := strconv.ParseBool(v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse("invalid success filter"))
+			return
+		}
+		q.Success = &success
+	}
+
+	resp, err := h.service.LoginHistory(r.Context(), userID, q)
+	if err != nil {
+		writeAppError(w, err)
 		return
 	}
 
 	writeJSON(w, http.StatusOK, resp)
 }
+
+// HandleListSessions handles GET /api/v1/auth/sessions requests.
+func (h *AuthHandler) HandleListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse("unauthorized"))
+		return
+	}
+
+	sessions, err := h.service.ListSessions(r.Context(), userID, middleware.SessionIDFromContext(r.Context()))
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+// HandleRevokeSession handles DELETE /api/v1/auth/sessions/{id} requests.
+func (h *AuthHandler) HandleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse("unauthorized"))
+		return
+	}
+
+	sessionID := chi.URLParam(r, "id")
+	if sessionID == "" || len(sessionID) > 64 {
+		writeJSON(w, http.StatusBadRequest, errorResponse("invalid session id"))
+		return
+	}
+
+	err := h.service.RevokeSession(r.Context(), userID, sessionID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleDeactivate handles POST /api/v1/auth/deactivate requests.
+func (h *AuthHandler) HandleDeactivate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse("unauthorized"))
+		return
+	}
+
+	if err := h.service.Deactivate(r.Context(), userID); err != nil {
+		writeInternalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetKDFProfile handles GET /api/v1/auth/kdf-profile requests.
+func (h *AuthHandler) HandleGetKDFProfile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse("unauthorized"))
+		return
+	}
+
+	profile, err := h.service.KDFProfile(r.Context(), userID)
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, profile)
+}
+
+// HandlePutKDFProfile handles PUT /api/v1/auth/kdf-profile requests.
+func (h *AuthHandler) HandlePutKDFProfile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse("unauthorized"))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxKDFProfileBody)
+
+	var req model.KDFProfile
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	profile, err := h.service.SetKDFProfile(r.Context(), userID, req)
+	if err != nil {
+		var verr *model.ValidationError
+		if errors.As(err, &verr) {
+			writeJSON(w, http.StatusBadRequest, validationErrorResponse(verr))
+			return
+		}
+		writeInternalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, profile)
+}
+
+// HandleLogoutAll handles POST /api/v1/auth/logout-all requests.
+func (h *AuthHandler) HandleLogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse("unauthorized"))
+		return
+	}
+
+	if err := h.service.LogoutAll(r.Context(), userID); err != nil {
+		writeInternalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// clientInfo extracts the non-secret client metadata recorded in audit logs.
+func clientInfo(r *http.Request) model.ClientInfo {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return model.ClientInfo{
+		IPAddress: ip,
+		UserAgent: r.UserAgent(),
+	}
+}
+
+// queryInt parses an optional integer query parameter, returning 0 when absent.
+func queryInt(r *http.Request, name string) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(v)
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/crypto"
+	"github.com/vaultpass/vaultpass-go/internal/repository"
+	"github.com/vaultpass/vaultpass-go/internal/service"
+)
+
+func newMemoryAuthHandler(opts ...AuthHandlerOption) *AuthHandler {
+	return NewAuthHandler(service.NewAuthService(
+		repository.NewMemoryUserRepository(),
+		repository.NewMemoryAuditRepository(),
+		repository.NewMemorySessionRepository(),
+		crypto.NewTokenManager(crypto.TokenConfig{Secret: "test-secret", Expiry: time.Hour}),
+	), opts...)
+}
+
+func TestHandleLogin_ConfiguredBodyLimit(t *testing.T) {
+	login := func(h *AuthHandler, password string) int {
+		body := `{"email":"alice@example.com","password":"` + password + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		h.HandleLogin(rec, req)
+		return rec.Code
+	}
+
+	h := newMemoryAuthHandler(WithMaxAuthBody(256))
+	if code := login(h, strings.Repeat("a", 100)); code != http.StatusUnauthorized {
+		t.Errorf("within limit: expected 401, got %d", code)
+	}
+	if code := login(h, strings.Repeat("a", 300)); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("over configured limit: expected 413, got %d", code)
+	}
+	// The same body is fine under the default limit.
+	if code := login(newMemoryAuthHandler(), strings.Repeat("a", 300)); code != http.StatusUnauthorized {
+		t.Errorf("default limit: expected 401, got %d", code)
+	}
+}
+
+func TestHandleRegister_RegistrationToggle(t *testing.T) {
+	register := func(h *AuthHandler) *httptest.ResponseRecorder {
+		body := `{"email":"alice@example.com","password":"correct horse battery"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		h.HandleRegister(rec, req)
+		return rec
+	}
+
+	if rec := register(newMemoryAuthHandler()); rec.Code != http.StatusCreated {
+		t.Errorf("open: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec := register(newMemoryAuthHandler(WithRegistrationClosed()))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("closed: expected 403, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp map[string]string
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp["error"] != "registration is closed on this server" {
+		t.Errorf("closed: unexpected error %q", resp["error"])
+	}
+}
+
+func TestHandleRegister_ValidationFields(t *testing.T) {
+	h := newMemoryAuthHandler()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", strings.NewReader(`{"email":"","password":""}`))
+	rec := httptest.NewRecorder()
+	h.HandleRegister(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+	var body struct {
+		Error struct {
+			Code   string            `json:"code"`
+			Fields map[string]string `json:"fields"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if body.Error.Code != "VALIDATION" {
+		t.Errorf("expected code VALIDATION, got %q", body.Error.Code)
+	}
+	want := map[string]string{"email": "required", "password": "required"}
+	if len(body.Error.Fields) != len(want) || body.Error.Fields["email"] != want["email"] || body.Error.Fields["password"] != want["password"] {
+		t.Errorf("expected fields %v, got %v", want, body.Error.Fields)
+	}
+}
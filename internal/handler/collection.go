@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/vaultpass/vaultpass-go/internal/middleware"
+	"github.com/vaultpass/vaultpass-go/internal/model"
+)
+
+// HandleListVaults handles GET /api/v1/vaults requests.
+func (h *VaultHandler) HandleListVaults(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse("unauthorized"))
+		return
+	}
+
+	vaults, err := h.service.ListVaults(r.Context(), userID)
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, vaults)
+}
+
+// HandleCreateVault handles POST /api/v1/vaults requests.
+func (h *VaultHandler) HandleCreateVault(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse("unauthorized"))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1MB
+
+	var req model.VaultRequest
+	if err := newDecoder(r).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	resp, err := h.service.CreateVault(r.Context(), userID, req)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// HandleGetVault handles GET /api/v1/vaults/{vault_id} requests.
+func (h *VaultHandler) HandleGetVault(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse("unauthorized"))
+		return
+	}
+
+	vaultID, ok := vaultIDParam(r)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, errorResponse("invalid vault id"))
+		return
+	}
+
+	resp, err := h.service.GetVault(r.Context(), userID, vaultID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// HandleRenameVault handles PATCH /api/v1/vaults/{vault_id} requests.
+func (h *VaultHandler) HandleRenameVault(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse("unauthorized"))
+		return
+	}
+
+	vaultID, ok := vaultIDParam(r)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, errorResponse("invalid vault id"))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1MB
+
+	var req model.VaultRequest
+	if err := newDecoder(r).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	resp, err := h.service.RenameVault(r.Context(), userID, vaultID, req)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// HandleDeleteVault handles DELETE /api/v1/vaults/{vault_id} requests.
+func (h *VaultHandler) HandleDeleteVault(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse("unauthorized"))
+		return
+	}
+
+	vaultID, ok := vaultIDParam(r)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, errorResponse("invalid vault id"))
+		return
+	}
+
+	if err := h.service.DeleteVault(r.Context(), userID, vaultID); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/vaultpass/vaultpass-go/internal/model"
+)
+
+// entryFields are the vault entry fields a ?fields= query may select, keyed by JSON name.
+var entryFields = map[string]func(e *model.VaultEntryResponse) any{
+	"entry_id":       func(e *model.VaultEntryResponse) any { return e.EntryID },
+	"encrypted_data": func(e *model.VaultEntryResponse) any { return e.EncryptedData },
+	"version":        func(e *model.VaultEntryResponse) any { return e.Version },
+	"favorite":       func(e *model.VaultEntryResponse) any { return e.Favorite },
+	"last_device_id": func(e *model.VaultEntryResponse) any { return e.LastDeviceID },
+	"created_at":     func(e *model.VaultEntryResponse) any { return e.CreatedAt },
+	"updated_at":     func(e *model.VaultEntryResponse) any { return e.UpdatedAt },
+	"deleted":        func(e *model.VaultEntryResponse) any { return e.Deleted },
+	"expires_at":     func(e *model.VaultEntryResponse) any { return e.ExpiresAt },
+
+	"last_accessed_at": func(e *model.VaultEntryResponse) any { return e.LastAccessedAt },
+}
+
+// entryFieldsParam parses a comma-separated ?fields= list of entry fields.
+// It returns nil when the parameter is absent, meaning every field.
+func entryFieldsParam(r *http.Request) ([]string, error) {
+	raw := r.URL.Query().Get("fields")
+	if raw == "" {
+		return nil, nil
+	}
+
+	var fields []string
+	for _, f := range strings.Split(raw, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" || slices.Contains(fields, f) {
+			continue
+		}
+		if _, ok := entryFields[f]; !ok {
+			return nil, fmt.Errorf("unknown field %q in fields", f)
+		}
+		fields = append(fields, f)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("fields must name at least one field")
+	}
+	return fields, nil
+}
+
+// writeEntries writes entries as JSON, projected onto fields when it is non-nil.
+func writeEntries(w http.ResponseWriter, status int, entries []model.VaultEntryResponse, fields []string) {
+	if fields == nil {
+		writeJSON(w, status, entries)
+		return
+	}
+
+	projected := make([]map[string]any, len(entries))
+	for i := range entries {
+		m := make(map[string]any, len(fields))
+		for _, f := range fields {
+			m[f] = entryFields[f](&entries[i])
+		}
+		projected[i] = m
+	}
+	writeJSON(w, status, projected)
+}
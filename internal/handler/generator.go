@@ -3,57 +3,189 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
 
-	"github.com/vaultpass/vaultpass-go/internal/crypto"
+	"github.com/vaultpass/vaultpass-go/internal/apperr"
+	"github.com/vaultpass/vaultpass-go/internal/middleware"
 	"github.com/vaultpass/vaultpass-go/internal/model"
 	"github.com/vaultpass/vaultpass-go/internal/service"
 )
 
+// MaxStrengthBatch caps the passwords scored by one batch strength request.
+const MaxStrengthBatch = 100
+
 // GeneratorHandler handles HTTP requests for password generation.
 type GeneratorHandler struct {
-	service *service.GeneratorService
+	service  *service.GeneratorService
+	visitors func() int
+}
+
+// GeneratorHandlerOption configures a GeneratorHandler.
+type GeneratorHandlerOption func(*GeneratorHandler)
+
+// WithRateLimitVisitors reports visitors() as the rate limiter visitor count
+// in the admin metrics.
+func WithRateLimitVisitors(visitors func() int) GeneratorHandlerOption {
+	return func(h *GeneratorHandler) {
+		h.visitors = visitors
+	}
 }
 
 // NewGeneratorHandler creates a new GeneratorHandler.
-func NewGeneratorHandler(svc *service.GeneratorService) *GeneratorHandler {
-	return &GeneratorHandler{service: svc}
+func NewGeneratorHandler(svc *service.GeneratorService, opts ...GeneratorHandlerOption) *GeneratorHandler {
+	h := &GeneratorHandler{service: svc}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // HandleGenerate handles POST /api/v1/generate requests.
 func (h *GeneratorHandler) HandleGenerate(w http.ResponseWriter, r *http.Request) {
+	// An empty body asks for the defaults.
 	var req model.GenerateRequest
 	if r.Body != nil {
 		r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1MB
 		defer r.Body.Close()
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			if err.Error() == "http: request body too large" {
-				writeJSON(w, http.StatusRequestEntityTooLarge, errorResponse("request body too large"))
-				return
-			}
-			writeJSON(w, http.StatusBadRequest, errorResponse("invalid request body"))
+		if err := newDecoder(r).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeDecodeError(w, err)
 			return
 		}
 	}
 
+	asFile := wantsFile(r)
+	if asFile && req.Recoverable {
+		writeJSON(w, http.StatusBadRequest, errorResponse("recoverable passwords cannot be downloaded as a file"))
+		return
+	}
+
 	resp, err := h.service.Generate(req)
 	if err != nil {
-		if isValidationError(err) {
-			writeJSON(w, http.StatusBadRequest, errorResponse(err.Error()))
-			return
-		}
-		writeJSON(w, http.StatusInternalServerError, errorResponse("internal server error"))
+		writeAppError(w, err)
+		return
+	}
+
+	if asFile {
+		writePasswordFile(w, resp.Password)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// wantsFile reports whether the client asked for the password as a plain-text
+// attachment, via ?format=file or an Accept header preferring text/plain.
+func wantsFile(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "file" {
+		return true
+	}
+	first, _, _ := strings.Cut(r.Header.Get("Accept"), ",")
+	mediaType, _, err := mime.ParseMediaType(first)
+	return err == nil && mediaType == "text/plain"
+}
+
+// writePasswordFile sends password as a password.txt download that no browser
+// or intermediary may store.
+func writePasswordFile(w http.ResponseWriter, password string) {
+	body := password + "\n"
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.Header().Set("Content-Disposition", `attachment; filename="password.txt"`)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, body)
+}
+
+// HandleDefaults handles GET /api/v1/generate/defaults requests.
+func (h *GeneratorHandler) HandleDefaults(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.service.Defaults())
+}
+
+// HandleCapabilities handles GET /api/v1/generate/capabilities requests.
+func (h *GeneratorHandler) HandleCapabilities(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.service.Capabilities())
+}
+
+// HandleMetrics handles GET /api/v1/admin/metrics requests.
+func (h *GeneratorHandler) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	resp := model.MetricsResponse{Generator: h.service.Metrics()}
+	if h.visitors != nil {
+		resp.RateLimit.Visitors = h.visitors()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// HandleRedeem handles POST /api/v1/generate/redeem requests.
+func (h *GeneratorHandler) HandleRedeem(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<10) // 1KB
+
+	var req model.RedeemRequest
+	if err := newDecoder(r).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	resp, err := h.service.Redeem(req.Handle)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// HandleStrength handles POST /api/v1/password/strength requests.
+func (h *GeneratorHandler) HandleStrength(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<10) // 1KB
+
+	var req model.StrengthRequest
+	if err := newDecoder(r).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	resp, err := h.service.Strength(r.Context(), req.Password)
+	if err != nil {
+		writeAppError(w, err)
 		return
 	}
 
+	w.Header().Set("Cache-Control", "no-store")
 	writeJSON(w, http.StatusOK, resp)
 }
 
-func isValidationError(err error) bool {
-	return errors.Is(err, crypto.ErrLengthTooShort) ||
-		errors.Is(err, crypto.ErrLengthTooLong) ||
-		errors.Is(err, crypto.ErrNoCharacterTypes) ||
-		errors.Is(err, crypto.ErrLengthInsufficient)
+// HandleStrengthBatch handles POST /api/v1/strength/batch requests.
+func (h *GeneratorHandler) HandleStrengthBatch(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 64<<10) // 64KB
+
+	var req model.StrengthBatchRequest
+	if err := newDecoder(r).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	if len(req.Passwords) == 0 {
+		writeJSON(w, http.StatusBadRequest, errorResponse("passwords is required"))
+		return
+	}
+	if len(req.Passwords) > MaxStrengthBatch {
+		writeJSON(w, http.StatusBadRequest, errorResponse(fmt.Sprintf("too many passwords in batch (max %d)", MaxStrengthBatch)))
+		return
+	}
+
+	resp := model.StrengthBatchResponse{Results: h.service.StrengthBatch(r.Context(), req.Passwords)}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, resp)
 }
 
 func writeJSON(w http.ResponseWriter, status int, v any) {
@@ -62,6 +194,116 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 	json.NewEncoder(w).Encode(v)
 }
 
+// retryAfterSeconds is the Retry-After hint sent with 503 responses. Clients
+// should back off further on repeated 503s rather than retry on a fixed beat.
+const retryAfterSeconds = "5"
+
+// writeInternalError answers a service error no handler case matched. Transient
+// infrastructure failures return 503 with a Retry-After hint so clients back
+// off; anything else is a bug and returns 500. Details never reach the client.
+func writeInternalError(w http.ResponseWriter, err error) {
+	if service.IsTransient(err) {
+		w.Header().Set("Retry-After", retryAfterSeconds)
+		writeJSON(w, http.StatusServiceUnavailable, errorResponse("service temporarily unavailable"))
+		return
+	}
+	writeJSON(w, http.StatusInternalServerError, errorResponse("internal server error"))
+}
+
+// writeAppError answers err with the status and code of the apperr.Error in
+// its chain. Errors without one are handled by writeInternalError.
+func writeAppError(w http.ResponseWriter, err error) {
+	if e, ok := apperr.As(err); ok {
+		writeJSON(w, e.Status, map[string]string{"error": err.Error(), "code": e.Code})
+		return
+	}
+	writeInternalError(w, err)
+}
+
 func errorResponse(msg string) map[string]string {
 	return map[string]string{"error": msg}
 }
+
+// newDecoder returns a decoder for r's body. Under middleware.StrictJSON it
+// rejects object fields the target type doesn't define.
+func newDecoder(r *http.Request) *json.Decoder {
+	dec := json.NewDecoder(r.Body)
+	if middleware.StrictJSONFromContext(r.Context()) {
+		dec.DisallowUnknownFields()
+	}
+	return dec
+}
+
+// writeDecodeError reports why a JSON request body could not be decoded:
+// 413 past the body limit, otherwise 400 naming the syntax or type problem.
+// Offending values are never echoed back.
+func writeDecodeError(w http.ResponseWriter, err error) {
+	var (
+		maxBytesErr *http.MaxBytesError
+		syntaxErr   *json.SyntaxError
+		typeErr     *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &maxBytesErr):
+		writeJSON(w, http.StatusRequestEntityTooLarge, errorResponse("request body too large"))
+	case errors.As(err, &syntaxErr):
+		writeJSON(w, http.StatusBadRequest, errorResponse(fmt.Sprintf("malformed JSON at byte %d", syntaxErr.Offset)))
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		writeJSON(w, http.StatusBadRequest, errorResponse("malformed JSON: unexpected end of body"))
+	case errors.Is(err, io.EOF):
+		writeJSON(w, http.StatusBadRequest, errorResponse("request body is empty"))
+	case errors.As(err, &typeErr):
+		writeJSON(w, http.StatusBadRequest, errorResponse(typeErrorMessage(typeErr)))
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// Returned by decoders that disallow unknown fields.
+		writeJSON(w, http.StatusBadRequest, errorResponse(strings.TrimPrefix(err.Error(), "json: ")))
+	default:
+		writeJSON(w, http.StatusBadRequest, errorResponse("invalid request body"))
+	}
+}
+
+// typeErrorMessage describes a JSON value that doesn't fit its Go field,
+// distinguishing out-of-range numbers from values of the wrong type.
+func typeErrorMessage(err *json.UnmarshalTypeError) string {
+	field := err.Field
+	if field == "" {
+		field = "request body"
+	}
+	number, isNumber := strings.CutPrefix(err.Value, "number ")
+	switch err.Type.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if isNumber && !strings.ContainsAny(number, ".eE") {
+			return field + " is out of range"
+		}
+		return field + " must be an integer"
+	case reflect.Float32, reflect.Float64:
+		if isNumber {
+			return field + " is out of range"
+		}
+		return field + " must be a number"
+	case reflect.String:
+		return field + " must be a string"
+	case reflect.Bool:
+		return field + " must be a boolean"
+	case reflect.Slice, reflect.Array:
+		return field + " must be an array"
+	default:
+		return field + " has the wrong type"
+	}
+}
+
+// validationErrorResponse reports each invalid field and its reason:
+// {"error": {"code": "VALIDATION", "fields": {"email": "invalid"}}}.
+func validationErrorResponse(verr *model.ValidationError) map[string]any {
+	fields := make(map[string]string, len(verr.Fields))
+	for _, f := range verr.Fields {
+		fields[f.Field] = f.Reason
+	}
+	return map[string]any{
+		"error": map[string]any{
+			"code":   "VALIDATION",
+			"fields": fields,
+		},
+	}
+}
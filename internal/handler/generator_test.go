@@ -0,0 +1,254 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/vaultpass/vaultpass-go/internal/crypto"
+	"github.com/vaultpass/vaultpass-go/internal/middleware"
+	"github.com/vaultpass/vaultpass-go/internal/model"
+	"github.com/vaultpass/vaultpass-go/internal/service"
+)
+
+func TestHandleGenerate_DecodeErrors(t *testing.T) {
+	h := NewGeneratorHandler(service.NewGeneratorService())
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"malformed JSON", `{"length": 16,}`, "malformed JSON at byte 15"},
+		{"truncated JSON", `{"length": 16`, "malformed JSON: unexpected end of body"},
+		{"numeric overflow", `{"length": 99999999999999999999}`, "length is out of range"},
+		{"fractional integer", `{"length": 16.5}`, "length must be an integer"},
+		{"wrong type", `{"length": "16"}`, "length must be an integer"},
+		{"bool as string", `{"symbols": "yes"}`, "symbols must be a boolean"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/generate", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			h.HandleGenerate(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d: %s", rec.Code, rec.Body)
+			}
+			var resp map[string]string
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+			if resp["error"] != tt.want {
+				t.Errorf("error = %q, want %q", resp["error"], tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleDefaults_ReflectsConfiguredDefaults(t *testing.T) {
+	h := NewGeneratorHandler(service.NewGeneratorService(
+		service.WithLengthBounds(crypto.LengthBounds{Min: 20, Max: 128}),
+		service.WithDefaults(crypto.GeneratorOptions{Length: 20, Uppercase: true, Lowercase: true, Numbers: true}),
+	))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/generate/defaults", nil)
+	rec := httptest.NewRecorder()
+	h.HandleDefaults(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp model.GenerateDefaultsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Mode != model.GenerateModeRandom || resp.Length != 20 || resp.Symbols || !resp.Uppercase {
+		t.Errorf("unexpected defaults: %+v", resp)
+	}
+	if resp.MinLength != 20 || resp.MaxLength != 128 {
+		t.Errorf("expected bounds 20-128, got %d-%d", resp.MinLength, resp.MaxLength)
+	}
+	if _, ok := resp.Presets["mobile"]; !ok || len(resp.Presets) != 4 {
+		t.Errorf("expected the four presets, got %v", resp.Presets)
+	}
+}
+
+func TestHandleStrengthBatch(t *testing.T) {
+	h := NewGeneratorHandler(service.NewGeneratorService())
+
+	post := func(passwords []string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(model.StrengthBatchRequest{Passwords: passwords})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/strength/batch", strings.NewReader(string(body)))
+		rec := httptest.NewRecorder()
+		h.HandleStrengthBatch(rec, req)
+		return rec
+	}
+
+	rec := post([]string{"abc", "", "correct horse battery staple"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp model.StrengthBatchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Results) != 3 || resp.Results[0].Length != 3 || resp.Results[1].Error == "" || resp.Results[2].Length != 28 {
+		t.Errorf("unexpected results: %+v", resp.Results)
+	}
+
+	tooMany := make([]string, MaxStrengthBatch+1)
+	for i := range tooMany {
+		tooMany[i] = "pw" + strconv.Itoa(i)
+	}
+	if rec := post(tooMany); rec.Code != http.StatusBadRequest {
+		t.Errorf("over the cap: expected 400, got %d", rec.Code)
+	}
+
+	if rec := post([]string{strings.Repeat("a", 70<<10)}); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body: expected 413, got %d", rec.Code)
+	}
+
+	if rec := post(nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("no passwords: expected 400, got %d", rec.Code)
+	}
+}
+
+func TestHandleGenerate_FileDownload(t *testing.T) {
+	h := NewGeneratorHandler(service.NewGeneratorService())
+
+	tests := []struct {
+		name   string
+		target string
+		accept string
+	}{
+		{"format query", "/api/v1/generate?format=file", ""},
+		{"accept header", "/api/v1/generate", "text/plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(`{"length": 20, "symbols": false}`))
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			h.HandleGenerate(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+			}
+			if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="password.txt"` {
+				t.Errorf("Content-Disposition = %q", got)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", got)
+			}
+			if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain") {
+				t.Errorf("Content-Type = %q, want text/plain", got)
+			}
+			if password := strings.TrimSuffix(rec.Body.String(), "\n"); len(password) != 20 {
+				t.Errorf("expected a bare 20-character password, got %q", rec.Body.String())
+			}
+		})
+	}
+
+	// Without either, the JSON response is unchanged.
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/generate", strings.NewReader(`{}`))
+	req.Header.Set("Accept", "application/json, text/plain")
+	rec := httptest.NewRecorder()
+	h.HandleGenerate(rec, req)
+	if got := rec.Header().Get("Content-Type"); got != "application/json" || rec.Header().Get("Content-Disposition") != "" {
+		t.Errorf("expected a JSON response, got Content-Type %q", got)
+	}
+}
+
+func TestHandleGenerate_PolicyRegex(t *testing.T) {
+	h := NewGeneratorHandler(service.NewGeneratorService())
+	generate := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.HandleGenerate(rec, httptest.NewRequest(http.MethodPost, "/api/v1/generate", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := generate(`{"length": 12, "policy_regex": "^[A-Z].*[0-9]$"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp model.GenerateResponse
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if first, last := resp.Password[0], resp.Password[len(resp.Password)-1]; first < 'A' || first > 'Z' || last < '0' || last > '9' {
+		t.Errorf("expected password matching the policy, got %q", resp.Password)
+	}
+
+	for body, want := range map[string]string{
+		`{"numbers": false, "policy_regex": "[0-9]"}`:    crypto.ErrPolicyUnsatisfied.Error(),
+		`{"policy_regex": "[0-9"}`:                       crypto.ErrPolicyInvalid.Error(),
+		`{"policy_regex": "(a{100}){100}"}`:              crypto.ErrPolicyTooComplex.Error(),
+		`{"mode": "hex", "policy_regex": "^[0-9a-f]+$"}`: service.ErrPolicyMode.Error(),
+	} {
+		rec := generate(body)
+		var resp map[string]string
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if rec.Code != http.StatusBadRequest || resp["error"] != want {
+			t.Errorf("%s: expected 400 %q, got %d %q", body, want, rec.Code, resp["error"])
+		}
+	}
+}
+
+func TestHandleGenerate_StrictJSON(t *testing.T) {
+	h := NewGeneratorHandler(service.NewGeneratorService())
+	const body = `{"lenght": 20}`
+
+	rec := httptest.NewRecorder()
+	h.HandleGenerate(rec, httptest.NewRequest(http.MethodPost, "/api/v1/generate", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("lenient: expected unknown fields to be ignored, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	middleware.StrictJSON(http.HandlerFunc(h.HandleGenerate)).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/generate", strings.NewReader(body)))
+	var resp map[string]string
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if rec.Code != http.StatusBadRequest || resp["error"] != `unknown field "lenght"` {
+		t.Errorf("strict: expected 400 naming the field, got %d %q", rec.Code, resp["error"])
+	}
+}
+
+func TestWriteAppError(t *testing.T) {
+	_, generateErr := service.NewGeneratorService().Generate(model.GenerateRequest{Length: 2})
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"not found", service.ErrVaultNotFound, http.StatusNotFound, "vault_not_found"},
+		{"wrapped conflict", fmt.Errorf("create: %w", service.ErrEntryExists), http.StatusConflict, "entry_exists"},
+		{"too large", service.ErrEntryTooLarge, http.StatusRequestEntityTooLarge, "entry_too_large"},
+		{"wrapped sentinel", service.ErrAccountDeactivated, http.StatusForbidden, "account_deactivated"},
+		{"generator options", generateErr, http.StatusBadRequest, "invalid_options"},
+		{"unknown", errors.New("boom"), http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeAppError(rec, tt.err)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			var resp map[string]string
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+			if resp["code"] != tt.code {
+				t.Errorf("code = %q, want %q", resp["code"], tt.code)
+			}
+			if tt.code != "" && resp["error"] != tt.err.Error() {
+				t.Errorf("error = %q, want %q", resp["error"], tt.err.Error())
+			}
+		})
+	}
+}
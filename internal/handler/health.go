@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/vaultpass/vaultpass-go/internal/crypto"
+)
+
+// HealthHandler reports server liveness and startup self-test results.
+type HealthHandler struct {
+	hashTiming crypto.HashTiming
+}
+
+// NewHealthHandler creates a new HealthHandler.
+func NewHealthHandler(hashTiming crypto.HashTiming) *HealthHandler {
+	return &HealthHandler{hashTiming: hashTiming}
+}
+
+type healthDetail struct {
+	Status string                 `json:"status"`
+	Checks map[string]healthCheck `json:"checks"`
+}
+
+type healthCheck struct {
+	DurationMs  int64 `json:"duration_ms"`
+	ThresholdMs int64 `json:"threshold_ms"`
+	Slow        bool  `json:"slow"`
+}
+
+// HandleHealth handles GET and HEAD /health requests; net/http drops the body for HEAD.
+// It returns plain "ok"; ?detail=true returns self-test results as JSON.
+func (h *HealthHandler) HandleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("detail") != "true" {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+		return
+	}
+
+	status := "ok"
+	if h.hashTiming.Slow {
+		status = "degraded"
+	}
+
+	writeJSON(w, http.StatusOK, healthDetail{
+		Status: status,
+		Checks: map[string]healthCheck{
+			"argon2": {
+				DurationMs:  h.hashTiming.Duration.Milliseconds(),
+				ThresholdMs: h.hashTiming.Threshold.Milliseconds(),
+				Slow:        h.hashTiming.Slow,
+			},
+		},
+	})
+}
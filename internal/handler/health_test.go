@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/crypto"
+)
+
+func TestHandleHealth_Plain(t *testing.T) {
+	h := NewHealthHandler(crypto.HashTiming{})
+
+	rec := httptest.NewRecorder()
+	h.HandleHealth(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+		t.Errorf("expected 200 ok, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleHealth_DetailReportsArgon2Timing(t *testing.T) {
+	h := NewHealthHandler(crypto.HashTiming{
+		Duration:  750 * time.Millisecond,
+		Threshold: 500 * time.Millisecond,
+		Slow:      true,
+	})
+
+	rec := httptest.NewRecorder()
+	h.HandleHealth(rec, httptest.NewRequest(http.MethodGet, "/health?detail=true", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var body healthDetail
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	argon := body.Checks["argon2"]
+	if body.Status != "degraded" || argon.DurationMs != 750 || argon.ThresholdMs != 500 || !argon.Slow {
+		t.Errorf("unexpected health detail: %+v", body)
+	}
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/vaultpass/vaultpass-go/internal/model"
+	"github.com/vaultpass/vaultpass-go/internal/service"
+)
+
+// InviteHandler handles admin requests for registration invite codes.
+type InviteHandler struct {
+	service *service.InviteService
+}
+
+// NewInviteHandler creates a new InviteHandler.
+func NewInviteHandler(svc *service.InviteService) *InviteHandler {
+	return &InviteHandler{service: svc}
+}
+
+// HandleCreate handles POST /api/v1/admin/invites requests. An empty body
+// mints a code that never expires.
+func (h *InviteHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<10) // 1KB
+
+	var req model.CreateInviteRequest
+	if err := newDecoder(r).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeDecodeError(w, err)
+		return
+	}
+
+	resp, err := h.service.Create(r.Context(), req)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusCreated, resp)
+}
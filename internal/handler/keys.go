@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/vaultpass/vaultpass-go/internal/crypto"
+)
+
+// KeysHandler publishes token verification keys for external verifiers.
+type KeysHandler struct {
+	tokens *crypto.TokenManager
+}
+
+// NewKeysHandler creates a new KeysHandler.
+func NewKeysHandler(tokens *crypto.TokenManager) *KeysHandler {
+	return &KeysHandler{tokens: tokens}
+}
+
+// HandleJWKS handles GET /.well-known/jwks.json requests.
+// Only public keys are served, and only when tokens use asymmetric signing.
+func (h *KeysHandler) HandleJWKS(w http.ResponseWriter, r *http.Request) {
+	jwks, ok := h.tokens.JWKS()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, errorResponse("not found"))
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeJSON(w, http.StatusOK, jwks)
+}
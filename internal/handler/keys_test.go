@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/crypto"
+)
+
+func TestHandleJWKS_Asymmetric(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() unexpected error: %v", err)
+	}
+	h := NewKeysHandler(crypto.NewTokenManager(crypto.TokenConfig{
+		Expiry:        time.Hour,
+		SigningMethod: crypto.SigningRS256,
+		PrivateKey:    key,
+		KeyID:         "primary",
+	}))
+
+	rec := httptest.NewRecorder()
+	h.HandleJWKS(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var jwks crypto.JWKS
+	if err := json.NewDecoder(rec.Body).Decode(&jwks); err != nil {
+		t.Fatalf("invalid JWKS JSON: %v", err)
+	}
+	if len(jwks.Keys) != 1 || jwks.Keys[0].Kid != "primary" || jwks.Keys[0].N == "" {
+		t.Errorf("unexpected JWKS: %+v", jwks)
+	}
+}
+
+func TestHandleJWKS_Symmetric(t *testing.T) {
+	h := NewKeysHandler(crypto.NewTokenManager(crypto.TokenConfig{Secret: "test-secret"}))
+
+	rec := httptest.NewRecorder()
+	h.HandleJWKS(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 in HS256 mode, got %d", rec.Code)
+	}
+}
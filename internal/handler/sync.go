@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/vaultpass/vaultpass-go/internal/model"
+	"github.com/vaultpass/vaultpass-go/internal/service"
+)
+
+// errTooManySyncEntries stops a sync stream once it exceeds the entry cap.
+var errTooManySyncEntries = errors.New("too many entries in sync request")
+
+// syncDecodeError marks a malformed sync body, as opposed to a failure applying it.
+type syncDecodeError struct {
+	err error
+}
+
+func (e *syncDecodeError) Error() string { return e.err.Error() }
+func (e *syncDecodeError) Unwrap() error { return e.err }
+
+// syncStream feeds decoded sync entries to an upload, counting them across
+// every entries key in the body.
+type syncStream struct {
+	upload     *service.SyncUpload
+	maxEntries int
+	n          int
+}
+
+// decodeSync reads a sync request body token by token. Each entry is passed
+// to upload as soon as it is decoded, so only one entry is held in memory at a
+// time. Entries are applied under the strategy in effect when they are read,
+// lww until conflict_strategy says otherwise, so a conflict_strategy that
+// differs from lww must come before entries; upload rejects it afterwards.
+// At most maxEntries entries are accepted in total, even when the body
+// repeats the entries key. When strict,
+// unknown fields are rejected, as with newDecoder. It returns the request's
+// other fields; Entries is always nil.
+func decodeSync(body io.Reader, upload *service.SyncUpload, maxEntries int, strict bool) (model.SyncRequest, error) {
+	var req model.SyncRequest
+	dec := json.NewDecoder(body)
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return req, &syncDecodeError{err}
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		return req, &syncDecodeError{&json.UnmarshalTypeError{Value: tokenKind(tok), Type: reflect.TypeOf(req), Offset: dec.InputOffset()}}
+	}
+
+	stream := &syncStream{upload: upload, maxEntries: maxEntries}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return req, &syncDecodeError{err}
+		}
+		key, _ := tok.(string)
+
+		// Keys match case-insensitively, as they do with json.Unmarshal.
+		switch strings.ToLower(key) {
+		case "entries":
+			if err := decodeSyncEntries(dec, stream); err != nil {
+				return req, err
+			}
+			continue
+		case "conflict_strategy":
+			if err := dec.Decode(&req.ConflictStrategy); err != nil {
+				return req, &syncDecodeError{err}
+			}
+			if err := upload.SetStrategy(req.ConflictStrategy); err != nil {
+				return req, err
+			}
+			continue
+		case "last_synced_at":
+			err = dec.Decode(&req.LastSyncedAt)
+		case "ack_cursor":
+			err = dec.Decode(&req.AckCursor)
+		case "device_id":
+			err = dec.Decode(&req.DeviceID)
+		case "limit":
+			err = dec.Decode(&req.Limit)
+		case "cursor":
+			err = dec.Decode(&req.Cursor)
+		default:
+			if strict {
+				return req, &syncDecodeError{fmt.Errorf("json: unknown field %q", key)}
+			}
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+		}
+		if err != nil {
+			return req, &syncDecodeError{err}
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return req, &syncDecodeError{err}
+	}
+	return req, nil
+}
+
+// decodeSyncEntries decodes the entries array into stream.
+func decodeSyncEntries(dec *json.Decoder, stream *syncStream) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return &syncDecodeError{err}
+	}
+	if tok == nil {
+		return nil
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '[' {
+		return &syncDecodeError{&json.UnmarshalTypeError{
+			Value: tokenKind(tok), Type: reflect.TypeOf([]model.VaultEntryRequest{}), Field: "entries", Offset: dec.InputOffset(),
+		}}
+	}
+
+	for dec.More() {
+		if stream.n >= stream.maxEntries {
+			return errTooManySyncEntries
+		}
+
+		var re model.VaultEntryRequest
+		if err := dec.Decode(&re); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				typeErr.Field = strings.TrimSuffix("entries."+typeErr.Field, ".")
+			}
+			return &syncDecodeError{err}
+		}
+		stream.n++
+		if err := stream.upload.Apply(re); err != nil {
+			return err
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return &syncDecodeError{err}
+	}
+	return nil
+}
+
+// tokenKind names the JSON type of a token for type errors.
+func tokenKind(tok json.Token) string {
+	switch v := tok.(type) {
+	case json.Delim:
+		if v == '[' {
+			return "array"
+		}
+		return "object"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case nil:
+		return "null"
+	default:
+		return "number"
+	}
+}
@@ -3,25 +3,58 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/vaultpass/vaultpass-go/internal/apperr"
 	"github.com/vaultpass/vaultpass-go/internal/middleware"
 	"github.com/vaultpass/vaultpass-go/internal/model"
 	"github.com/vaultpass/vaultpass-go/internal/service"
 )
 
+// DefaultMaxSyncEntries is the default cap on entries in one sync request.
+const DefaultMaxSyncEntries = 1000
+
+// DefaultMaxVaultBody is the default request body limit for endpoints that carry
+// entry data: create, update, sync and import.
+const DefaultMaxVaultBody int64 = 10 << 20 // 10MB
+
 // VaultHandler handles HTTP requests for vault entry operations.
 type VaultHandler struct {
-	service *service.VaultService
+	service        *service.VaultService
+	maxSyncEntries int
+	maxBody        int64
+}
+
+// VaultHandlerOption configures a VaultHandler.
+type VaultHandlerOption func(*VaultHandler)
+
+// WithMaxSyncEntries overrides the DefaultMaxSyncEntries cap. n must be positive.
+func WithMaxSyncEntries(n int) VaultHandlerOption {
+	return func(h *VaultHandler) {
+		h.maxSyncEntries = n
+	}
+}
+
+// WithMaxVaultBody overrides the DefaultMaxVaultBody limit. n must be positive.
+func WithMaxVaultBody(n int64) VaultHandlerOption {
+	return func(h *VaultHandler) {
+		h.maxBody = n
+	}
 }
 
 // NewVaultHandler creates a new VaultHandler.
-func NewVaultHandler(svc *service.VaultService) *VaultHandler {
-	return &VaultHandler{service: svc}
+func NewVaultHandler(svc *service.VaultService, opts ...VaultHandlerOption) *VaultHandler {
+	h := &VaultHandler{service: svc, maxSyncEntries: DefaultMaxSyncEntries, maxBody: DefaultMaxVaultBody}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
-// HandleCreateEntry handles POST /api/v1/vault requests.
+// HandleCreateEntry handles POST /api/v1/vault and POST /api/v1/vaults/{vault_id}/entries requests.
 func (h *VaultHandler) HandleCreateEntry(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.UserIDFromContext(r.Context())
 	if !ok {
@@ -29,33 +62,31 @@ func (h *VaultHandler) HandleCreateEntry(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	r.Body = http.MaxBytesReader(w, r.Body, 10<<20) // 10MB
+	vaultID, ok := vaultIDParam(r)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, errorResponse("invalid vault id"))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxBody)
 
 	var req model.VaultEntryRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		if err.Error() == "http: request body too large" {
-			writeJSON(w, http.StatusRequestEntityTooLarge, errorResponse("request body too large"))
-			return
-		}
-		writeJSON(w, http.StatusBadRequest, errorResponse("invalid request body"))
+	if err := newDecoder(r).Decode(&req); err != nil {
+		writeDecodeError(w, err)
 		return
 	}
 
-	resp, err := h.service.CreateEntry(r.Context(), userID, req)
+	resp, err := h.service.CreateEntry(r.Context(), userID, vaultID, req)
 	if err != nil {
-		switch {
-		case errors.Is(err, service.ErrEntryIDRequired), errors.Is(err, service.ErrEncryptedDataRequired):
-			writeJSON(w, http.StatusBadRequest, errorResponse(err.Error()))
-		default:
-			writeJSON(w, http.StatusInternalServerError, errorResponse("internal server error"))
-		}
+		writeAppError(w, err)
 		return
 	}
 
 	writeJSON(w, http.StatusCreated, resp)
 }
 
-// HandleListEntries handles GET /api/v1/vault requests.
+// HandleListEntries handles GET /api/v1/vault and GET /api/v1/vaults/{vault_id}/entries requests.
+// Passing ?favorites=true restricts the list to favorite entries; ?fields= selects the fields returned.
 func (h *VaultHandler) HandleListEntries(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.UserIDFromContext(r.Context())
 	if !ok {
@@ -63,16 +94,303 @@ func (h *VaultHandler) HandleListEntries(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	entries, err := h.service.ListEntries(r.Context(), userID)
+	vaultID, ok := vaultIDParam(r)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, errorResponse("invalid vault id"))
+		return
+	}
+
+	fields, err := entryFieldsParam(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	var entries []model.VaultEntryResponse
+	if r.URL.Query().Get("favorites") == "true" {
+		entries, err = h.service.ListFavorites(r.Context(), userID, vaultID)
+	} else {
+		entries, err = h.service.ListEntries(r.Context(), userID, vaultID)
+	}
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeEntries(w, http.StatusOK, entries, fields)
+}
+
+// HandleTrash handles GET /api/v1/vault/trash and GET /api/v1/vaults/{vault_id}/trash requests.
+// ?fields= selects the fields returned.
+func (h *VaultHandler) HandleTrash(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse("unauthorized"))
+		return
+	}
+
+	vaultID, ok := vaultIDParam(r)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, errorResponse("invalid vault id"))
+		return
+	}
+
+	fields, err := entryFieldsParam(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	entries, err := h.service.Trash(r.Context(), userID, vaultID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeEntries(w, http.StatusOK, entries, fields)
+}
+
+// HandleQuota handles GET /api/v1/vault/quota requests.
+func (h *VaultHandler) HandleQuota(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse("unauthorized"))
+		return
+	}
+
+	quota, err := h.service.Quota(r.Context(), userID)
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+	quota.MaxRequestBytes = h.maxBody
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, quota)
+}
+
+// HandleDuplicateStats handles GET /api/v1/admin/stats/duplicates requests.
+func (h *VaultHandler) HandleDuplicateStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.service.DuplicateStats(r.Context())
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// HandleWipe handles POST /api/v1/vault/wipe requests.
+func (h *VaultHandler) HandleWipe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse("unauthorized"))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<10) // 1KB
+
+	var req model.WipeRequest
+	if err := newDecoder(r).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	resp, err := h.service.Wipe(r.Context(), userID, req)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// HandleRekey handles POST /api/v1/vault/rekey requests.
+func (h *VaultHandler) HandleRekey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse("unauthorized"))
+		return
+	}
+
+	resp, err := h.service.Rekey(r.Context(), userID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// HandleManifest handles GET /api/v1/vault/manifest and GET /api/v1/vaults/{vault_id}/manifest requests.
+func (h *VaultHandler) HandleManifest(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse("unauthorized"))
+		return
+	}
+
+	vaultID, ok := vaultIDParam(r)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, errorResponse("invalid vault id"))
+		return
+	}
+
+	manifest, err := h.service.Manifest(r.Context(), userID, vaultID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, manifest)
+}
+
+// HandleBatchGet handles POST /api/v1/vault/batch-get and POST /api/v1/vaults/{vault_id}/batch-get requests.
+// ?fields= selects the fields returned.
+func (h *VaultHandler) HandleBatchGet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse("unauthorized"))
+		return
+	}
+
+	vaultID, ok := vaultIDParam(r)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, errorResponse("invalid vault id"))
+		return
+	}
+
+	fields, err := entryFieldsParam(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1MB
+
+	var req model.BatchGetRequest
+	if err := newDecoder(r).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	entries, err := h.service.BatchGet(r.Context(), userID, vaultID, req.EntryIDs)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeEntries(w, http.StatusOK, entries, fields)
+}
+
+// HandleGetEntry handles GET /api/v1/vault/{entry_id} and GET /api/v1/vaults/{vault_id}/entries/{entry_id}
+// requests. Each fetch records the entry's last_accessed_at.
+func (h *VaultHandler) HandleGetEntry(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse("unauthorized"))
+		return
+	}
+
+	vaultID, ok := vaultIDParam(r)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, errorResponse("invalid vault id"))
+		return
+	}
+
+	entryID := chi.URLParam(r, "entry_id")
+	if entryID == "" || len(entryID) > 36 {
+		writeJSON(w, http.StatusBadRequest, errorResponse("invalid entry id"))
+		return
+	}
+
+	entry, err := h.service.GetEntry(r.Context(), userID, vaultID, entryID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, errorResponse("internal server error"))
+		writeAppError(w, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, entries)
+	writeJSON(w, http.StatusOK, entry)
 }
 
-// HandleUpdateEntry handles PUT /api/v1/vault/{entry_id} requests.
+// HandleExport handles GET and HEAD /api/v1/vault/export and /api/v1/vaults/{vault_id}/export requests.
+// The export is serialized up front so Content-Length is exact, letting HEAD
+// report the download size without sending the body.
+func (h *VaultHandler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse("unauthorized"))
+		return
+	}
+
+	vaultID, ok := vaultIDParam(r)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, errorResponse("invalid vault id"))
+		return
+	}
+
+	export, err := h.service.ExportEntries(r.Context(), userID, vaultID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	body, err := json.Marshal(export)
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.Header().Set("Content-Disposition", `attachment; filename="vaultpass-export.json"`)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(body)
+}
+
+// HandleImport handles POST /api/v1/vault/import and POST /api/v1/vaults/{vault_id}/import requests.
+func (h *VaultHandler) HandleImport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse("unauthorized"))
+		return
+	}
+
+	vaultID, ok := vaultIDParam(r)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, errorResponse("invalid vault id"))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxBody)
+
+	var req model.VaultImport
+	if err := newDecoder(r).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	if len(req.Entries) > h.maxSyncEntries {
+		writeJSON(w, http.StatusBadRequest, errorResponse(fmt.Sprintf("too many entries in import (max %d)", h.maxSyncEntries)))
+		return
+	}
+
+	resp, err := h.service.ImportEntries(r.Context(), userID, vaultID, req)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// HandleUpdateEntry handles PUT /api/v1/vault/{entry_id} and PUT /api/v1/vaults/{vault_id}/entries/{entry_id} requests.
 func (h *VaultHandler) HandleUpdateEntry(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.UserIDFromContext(r.Context())
 	if !ok {
@@ -80,41 +398,79 @@ func (h *VaultHandler) HandleUpdateEntry(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	vaultID, ok := vaultIDParam(r)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, errorResponse("invalid vault id"))
+		return
+	}
+
 	entryID := chi.URLParam(r, "entry_id")
 	if entryID == "" || len(entryID) > 36 {
 		writeJSON(w, http.StatusBadRequest, errorResponse("invalid entry id"))
 		return
 	}
 
-	r.Body = http.MaxBytesReader(w, r.Body, 10<<20) // 10MB
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxBody)
 
 	var req model.VaultEntryRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		if err.Error() == "http: request body too large" {
-			writeJSON(w, http.StatusRequestEntityTooLarge, errorResponse("request body too large"))
+	if err := newDecoder(r).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		version, ok := parseVersionETag(ifMatch)
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse("If-Match must be a quoted entry version"))
 			return
 		}
-		writeJSON(w, http.StatusBadRequest, errorResponse("invalid request body"))
-		return
+		req.ExpectedVersion = &version
 	}
 
-	resp, err := h.service.UpdateEntry(r.Context(), userID, entryID, req)
+	resp, err := h.service.UpdateEntry(r.Context(), userID, vaultID, entryID, req)
 	if err != nil {
-		switch {
-		case errors.Is(err, service.ErrEncryptedDataRequired):
-			writeJSON(w, http.StatusBadRequest, errorResponse(err.Error()))
-		case errors.Is(err, service.ErrEntryNotFound):
-			writeJSON(w, http.StatusNotFound, errorResponse(err.Error()))
-		default:
-			writeJSON(w, http.StatusInternalServerError, errorResponse("internal server error"))
-		}
+		writeAppError(w, err)
 		return
 	}
 
+	w.Header().Set("ETag", versionETag(resp.Version))
 	writeJSON(w, http.StatusOK, resp)
 }
 
-// HandleDeleteEntry handles DELETE /api/v1/vault/{entry_id} requests.
+// vaultIDParam parses the {vault_id} URL parameter. Routes without one operate
+// on the default vault, reported as 0.
+func vaultIDParam(r *http.Request) (int64, bool) {
+	param := chi.URLParam(r, "vault_id")
+	if param == "" {
+		return 0, true
+	}
+	id, err := strconv.ParseInt(param, 10, 64)
+	if err != nil || id < 1 {
+		return 0, false
+	}
+	return id, true
+}
+
+// versionETag renders an entry version as a strong ETag, e.g. "3".
+func versionETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// parseVersionETag parses an If-Match value produced by versionETag.
+// Bare numbers are accepted too since some clients omit the quotes.
+func parseVersionETag(v string) (int, bool) {
+	if unquoted, err := strconv.Unquote(v); err == nil {
+		v = unquoted
+	}
+	version, err := strconv.Atoi(v)
+	if err != nil || version < 1 {
+		return 0, false
+	}
+	return version, true
+}
+
+// HandleDeleteEntry handles DELETE /api/v1/vault/{entry_id} and DELETE /api/v1/vaults/{vault_id}/entries/{entry_id} requests.
+// An optional ?reason= is recorded as the entry's delete_reason.
 func (h *VaultHandler) HandleDeleteEntry(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.UserIDFromContext(r.Context())
 	if !ok {
@@ -122,27 +478,58 @@ func (h *VaultHandler) HandleDeleteEntry(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	vaultID, ok := vaultIDParam(r)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, errorResponse("invalid vault id"))
+		return
+	}
+
 	entryID := chi.URLParam(r, "entry_id")
 	if entryID == "" || len(entryID) > 36 {
 		writeJSON(w, http.StatusBadRequest, errorResponse("invalid entry id"))
 		return
 	}
 
-	err := h.service.DeleteEntry(r.Context(), userID, entryID)
+	err := h.service.DeleteEntryWithReason(r.Context(), userID, vaultID, entryID, r.URL.Query().Get("reason"))
 	if err != nil {
-		switch {
-		case errors.Is(err, service.ErrEntryNotFound):
-			writeJSON(w, http.StatusNotFound, errorResponse(err.Error()))
-		default:
-			writeJSON(w, http.StatusInternalServerError, errorResponse("internal server error"))
-		}
+		writeAppError(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// HandleSync handles POST /api/v1/vault/sync requests.
+// HandleRestoreEntry handles POST /api/v1/vault/{entry_id}/restore and
+// POST /api/v1/vaults/{vault_id}/entries/{entry_id}/restore requests.
+func (h *VaultHandler) HandleRestoreEntry(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse("unauthorized"))
+		return
+	}
+
+	vaultID, ok := vaultIDParam(r)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, errorResponse("invalid vault id"))
+		return
+	}
+
+	entryID := chi.URLParam(r, "entry_id")
+	if entryID == "" || len(entryID) > 36 {
+		writeJSON(w, http.StatusBadRequest, errorResponse("invalid entry id"))
+		return
+	}
+
+	resp, err := h.service.RestoreEntry(r.Context(), userID, vaultID, entryID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// HandleSync handles POST /api/v1/vault/sync and POST /api/v1/vaults/{vault_id}/sync requests.
 func (h *VaultHandler) HandleSync(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.UserIDFromContext(r.Context())
 	if !ok {
@@ -150,26 +537,39 @@ func (h *VaultHandler) HandleSync(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	r.Body = http.MaxBytesReader(w, r.Body, 10<<20) // 10MB
+	vaultID, ok := vaultIDParam(r)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, errorResponse("invalid vault id"))
+		return
+	}
 
-	var req model.SyncRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		if err.Error() == "http: request body too large" {
-			writeJSON(w, http.StatusRequestEntityTooLarge, errorResponse("request body too large"))
-			return
-		}
-		writeJSON(w, http.StatusBadRequest, errorResponse("invalid request body"))
+	upload, err := h.service.BeginSync(r.Context(), userID, vaultID)
+	if err != nil {
+		writeAppError(w, err)
 		return
 	}
+	defer upload.Close()
 
-	if len(req.Entries) > 1000 {
-		writeJSON(w, http.StatusBadRequest, errorResponse("too many entries in sync request (max 1000)"))
+	// Entries are applied as they are decoded rather than after reading the
+	// whole body, which bounds memory use for large uploads.
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxBody)
+	req, err := decodeSync(r.Body, upload, h.maxSyncEntries, middleware.StrictJSONFromContext(r.Context()))
+	if err != nil {
+		var decodeErr *syncDecodeError
+		switch {
+		case errors.As(err, &decodeErr):
+			writeDecodeError(w, decodeErr.err)
+		case errors.Is(err, errTooManySyncEntries):
+			writeAppError(w, apperr.New(http.StatusBadRequest, "too_many_entries", fmt.Sprintf("%v (max %d)", err, h.maxSyncEntries)))
+		default:
+			writeAppError(w, err)
+		}
 		return
 	}
 
-	resp, err := h.service.Sync(r.Context(), userID, req)
+	resp, err := upload.Finish(req)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, errorResponse("internal server error"))
+		writeAppError(w, err)
 		return
 	}
 
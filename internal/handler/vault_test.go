@@ -0,0 +1,693 @@
+package handler
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vaultpass/vaultpass-go/internal/crypto"
+	"github.com/vaultpass/vaultpass-go/internal/middleware"
+	"github.com/vaultpass/vaultpass-go/internal/model"
+	"github.com/vaultpass/vaultpass-go/internal/repository"
+	"github.com/vaultpass/vaultpass-go/internal/service"
+)
+
+// newAuthedVault returns vault routes behind JWT auth and a bearer token for user 1.
+func newAuthedVault(t *testing.T, opts ...VaultHandlerOption) (*service.VaultService, http.Handler, string) {
+	t.Helper()
+	tokens := crypto.NewTokenManager(crypto.TokenConfig{Secret: "test-secret", Expiry: time.Hour})
+	token, err := tokens.Generate(1, "")
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	svc := service.NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	h := NewVaultHandler(svc, opts...)
+
+	r := chi.NewRouter()
+	r.Use(middleware.JWTAuth(tokens, nil))
+	r.Get("/api/v1/vault", h.HandleListEntries)
+	r.Post("/api/v1/vault", h.HandleCreateEntry)
+	r.Get("/api/v1/vault/{entry_id}", h.HandleGetEntry)
+	r.Put("/api/v1/vault/{entry_id}", h.HandleUpdateEntry)
+	r.Delete("/api/v1/vault/{entry_id}", h.HandleDeleteEntry)
+	r.Get("/api/v1/vault/export", h.HandleExport)
+	r.Post("/api/v1/vault/sync", h.HandleSync)
+	r.Get("/api/v1/vault/manifest", h.HandleManifest)
+	r.Get("/api/v1/vault/trash", h.HandleTrash)
+	r.Post("/api/v1/vault/{entry_id}/restore", h.HandleRestoreEntry)
+	r.Head("/api/v1/vault/export", h.HandleExport)
+	r.Post("/api/v1/vault/import", h.HandleImport)
+	r.Post("/api/v1/vaults", h.HandleCreateVault)
+	r.Delete("/api/v1/vaults/{vault_id}", h.HandleDeleteVault)
+	r.Get("/api/v1/vaults/{vault_id}/entries", h.HandleListEntries)
+	r.Post("/api/v1/vaults/{vault_id}/entries", h.HandleCreateEntry)
+	return svc, r, token
+}
+
+func doVault(handler http.Handler, method, path, token string, body string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func doExport(handler http.Handler, method, token string) *httptest.ResponseRecorder {
+	return doVault(handler, method, "/api/v1/vault/export", token, "", nil)
+}
+
+func TestHandleExport_GetSetsContentLength(t *testing.T) {
+	svc, handler, token := newAuthedVault(t)
+	if _, err := svc.CreateEntry(context.Background(), 1, 0, model.VaultEntryRequest{EntryID: "e1", EncryptedData: "YmxvYg=="}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+
+	rec := doExport(handler, http.MethodGet, token)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Length"); got != strconv.Itoa(rec.Body.Len()) {
+		t.Errorf("Content-Length = %q, body is %d bytes", got, rec.Body.Len())
+	}
+	var export model.VaultExport
+	if err := json.Unmarshal(rec.Body.Bytes(), &export); err != nil {
+		t.Fatalf("invalid export JSON: %v", err)
+	}
+	if len(export.Entries) != 1 || export.Entries[0].EntryID != "e1" {
+		t.Errorf("unexpected export: %+v", export)
+	}
+}
+
+func TestHandleExport_HeadReturnsLengthWithoutBody(t *testing.T) {
+	svc, handler, token := newAuthedVault(t)
+	if _, err := svc.CreateEntry(context.Background(), 1, 0, model.VaultEntryRequest{EntryID: "e1", EncryptedData: "YmxvYg=="}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+
+	get := doExport(handler, http.MethodGet, token)
+	head := doExport(handler, http.MethodHead, token)
+
+	if head.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", head.Code)
+	}
+	if head.Body.Len() != 0 {
+		t.Errorf("HEAD must not send a body, got %d bytes", head.Body.Len())
+	}
+	n, err := strconv.Atoi(head.Header().Get("Content-Length"))
+	if err != nil || n <= 0 {
+		t.Fatalf("expected positive Content-Length, got %q", head.Header().Get("Content-Length"))
+	}
+	// exported_at is stamped per request and may differ in fractional-second digits.
+	if diff := n - get.Body.Len(); diff < -9 || diff > 9 {
+		t.Errorf("HEAD length %d far from GET body %d", n, get.Body.Len())
+	}
+}
+
+func TestHandleExport_Unauthorized(t *testing.T) {
+	_, handler, _ := newAuthedVault(t)
+
+	rec := doExport(handler, http.MethodHead, "")
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", rec.Code)
+	}
+}
+
+func TestHandleUpdateEntry_IfMatch(t *testing.T) {
+	svc, handler, token := newAuthedVault(t)
+	if _, err := svc.CreateEntry(context.Background(), 1, 0, model.VaultEntryRequest{EntryID: "e1", EncryptedData: "djE="}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+
+	// Matching version applies and returns the new version as the ETag.
+	rec := doVault(handler, http.MethodPut, "/api/v1/vault/e1", token, `{"encrypted_data":"djI="}`,
+		http.Header{"If-Match": {`"1"`}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for matching version, got %d: %s", rec.Code, rec.Body)
+	}
+	if etag := rec.Header().Get("ETag"); etag != `"2"` {
+		t.Errorf("ETag = %q, want %q", etag, `"2"`)
+	}
+
+	// A second device still holding version 1 is refused.
+	rec = doVault(handler, http.MethodPut, "/api/v1/vault/e1", token, `{"encrypted_data":"c3RhbGU="}`,
+		http.Header{"If-Match": {`"1"`}})
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for stale version, got %d", rec.Code)
+	}
+
+	rec = doVault(handler, http.MethodPut, "/api/v1/vault/e1", token, `{"encrypted_data":"djM="}`,
+		http.Header{"If-Match": {"*"}})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for malformed If-Match, got %d", rec.Code)
+	}
+}
+
+func TestHandleUpdateEntry_ExpectedVersionBody(t *testing.T) {
+	svc, handler, token := newAuthedVault(t)
+	if _, err := svc.CreateEntry(context.Background(), 1, 0, model.VaultEntryRequest{EntryID: "e1", EncryptedData: "djE="}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+
+	rec := doVault(handler, http.MethodPut, "/api/v1/vault/e1", token, `{"encrypted_data":"djI=","expected_version":5}`, nil)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for stale expected_version, got %d", rec.Code)
+	}
+
+	rec = doVault(handler, http.MethodPut, "/api/v1/vault/missing", token, `{"encrypted_data":"djI=","expected_version":1}`, nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for missing entry, got %d", rec.Code)
+	}
+}
+
+func TestVaultRoutes_ScopedByVault(t *testing.T) {
+	svc, handler, token := newAuthedVault(t)
+	if _, err := svc.CreateEntry(context.Background(), 1, 0, model.VaultEntryRequest{EntryID: "e1", EncryptedData: "YmxvYg=="}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+
+	rec := doVault(handler, http.MethodPost, "/api/v1/vaults", token, `{"name":"Work"}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 creating a vault, got %d: %s", rec.Code, rec.Body)
+	}
+	var work model.VaultResponse
+	json.Unmarshal(rec.Body.Bytes(), &work)
+	path := "/api/v1/vaults/" + strconv.FormatInt(work.ID, 10)
+
+	rec = doVault(handler, http.MethodPost, path+"/entries", token, `{"entry_id":"w1","encrypted_data":"d29yaw=="}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 creating an entry, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = doVault(handler, http.MethodGet, path+"/entries", token, "", nil)
+	var entries []model.VaultEntryResponse
+	json.Unmarshal(rec.Body.Bytes(), &entries)
+	if rec.Code != http.StatusOK || len(entries) != 1 || entries[0].EntryID != "w1" {
+		t.Fatalf("expected only the work entry, got %d %+v", rec.Code, entries)
+	}
+
+	if rec := doVault(handler, http.MethodGet, "/api/v1/vaults/999/entries", token, "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown vault, got %d", rec.Code)
+	}
+	if rec := doVault(handler, http.MethodGet, "/api/v1/vaults/abc/entries", token, "", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a malformed vault id, got %d", rec.Code)
+	}
+	if rec := doVault(handler, http.MethodDelete, path, token, "", nil); rec.Code != http.StatusNoContent {
+		t.Errorf("expected 204 deleting the vault, got %d", rec.Code)
+	}
+}
+
+func TestHandleSync_ConfiguredEntryLimit(t *testing.T) {
+	_, handler, token := newAuthedVault(t, WithMaxSyncEntries(2))
+
+	syncBody := func(n int) string {
+		entries := make([]string, n)
+		for i := range entries {
+			entries[i] = `{"entry_id":"e` + strconv.Itoa(i) + `","encrypted_data":"YmxvYg=="}`
+		}
+		return `{"entries":[` + strings.Join(entries, ",") + `]}`
+	}
+
+	if rec := doVault(handler, http.MethodPost, "/api/v1/vault/sync", token, syncBody(2), nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 at the limit, got %d: %s", rec.Code, rec.Body)
+	}
+	rec := doVault(handler, http.MethodPost, "/api/v1/vault/sync", token, syncBody(3), nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 over the limit, got %d", rec.Code)
+	}
+	var errResp map[string]string
+	json.Unmarshal(rec.Body.Bytes(), &errResp)
+	if errResp["code"] != "too_many_entries" || !strings.Contains(errResp["error"], "max 2") {
+		t.Errorf("expected too_many_entries with the configured limit, got %s", rec.Body)
+	}
+}
+
+func TestHandleSync_StrictJSON(t *testing.T) {
+	_, handler, token := newAuthedVault(t)
+	strict := middleware.StrictJSON(handler)
+
+	for _, body := range []string{
+		`{"entries": [], "client_version": "2.1"}`,
+		`{"entries": [{"entry_id": "e1", "encrypted_data": "YmxvYg==", "titel": "x"}]}`,
+	} {
+		if rec := doVault(handler, http.MethodPost, "/api/v1/vault/sync", token, body, nil); rec.Code != http.StatusOK {
+			t.Errorf("lenient %s: expected 200, got %d: %s", body, rec.Code, rec.Body)
+		}
+		rec := doVault(strict, http.MethodPost, "/api/v1/vault/sync", token, body, nil)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "unknown field") {
+			t.Errorf("strict %s: expected 400 for the unknown field, got %d: %s", body, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestHandleSync_LimitAndCursor(t *testing.T) {
+	_, handler, token := newAuthedVault(t)
+
+	body := `{"limit":2,"entries":[` +
+		`{"entry_id":"a","encrypted_data":"YmxvYg=="},{"entry_id":"b","encrypted_data":"YmxvYg=="},` +
+		`{"entry_id":"c","encrypted_data":"YmxvYg=="}]}`
+	rec := doVault(handler, http.MethodPost, "/api/v1/vault/sync", token, body, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var first model.SyncResponse
+	json.Unmarshal(rec.Body.Bytes(), &first)
+	if len(first.Entries) != 2 || !first.HasMore || first.NextCursor == "" {
+		t.Fatalf("expected a partial first page, got %d entries, has_more=%v", len(first.Entries), first.HasMore)
+	}
+
+	rec = doVault(handler, http.MethodPost, "/api/v1/vault/sync", token, `{"limit":2,"cursor":"`+first.NextCursor+`"}`, nil)
+	var second model.SyncResponse
+	json.Unmarshal(rec.Body.Bytes(), &second)
+	if rec.Code != http.StatusOK || len(second.Entries) != 1 || second.HasMore {
+		t.Fatalf("expected the last entry and has_more=false, got %d: %s", rec.Code, rec.Body)
+	}
+
+	if rec := doVault(handler, http.MethodPost, "/api/v1/vault/sync", token, `{"cursor":"bogus"}`, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a bad cursor, got %d", rec.Code)
+	}
+}
+
+func TestHandleSync_StreamsLargeUpload(t *testing.T) {
+	const n = 5000
+	svc, handler, token := newAuthedVault(t, WithMaxSyncEntries(n))
+
+	var body strings.Builder
+	body.WriteString(`{"last_synced_at":null,"entries":[`)
+	for i := range n {
+		if i > 0 {
+			body.WriteByte(',')
+		}
+		body.WriteString(`{"entry_id":"e` + strconv.Itoa(i) + `","encrypted_data":"YmxvYg==","version":1}`)
+	}
+	body.WriteString(`],"unknown_field":{"ignored":true}}`)
+
+	rec := doVault(handler, http.MethodPost, "/api/v1/vault/sync", token, body.String(), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp model.SyncResponse
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	// Without a limit every change comes back in one response.
+	if len(resp.Entries) != n || resp.HasMore || len(resp.Skipped) != 0 {
+		t.Fatalf("expected all %d entries and none skipped, got %d, has_more=%v (skipped %v)",
+			n, len(resp.Entries), resp.HasMore, resp.Skipped)
+	}
+
+	entries, err := svc.ListEntries(context.Background(), 1, 0)
+	if err != nil || len(entries) != n {
+		t.Errorf("expected %d stored entries, got %d (err %v)", n, len(entries), err)
+	}
+}
+
+func TestHandleSync_StreamCapAndOrdering(t *testing.T) {
+	svc, handler, token := newAuthedVault(t, WithMaxSyncEntries(2))
+	entry := func(id string) string { return `{"entry_id":"` + id + `","encrypted_data":"YmxvYg=="}` }
+
+	// The cap triggers on the third entry, before the malformed tail is read.
+	body := `{"entries":[` + entry("a") + `,` + entry("b") + `,` + entry("c") + `,{"entry_id":`
+	rec := doVault(handler, http.MethodPost, "/api/v1/vault/sync", token, body, nil)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "max 2") {
+		t.Fatalf("expected 400 naming the cap, got %d: %s", rec.Code, rec.Body)
+	}
+	if entries, _ := svc.ListEntries(context.Background(), 1, 0); len(entries) != 0 {
+		t.Fatalf("expected entries applied before the cap to be rolled back, got %d", len(entries))
+	}
+
+	// Repeating the entries key doesn't reset the count.
+	body = `{"entries":[` + entry("a") + `,` + entry("b") + `],"entries":[` + entry("c") + `]}`
+	rec = doVault(handler, http.MethodPost, "/api/v1/vault/sync", token, body, nil)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "max 2") {
+		t.Fatalf("expected 400 for entries split across keys, got %d: %s", rec.Code, rec.Body)
+	}
+	if entries, _ := svc.ListEntries(context.Background(), 1, 0); len(entries) != 0 {
+		t.Fatalf("expected split entries to be rolled back, got %d", len(entries))
+	}
+
+	body = `{"conflict_strategy":"manual","entries":[` + entry("a") + `],"ack_cursor":null}`
+	if rec := doVault(handler, http.MethodPost, "/api/v1/vault/sync", token, body, nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 with the strategy first, got %d: %s", rec.Code, rec.Body)
+	}
+
+	body = `{"conflict_strategy":"manual","entries":[` + entry("b") + `],"conflict_strategy":"lww"}`
+	rec = doVault(handler, http.MethodPost, "/api/v1/vault/sync", token, body, nil)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "late_conflict_strategy") {
+		t.Fatalf("expected 400 for a strategy changed after entries, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = doVault(handler, http.MethodPost, "/api/v1/vault/sync", token, `{"entries":[{"entry_id":"a","version":"x"}]}`, nil)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "entries.version must be an integer") {
+		t.Errorf("expected a field-level type error, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestHandleSync_StrategyAfterEntries(t *testing.T) {
+	_, handler, token := newAuthedVault(t)
+	entry := func(data string, version int) string {
+		return `{"entry_id":"e1","encrypted_data":"` + data + `","version":` + strconv.Itoa(version) + `}`
+	}
+
+	if rec := doVault(handler, http.MethodPost, "/api/v1/vault/sync", token, `{"entries":[`+entry("b25l", 2)+`]}`, nil); rec.Code != http.StatusOK {
+		t.Fatalf("seed sync: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	// Entries are applied under lww as they are read, so naming another
+	// strategy after them is rejected rather than silently ignored.
+	body := `{"entries":[` + entry("dHdv", 2) + `],"conflict_strategy":"manual"}`
+	rec := doVault(handler, http.MethodPost, "/api/v1/vault/sync", token, body, nil)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "late_conflict_strategy") {
+		t.Fatalf("expected 400 for manual after entries, got %d: %s", rec.Code, rec.Body)
+	}
+
+	// Naming the default after the entries changes nothing.
+	body = `{"entries":[` + entry("dHdv", 3) + `],"conflict_strategy":"lww"}`
+	if rec := doVault(handler, http.MethodPost, "/api/v1/vault/sync", token, body, nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for lww after entries, got %d: %s", rec.Code, rec.Body)
+	}
+
+	body = `{"conflict_strategy":"manual","entries":[` + entry("dGhyZWU", 3) + `]}`
+	rec = doVault(handler, http.MethodPost, "/api/v1/vault/sync", token, body, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 with the strategy first, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp model.SyncResponse
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if len(resp.Conflicts) != 1 {
+		t.Fatalf("expected the stale edit staged as a conflict, got %+v", resp.Conflicts)
+	}
+}
+
+func TestHandleManifest_OmitsDataIncludesDeleted(t *testing.T) {
+	svc, handler, token := newAuthedVault(t)
+	ctx := context.Background()
+	for _, id := range []string{"e1", "e2"} {
+		if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: id, EncryptedData: "c2VjcmV0"}); err != nil {
+			t.Fatalf("CreateEntry() unexpected error: %v", err)
+		}
+	}
+	if err := svc.DeleteEntry(ctx, 1, 0, "e2"); err != nil {
+		t.Fatalf("DeleteEntry() unexpected error: %v", err)
+	}
+
+	rec := doVault(handler, http.MethodGet, "/api/v1/vault/manifest", token, "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	var manifest []map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &manifest); err != nil {
+		t.Fatalf("decoding manifest: %v", err)
+	}
+	if len(manifest) != 2 {
+		t.Fatalf("expected both entries, got %+v", manifest)
+	}
+	for _, m := range manifest {
+		if _, ok := m["encrypted_data"]; ok {
+			t.Errorf("manifest entry %v must not carry encrypted_data", m["entry_id"])
+		}
+	}
+	if manifest[1]["entry_id"] != "e2" || manifest[1]["deleted"] != true {
+		t.Errorf("expected e2 reported as deleted, got %+v", manifest[1])
+	}
+}
+
+func TestHandleDeleteEntry_Reason(t *testing.T) {
+	svc, handler, token := newAuthedVault(t)
+	ctx := context.Background()
+	if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: "e1", EncryptedData: "c2VjcmV0"}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+
+	rec := doVault(handler, http.MethodDelete, "/api/v1/vault/e1?reason=spam", token, "", nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown reason, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = doVault(handler, http.MethodDelete, "/api/v1/vault/e1?reason=superseded", token, "", nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = doVault(handler, http.MethodPost, "/api/v1/vault/sync", token, `{}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp model.SyncResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding sync response: %v", err)
+	}
+	if len(resp.Entries) != 1 || !resp.Entries[0].Deleted || resp.Entries[0].DeleteReason != model.DeleteReasonSuperseded {
+		t.Errorf("expected a superseded tombstone, got %+v", resp.Entries)
+	}
+}
+
+func TestHandleCreateEntry_DuplicateIDConflicts(t *testing.T) {
+	svc, handler, token := newAuthedVault(t)
+
+	rec := doVault(handler, http.MethodPost, "/api/v1/vault", token, `{"entry_id":"e1","encrypted_data":"Zmlyc3Q="}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("fresh entry_id: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = doVault(handler, http.MethodPost, "/api/v1/vault", token, `{"entry_id":"e1","encrypted_data":"c2Vjb25k"}`, nil)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("duplicate entry_id: expected 409, got %d: %s", rec.Code, rec.Body)
+	}
+	got, err := svc.GetEntry(context.Background(), 1, 0, "e1")
+	if err != nil || got.EncryptedData != "Zmlyc3Q=" || got.Version != 1 {
+		t.Errorf("expected the original entry untouched, got %+v (err %v)", got, err)
+	}
+
+	// The same ID in another vault is a different entry.
+	vault, err := svc.CreateVault(context.Background(), 1, model.VaultRequest{Name: "Work"})
+	if err != nil {
+		t.Fatalf("CreateVault() unexpected error: %v", err)
+	}
+	path := "/api/v1/vaults/" + strconv.FormatInt(vault.ID, 10) + "/entries"
+	if rec := doVault(handler, http.MethodPost, path, token, `{"entry_id":"e1","encrypted_data":"Zmlyc3Q="}`, nil); rec.Code != http.StatusCreated {
+		t.Errorf("same entry_id in another vault: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestHandleGetEntry(t *testing.T) {
+	svc, handler, token := newAuthedVault(t)
+	ctx := context.Background()
+	if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: "e1", EncryptedData: "c2VjcmV0"}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+
+	rec := doVault(handler, http.MethodGet, "/api/v1/vault/e1", token, "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var entry model.VaultEntryResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &entry); err != nil {
+		t.Fatalf("decoding entry: %v", err)
+	}
+	if entry.EntryID != "e1" || entry.EncryptedData != "c2VjcmV0" || entry.LastAccessedAt == nil {
+		t.Errorf("expected e1 with last_accessed_at, got %+v", entry)
+	}
+
+	if rec := doVault(handler, http.MethodGet, "/api/v1/vault/missing", token, "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing entry, got %d", rec.Code)
+	}
+	if rec := doVault(handler, http.MethodGet, "/api/v1/vault/manifest", token, "", nil); rec.Code != http.StatusOK {
+		t.Errorf("expected the manifest route to win over the entry route, got %d", rec.Code)
+	}
+}
+
+func TestHandleTrash_OnlyDeletedEntries(t *testing.T) {
+	svc, handler, token := newAuthedVault(t)
+	ctx := context.Background()
+	for _, id := range []string{"live", "gone"} {
+		if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: id, EncryptedData: "c2VjcmV0"}); err != nil {
+			t.Fatalf("CreateEntry() unexpected error: %v", err)
+		}
+	}
+	if err := svc.DeleteEntry(ctx, 1, 0, "gone"); err != nil {
+		t.Fatalf("DeleteEntry() unexpected error: %v", err)
+	}
+
+	rec := doVault(handler, http.MethodGet, "/api/v1/vault/trash", token, "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var trash []model.VaultEntryResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &trash); err != nil {
+		t.Fatalf("decoding trash: %v", err)
+	}
+	if len(trash) != 1 || trash[0].EntryID != "gone" {
+		t.Fatalf("expected only the deleted entry, got %+v", trash)
+	}
+
+	rec = doVault(handler, http.MethodPost, "/api/v1/vault/gone/restore", token, "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("restore: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	rec = doVault(handler, http.MethodPost, "/api/v1/vault/live/restore", token, "", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("restoring a live entry: expected 404, got %d", rec.Code)
+	}
+
+	rec = doVault(handler, http.MethodGet, "/api/v1/vault/trash", token, "", nil)
+	if strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Errorf("expected empty trash after restore, got %s", rec.Body)
+	}
+}
+
+func TestHandleImport_CreatedAt(t *testing.T) {
+	_, handler, token := newAuthedVault(t)
+
+	body := `{"entries":[{"entry_id":"e1","encrypted_data":"c2VjcmV0","created_at":"2015-06-01T12:00:00Z"}]}`
+	rec := doVault(handler, http.MethodPost, "/api/v1/vault/import", token, body, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	// The export carries the original created_at.
+	var export model.VaultExport
+	json.Unmarshal(doExport(handler, http.MethodGet, token).Body.Bytes(), &export)
+	if len(export.Entries) != 1 || export.Entries[0].CreatedAt == nil ||
+		!export.Entries[0].CreatedAt.Equal(time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected created_at preserved in export, got %+v", export.Entries)
+	}
+
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	body = `{"entries":[{"entry_id":"e2","encrypted_data":"c2VjcmV0","created_at":"` + future + `"}]}`
+	rec = doVault(handler, http.MethodPost, "/api/v1/vault/import", token, body, nil)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "entries[0]") {
+		t.Errorf("future created_at: expected 400 naming the entry, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestVaultHandler_ConfiguredBodyLimit(t *testing.T) {
+	_, handler, token := newAuthedVault(t, WithMaxVaultBody(1<<10))
+
+	small := `{"entry_id":"e1","encrypted_data":"c2VjcmV0"}`
+	if rec := doVault(handler, http.MethodPost, "/api/v1/vault", token, small, nil); rec.Code != http.StatusCreated {
+		t.Fatalf("within limit: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+
+	large := `{"entry_id":"e2","encrypted_data":"` + strings.Repeat("A", 2<<10) + `"}`
+	for _, path := range []string{"/api/v1/vault", "/api/v1/vault/e1", "/api/v1/vault/sync", "/api/v1/vault/import"} {
+		method := http.MethodPost
+		if path == "/api/v1/vault/e1" {
+			method = http.MethodPut
+		}
+		if rec := doVault(handler, method, path, token, large, nil); rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("%s %s: expected 413 past the configured limit, got %d", method, path, rec.Code)
+		}
+	}
+}
+
+func TestHandleListEntries_Fields(t *testing.T) {
+	svc, handler, token := newAuthedVault(t)
+	ctx := context.Background()
+	for _, id := range []string{"e1", "e2"} {
+		if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: id, EncryptedData: "c2VjcmV0"}); err != nil {
+			t.Fatalf("CreateEntry() unexpected error: %v", err)
+		}
+	}
+	if err := svc.DeleteEntry(ctx, 1, 0, "e2"); err != nil {
+		t.Fatalf("DeleteEntry() unexpected error: %v", err)
+	}
+
+	for _, path := range []string{"/api/v1/vault?fields=entry_id,version,%20updated_at,version", "/api/v1/vault/trash?fields=entry_id,version,updated_at"} {
+		rec := doVault(handler, http.MethodGet, path, token, "", nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", path, rec.Code, rec.Body)
+		}
+		var entries []map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+			t.Fatalf("%s: decoding entries: %v", path, err)
+		}
+		if len(entries) != 1 {
+			t.Fatalf("%s: expected one entry, got %+v", path, entries)
+		}
+		if len(entries[0]) != 3 || entries[0]["version"] == nil || entries[0]["updated_at"] == nil {
+			t.Errorf("%s: expected only entry_id, version and updated_at, got %+v", path, entries[0])
+		}
+	}
+
+	for _, fields := range []string{"entry_id,password", ",", "Entry_ID"} {
+		rec := doVault(handler, http.MethodGet, "/api/v1/vault?fields="+fields, token, "", nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("fields=%s: expected 400, got %d", fields, rec.Code)
+		}
+	}
+
+	// Without fields every field is returned.
+	rec := doVault(handler, http.MethodGet, "/api/v1/vault", token, "", nil)
+	if !strings.Contains(rec.Body.String(), `"encrypted_data"`) {
+		t.Errorf("expected full entries without fields, got %s", rec.Body)
+	}
+}
+
+// failingVaultStore fails every entry listing with err.
+type failingVaultStore struct {
+	repository.VaultStore
+	err error
+}
+
+func (s failingVaultStore) ListByUser(ctx context.Context, userID, vaultID int64) ([]model.VaultEntry, error) {
+	return nil, s.err
+}
+
+func TestVaultHandler_TransientErrorsAre503(t *testing.T) {
+	tokens := crypto.NewTokenManager(crypto.TokenConfig{Secret: "test-secret", Expiry: time.Hour})
+	token, _ := tokens.Generate(1, "")
+	serve := func(store repository.VaultStore, method, body string) *httptest.ResponseRecorder {
+		h := NewVaultHandler(service.NewVaultService(store, repository.NewMemoryCollectionRepository()))
+		r := chi.NewRouter()
+		r.Use(middleware.JWTAuth(tokens, nil))
+		r.Get("/api/v1/vault", h.HandleListEntries)
+		r.Post("/api/v1/vault", h.HandleCreateEntry)
+		return doVault(r, method, "/api/v1/vault", token, body, nil)
+	}
+
+	rec := serve(failingVaultStore{repository.NewMemoryVaultRepository(), driver.ErrBadConn}, http.MethodGet, "")
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("connection error: expected 503 with Retry-After, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	rec = serve(failingVaultStore{repository.NewMemoryVaultRepository(), errors.New("scan: unexpected column")}, http.MethodGet, "")
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get("Retry-After") != "" {
+		t.Errorf("permanent error: expected 500 without Retry-After, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// Validation fails before storage is touched, so it stays a 400 even with the database down.
+	rec = serve(failingVaultStore{repository.NewMemoryVaultRepository(), driver.ErrBadConn}, http.MethodPost, `{"encrypted_data":"!!"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("validation error: expected 400, got %d", rec.Code)
+	}
+}
+
+func TestHandleEntry_InvalidBase64Is400(t *testing.T) {
+	svc, handler, token := newAuthedVault(t)
+	if _, err := svc.CreateEntry(context.Background(), 1, 0, model.VaultEntryRequest{EntryID: "e1", EncryptedData: "YmxvYg=="}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+
+	rec := doVault(handler, http.MethodPost, "/api/v1/vaults/1/entries", token, `{"entry_id":"e2","encrypted_data":"not base64!"}`, nil)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), service.ErrInvalidEncoding.Error()) {
+		t.Errorf("create: expected 400 %q, got %d: %s", service.ErrInvalidEncoding, rec.Code, rec.Body)
+	}
+
+	rec = doVault(handler, http.MethodPut, "/api/v1/vault/e1", token, `{"encrypted_data":"%%%"}`, nil)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), service.ErrInvalidEncoding.Error()) {
+		t.Errorf("update: expected 400 %q, got %d: %s", service.ErrInvalidEncoding, rec.Code, rec.Body)
+	}
+}
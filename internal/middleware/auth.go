@@ -3,42 +3,92 @@ package middleware
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/vaultpass/vaultpass-go/internal/crypto"
+	"github.com/vaultpass/vaultpass-go/internal/model"
 )
 
 type contextKey string
 
-const userIDKey contextKey = "userID"
+const (
+	userIDKey    contextKey = "userID"
+	sessionIDKey contextKey = "sessionID"
+)
+
+// SessionValidator checks that the server-side session behind a token is still active.
+// It returns an error matching model.ErrAccountDeactivated when the token's user has
+// been deactivated.
+type SessionValidator interface {
+	ValidateSession(ctx context.Context, claims *crypto.Claims) error
+}
 
 // JWTAuth returns middleware that validates a Bearer token from the Authorization header.
-func JWTAuth(secret string) func(http.Handler) http.Handler {
+// If sessions is non-nil, tokens whose session has been revoked or whose user has been
+// deactivated are rejected.
+func JWTAuth(tokens *crypto.TokenManager, sessions SessionValidator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
-				writeJSONError(w, http.StatusUnauthorized, "missing authorization header")
+			claims, status, msg := authenticate(r, tokens, sessions)
+			if claims == nil {
+				writeJSONError(w, status, msg)
 				return
 			}
+			next.ServeHTTP(w, r.WithContext(withClaims(r.Context(), claims)))
+		})
+	}
+}
 
-			token, found := strings.CutPrefix(authHeader, "Bearer ")
-			if !found || token == "" {
-				writeJSONError(w, http.StatusUnauthorized, "invalid authorization format")
-				return
+// OptionalJWTAuth is JWTAuth for routes that also serve anonymous clients: a
+// valid token puts its user ID in the context, while a missing, malformed or
+// rejected token lets the request through unauthenticated.
+func OptionalJWTAuth(tokens *crypto.TokenManager, sessions SessionValidator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if claims, _, _ := authenticate(r, tokens, sessions); claims != nil {
+				r = r.WithContext(withClaims(r.Context(), claims))
 			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
 
-			claims, err := crypto.ValidateToken(token, secret)
-			if err != nil {
-				writeJSONError(w, http.StatusUnauthorized, "invalid or expired token")
-				return
-			}
+// authenticate validates the request's Bearer token. On failure it returns nil
+// claims with the status and message to reject the request with.
+func authenticate(r *http.Request, tokens *crypto.TokenManager, sessions SessionValidator) (*crypto.Claims, int, string) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, http.StatusUnauthorized, "missing authorization header"
+	}
 
-			ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
-			next.ServeHTTP(w, r.WithContext(ctx))
-		})
+	token, found := strings.CutPrefix(authHeader, "Bearer ")
+	if !found || token == "" {
+		return nil, http.StatusUnauthorized, "invalid authorization format"
 	}
+
+	claims, err := tokens.Validate(token)
+	if err != nil {
+		return nil, http.StatusUnauthorized, "invalid or expired token"
+	}
+
+	if sessions != nil {
+		if err := sessions.ValidateSession(r.Context(), claims); err != nil {
+			if errors.Is(err, model.ErrAccountDeactivated) {
+				return nil, http.StatusForbidden, err.Error()
+			}
+			return nil, http.StatusUnauthorized, "invalid or expired token"
+		}
+	}
+
+	return claims, 0, ""
+}
+
+// withClaims stores the token's user and session IDs in ctx.
+func withClaims(ctx context.Context, claims *crypto.Claims) context.Context {
+	ctx = context.WithValue(ctx, userIDKey, claims.UserID)
+	return context.WithValue(ctx, sessionIDKey, claims.ID)
 }
 
 // UserIDFromContext extracts the authenticated user ID from the request context.
@@ -47,6 +97,13 @@ func UserIDFromContext(ctx context.Context) (int64, bool) {
 	return id, ok
 }
 
+// SessionIDFromContext extracts the session ID of the authenticated token from the request context.
+// It is empty for tokens issued without a session.
+func SessionIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(sessionIDKey).(string)
+	return id
+}
+
 func writeJSONError(w http.ResponseWriter, status int, msg string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
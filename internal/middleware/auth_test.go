@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/crypto"
+	"github.com/vaultpass/vaultpass-go/internal/model"
+	"github.com/vaultpass/vaultpass-go/internal/repository"
+	"github.com/vaultpass/vaultpass-go/internal/service"
+)
+
+type stubSessions struct {
+	revoked     map[string]bool
+	deactivated bool
+}
+
+func (s stubSessions) ValidateSession(_ context.Context, claims *crypto.Claims) error {
+	if s.deactivated {
+		return model.ErrAccountDeactivated
+	}
+	if s.revoked[claims.ID] {
+		return errors.New("revoked")
+	}
+	return nil
+}
+
+func testTokens() *crypto.TokenManager {
+	return crypto.NewTokenManager(crypto.TokenConfig{Secret: "test-secret", Expiry: time.Hour})
+}
+
+func serveWithToken(t *testing.T, mw func(http.Handler) http.Handler, token string) (*httptest.ResponseRecorder, int64, string) {
+	t.Helper()
+
+	var gotUserID int64
+	var gotSessionID string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserID, _ = UserIDFromContext(r.Context())
+		gotSessionID = SessionIDFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	mw(next).ServeHTTP(rec, req)
+	return rec, gotUserID, gotSessionID
+}
+
+func TestJWTAuth_ValidSession(t *testing.T) {
+	token, err := crypto.GenerateSessionToken(42, "session-1", "test-secret", time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateSessionToken() unexpected error: %v", err)
+	}
+
+	mw := JWTAuth(testTokens(), stubSessions{revoked: map[string]bool{}})
+	rec, userID, sessionID := serveWithToken(t, mw, token)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if userID != 42 {
+		t.Errorf("expected user ID 42 in context, got %d", userID)
+	}
+	if sessionID != "session-1" {
+		t.Errorf("expected session ID in context, got %q", sessionID)
+	}
+}
+
+func TestJWTAuth_RevokedSession(t *testing.T) {
+	token, err := crypto.GenerateSessionToken(42, "session-1", "test-secret", time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateSessionToken() unexpected error: %v", err)
+	}
+
+	mw := JWTAuth(testTokens(), stubSessions{revoked: map[string]bool{"session-1": true}})
+	rec, _, _ := serveWithToken(t, mw, token)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for revoked session, got %d", rec.Code)
+	}
+}
+
+func TestJWTAuth_DeactivatedAccount(t *testing.T) {
+	token, err := crypto.GenerateSessionToken(42, "session-1", "test-secret", time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateSessionToken() unexpected error: %v", err)
+	}
+
+	mw := JWTAuth(testTokens(), stubSessions{deactivated: true})
+	rec, _, _ := serveWithToken(t, mw, token)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for deactivated account, got %d", rec.Code)
+	}
+}
+
+func TestJWTAuth_DeactivatedAccountSessionlessToken(t *testing.T) {
+	tokens := testTokens()
+	auth := service.NewAuthService(
+		repository.NewMemoryUserRepository(),
+		repository.NewMemoryAuditRepository(),
+		repository.NewMemorySessionRepository(),
+		tokens,
+	)
+	ctx := context.Background()
+	reg, err := auth.Register(ctx, model.CreateUserRequest{Email: "a@example.com", Password: "password123"}, model.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+	token, err := tokens.Generate(reg.User.ID, "")
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	mw := JWTAuth(tokens, auth)
+	if rec, _, _ := serveWithToken(t, mw, token); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 before deactivation, got %d", rec.Code)
+	}
+
+	if err := auth.Deactivate(ctx, reg.User.ID); err != nil {
+		t.Fatalf("Deactivate() unexpected error: %v", err)
+	}
+	if rec, _, _ := serveWithToken(t, mw, token); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a sessionless token of a deactivated account, got %d", rec.Code)
+	}
+}
+
+func TestJWTAuth_MissingHeader(t *testing.T) {
+	rec, _, _ := serveWithToken(t, JWTAuth(testTokens(), nil), "")
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for missing header, got %d", rec.Code)
+	}
+}
+
+func TestOptionalJWTAuth(t *testing.T) {
+	token, err := crypto.GenerateSessionToken(42, "session-1", "test-secret", time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateSessionToken() unexpected error: %v", err)
+	}
+	mw := OptionalJWTAuth(testTokens(), stubSessions{revoked: map[string]bool{"revoked": true}})
+
+	rec, userID, _ := serveWithToken(t, mw, token)
+	if rec.Code != http.StatusOK || userID != 42 {
+		t.Errorf("valid token: expected 200 with user 42, got %d with user %d", rec.Code, userID)
+	}
+
+	for name, tok := range map[string]string{"no token": "", "invalid token": "not-a-jwt"} {
+		rec, userID, _ := serveWithToken(t, mw, tok)
+		if rec.Code != http.StatusOK || userID != 0 {
+			t.Errorf("%s: expected anonymous 200, got %d with user %d", name, rec.Code, userID)
+		}
+	}
+
+	revoked, _ := crypto.GenerateSessionToken(42, "revoked", "test-secret", time.Hour)
+	if rec, userID, _ := serveWithToken(t, mw, revoked); rec.Code != http.StatusOK || userID != 0 {
+		t.Errorf("revoked session: expected anonymous 200, got %d with user %d", rec.Code, userID)
+	}
+}
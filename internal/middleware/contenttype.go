@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+)
+
+// RequireJSON rejects POST, PUT and PATCH requests whose body is not declared
+// as application/json with 415 Unsupported Media Type. Requests without a body
+// pass through, so handlers that tolerate an empty body keep doing so and the
+// rest report it themselves.
+func RequireJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.ContentLength == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != "application/json" {
+			writeJSONError(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
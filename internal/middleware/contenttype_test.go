@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequireJSON(t *testing.T) {
+	handler := RequireJSON(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name        string
+		method      string
+		body        string
+		contentType string
+		want        int
+	}{
+		{"json", http.MethodPost, `{}`, "application/json", http.StatusOK},
+		{"json with charset", http.MethodPut, `{}`, "application/json; charset=utf-8", http.StatusOK},
+		{"form", http.MethodPost, "length=16", "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType},
+		{"text", http.MethodPatch, `{}`, "text/plain", http.StatusUnsupportedMediaType},
+		{"missing", http.MethodPost, `{}`, "", http.StatusUnsupportedMediaType},
+		{"empty body", http.MethodPost, "", "", http.StatusOK},
+		{"get ignored", http.MethodGet, "", "text/plain", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1/generate", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected %d, got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+)
+
+// fixedWindow allows up to limit requests per clock-aligned window. Unlike a
+// token bucket its quota is easy to reason about ("100 per minute, reset on
+// the minute"), but a client can spend two full quotas back to back across a
+// window boundary.
+type fixedWindow struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	start  time.Time
+	count  int
+}
+
+func newFixedWindow(limit int, window time.Duration) *fixedWindow {
+	return &fixedWindow{limit: limit, window: window}
+}
+
+// Allow reports whether a request may proceed now.
+func (f *fixedWindow) Allow() bool {
+	return f.allowAt(time.Now())
+}
+
+func (f *fixedWindow) allowAt(now time.Time) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if start := now.Truncate(f.window); !start.Equal(f.start) {
+		f.start = start
+		f.count = 0
+	}
+	if f.count >= f.limit {
+		return false
+	}
+	f.count++
+	return true
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFixedWindow_ResetsAtBoundary(t *testing.T) {
+	fw := newFixedWindow(3, time.Minute)
+	minute := time.Date(2026, 2, 23, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		if !fw.allowAt(minute.Add(58 * time.Second)) {
+			t.Fatalf("request %d within quota should be allowed", i+1)
+		}
+	}
+	if fw.allowAt(minute.Add(58 * time.Second)) {
+		t.Error("request over quota should be denied")
+	}
+	if fw.allowAt(minute.Add(time.Minute - time.Nanosecond)) {
+		t.Error("request just before the boundary should still be denied")
+	}
+
+	// The next window starts on the minute with a fresh quota.
+	next := minute.Add(time.Minute)
+	for i := 0; i < 3; i++ {
+		if !fw.allowAt(next) {
+			t.Fatalf("request %d after the boundary should be allowed", i+1)
+		}
+	}
+	if fw.allowAt(next.Add(30 * time.Second)) {
+		t.Error("quota should not reset mid-window")
+	}
+}
+
+func TestRateLimiter_FixedWindowQuota(t *testing.T) {
+	// 0.05 rps over a minute is a quota of 3 requests.
+	rl := NewRateLimiter(0.05, 1, 100, WithFixedWindow(time.Minute))
+
+	l := rl.getLimiter("10.0.0.1")
+	if _, ok := l.(*fixedWindow); !ok {
+		t.Fatalf("expected a fixed-window limiter, got %T", l)
+	}
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if l.Allow() {
+			allowed++
+		}
+	}
+	// A window boundary may fall inside the loop, so allow up to two quotas.
+	if allowed < 3 || allowed > 6 {
+		t.Errorf("expected a quota of 3 per window, got %d allowed", allowed)
+	}
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// AllowedHosts returns middleware that rejects requests whose Host header is
+// not in hosts with 400, guarding against host-header injection. A "*.example.com"
+// entry matches any subdomain of example.com but not example.com itself. Ports
+// are ignored and matching is case-insensitive. With no hosts it does nothing.
+func AllowedHosts(hosts []string) func(http.Handler) http.Handler {
+	if len(hosts) == 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	var exact, suffixes []string
+	for _, h := range hosts {
+		h = normalizeHost(hostname(h))
+		if rest, ok := strings.CutPrefix(h, "*."); ok {
+			suffixes = append(suffixes, "."+rest)
+		} else {
+			exact = append(exact, h)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host := normalizeHost(hostname(r.Host))
+			if !hostAllowed(host, exact, suffixes) {
+				writeJSONError(w, http.StatusBadRequest, "invalid host")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func hostAllowed(host string, exact, suffixes []string) bool {
+	if host == "" {
+		return false
+	}
+	if slices.Contains(exact, host) {
+		return true
+	}
+	for _, suffix := range suffixes {
+		// The subdomain label before the suffix must be non-empty.
+		if len(host) > len(suffix) && strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostname strips any port from a Host header value, and the brackets from an IPv6 literal.
+func hostname(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(hostport, "["), "]")
+}
+
+// normalizeHost lowercases h and drops a trailing dot, so "API.Example.com."
+// matches "api.example.com".
+func normalizeHost(h string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(h)), ".")
+}
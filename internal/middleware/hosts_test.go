@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowedHosts(t *testing.T) {
+	handler := AllowedHosts([]string{"api.example.com", "*.vaultpass.app", "[::1]"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		host string
+		want int
+	}{
+		{"api.example.com", http.StatusOK},
+		{"API.Example.com:8443", http.StatusOK},
+		{"api.example.com.", http.StatusOK},
+		{"eu.vaultpass.app", http.StatusOK},
+		{"a.b.vaultpass.app:443", http.StatusOK},
+		{"[::1]:8080", http.StatusOK},
+		{"evil.com", http.StatusBadRequest},
+		{"api.example.com.evil.com", http.StatusBadRequest},
+		{"example.com", http.StatusBadRequest},
+		{"vaultpass.app", http.StatusBadRequest},
+		{"evilvaultpass.app", http.StatusBadRequest},
+		{"", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/health", nil)
+			req.Host = tt.host
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("Host %q: expected %d, got %d", tt.host, tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestAllowedHosts_EmptyAllowsAll(t *testing.T) {
+	handler := AllowedHosts(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Host = "anything.example"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", rec.Code)
+	}
+}
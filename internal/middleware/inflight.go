@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// MaxInFlight returns middleware that allows at most n requests to be served
+// concurrently. Requests beyond that are rejected immediately with 503 and a
+// Retry-After hint rather than queued. A slot is released even if the handler
+// panics. n <= 0 disables the limit.
+func MaxInFlight(n int) func(http.Handler) http.Handler {
+	if n <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	sem := make(chan struct{}, n)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case sem <- struct{}{}:
+			default:
+				w.Header().Set("Retry-After", "1")
+				writeJSONError(w, http.StatusServiceUnavailable, "server is at capacity, retry shortly")
+				return
+			}
+			defer func() { <-sem }()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMaxInFlight_RejectsOverCapacity(t *testing.T) {
+	const limit = 3
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	started.Add(limit)
+
+	handler := MaxInFlight(limit)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hold" {
+			started.Done()
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	var done sync.WaitGroup
+	codes := make([]int, limit)
+	for i := range limit {
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/hold", nil))
+			codes[i] = rec.Code
+		}()
+	}
+	started.Wait()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/vault/sync", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 for request %d, got %d", limit+1, rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header on 503")
+	}
+
+	close(release)
+	done.Wait()
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("held request %d: expected 200, got %d", i, code)
+		}
+	}
+
+	// Slots are returned once the held requests finish.
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/vault/sync", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 after capacity freed, got %d", rec.Code)
+	}
+}
+
+func TestMaxInFlight_ReleasesOnPanic(t *testing.T) {
+	handler := Recover(MaxInFlight(1)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/panic" {
+			panic("boom")
+		}
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	logs := captureLogs(t)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500 from recovered panic, got %d", rec.Code)
+	}
+	if !strings.Contains(logs.String(), "panic serving request") {
+		t.Errorf("expected panic to be logged, got %q", logs.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected slot to be released after panic, got %d", rec.Code)
+	}
+}
+
+func TestMaxInFlight_ZeroDisables(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	rec := httptest.NewRecorder()
+	MaxInFlight(0)(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected pass-through, got %d", rec.Code)
+	}
+}
@@ -3,17 +3,88 @@ package middleware
 import (
 	"log/slog"
 	"net/http"
+	"sync/atomic"
 	"time"
 )
 
+// statusRecorder wraps a ResponseWriter to capture the status code and response size.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Unwrap exposes the underlying ResponseWriter to http.ResponseController.
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// Logger emits one structured log line per request. Request bodies are never
+// logged since they carry credentials and encrypted vault data.
 func Logger(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		next.ServeHTTP(w, r)
-		slog.Info("request",
-			"method", r.Method,
-			"path", r.URL.Path,
-			"duration", time.Since(start),
-		)
-	})
+	return SampledLogger(1)(next)
+}
+
+// SampledLogger is Logger that logs only every nth successful request. 4xx and
+// 5xx responses are always logged. Sampled lines carry sample_rate so counts
+// can be scaled back up. n <= 1 logs every request.
+func SampledLogger(n int) func(http.Handler) http.Handler {
+	var successes atomic.Uint64
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w}
+
+			next.ServeHTTP(rec, r)
+
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			attrs := []any{
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", status,
+				"bytes", rec.bytes,
+				"duration", time.Since(start),
+				"request_id", RequestIDFromContext(r.Context()),
+			}
+			if n > 1 && status < 400 {
+				if (successes.Add(1)-1)%uint64(n) != 0 {
+					return
+				}
+				attrs = append(attrs, "sample_rate", n)
+			}
+			slog.Log(r.Context(), levelForStatus(status), "request", attrs...)
+		})
+	}
+}
+
+// levelForStatus maps a response status to a log level: 5xx are errors and 4xx are warnings.
+func levelForStatus(status int) slog.Level {
+	switch {
+	case status >= 500:
+		return slog.LevelError
+	case status >= 400:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
 }
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// captureLogs redirects the default slog logger to a buffer for the duration of the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	original := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(original) })
+	return &buf
+}
+
+func TestLogger_CapturesStatusAndBytes(t *testing.T) {
+	buf := captureLogs(t)
+
+	h := RequestID(Logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	})))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/vault", strings.NewReader(`{"encrypted_data":"c2VjcmV0"}`))
+	req.Header.Set(RequestIDHeader, "req-123")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("expected a single JSON log line, got %q: %v", buf.String(), err)
+	}
+	if line["status"] != float64(http.StatusCreated) {
+		t.Errorf("expected status 201, got %v", line["status"])
+	}
+	if line["bytes"] != float64(len("created")) {
+		t.Errorf("expected bytes %d, got %v", len("created"), line["bytes"])
+	}
+	if line["request_id"] != "req-123" {
+		t.Errorf("expected request_id req-123, got %v", line["request_id"])
+	}
+	if line["level"] != "INFO" {
+		t.Errorf("expected INFO level, got %v", line["level"])
+	}
+	if strings.Contains(buf.String(), "c2VjcmV0") {
+		t.Error("request body must not be logged")
+	}
+}
+
+func TestLogger_ErrorStatusLevels(t *testing.T) {
+	tests := []struct {
+		status int
+		level  string
+	}{
+		{http.StatusOK, "INFO"},
+		{http.StatusNotFound, "WARN"},
+		{http.StatusInternalServerError, "ERROR"},
+	}
+
+	for _, tt := range tests {
+		buf := captureLogs(t)
+		h := Logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		var line map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+			t.Fatalf("invalid log line: %v", err)
+		}
+		if line["level"] != tt.level {
+			t.Errorf("status %d: expected level %s, got %v", tt.status, tt.level, line["level"])
+		}
+	}
+}
+
+func TestRequestID_GeneratesWhenMissingOrInvalid(t *testing.T) {
+	var got string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = RequestIDFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "bad id\nwith newline")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if got == "" || got == "bad id\nwith newline" {
+		t.Errorf("expected a generated request ID, got %q", got)
+	}
+	if rec.Header().Get(RequestIDHeader) != got {
+		t.Errorf("expected response header to echo request ID %q", got)
+	}
+}
+
+func TestSampledLogger(t *testing.T) {
+	buf := captureLogs(t)
+
+	status := http.StatusOK
+	h := SampledLogger(10)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	for range 100 {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	for _, status = range []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError} {
+		for range 5 {
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		}
+	}
+
+	counts := map[float64]int{}
+	for _, raw := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var line map[string]any
+		if err := json.Unmarshal(raw, &line); err != nil {
+			t.Fatalf("invalid log line %q: %v", raw, err)
+		}
+		counts[line["status"].(float64)]++
+		if line["status"] == float64(http.StatusOK) && line["sample_rate"] != float64(10) {
+			t.Errorf("expected sampled line to carry sample_rate 10, got %v", line["sample_rate"])
+		}
+		if line["status"] != float64(http.StatusOK) && line["sample_rate"] != nil {
+			t.Errorf("expected error line without sample_rate, got %v", line["sample_rate"])
+		}
+	}
+
+	if counts[http.StatusOK] != 10 {
+		t.Errorf("expected 10 of 100 successes logged, got %d", counts[http.StatusOK])
+	}
+	for _, status := range []float64{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError} {
+		if counts[status] != 5 {
+			t.Errorf("expected every %v response logged, got %d of 5", status, counts[status])
+		}
+	}
+}
@@ -1,85 +1,310 @@
 package middleware
 
 import (
+	"container/list"
+	"context"
 	"encoding/json"
+	"math"
 	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"golang.org/x/time/rate"
 )
 
-type visitor struct {
-	limiter  *rate.Limiter
-	lastSeen time.Time
+// DefaultMaxVisitors bounds the number of tracked IPs per limiter so a flood of
+// distinct source addresses can't grow the visitor table without limit.
+const DefaultMaxVisitors = 10000
+
+// resetCooldown is how often one visitor's limit may be reset by ResetRateLimit,
+// so a client holding one valid account can't log in between guesses at others
+// to keep clearing its limit.
+const resetCooldown = 10 * time.Minute
+
+// rateLimitResetKey carries the reset callbacks of the limiters that admitted a request.
+const rateLimitResetKey contextKey = "rateLimitReset"
+
+// Limiter decides whether one visitor's next request is allowed.
+// *rate.Limiter (token bucket) and the fixed-window counter both implement it.
+type Limiter interface {
+	Allow() bool
 }
 
-type ipRateLimiter struct {
-	mu       sync.Mutex
-	visitors map[string]*visitor
+type visitor struct {
+	key      string // client IP, or "user:<id>" for per-user limits
+	limiter  Limiter
 	rps      rate.Limit
 	burst    int
+	lastSeen time.Time
+	resetAt  time.Time
 }
 
-func newIPRateLimiter(rps float64, burst int) *ipRateLimiter {
-	rl := &ipRateLimiter{
-		visitors: make(map[string]*visitor),
-		rps:      rate.Limit(rps),
-		burst:    burst,
+// RateLimiter limits requests per client IP using a Limiter per visitor, a
+// token bucket by default. Visitors are kept in least-recently-seen order; once
+// maxVisitors is reached, the stalest visitor is evicted to make room for a new one.
+type RateLimiter struct {
+	mu          sync.Mutex
+	visitors    map[string]*list.Element
+	order       *list.List // front = most recently seen
+	rps         rate.Limit
+	burst       int
+	userRPS     rate.Limit // non-zero limits authenticated users per user instead of per IP
+	userBurst   int
+	window      time.Duration // non-zero selects a fixed-window counter
+	maxVisitors int
+	exempt      []*net.IPNet
+}
+
+// RateLimitOption configures a RateLimiter.
+type RateLimitOption func(*RateLimiter)
+
+// WithExemptNets skips limiting for clients whose IP falls in any of nets,
+// such as monitoring probes or internal services. IPv4 and IPv6 networks may
+// be mixed; IPv4-mapped IPv6 addresses match their IPv4 network.
+func WithExemptNets(nets []*net.IPNet) RateLimitOption {
+	return func(rl *RateLimiter) {
+		rl.exempt = nets
+	}
+}
+
+// WithUserLimit gives requests carrying an authenticated user ID (see
+// OptionalJWTAuth) their own per-user limit of rps and burst instead of the
+// per-IP one, so signed-in users can be allowed more than anonymous clients.
+func WithUserLimit(rps float64, burst int) RateLimitOption {
+	return func(rl *RateLimiter) {
+		rl.userRPS = rate.Limit(rps)
+		rl.userBurst = burst
+	}
+}
+
+// WithFixedWindow replaces the token bucket with a fixed-window counter that
+// allows rps*window requests (at least one) per window. Windows are aligned to
+// the clock, so a one-minute window resets on the minute.
+func WithFixedWindow(window time.Duration) RateLimitOption {
+	return func(rl *RateLimiter) {
+		rl.window = window
+	}
+}
+
+// NewRateLimiter creates a RateLimiter and starts its background cleanup.
+// rps is the allowed requests per second, burst is the maximum burst size.
+// An rps of 0 leaves clients unlimited per IP, so only WithUserLimit applies.
+func NewRateLimiter(rps float64, burst, maxVisitors int, opts ...RateLimitOption) *RateLimiter {
+	if maxVisitors <= 0 {
+		maxVisitors = DefaultMaxVisitors
+	}
+	rl := &RateLimiter{
+		visitors:    make(map[string]*list.Element),
+		order:       list.New(),
+		rps:         rate.Limit(rps),
+		burst:       burst,
+		maxVisitors: maxVisitors,
+	}
+	for _, opt := range opts {
+		opt(rl)
 	}
 	go rl.cleanup()
 	return rl
 }
 
-func (rl *ipRateLimiter) getLimiter(ip string) *rate.Limiter {
+func (rl *RateLimiter) getLimiter(ip string) Limiter {
+	return rl.visitorLimiter(ip, rl.rps, rl.burst)
+}
+
+// visitorLimiter returns the Limiter tracked under key, creating one with rps
+// and burst if the key is new.
+func (rl *RateLimiter) visitorLimiter(key string, rps rate.Limit, burst int) Limiter {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	v, exists := rl.visitors[ip]
-	if !exists {
-		limiter := rate.NewLimiter(rl.rps, rl.burst)
-		rl.visitors[ip] = &visitor{limiter: limiter, lastSeen: time.Now()}
-		return limiter
+	if el, exists := rl.visitors[key]; exists {
+		v := el.Value.(*visitor)
+		v.lastSeen = time.Now()
+		rl.order.MoveToFront(el)
+		return v.limiter
 	}
 
-	v.lastSeen = time.Now()
+	for len(rl.visitors) >= rl.maxVisitors {
+		rl.evictOldest()
+	}
+
+	v := &visitor{key: key, limiter: rl.newLimiter(rps, burst), rps: rps, burst: burst, lastSeen: time.Now()}
+	rl.visitors[key] = rl.order.PushFront(v)
 	return v.limiter
 }
 
-func (rl *ipRateLimiter) cleanup() {
+// reset gives the visitor tracked under key a fresh Limiter at full capacity,
+// unless it was already reset within resetCooldown.
+func (rl *RateLimiter) reset(key string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	el, exists := rl.visitors[key]
+	if !exists {
+		return
+	}
+	v := el.Value.(*visitor)
+	now := time.Now()
+	if !v.resetAt.IsZero() && now.Sub(v.resetAt) < resetCooldown {
+		return
+	}
+	v.limiter = rl.newLimiter(v.rps, v.burst)
+	v.resetAt = now
+}
+
+// newLimiter builds the Limiter for a new visitor.
+func (rl *RateLimiter) newLimiter(rps rate.Limit, burst int) Limiter {
+	if rl.window > 0 {
+		quota := max(1, int(math.Round(float64(rps)*rl.window.Seconds())))
+		return newFixedWindow(quota, rl.window)
+	}
+	return rate.NewLimiter(rps, burst)
+}
+
+// evictOldest removes the least recently seen visitor. Callers must hold rl.mu.
+func (rl *RateLimiter) evictOldest() {
+	el := rl.order.Back()
+	if el == nil {
+		return
+	}
+	rl.order.Remove(el)
+	delete(rl.visitors, el.Value.(*visitor).key)
+}
+
+// Visitors returns the number of IPs and users currently tracked.
+func (rl *RateLimiter) Visitors() int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return len(rl.visitors)
+}
+
+// RateLimiterSet collects the limiters of every route group so their visitor
+// counts can be reported together. The zero value is ready to use, and a nil
+// set ignores Add.
+type RateLimiterSet struct {
+	mu       sync.Mutex
+	limiters []*RateLimiter
+}
+
+// Add includes rl in the set.
+func (s *RateLimiterSet) Add(rl *RateLimiter) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limiters = append(s.limiters, rl)
+}
+
+// Visitors returns the number of IPs and users tracked across every limiter
+// in the set. A visitor limited by several route groups is counted once per group.
+func (s *RateLimiterSet) Visitors() int {
+	s.mu.Lock()
+	limiters := s.limiters
+	s.mu.Unlock()
+
+	n := 0
+	for _, rl := range limiters {
+		n += rl.Visitors()
+	}
+	return n
+}
+
+func (rl *RateLimiter) cleanup() {
 	for {
 		time.Sleep(10 * time.Minute)
 		rl.mu.Lock()
-		for ip, v := range rl.visitors {
-			if time.Since(v.lastSeen) > 10*time.Minute {
-				delete(rl.visitors, ip)
+		// Visitors are ordered by last use, so stale ones are all at the back.
+		for el := rl.order.Back(); el != nil; el = rl.order.Back() {
+			if time.Since(el.Value.(*visitor).lastSeen) <= 10*time.Minute {
+				break
 			}
+			rl.evictOldest()
 		}
 		rl.mu.Unlock()
 	}
 }
 
-// RateLimit returns middleware that limits requests per IP address.
-// rps is the allowed requests per second, burst is the maximum burst size.
-func RateLimit(rps float64, burst int) func(http.Handler) http.Handler {
-	limiter := newIPRateLimiter(rps, burst)
-
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ip, _, err := net.SplitHostPort(r.RemoteAddr)
-			if err != nil {
-				ip = r.RemoteAddr
-			}
+// Middleware returns middleware that rejects requests over the limit with 429.
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
 
-			if !limiter.getLimiter(ip).Allow() {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusTooManyRequests)
-				json.NewEncoder(w).Encode(map[string]string{"error": "too many requests"})
-				return
-			}
+		if rl.isExempt(ip) {
+			next.ServeHTTP(w, r)
+			return
+		}
 
+		var key string
+		var limiter Limiter
+		if userID, ok := UserIDFromContext(r.Context()); ok && rl.userRPS > 0 {
+			key = "user:" + strconv.FormatInt(userID, 10)
+			limiter = rl.visitorLimiter(key, rl.userRPS, rl.userBurst)
+		} else if rl.rps > 0 {
+			key = ip
+			limiter = rl.getLimiter(ip)
+		} else {
 			next.ServeHTTP(w, r)
-		})
+			return
+		}
+		if !limiter.Allow() {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]string{"error": "too many requests"})
+			return
+		}
+
+		prev, _ := r.Context().Value(rateLimitResetKey).(func())
+		resetFn := func() {
+			if prev != nil {
+				prev()
+			}
+			rl.reset(key)
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), rateLimitResetKey, resetFn)))
+	})
+}
+
+// ResetRateLimit restores full capacity to the rate limiters that admitted the
+// request carrying ctx, for the same client only. Handlers call it once a client
+// has proven itself, e.g. after a successful login, so earlier failed attempts
+// stop counting against it. Each client can be reset at most once per ten
+// minutes. It does nothing if no limiter admitted the request.
+func ResetRateLimit(ctx context.Context) {
+	if reset, ok := ctx.Value(rateLimitResetKey).(func()); ok {
+		reset()
+	}
+}
+
+// isExempt reports whether ip falls in one of the exempt networks.
+func (rl *RateLimiter) isExempt(ip string) bool {
+	if len(rl.exempt) == 0 {
+		return false
+	}
+	// Link-local IPv6 peers carry a zone, e.g. "fe80::1%eth0", which ParseIP rejects.
+	if i := strings.IndexByte(ip, '%'); i >= 0 {
+		ip = ip[:i]
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range rl.exempt {
+		if n.Contains(parsed) {
+			return true
+		}
 	}
+	return false
+}
+
+// RateLimit returns middleware that limits requests per IP address.
+// rps is the allowed requests per second, burst is the maximum burst size.
+func RateLimit(rps float64, burst int, opts ...RateLimitOption) func(http.Handler) http.Handler {
+	return NewRateLimiter(rps, burst, DefaultMaxVisitors, opts...).Middleware
 }
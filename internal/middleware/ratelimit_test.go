@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRateLimiter_EvictsPastCap(t *testing.T) {
+	rl := NewRateLimiter(5, 10, 100)
+
+	for i := 0; i < 1000; i++ {
+		rl.getLimiter(fmt.Sprintf("10.0.%d.%d", i/256, i%256))
+	}
+
+	if n := rl.Visitors(); n != 100 {
+		t.Fatalf("expected visitor table bounded at 100, got %d", n)
+	}
+
+	// The most recent IPs are retained; the earliest were evicted.
+	rl.mu.Lock()
+	_, newest := rl.visitors["10.0.3.231"]
+	_, oldest := rl.visitors["10.0.0.0"]
+	rl.mu.Unlock()
+	if !newest {
+		t.Error("expected most recently seen IP to be retained")
+	}
+	if oldest {
+		t.Error("expected least recently seen IP to be evicted")
+	}
+}
+
+func TestRateLimiterSet_SumsVisitors(t *testing.T) {
+	a, b := NewRateLimiter(5, 10, 0), NewRateLimiter(5, 10, 0)
+	a.getLimiter("10.0.0.1")
+	a.getLimiter("10.0.0.2")
+	b.getLimiter("10.0.0.1")
+
+	var set RateLimiterSet
+	set.Add(a)
+	set.Add(b)
+	if n := set.Visitors(); n != 3 {
+		t.Errorf("expected 3 visitors across both limiters, got %d", n)
+	}
+
+	var none *RateLimiterSet
+	none.Add(a) // must not panic
+}
+
+func TestRateLimiter_RecentUseProtectsFromEviction(t *testing.T) {
+	rl := NewRateLimiter(5, 10, 3)
+
+	rl.getLimiter("a")
+	rl.getLimiter("b")
+	rl.getLimiter("c")
+	rl.getLimiter("a") // a becomes most recent, b is now oldest
+	rl.getLimiter("d")
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if _, ok := rl.visitors["a"]; !ok {
+		t.Error("expected recently used visitor to survive eviction")
+	}
+	if _, ok := rl.visitors["b"]; ok {
+		t.Error("expected least recently used visitor to be evicted")
+	}
+}
+
+func TestRateLimiter_Middleware(t *testing.T) {
+	rl := NewRateLimiter(1, 2, 10)
+	h := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	codes := make([]int, 3)
+	for i := range codes {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		codes[i] = rec.Code
+	}
+
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Errorf("expected burst of 2 then 429, got %v", codes)
+	}
+}
+
+func TestResetRateLimit(t *testing.T) {
+	rl := NewRateLimiter(0.001, 2, 10)
+	h := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("ok") == "1" {
+			ResetRateLimit(r.Context())
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	do := func(path, addr string) int {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	do("/login", "192.0.2.1:1234")
+	do("/login?ok=1", "192.0.2.1:1234") // exhausts the burst, then resets it
+	if code := do("/login", "198.51.100.7:1234"); code != http.StatusOK {
+		t.Fatalf("other client: expected 200, got %d", code)
+	}
+	for i := range 2 {
+		if code := do("/login", "192.0.2.1:1234"); code != http.StatusOK {
+			t.Fatalf("after reset %d: expected 200, got %d", i+1, code)
+		}
+	}
+	if code := do("/login", "192.0.2.1:1234"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 once the restored burst is spent, got %d", code)
+	}
+
+	// A second reset within the cooldown leaves the bucket empty.
+	rl.reset("192.0.2.1")
+	if code := do("/login", "192.0.2.1:1234"); code != http.StatusTooManyRequests {
+		t.Errorf("expected reset cooldown to keep the client throttled, got %d", code)
+	}
+
+	// Outside a limiter it is a no-op.
+	ResetRateLimit(httptest.NewRequest(http.MethodGet, "/", nil).Context())
+}
+
+func TestRateLimiter_ExemptNets(t *testing.T) {
+	var nets []*net.IPNet
+	for _, cidr := range []string{"10.0.0.0/8", "2001:db8::/32"} {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatalf("ParseCIDR(%q) unexpected error: %v", cidr, err)
+		}
+		nets = append(nets, n)
+	}
+	rl := NewRateLimiter(1, 1, 10, WithExemptNets(nets))
+	h := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for _, addr := range []string{"10.1.2.3:1234", "[2001:db8::7]:1234", "[::ffff:10.9.9.9]:1234"} {
+		for i := range 5 {
+			if code := send(addr); code != http.StatusOK {
+				t.Fatalf("%s request %d: expected exempt client to bypass the limiter, got %d", addr, i+1, code)
+			}
+		}
+	}
+	if rl.Visitors() != 0 {
+		t.Errorf("expected exempt clients not to be tracked, got %d visitors", rl.Visitors())
+	}
+
+	for _, addr := range []string{"192.0.2.1:1234", "[2001:db9::1]:1234"} {
+		if code := send(addr); code != http.StatusOK {
+			t.Fatalf("%s: expected first request within burst, got %d", addr, code)
+		}
+		if code := send(addr); code != http.StatusTooManyRequests {
+			t.Errorf("%s: expected non-exempt client to be limited, got %d", addr, code)
+		}
+	}
+}
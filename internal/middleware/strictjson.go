@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+const strictJSONKey contextKey = "strictJSON"
+
+// StrictJSON marks each request for strict body decoding, so handlers reject
+// JSON fields their request types don't define instead of ignoring them.
+func StrictJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), strictJSONKey, true)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// StrictJSONFromContext reports whether StrictJSON applies to the request.
+func StrictJSONFromContext(ctx context.Context) bool {
+	strict, _ := ctx.Value(strictJSONKey).(bool)
+	return strict
+}
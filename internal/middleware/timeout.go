@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RequestTimeoutHeader lets a client ask for a deadline on its request, as a
+// Go duration ("2s", "500ms") or a number of seconds ("1.5").
+const RequestTimeoutHeader = "X-Request-Timeout"
+
+// timeoutBody is the JSON error sent when a request runs out of time.
+const timeoutBody = `{"error":"request timed out"}` + "\n"
+
+// Timeout returns middleware that gives each request at most d to respond.
+// The request context is cancelled at the deadline so storage calls give up,
+// and a response the handler starts after the deadline, or a handler that
+// writes nothing, becomes a 503. It waits for the handler to return rather
+// than abandoning it, so the request keeps holding its MaxInFlight slot until
+// the work has really stopped. A response already under way at the deadline
+// is left alone. d <= 0 disables the limit.
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	if d <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			serveWithDeadline(next, w, r, d)
+		})
+	}
+}
+
+// ClientTimeout returns middleware that honors X-Request-Timeout. The request
+// context gets the client's deadline, capped at limit, so storage calls give
+// up once the client's budget is spent. A response the handler starts after
+// the deadline, or a handler that writes nothing, becomes a 503. Responses are
+// not buffered, so it is safe on streaming routes; a response already under
+// way at the deadline is left alone. Unparseable or non-positive header
+// values are ignored, and limit <= 0 ignores the header altogether.
+func ClientTimeout(limit time.Duration) func(http.Handler) http.Handler {
+	if limit <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			d, ok := clientTimeout(r, limit)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			serveWithDeadline(next, w, r, d)
+		})
+	}
+}
+
+// serveWithDeadline runs next with a request context that expires after d,
+// replacing a response it starts after the deadline, or a missing one, with
+// the timeout error.
+func serveWithDeadline(next http.Handler, w http.ResponseWriter, r *http.Request, d time.Duration) {
+	ctx, cancel := context.WithTimeout(r.Context(), d)
+	defer cancel()
+	dw := &deadlineWriter{ResponseWriter: w, ctx: ctx}
+	next.ServeHTTP(dw, r.WithContext(ctx))
+	if !dw.wroteHeader && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		dw.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
+
+// clientTimeout returns the deadline requested by r's X-Request-Timeout
+// header, clamped to limit. It reports false when the header is absent or
+// unusable.
+func clientTimeout(r *http.Request, limit time.Duration) (time.Duration, bool) {
+	v := r.Header.Get(RequestTimeoutHeader)
+	if v == "" {
+		return 0, false
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		secs, ferr := strconv.ParseFloat(v, 64)
+		if ferr != nil || !(secs > 0) { // also rejects NaN
+			return 0, false
+		}
+		if secs >= limit.Seconds() { // also catches Inf before it overflows
+			return limit, true
+		}
+		d = time.Duration(secs * float64(time.Second))
+	}
+	if d <= 0 {
+		return 0, false
+	}
+	return min(d, limit), true
+}
+
+// deadlineWriter replaces a response started after its context's deadline
+// with the timeout error, and discards whatever the handler writes after it.
+type deadlineWriter struct {
+	http.ResponseWriter
+	ctx         context.Context
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (w *deadlineWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	if errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		w.timedOut = true
+		h := w.Header()
+		h.Del("Content-Length")
+		h.Del("ETag")
+		h.Set("Content-Type", "application/json")
+		w.ResponseWriter.WriteHeader(http.StatusServiceUnavailable)
+		io.WriteString(w.ResponseWriter, timeoutBody)
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *deadlineWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap exposes the underlying ResponseWriter to http.ResponseController.
+func (w *deadlineWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
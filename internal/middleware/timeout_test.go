@@ -0,0 +1,218 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeout_CutsOffSlowHandler(t *testing.T) {
+	cancelled := make(chan struct{})
+	handler := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(cancelled)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/vault", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected JSON content type, got %q", ct)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["error"] != "request timed out" {
+		t.Errorf("unexpected body %q", rec.Body.String())
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("expected the handler's context to be cancelled")
+	}
+}
+
+func TestTimeout_HoldsInFlightSlotUntilHandlerReturns(t *testing.T) {
+	release := make(chan struct{})
+	handler := MaxInFlight(1)(Timeout(10 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release // ignores its context, like a stuck query
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	first := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		first <- rec.Code
+	}()
+
+	// Past the deadline the stuck handler still holds the only slot.
+	time.Sleep(50 * time.Millisecond)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("expected the second request rejected at capacity, got %d", rec.Code)
+	}
+
+	close(release)
+	if code := <-first; code != http.StatusServiceUnavailable {
+		t.Errorf("expected the stuck request to time out, got %d", code)
+	}
+}
+
+func TestTimeout_FastHandlerPasses(t *testing.T) {
+	handler := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"1"`)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/vault", nil))
+
+	if rec.Code != http.StatusCreated || rec.Body.String() != `{"ok":true}` || rec.Header().Get("ETag") != `"1"` {
+		t.Errorf("expected the handler's response, got %d %q %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+}
+
+func TestTimeout_ZeroDisables(t *testing.T) {
+	handler := Timeout(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); ok {
+			t.Error("expected no deadline when disabled")
+		}
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected pass-through, got %d", rec.Code)
+	}
+}
+
+func TestClientTimeout_HeaderAbortsSlowHandler(t *testing.T) {
+	handler := ClientTimeout(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("late"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/vault/export", nil)
+	req.Header.Set(RequestTimeoutHeader, "20ms")
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != timeoutBody {
+		t.Fatalf("expected 503 with the timeout body, got %d %q", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected JSON content type, got %q", ct)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the client's 20ms budget to apply, took %v", elapsed)
+	}
+}
+
+func TestClientTimeout_SilentHandler(t *testing.T) {
+	handler := ClientTimeout(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestTimeoutHeader, "10ms")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 when the handler writes nothing, got %d", rec.Code)
+	}
+}
+
+func TestClientTimeout_WithoutHeaderOrLimit(t *testing.T) {
+	noDeadline := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); ok {
+			t.Error("expected no deadline")
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	ClientTimeout(time.Minute)(noDeadline).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("without the header: expected pass-through, got %d", rec.Code)
+	}
+}
+
+func TestClientTimeout_ZeroLimitIgnoresHeader(t *testing.T) {
+	handler := ClientTimeout(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); ok {
+			t.Error("expected no deadline when the limit is zero")
+		}
+		time.Sleep(20 * time.Millisecond) // well past the requested 1ms
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestTimeoutHeader, "1ms")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected the header to be ignored, got %d", rec.Code)
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	const limit = 20 * time.Second
+
+	cases := map[string]time.Duration{
+		"":       0,
+		"2s":     2 * time.Second,
+		"500ms":  500 * time.Millisecond,
+		"1.5":    1500 * time.Millisecond,
+		"1h":     limit,
+		"3600":   limit,
+		"1e300":  limit,
+		"+Inf":   limit,
+		"0":      0,
+		"-1s":    0,
+		"NaN":    0,
+		"banana": 0,
+	}
+	for header, want := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			req.Header.Set(RequestTimeoutHeader, header)
+		}
+		if got, ok := clientTimeout(req, limit); got != want || ok != (want != 0) {
+			t.Errorf("clientTimeout(%q) = %v, %v; want %v", header, got, ok, want)
+		}
+	}
+}
+
+func TestClientTimeout_HeaderCappedByServer(t *testing.T) {
+	handler := ClientTimeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/vault", nil)
+	req.Header.Set(RequestTimeoutHeader, "1h")
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rec.Code)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the server max to cap the header, took %v", elapsed)
+	}
+}
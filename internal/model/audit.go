@@ -0,0 +1,62 @@
+package model
+
+import "time"
+
+// LoginEvent represents a single login attempt in the audit log.
+// UserID is nil when the attempted email does not belong to any account.
+type LoginEvent struct {
+	ID        int64
+	UserID    *int64
+	Email     string
+	Success   bool
+	IPAddress string
+	UserAgent string
+	CreatedAt time.Time
+}
+
+// ClientInfo carries non-secret request metadata used for auditing.
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+// LoginEventResponse represents a login event safe for API responses.
+type LoginEventResponse struct {
+	ID        int64     `json:"id"`
+	Success   bool      `json:"success"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LoginCursor marks a position in the login history, ordered by time then ID.
+type LoginCursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+// LoginEventFilter selects a page of a user's login events, most recent first.
+// Before and Success are optional.
+type LoginEventFilter struct {
+	Limit   int
+	Offset  int
+	Before  *LoginCursor
+	Success *bool
+}
+
+// LoginHistoryQuery holds the client's paging and filter parameters.
+type LoginHistoryQuery struct {
+	Limit   int
+	Offset  int
+	Cursor  string
+	Success *bool
+}
+
+// LoginHistoryResponse represents a page of login events.
+type LoginHistoryResponse struct {
+	Events     []LoginEventResponse `json:"events"`
+	Limit      int                  `json:"limit"`
+	Offset     int                  `json:"offset"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+	HasMore    bool                 `json:"has_more"`
+}
@@ -1,17 +1,134 @@
 package model
 
+import "time"
+
 // GenerateRequest represents a password generation request.
-// Pointer bools allow distinguishing between missing (nil -> default true) and explicit false.
+// Pointer bools allow distinguishing between missing (nil -> server default) and explicit false.
 type GenerateRequest struct {
-	Length    int   `json:"length"`
-	Uppercase *bool `json:"uppercase"`
-	Lowercase *bool `json:"lowercase"`
-	Numbers   *bool `json:"numbers"`
-	Symbols   *bool `json:"symbols"`
+	// Preset names a bundle of defaults ("nist", "pin", "max-compatibility", "mobile"); explicit fields override it.
+	Preset    string `json:"preset,omitempty"`
+	Mode      string `json:"mode"` // "random" (default), "pronounceable", "hex", "base32" or "base64url"
+	Length    int    `json:"length"`
+	Bytes     int    `json:"bytes"` // random byte count for token modes
+	Uppercase *bool  `json:"uppercase"`
+	Lowercase *bool  `json:"lowercase"`
+	Numbers   *bool  `json:"numbers"`
+	Symbols   *bool  `json:"symbols"`
+	// MobileFriendly restricts symbols to a few that are easy to type on phone
+	// keyboards. Without a length, the password is lengthened to make up the entropy.
+	MobileFriendly bool `json:"mobile_friendly"`
+	// Recoverable asks for a one-time recovery handle alongside the password.
+	Recoverable bool `json:"recoverable"`
+	// PolicyRegex is an RE2 pattern the password must match; random and
+	// pronounceable passwords are regenerated until one does.
+	PolicyRegex string `json:"policy_regex,omitempty"`
 }
 
+// Generation modes accepted in GenerateRequest.Mode.
+const (
+	GenerateModeRandom        = "random"
+	GenerateModePronounceable = "pronounceable"
+	GenerateModeHex           = "hex"
+	GenerateModeBase32        = "base32"
+	GenerateModeBase64URL     = "base64url"
+)
+
 // GenerateResponse represents a password generation response.
 type GenerateResponse struct {
+	Password    string  `json:"password"`
+	Length      int     `json:"length"`
+	Mode        string  `json:"mode"`
+	Encoding    string  `json:"encoding,omitempty"`
+	Bytes       int     `json:"bytes,omitempty"`
+	EntropyBits float64 `json:"entropy_bits"`
+	// MobileFriendly reports that symbols were drawn from the mobile keyboard set.
+	MobileFriendly bool `json:"mobile_friendly,omitempty"`
+
+	RecoveryHandle    string     `json:"recovery_handle,omitempty"`
+	RecoveryExpiresAt *time.Time `json:"recovery_expires_at,omitempty"`
+}
+
+// GenerateDefaultsResponse reports the options a generate request gets for the
+// fields it leaves unset, the accepted length range and the available presets.
+type GenerateDefaultsResponse struct {
+	Mode      string                    `json:"mode"`
+	Length    int                       `json:"length"`
+	Uppercase bool                      `json:"uppercase"`
+	Lowercase bool                      `json:"lowercase"`
+	Numbers   bool                      `json:"numbers"`
+	Symbols   bool                      `json:"symbols"`
+	MinLength int                       `json:"min_length"`
+	MaxLength int                       `json:"max_length"`
+	Presets   map[string]GeneratePreset `json:"presets"`
+}
+
+// GeneratePreset describes the options a named preset supplies.
+type GeneratePreset struct {
+	Mode           string `json:"mode"`
+	Length         int    `json:"length"`
+	Uppercase      bool   `json:"uppercase"`
+	Lowercase      bool   `json:"lowercase"`
+	Numbers        bool   `json:"numbers"`
+	Symbols        bool   `json:"symbols"`
+	MobileFriendly bool   `json:"mobile_friendly,omitempty"`
+}
+
+// GenerateCapabilities lists what this server's generator supports, so clients
+// can detect features instead of keying them on the server version. A feature
+// missing from an older server's response reads as false.
+type GenerateCapabilities struct {
+	Modes    []GenerateModeCapability `json:"modes"`
+	Presets  []string                 `json:"presets"`
+	Features GenerateFeatures         `json:"features"`
+}
+
+// GenerateModeCapability names a generation mode, the request fields it honors
+// and its accepted range: a length for password modes, a byte count for token modes.
+type GenerateModeCapability struct {
+	Mode      string   `json:"mode"`
+	Options   []string `json:"options"`
+	MinLength int      `json:"min_length,omitempty"`
+	MaxLength int      `json:"max_length,omitempty"`
+	MinBytes  int      `json:"min_bytes,omitempty"`
+	MaxBytes  int      `json:"max_bytes,omitempty"`
+}
+
+// GenerateFeatures reports optional generator behavior enabled on this server.
+type GenerateFeatures struct {
+	Recovery     bool `json:"recovery"`      // "recoverable" requests and POST /generate/redeem
+	Denylist     bool `json:"denylist"`      // passwords avoid configured words
+	BreachCheck  bool `json:"breach_check"`  // strength results include breach status
+	FileDownload bool `json:"file_download"` // ?format=file on POST /generate
+}
+
+// GeneratorMetrics counts generated passwords without recording any of them.
+// Lengths are keyed by bucket ("12-15", "64+", ...) and Classes by the enabled
+// character classes joined with "+"; token modes have no classes.
+type GeneratorMetrics struct {
+	Total   int64            `json:"total"`
+	Modes   map[string]int64 `json:"modes"`
+	Lengths map[string]int64 `json:"lengths"`
+	Classes map[string]int64 `json:"classes"`
+}
+
+// MetricsResponse is served by the admin metrics endpoint.
+type MetricsResponse struct {
+	Generator GeneratorMetrics `json:"generator"`
+	RateLimit RateLimitMetrics `json:"rate_limit"`
+}
+
+// RateLimitMetrics reports the state of the rate limiters.
+type RateLimitMetrics struct {
+	// Visitors is the number of IPs and users currently tracked, summed over route groups.
+	Visitors int `json:"visitors"`
+}
+
+// RedeemRequest redeems a recovery handle issued by a recoverable generate request.
+type RedeemRequest struct {
+	Handle string `json:"handle"`
+}
+
+// RedeemResponse carries the recovered password.
+type RedeemResponse struct {
 	Password string `json:"password"`
-	Length   int    `json:"length"`
 }
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInviteRequired = errors.New("invite code is required")
+	ErrInviteInvalid  = errors.New("invite code is not valid")
+	ErrInviteUsed     = errors.New("invite code has already been used")
+	ErrInviteExpired  = errors.New("invite code has expired")
+)
+
+// Invite is a single-use registration code. Only a hash of the code is stored.
+type Invite struct {
+	CodeHash  string
+	CreatedAt time.Time
+	ExpiresAt *time.Time // nil means the code never expires
+	UsedAt    *time.Time
+	UsedBy    string // email of the account registered with the code
+}
+
+// CreateInviteRequest mints an invite code. Without ExpiresAt the code never expires.
+type CreateInviteRequest struct {
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// InviteResponse carries a newly minted code. The code is only ever shown here.
+type InviteResponse struct {
+	Code      string     `json:"code"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
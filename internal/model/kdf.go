@@ -0,0 +1,77 @@
+package model
+
+import (
+	"encoding/base64"
+	"errors"
+)
+
+var (
+	ErrKDFRequired    = errors.New("kdf is required")
+	ErrKDFInvalid     = errors.New("kdf must be at most 32 lowercase letters, digits or '-'")
+	ErrKDFIterations  = errors.New("iterations must be between 1 and 100000000")
+	ErrKDFMemory      = errors.New("memory_kib must be between 0 and 4194304")
+	ErrKDFParallelism = errors.New("parallelism must be between 0 and 255")
+	ErrKDFSalt        = errors.New("salt must be base64 encoding 8 to 64 bytes")
+)
+
+const (
+	maxKDFNameLength  = 32
+	maxKDFIterations  = 100_000_000
+	maxKDFMemoryKiB   = 4 << 20 // 4 GiB
+	maxKDFParallelism = 255
+	minKDFSaltBytes   = 8
+	maxKDFSaltBytes   = 64
+)
+
+// KDFProfile records the parameters a client used to derive its vault key, so
+// another device can reproduce the derivation. It is not secret: the server
+// stores it as given and never derives keys itself. The zero value means no
+// profile has been stored.
+type KDFProfile struct {
+	KDF         string `json:"kdf,omitempty"` // e.g. "argon2id" or "pbkdf2-sha256"
+	Iterations  int    `json:"iterations,omitempty"`
+	MemoryKiB   int    `json:"memory_kib,omitempty"`
+	Parallelism int    `json:"parallelism,omitempty"`
+	Salt        string `json:"salt,omitempty"` // standard base64
+}
+
+// Validate reports every invalid field in the profile. Any KDF name is
+// accepted as long as it is well-formed.
+func (p KDFProfile) Validate() error {
+	var verr ValidationError
+
+	switch {
+	case p.KDF == "":
+		verr.Add("kdf", "required", ErrKDFRequired)
+	case !validKDFName(p.KDF):
+		verr.Add("kdf", "invalid", ErrKDFInvalid)
+	}
+
+	if p.Iterations < 1 || p.Iterations > maxKDFIterations {
+		verr.Add("iterations", "out of range", ErrKDFIterations)
+	}
+	if p.MemoryKiB < 0 || p.MemoryKiB > maxKDFMemoryKiB {
+		verr.Add("memory_kib", "out of range", ErrKDFMemory)
+	}
+	if p.Parallelism < 0 || p.Parallelism > maxKDFParallelism {
+		verr.Add("parallelism", "out of range", ErrKDFParallelism)
+	}
+
+	if salt, err := base64.StdEncoding.DecodeString(p.Salt); err != nil || len(salt) < minKDFSaltBytes || len(salt) > maxKDFSaltBytes {
+		verr.Add("salt", "invalid", ErrKDFSalt)
+	}
+
+	return verr.Err()
+}
+
+func validKDFName(name string) bool {
+	if len(name) > maxKDFNameLength {
+		return false
+	}
+	for _, c := range name {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
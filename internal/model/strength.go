@@ -0,0 +1,43 @@
+package model
+
+// Breach check outcomes reported in StrengthResponse.Breach.
+const (
+	BreachDisabled    = "disabled"    // the server does not check breaches
+	BreachUnavailable = "unavailable" // the range API could not be reached
+	BreachNotFound    = "not_found"
+	BreachFound       = "found"
+)
+
+// StrengthRequest asks for an assessment of a candidate password.
+type StrengthRequest struct {
+	Password string `json:"password"`
+}
+
+// StrengthResponse describes a password's estimated entropy, score and breach
+// status. Findings lists the weaknesses behind the score, such as "too_short"
+// or "sequence", so clients can explain it; it is empty for a clean password.
+type StrengthResponse struct {
+	Length      int      `json:"length"`
+	EntropyBits float64  `json:"entropy_bits"`
+	Score       int      `json:"score"`
+	Findings    []string `json:"findings"`
+	Breach      string   `json:"breach"`
+	BreachCount int      `json:"breach_count,omitempty"`
+}
+
+// StrengthBatchRequest asks for an assessment of several passwords at once.
+type StrengthBatchRequest struct {
+	Passwords []string `json:"passwords"`
+}
+
+// StrengthResult is one password's assessment in a batch. Error is set instead
+// of the estimate when that password could not be scored.
+type StrengthResult struct {
+	StrengthResponse
+	Error string `json:"error,omitempty"`
+}
+
+// StrengthBatchResponse holds one result per requested password, in request order.
+type StrengthBatchResponse struct {
+	Results []StrengthResult `json:"results"`
+}
@@ -1,6 +1,24 @@
 package model
 
-import "time"
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrEmailRequired    = errors.New("email is required")
+	ErrEmailInvalid     = errors.New("email is not a valid address")
+	ErrPasswordRequired = errors.New("password is required")
+	ErrPasswordBreached = errors.New("password appears in a known data breach")
+	ErrWeakPassword     = errors.New("password is too common")
+	// ErrAccountDeactivated is returned for a deactivated user's login or token;
+	// the auth middleware answers it with 403 rather than the generic 401.
+	ErrAccountDeactivated = errors.New("account is deactivated")
+)
+
+// maxEmailLength matches the users.email column.
+const maxEmailLength = 255
 
 // User represents a user in the database.
 type User struct {
@@ -9,18 +27,81 @@ type User struct {
 	AuthHash  string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	// DeactivatedAt is set while the account is deactivated; its data is kept until purged.
+	DeactivatedAt *time.Time
+	// TokenEpoch rejects tokens issued with a lower epoch; logging out everywhere bumps it.
+	TokenEpoch int64
+	// RekeyVersion counts master password changes; devices that synced under
+	// an older version must discard local state and pull fresh blobs.
+	RekeyVersion int64
+}
+
+// Active reports whether the user may sign in.
+func (u *User) Active() bool {
+	return u.DeactivatedAt == nil
 }
 
 // CreateUserRequest represents a user registration request.
+// InviteCode is only required while registration is invite-only.
 type CreateUserRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	InviteCode string `json:"invite_code,omitempty"`
+}
+
+// Validate reports every invalid field in the registration request.
+func (r CreateUserRequest) Validate() error {
+	var verr ValidationError
+
+	if r.Email == "" {
+		verr.Add("email", "required", ErrEmailRequired)
+	}
+	if r.Password == "" {
+		verr.Add("password", "required", ErrPasswordRequired)
+	}
+
+	return verr.Err()
+}
+
+// validEmail performs a light syntactic check: one "@" with text on both sides.
+func validEmail(email string) bool {
+	if len(email) > maxEmailLength || strings.ContainsAny(email, " \t\r\n") {
+		return false
+	}
+	local, domain, ok := strings.Cut(email, "@")
+	return ok && local != "" && domain != "" && !strings.Contains(domain, "@")
 }
 
 // LoginRequest represents a user login request.
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// Remember asks for a token with the longer JWT_EXPIRY_REMEMBER lifetime.
+	Remember bool `json:"remember"`
+}
+
+// CheckEmailRequest asks whether an email address is free to register.
+type CheckEmailRequest struct {
+	Email string `json:"email"`
+}
+
+// Validate reports whether the email is missing or malformed.
+func (r CheckEmailRequest) Validate() error {
+	var verr ValidationError
+
+	switch {
+	case r.Email == "":
+		verr.Add("email", "required", ErrEmailRequired)
+	case !validEmail(r.Email):
+		verr.Add("email", "invalid", ErrEmailInvalid)
+	}
+
+	return verr.Err()
+}
+
+// CheckEmailResponse reports whether an email address can be registered.
+type CheckEmailResponse struct {
+	Available bool `json:"available"`
 }
 
 // AuthResponse represents an authentication response with a JWT token and user info.
@@ -35,3 +116,54 @@ type UserResponse struct {
 	Email     string    `json:"email"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// UserCursor marks a position in the user list, ordered by creation time then ID.
+type UserCursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+// UserListFilter selects a page of users, oldest first. After is optional.
+type UserListFilter struct {
+	Limit int
+	After *UserCursor
+}
+
+// UserListQuery holds an admin's paging parameters for the user list.
+type UserListQuery struct {
+	Limit  int
+	Cursor string
+}
+
+// UserListResponse represents a page of user accounts.
+type UserListResponse struct {
+	Users      []UserResponse `json:"users"`
+	Limit      int            `json:"limit"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	HasMore    bool           `json:"has_more"`
+}
+
+// AccountExport is an admin export of one user's account: profile, vaults and
+// every stored entry, including deleted ones. Entries stay client-encrypted.
+type AccountExport struct {
+	ExportedAt time.Time            `json:"exported_at"`
+	User       AccountExportUser    `json:"user"`
+	KDFProfile *KDFProfile          `json:"kdf_profile,omitempty"`
+	Vaults     []AccountExportVault `json:"vaults"`
+}
+
+// AccountExportUser is the account metadata in an AccountExport. It never
+// includes the auth hash.
+type AccountExportUser struct {
+	ID            int64      `json:"id"`
+	Email         string     `json:"email"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+}
+
+// AccountExportVault is one vault in an AccountExport with its entries.
+type AccountExportVault struct {
+	VaultResponse
+	Entries []VaultEntryResponse `json:"entries"`
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrValidation matches any *ValidationError via errors.Is.
+var ErrValidation = errors.New("validation failed")
+
+// FieldError describes one invalid request field.
+type FieldError struct {
+	Field  string // JSON field path, e.g. "email"
+	Reason string // short machine-friendly reason, e.g. "required"
+	Err    error  // sentinel for callers that match on a single error
+}
+
+// ValidationError lists every invalid field in a request.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Add records an invalid field.
+func (e *ValidationError) Add(field, reason string, err error) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Reason: reason, Err: err})
+}
+
+// Err returns e if any field was recorded, or nil.
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Is reports whether target is ErrValidation.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// Unwrap exposes each field's sentinel so errors.Is keeps matching them.
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Fields))
+	for i, f := range e.Fields {
+		errs[i] = f.Err
+	}
+	return errs
+}
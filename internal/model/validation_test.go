@@ -0,0 +1,89 @@
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCreateUserRequest_ValidateReportsAllFields(t *testing.T) {
+	err := CreateUserRequest{}.Validate()
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	got := map[string]string{}
+	for _, f := range verr.Fields {
+		got[f.Field] = f.Reason
+	}
+	if len(got) != 2 || got["email"] != "required" || got["password"] != "required" {
+		t.Errorf("expected email and password errors together, got %v", got)
+	}
+
+	// Each field's sentinel and the umbrella error stay matchable.
+	for _, target := range []error{ErrValidation, ErrEmailRequired, ErrPasswordRequired} {
+		if !errors.Is(err, target) {
+			t.Errorf("expected errors.Is(err, %v)", target)
+		}
+	}
+	if err.Error() != "email is required; password is required" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+}
+
+func TestCreateUserRequest_ValidateOneField(t *testing.T) {
+	err := CreateUserRequest{Email: "a@example.com"}.Validate()
+
+	if !errors.Is(err, ErrPasswordRequired) || errors.Is(err, ErrEmailRequired) {
+		t.Errorf("expected only the password error, got %v", err)
+	}
+}
+
+func TestCreateUserRequest_ValidateOK(t *testing.T) {
+	// Registration only requires the fields; their format is up to the client.
+	if err := (CreateUserRequest{Email: "a@example.com", Password: "short"}).Validate(); err != nil {
+		t.Errorf("expected valid request, got %v", err)
+	}
+}
+
+func TestValidEmail(t *testing.T) {
+	tests := map[string]bool{
+		"a@example.com":   true,
+		"@example.com":    false,
+		"a@":              false,
+		"a@b@example.com": false,
+		"a b@example.com": false,
+		"plainaddress":    false,
+	}
+	for email, want := range tests {
+		if got := validEmail(email); got != want {
+			t.Errorf("validEmail(%q) = %v, want %v", email, got, want)
+		}
+	}
+}
+
+func TestKDFProfile_Validate(t *testing.T) {
+	valid := KDFProfile{KDF: "pbkdf2-sha256", Iterations: 600000, Salt: "c2FsdHNhbHRzYWx0c2FsdA=="}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected valid profile, got %v", err)
+	}
+
+	err := KDFProfile{KDF: "PBKDF2", Iterations: -1, MemoryKiB: -1, Parallelism: 1000, Salt: "!!"}.Validate()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	want := []string{"kdf", "iterations", "memory_kib", "parallelism", "salt"}
+	if len(verr.Fields) != len(want) {
+		t.Fatalf("expected %d field errors, got %+v", len(want), verr.Fields)
+	}
+	for i, f := range verr.Fields {
+		if f.Field != want[i] {
+			t.Errorf("field %d: expected %q, got %q", i, want[i], f.Field)
+		}
+	}
+
+	if err := (KDFProfile{}).Validate(); !errors.Is(err, ErrKDFRequired) || !errors.Is(err, ErrKDFSalt) {
+		t.Errorf("expected the zero profile to require kdf and salt, got %v", err)
+	}
+}
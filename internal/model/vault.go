@@ -2,16 +2,84 @@ package model
 
 import "time"
 
+// DefaultVaultName names the vault created for every user on first use.
+const DefaultVaultName = "Personal"
+
+// Vault is a named collection of entries. Every user has exactly one default
+// vault, which the unscoped /api/v1/vault routes operate on.
+type Vault struct {
+	ID        int64
+	UserID    int64
+	Name      string
+	IsDefault bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// VaultRequest creates or renames a vault.
+type VaultRequest struct {
+	Name string `json:"name"`
+}
+
+// VaultResponse represents a vault in API responses.
+type VaultResponse struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Default   bool      `json:"default"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 // VaultEntry represents an encrypted vault entry in the database.
 type VaultEntry struct {
 	ID            int64
 	UserID        int64
+	VaultID       int64
 	EntryID       string
 	EncryptedData []byte
 	Version       int
+	Favorite      bool
+	LastDeviceID  string
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 	Deleted       bool
+	DeleteReason  string     // one of the DeleteReason constants, or empty
+	ExpiresAt     *time.Time // nil means the entry never expires
+
+	// LastAccessedAt is when the entry was last fetched on its own or by
+	// batch-get; nil if never. Recording it does not change UpdatedAt.
+	LastAccessedAt *time.Time
+}
+
+// Expired reports whether the entry's expiry has passed at now.
+func (e *VaultEntry) Expired(now time.Time) bool {
+	return e.ExpiresAt != nil && !e.ExpiresAt.After(now)
+}
+
+// ChangedAt is when the entry last changed as sync sees it at now: when it
+// was written, or when it expired if that came later.
+func (e *VaultEntry) ChangedAt(now time.Time) time.Time {
+	if e.Expired(now) && e.ExpiresAt.After(e.UpdatedAt) {
+		return *e.ExpiresAt
+	}
+	return e.UpdatedAt
+}
+
+// ChangeCursor marks a position in a vault's changes, ordered by change time
+// then entry ID.
+type ChangeCursor struct {
+	ChangedAt time.Time
+	EntryID   string
+}
+
+// ChangeFilter selects a page of a vault's changes, oldest first: entries
+// changed after Since and, when After is set, ordered after it. Expiry is
+// judged at Now. At most Limit entries are returned; zero means no limit.
+type ChangeFilter struct {
+	Since time.Time
+	After *ChangeCursor
+	Now   time.Time
+	Limit int
 }
 
 // VaultEntryRequest represents a single vault entry in a sync upload.
@@ -19,27 +87,209 @@ type VaultEntryRequest struct {
 	EntryID       string `json:"entry_id"`
 	EncryptedData string `json:"encrypted_data"` // base64 encoded
 	Version       int    `json:"version"`
+	Favorite      bool   `json:"favorite"`
 	Deleted       bool   `json:"deleted"`
+	DeleteReason  string `json:"delete_reason,omitempty"` // only kept when deleted is true
+	DeviceID      string `json:"device_id,omitempty"`     // non-secret identifier of the writing device
+
+	// ExpiresAt is non-secret metadata; once it passes the entry is treated as deleted.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// ExpectedVersion makes an update conditional on the stored version (optimistic concurrency).
+	ExpectedVersion *int `json:"expected_version,omitempty"`
 }
 
 // VaultEntryResponse represents a single vault entry in a sync download.
 type VaultEntryResponse struct {
-	EntryID       string    `json:"entry_id"`
-	EncryptedData string    `json:"encrypted_data"` // base64 encoded
-	Version       int       `json:"version"`
-	UpdatedAt     time.Time `json:"updated_at"`
-	Deleted       bool      `json:"deleted"`
+	EntryID       string     `json:"entry_id"`
+	EncryptedData string     `json:"encrypted_data"` // base64 encoded
+	Version       int        `json:"version"`
+	Favorite      bool       `json:"favorite"`
+	LastDeviceID  string     `json:"last_device_id,omitempty"`
+	CreatedAt     *time.Time `json:"created_at,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Deleted       bool       `json:"deleted"`
+	DeleteReason  string     `json:"delete_reason,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+}
+
+// Delete reasons accepted as delete_reason. They are non-secret codes that let
+// clients show why an entry was removed.
+const (
+	// DeleteReasonUser marks an entry the user deleted.
+	DeleteReasonUser = "user"
+	// DeleteReasonSuperseded marks an entry replaced by a newer one.
+	DeleteReasonSuperseded = "superseded"
+	// DeleteReasonDuplicate marks an entry removed as a duplicate of another.
+	DeleteReasonDuplicate = "duplicate"
+)
+
+// Conflict strategies accepted in SyncRequest.ConflictStrategy.
+const (
+	// ConflictLWW keeps the higher version and silently discards the other write.
+	ConflictLWW = "lww"
+	// ConflictManual stages discarded writes that differ from the stored entry
+	// so the client can resolve them.
+	ConflictManual = "manual"
+)
+
+// VaultConflict is an incoming write that lost to the stored entry and was staged
+// for client resolution.
+type VaultConflict struct {
+	ID            int64
+	UserID        int64
+	VaultID       int64
+	EntryID       string
+	EncryptedData []byte
+	Version       int
+	Favorite      bool
+	Deleted       bool
+	DeviceID      string
+	CreatedAt     time.Time
+}
+
+// ManifestEntry is the metadata of one entry, without its encrypted data.
+// Clients diff a manifest against local state and batch-get what changed.
+type ManifestEntry struct {
+	EntryID   string    `json:"entry_id"`
+	Version   int       `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Deleted   bool      `json:"deleted"` // soft-deleted or expired
+}
+
+// WipeConfirmation is the phrase a WipeRequest must carry to delete every entry.
+const WipeConfirmation = "DELETE ALL ENTRIES"
+
+// WipeRequest asks to delete every entry in all of the user's vaults.
+type WipeRequest struct {
+	Confirm string `json:"confirm"` // must equal WipeConfirmation
+}
+
+// WipeResponse reports how many entries a wipe deleted.
+type WipeResponse struct {
+	Wiped int64 `json:"wiped"`
+}
+
+// DuplicateStats counts live entries whose encrypted blob is byte-identical to
+// another entry of the same user. Duplicates are never matched across users.
+type DuplicateStats struct {
+	Groups    int64 `json:"duplicate_groups"`  // distinct (user, blob) pairs stored more than once
+	Entries   int64 `json:"duplicate_entries"` // entries in those groups
+	Redundant int64 `json:"redundant_entries"` // entries beyond the first in each group
+	Users     int64 `json:"affected_users"`
+	Unhashed  int64 `json:"unhashed_entries"` // written before hashes were recorded; not counted
+}
+
+// VaultUsage counts a user's live entries across all vaults and the bytes
+// their blobs take up in storage.
+type VaultUsage struct {
+	Entries int64
+	Bytes   int64
+}
+
+// VaultQuota reports a user's storage usage against the configured limits.
+// A zero maximum means no limit.
+type VaultQuota struct {
+	Entries         int64 `json:"entries"`
+	Bytes           int64 `json:"bytes"` // as stored, after any server-side compression
+	MaxEntries      int   `json:"max_entries"`
+	MaxEntryBytes   int   `json:"max_entry_bytes"`   // decoded encrypted_data of a single entry
+	MaxRequestBytes int64 `json:"max_request_bytes"` // body of a single vault write request
+}
+
+// BatchGetRequest lists the entry IDs to fetch in one call.
+type BatchGetRequest struct {
+	EntryIDs []string `json:"entry_ids"`
+}
+
+// VaultExport is a point-in-time backup of a user's live vault entries.
+type VaultExport struct {
+	ExportedAt time.Time            `json:"exported_at"`
+	Entries    []VaultEntryResponse `json:"entries"`
+}
+
+// ImportEntry is one entry in a VaultImport. The export format decodes as one,
+// so a backup can be imported as is.
+type ImportEntry struct {
+	EntryID       string     `json:"entry_id"`       // optional; the server assigns a UUID if empty
+	EncryptedData string     `json:"encrypted_data"` // base64 encoded
+	Favorite      bool       `json:"favorite"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	CreatedAt     *time.Time `json:"created_at,omitempty"` // original creation time; defaults to now
+}
+
+// VaultImport adds entries from a backup or another product to a vault.
+type VaultImport struct {
+	Entries []ImportEntry `json:"entries"`
+}
+
+// ImportResponse reports the outcome of an import. Entries whose ID already
+// exists in the vault are left untouched and listed in Skipped.
+type ImportResponse struct {
+	Imported int      `json:"imported"`
+	Skipped  []string `json:"skipped"`
 }
 
 // SyncRequest represents a client sync request with optional last sync timestamp.
+// ConflictStrategy precedes Entries so encoded requests name it before the
+// server starts applying entries.
 type SyncRequest struct {
-	LastSyncedAt *time.Time          `json:"last_synced_at"`
-	Entries      []VaultEntryRequest `json:"entries"`
+	LastSyncedAt     *time.Time          `json:"last_synced_at"`
+	ConflictStrategy string              `json:"conflict_strategy,omitempty"` // "lww" (default) or "manual"
+	Entries          []VaultEntryRequest `json:"entries"`
+
+	// AckCursor confirms the client has applied every change up to this point,
+	// normally the synced_at of a previous response. Acks are kept per DeviceID;
+	// tombstones covered by every device's ack are purged.
+	AckCursor *time.Time `json:"ack_cursor,omitempty"`
+	DeviceID  string     `json:"device_id,omitempty"`
+
+	// Limit bounds how many changed entries the response carries, up to the
+	// server's maximum page size; zero means every change. Cursor is a previous response's next_cursor. To page
+	// through a large delta, keep last_synced_at fixed and repeat with each
+	// next_cursor until has_more is false.
+	Limit  int    `json:"limit,omitempty"`
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// VaultConflictResponse pairs a staged write with the entry it conflicts with.
+type VaultConflictResponse struct {
+	ID       int64               `json:"id"`
+	EntryID  string              `json:"entry_id"`
+	Incoming VaultEntryResponse  `json:"incoming"`
+	Existing *VaultEntryResponse `json:"existing"`
+}
+
+// SkipWriteFailed is the SkippedEntry reason for an entry that was valid but
+// could not be stored. Other reasons are the error code the same problem gets
+// outside sync, such as "entry_too_large" or "invalid_device_id".
+const SkipWriteFailed = "write_failed"
+
+// SkippedEntry names an uploaded sync entry that was not applied, and why.
+type SkippedEntry struct {
+	EntryID string `json:"entry_id"`
+	Reason  string `json:"reason"`
 }
 
 // SyncResponse represents a server sync response with changed entries.
 type SyncResponse struct {
-	SyncedAt time.Time            `json:"synced_at"`
-	Entries  []VaultEntryResponse `json:"entries"`
-	Skipped  int                  `json:"skipped,omitempty"`
+	SyncedAt  time.Time               `json:"synced_at"`
+	Entries   []VaultEntryResponse    `json:"entries"`
+	Skipped   []SkippedEntry          `json:"skipped,omitempty"`
+	Conflicts []VaultConflictResponse `json:"conflicts,omitempty"`
+
+	// HasMore is set when Limit cut the delta short; NextCursor fetches the rest.
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+
+	// RekeyVersion is the user's current rekey version. A device that last
+	// synced under a lower one should discard local state and pull fresh blobs.
+	RekeyVersion int64 `json:"rekey_version"`
+}
+
+// RekeyResponse reports the user's rekey version after a master password change.
+type RekeyResponse struct {
+	RekeyVersion int64 `json:"rekey_version"`
 }
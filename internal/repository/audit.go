@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/vaultpass/vaultpass-go/internal/model"
+)
+
+// AuditRepository handles login audit log persistence operations.
+type AuditRepository struct {
+	db *sql.DB
+}
+
+// NewAuditRepository creates a new AuditRepository.
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// RecordLogin inserts a login event and sets the generated ID on the event struct.
+func (r *AuditRepository) RecordLogin(ctx context.Context, event *model.LoginEvent) error {
+	query := `INSERT INTO login_events (user_id, email, success, ip_address, user_agent) VALUES (?, ?, ?, ?, ?)`
+
+	var userID sql.NullInt64
+	if event.UserID != nil {
+		userID = sql.NullInt64{Int64: *event.UserID, Valid: true}
+	}
+
+	result, err := r.db.ExecContext(ctx, query,
+		userID, event.Email, event.Success, event.IPAddress, event.UserAgent,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	event.ID = id
+	return nil
+}
+
+// ListLoginsByUser retrieves a page of login events for a user, most recent first.
+// Events for emails that do not belong to the user are never returned.
+func (r *AuditRepository) ListLoginsByUser(ctx context.Context, userID int64, filter model.LoginEventFilter) ([]model.LoginEvent, error) {
+	query := `SELECT id, user_id, email, success, ip_address, user_agent, created_at
+		FROM login_events WHERE user_id = ?`
+	args := []any{userID}
+
+	if filter.Success != nil {
+		query += ` AND success = ?`
+		args = append(args, *filter.Success)
+	}
+	if c := filter.Before; c != nil {
+		query += ` AND (created_at < ? OR (created_at = ? AND id < ?))`
+		args = append(args, c.CreatedAt, c.CreatedAt, c.ID)
+	}
+	query += ` ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []model.LoginEvent
+	for rows.Next() {
+		var e model.LoginEvent
+		var uid sql.NullInt64
+		if err := rows.Scan(
+			&e.ID, &uid, &e.Email, &e.Success, &e.IPAddress, &e.UserAgent, &e.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if uid.Valid {
+			e.UserID = &uid.Int64
+		}
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
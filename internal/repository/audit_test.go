@@ -0,0 +1,13 @@
+package repository
+
+import "testing"
+
+func TestNewAuditRepository(t *testing.T) {
+	repo := NewAuditRepository(nil)
+	if repo == nil {
+		t.Fatal("expected non-nil AuditRepository")
+	}
+	if repo.db != nil {
+		t.Fatal("expected nil db when constructed with nil")
+	}
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrStorageKeyRequired = errors.New("row is server-encrypted but no storage key is configured")
+
+// storedBlob is encrypted_data as persisted, together with the flags that
+// describe how it was encoded.
+type storedBlob struct {
+	data       []byte
+	compressed bool
+	encrypted  bool
+	nonce      []byte
+}
+
+// blobCodec transforms encrypted_data between its API form and its stored form.
+// Rows record how they were stored, so rows written before an option was
+// enabled remain readable after it is turned on.
+type blobCodec struct {
+	compress bool
+	aead     cipher.AEAD
+}
+
+// NewBlobCipher returns an AES-GCM cipher for server-side envelope encryption.
+// The key must be 16, 24, or 32 bytes.
+func NewBlobCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// blobAAD binds a sealed blob to its row so it cannot be swapped into another.
+func blobAAD(userID int64, entryID string) []byte {
+	return fmt.Appendf(nil, "%d:%s", userID, entryID)
+}
+
+// contentHash fingerprints a client blob for duplicate detection. The user ID
+// salts the hash, so identical blobs of different users never share a hash
+// and stats can't correlate data across accounts.
+func contentHash(userID int64, data []byte) string {
+	h := sha256.New()
+	binary.Write(h, binary.BigEndian, userID)
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// encode compresses and then seals data as configured. Compression is only
+// kept when it actually shrinks the blob.
+func (c blobCodec) encode(data, aad []byte) (storedBlob, error) {
+	blob := storedBlob{data: data}
+
+	if c.compress {
+		packed, err := gzipBytes(data)
+		if err != nil {
+			return storedBlob{}, err
+		}
+		if len(packed) < len(data) {
+			blob.data = packed
+			blob.compressed = true
+		}
+	}
+
+	if c.aead != nil {
+		nonce := make([]byte, c.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return storedBlob{}, err
+		}
+		blob.data = c.aead.Seal(nil, nonce, blob.data, aad)
+		blob.encrypted = true
+		blob.nonce = nonce
+	}
+
+	return blob, nil
+}
+
+// decode reverses encode using the flags stored alongside the row.
+func (c blobCodec) decode(blob storedBlob, aad []byte) ([]byte, error) {
+	data := blob.data
+
+	if blob.encrypted {
+		if c.aead == nil {
+			return nil, ErrStorageKeyRequired
+		}
+		opened, err := c.aead.Open(nil, blob.nonce, data, aad)
+		if err != nil {
+			return nil, err
+		}
+		data = opened
+	}
+
+	if !blob.compressed {
+		return data, nil
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
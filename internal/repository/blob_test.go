@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+var testAAD = blobAAD(1, "entry-1")
+
+func testCipherCodec(t *testing.T) blobCodec {
+	t.Helper()
+	aead, err := NewBlobCipher(bytes.Repeat([]byte{0x42}, 32))
+	if err != nil {
+		t.Fatalf("NewBlobCipher() unexpected error: %v", err)
+	}
+	return blobCodec{aead: aead}
+}
+
+func TestBlobCodec_CompressedRoundTrip(t *testing.T) {
+	codec := blobCodec{compress: true}
+	data := bytes.Repeat([]byte(`{"v":1,"iv":"AAAA","ct":"BBBB"}`), 100)
+
+	blob, err := codec.encode(data, testAAD)
+	if err != nil {
+		t.Fatalf("encode() unexpected error: %v", err)
+	}
+	if !blob.compressed {
+		t.Fatal("expected compressible blob to be stored compressed")
+	}
+	if len(blob.data) >= len(data) {
+		t.Errorf("expected stored blob to shrink, %d >= %d", len(blob.data), len(data))
+	}
+
+	got, err := codec.decode(blob, testAAD)
+	if err != nil {
+		t.Fatalf("decode() unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("round trip did not return the original blob")
+	}
+}
+
+func TestBlobCodec_IncompressibleStoredAsIs(t *testing.T) {
+	codec := blobCodec{compress: true}
+	data := make([]byte, 512)
+	rand.Read(data)
+
+	blob, err := codec.encode(data, testAAD)
+	if err != nil {
+		t.Fatalf("encode() unexpected error: %v", err)
+	}
+	if blob.compressed || !bytes.Equal(blob.data, data) {
+		t.Error("expected incompressible blob to be stored uncompressed")
+	}
+}
+
+func TestBlobCodec_LegacyUncompressedRows(t *testing.T) {
+	// Rows written before compression was enabled have compressed = false.
+	codec := blobCodec{compress: true}
+	legacy := []byte("legacy-encrypted-blob")
+
+	got, err := codec.decode(storedBlob{data: legacy}, testAAD)
+	if err != nil {
+		t.Fatalf("decode() unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, legacy) {
+		t.Errorf("expected legacy row unchanged, got %q", got)
+	}
+}
+
+func TestBlobCodec_Disabled(t *testing.T) {
+	codec := blobCodec{}
+	data := bytes.Repeat([]byte("a"), 1000)
+
+	blob, _ := codec.encode(data, testAAD)
+	if blob.compressed || blob.encrypted || !bytes.Equal(blob.data, data) {
+		t.Error("expected blob stored as-is when no options are enabled")
+	}
+}
+
+func TestBlobCodec_EncryptOnWriteDecryptOnRead(t *testing.T) {
+	codec := testCipherCodec(t)
+	data := []byte("client-encrypted-blob")
+
+	blob, err := codec.encode(data, testAAD)
+	if err != nil {
+		t.Fatalf("encode() unexpected error: %v", err)
+	}
+	if !blob.encrypted || len(blob.nonce) != 12 {
+		t.Fatalf("expected sealed blob with 12-byte nonce, got encrypted=%v nonce=%d", blob.encrypted, len(blob.nonce))
+	}
+	if bytes.Contains(blob.data, data) {
+		t.Error("stored blob must not contain the original bytes")
+	}
+
+	got, err := codec.decode(blob, testAAD)
+	if err != nil {
+		t.Fatalf("decode() unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("expected %q, got %q", data, got)
+	}
+}
+
+func TestBlobCodec_UniqueNoncePerWrite(t *testing.T) {
+	codec := testCipherCodec(t)
+
+	a, _ := codec.encode([]byte("same"), testAAD)
+	b, _ := codec.encode([]byte("same"), testAAD)
+	if bytes.Equal(a.nonce, b.nonce) {
+		t.Error("expected a fresh nonce for each write")
+	}
+}
+
+func TestBlobCodec_EncryptedRowBoundToEntry(t *testing.T) {
+	codec := testCipherCodec(t)
+
+	blob, _ := codec.encode([]byte("secret"), testAAD)
+	if _, err := codec.decode(blob, blobAAD(2, "entry-1")); err == nil {
+		t.Error("expected decrypt to fail when the blob is moved to another row")
+	}
+}
+
+func TestBlobCodec_LegacyRowsWithKeyConfigured(t *testing.T) {
+	codec := testCipherCodec(t)
+	legacy := []byte("legacy-plain-row")
+
+	got, err := codec.decode(storedBlob{data: legacy}, testAAD)
+	if err != nil {
+		t.Fatalf("decode() unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, legacy) {
+		t.Errorf("expected legacy row unchanged, got %q", got)
+	}
+}
+
+func TestBlobCodec_CompressThenEncrypt(t *testing.T) {
+	codec := testCipherCodec(t)
+	codec.compress = true
+	data := bytes.Repeat([]byte("compressible "), 200)
+
+	blob, err := codec.encode(data, testAAD)
+	if err != nil {
+		t.Fatalf("encode() unexpected error: %v", err)
+	}
+	if !blob.compressed || !blob.encrypted {
+		t.Fatalf("expected compressed and encrypted, got %+v", blob)
+	}
+
+	got, err := codec.decode(blob, testAAD)
+	if err != nil {
+		t.Fatalf("decode() unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("round trip did not return the original blob")
+	}
+}
+
+func TestBlobCodec_EncryptedRowWithoutKey(t *testing.T) {
+	sealed, _ := testCipherCodec(t).encode([]byte("secret"), testAAD)
+
+	_, err := blobCodec{}.decode(sealed, testAAD)
+	if !errors.Is(err, ErrStorageKeyRequired) {
+		t.Errorf("expected ErrStorageKeyRequired, got %v", err)
+	}
+}
+
+func TestNewBlobCipher_RejectsBadKey(t *testing.T) {
+	if _, err := NewBlobCipher([]byte("short")); err == nil {
+		t.Error("expected error for invalid key length")
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	blob := []byte("ciphertext")
+
+	if contentHash(1, blob) != contentHash(1, []byte("ciphertext")) {
+		t.Error("identical blobs of one user must share a hash")
+	}
+	if contentHash(1, blob) == contentHash(1, []byte("ciphertexT")) {
+		t.Error("distinct blobs must not share a hash")
+	}
+	if contentHash(1, blob) == contentHash(2, blob) {
+		t.Error("identical blobs of different users must not share a hash")
+	}
+	if got := len(contentHash(1, blob)); got != 64 {
+		t.Errorf("expected 64 hex characters to fit content_hash, got %d", got)
+	}
+}
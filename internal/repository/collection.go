@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/vaultpass/vaultpass-go/internal/model"
+)
+
+var ErrVaultNotFound = errors.New("vault not found")
+
+// CollectionRepository handles vault (entry collection) persistence operations.
+type CollectionRepository struct {
+	db *sql.DB
+}
+
+// NewCollectionRepository creates a new CollectionRepository.
+func NewCollectionRepository(db *sql.DB) *CollectionRepository {
+	return &CollectionRepository{db: db}
+}
+
+// collectionColumns lists the columns read by scanVault, in scan order.
+const collectionColumns = `id, user_id, name, is_default, created_at, updated_at`
+
+// scanVault scans a single vault row.
+func scanVault(row rowScanner) (*model.Vault, error) {
+	v := &model.Vault{}
+	var isDefault sql.NullBool
+	if err := row.Scan(&v.ID, &v.UserID, &v.Name, &isDefault, &v.CreatedAt, &v.UpdatedAt); err != nil {
+		return nil, err
+	}
+	v.IsDefault = isDefault.Valid && isDefault.Bool
+	return v, nil
+}
+
+// Create inserts a new, non-default vault and fills in its ID and timestamps.
+func (r *CollectionRepository) Create(ctx context.Context, vault *model.Vault) error {
+	result, err := r.db.ExecContext(ctx, `INSERT INTO vaults (user_id, name) VALUES (?, ?)`, vault.UserID, vault.Name)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	created, err := r.GetByID(ctx, vault.UserID, id)
+	if err != nil {
+		return err
+	}
+	*vault = *created
+	return nil
+}
+
+// EnsureDefault returns the user's default vault, creating it on first use.
+func (r *CollectionRepository) EnsureDefault(ctx context.Context, userID int64) (*model.Vault, error) {
+	// The unique (user_id, is_default) index makes concurrent first calls converge on one row.
+	insert := `INSERT INTO vaults (user_id, name, is_default) VALUES (?, ?, TRUE)
+		ON DUPLICATE KEY UPDATE id = id`
+	if _, err := r.db.ExecContext(ctx, insert, userID, model.DefaultVaultName); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + collectionColumns + ` FROM vaults WHERE user_id = ? AND is_default = TRUE`
+	return scanVault(r.db.QueryRowContext(ctx, query, userID))
+}
+
+// GetByID retrieves one of the user's vaults.
+func (r *CollectionRepository) GetByID(ctx context.Context, userID, id int64) (*model.Vault, error) {
+	query := `SELECT ` + collectionColumns + ` FROM vaults WHERE user_id = ? AND id = ?`
+
+	vault, err := scanVault(r.db.QueryRowContext(ctx, query, userID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrVaultNotFound
+		}
+		return nil, err
+	}
+	return vault, nil
+}
+
+// ListByUser retrieves the user's vaults, default first and then in creation order.
+func (r *CollectionRepository) ListByUser(ctx context.Context, userID int64) ([]model.Vault, error) {
+	query := `SELECT ` + collectionColumns + ` FROM vaults WHERE user_id = ?
+		ORDER BY is_default IS NULL, id ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var vaults []model.Vault
+	for rows.Next() {
+		v, err := scanVault(rows)
+		if err != nil {
+			return nil, err
+		}
+		vaults = append(vaults, *v)
+	}
+	return vaults, rows.Err()
+}
+
+// Rename changes the name of one of the user's vaults.
+func (r *CollectionRepository) Rename(ctx context.Context, userID, id int64, name string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE vaults SET name = ? WHERE user_id = ? AND id = ?`, name, userID, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		// MySQL reports zero rows when the value is unchanged, so confirm the vault exists.
+		_, err := r.GetByID(ctx, userID, id)
+		return err
+	}
+	return nil
+}
+
+// Delete removes one of the user's non-default vaults. Its entries and staged
+// conflicts are removed with it by the vault_id foreign keys.
+func (r *CollectionRepository) Delete(ctx context.Context, userID, id int64) error {
+	query := `DELETE FROM vaults WHERE user_id = ? AND id = ? AND is_default IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, userID, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrVaultNotFound
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks a position in a keyset-paginated list ordered by timestamp then
+// ID. The ID breaks ties between rows created in the same instant, so every
+// row has exactly one position and pages never skip or repeat rows. Lists
+// whose rows are identified by a string, such as vault entries by entry ID,
+// set Key instead of ID.
+type Cursor struct {
+	At  time.Time
+	ID  int64
+	Key string
+}
+
+// Encode renders c as an opaque URL-safe token for clients to send back.
+func (c Cursor) Encode() string {
+	raw := strconv.FormatInt(c.At.UnixNano(), 10) + ":" + strconv.FormatInt(c.ID, 10)
+	if c.Key != "" {
+		raw += ":" + c.Key
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Compare orders the row at (at, id) relative to c, returning -1 if it sorts
+// before c, 0 if it is c, and +1 if it sorts after.
+func (c Cursor) Compare(at time.Time, id int64) int {
+	if cmp := at.Compare(c.At); cmp != 0 {
+		return cmp
+	}
+	switch {
+	case id < c.ID:
+		return -1
+	case id > c.ID:
+		return 1
+	}
+	return 0
+}
+
+// CompareKey is Compare for cursors that set Key.
+func (c Cursor) CompareKey(at time.Time, key string) int {
+	if cmp := at.Compare(c.At); cmp != 0 {
+		return cmp
+	}
+	return strings.Compare(key, c.Key)
+}
+
+// DecodeCursor parses a token produced by Encode. Anything else, including a
+// well-formed token with a non-positive timestamp, or with neither a positive
+// ID nor a key, is rejected with ErrInvalidCursor.
+func DecodeCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	// A key cursor carries a zero ID followed by the key, which may itself
+	// contain colons.
+	parts := strings.SplitN(string(raw), ":", 3)
+	if len(parts) < 2 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	n, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || n <= 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	c := Cursor{At: time.Unix(0, n).UTC()}
+	if len(parts) == 3 {
+		if parts[1] != "0" || parts[2] == "" {
+			return Cursor{}, ErrInvalidCursor
+		}
+		c.Key = parts[2]
+		return c, nil
+	}
+	if c.ID, err = strconv.ParseInt(parts[1], 10, 64); err != nil || c.ID <= 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// Paginate trims rows fetched with a limit of limit+1 down to one page. When
+// the extra row was present it reports hasMore and returns the cursor of the
+// page's last row, which the next request resumes from.
+func Paginate[T any](rows []T, limit int, key func(T) Cursor) (page []T, next string, hasMore bool) {
+	if len(rows) <= limit {
+		return rows, "", false
+	}
+	page = rows[:limit]
+	if limit > 0 {
+		next = key(page[limit-1]).Encode()
+	}
+	return page, next, true
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	for _, c := range []Cursor{
+		{At: time.Date(2026, 2, 23, 12, 0, 0, 0, time.UTC), ID: 12},
+		{At: time.Date(2025, 11, 2, 9, 30, 0, 123456789, time.UTC), ID: 1},
+		{At: time.Unix(0, 1).UTC(), ID: 1<<63 - 1},
+		{At: time.Date(2026, 2, 23, 12, 0, 0, 0, time.UTC), Key: "entry-1"},
+		{At: time.Date(2026, 2, 23, 12, 0, 0, 0, time.UTC), Key: "a:b:c"},
+	} {
+		got, err := DecodeCursor(c.Encode())
+		if err != nil {
+			t.Fatalf("DecodeCursor(%v): %v", c, err)
+		}
+		if !got.At.Equal(c.At) || got.ID != c.ID || got.Key != c.Key {
+			t.Errorf("round trip = %+v, want %+v", got, c)
+		}
+	}
+}
+
+func TestDecodeCursor_RejectsTampered(t *testing.T) {
+	enc := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	valid := Cursor{At: time.Date(2026, 2, 23, 12, 0, 0, 0, time.UTC), ID: 12}.Encode()
+
+	for name, cursor := range map[string]string{
+		"empty":          "",
+		"not base64":     "!!!",
+		"padded base64":  valid + "==",
+		"truncated":      valid[:len(valid)-3],
+		"no separator":   enc("no-colon"),
+		"bad timestamp":  enc("abc:12"),
+		"bad id":         enc("1771848000000000000:x"),
+		"zero timestamp": enc("0:12"),
+		"negative id":    enc("1771848000000000000:-5"),
+		"zero id":        enc("1771848000000000000:0"),
+		"extra field":    enc("1771848000000000000:12:7"),
+		"empty key":      enc("1771848000000000000:0:"),
+	} {
+		if _, err := DecodeCursor(cursor); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: expected ErrInvalidCursor, got %v", name, err)
+		}
+	}
+}
+
+func TestCursor_Compare(t *testing.T) {
+	at := time.Date(2026, 2, 23, 12, 0, 0, 0, time.UTC)
+	c := Cursor{At: at, ID: 5}
+
+	cases := []struct {
+		at   time.Time
+		id   int64
+		want int
+	}{
+		{at.Add(-time.Second), 9, -1},
+		{at, 4, -1},
+		{at, 5, 0},
+		{at, 6, 1},
+		{at.Add(time.Second), 1, 1},
+	}
+	for _, tc := range cases {
+		if got := c.Compare(tc.at, tc.id); got != tc.want {
+			t.Errorf("Compare(%v, %d) = %d, want %d", tc.at, tc.id, got, tc.want)
+		}
+	}
+}
+
+func TestCursor_CompareKey(t *testing.T) {
+	at := time.Date(2026, 2, 23, 12, 0, 0, 0, time.UTC)
+	c := Cursor{At: at, Key: "m"}
+
+	cases := []struct {
+		at   time.Time
+		key  string
+		want int
+	}{
+		{at.Add(-time.Second), "z", -1},
+		{at, "a", -1},
+		{at, "m", 0},
+		{at, "n", 1},
+		{at.Add(time.Second), "a", 1},
+	}
+	for _, tc := range cases {
+		if got := c.CompareKey(tc.at, tc.key); got != tc.want {
+			t.Errorf("CompareKey(%v, %q) = %d, want %d", tc.at, tc.key, got, tc.want)
+		}
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	at := time.Date(2026, 2, 23, 12, 0, 0, 0, time.UTC)
+	key := func(id int64) Cursor { return Cursor{At: at, ID: id} }
+
+	page, next, hasMore := Paginate([]int64{1, 2, 3}, 2, key)
+	if len(page) != 2 || !hasMore {
+		t.Fatalf("Paginate = %v, hasMore %v; want 2 rows and more", page, hasMore)
+	}
+	c, err := DecodeCursor(next)
+	if err != nil || c.ID != 2 {
+		t.Errorf("next cursor = %+v, %v; want the last row's position", c, err)
+	}
+
+	page, next, hasMore = Paginate([]int64{1, 2}, 2, key)
+	if len(page) != 2 || hasMore || next != "" {
+		t.Errorf("final page = %v, %q, %v; want both rows and no cursor", page, next, hasMore)
+	}
+}
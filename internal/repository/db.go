@@ -1,15 +1,69 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"log/slog"
+	"net"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
+// maxPingInterval caps the backoff between startup pings.
+const maxPingInterval = 30 * time.Second
+
+// transientMySQLErrors are server error numbers that clear on retry: too many
+// connections, lock wait timeout, deadlock, and the server going away mid-query.
+var transientMySQLErrors = map[uint16]bool{1040: true, 1205: true, 1213: true, 1053: true, 1927: true}
+
+// IsTransient reports whether err is a storage failure expected to clear on
+// its own, such as a dropped connection, a timeout or a deadlock, rather than
+// a bug or a bad request. Retrying the whole operation later may succeed.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, mysql.ErrInvalidConn) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return transientMySQLErrors[mysqlErr.Number]
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// dbConfig holds the settings DBOptions adjust.
+type dbConfig struct {
+	pingAttempts int
+	pingInterval time.Duration
+}
+
+// DBOption configures NewDB.
+type DBOption func(*dbConfig)
+
+// WithPingRetry makes NewDB ping up to attempts times, waiting interval before
+// the first retry and doubling the wait after each failure (up to 30s).
+func WithPingRetry(attempts int, interval time.Duration) DBOption {
+	return func(c *dbConfig) {
+		c.pingAttempts = attempts
+		c.pingInterval = interval
+	}
+}
+
 // NewDB creates a new MySQL database connection pool with the given DSN.
-func NewDB(dsn string) (*sql.DB, error) {
+// It returns an error if the database is still unreachable after every ping attempt.
+func NewDB(dsn string, opts ...DBOption) (*sql.DB, error) {
+	cfg := dbConfig{pingAttempts: 1, pingInterval: time.Second}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
 		return nil, err
@@ -19,9 +73,42 @@ func NewDB(dsn string) (*sql.DB, error) {
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(5 * time.Minute)
 
-	if err := db.Ping(); err != nil {
-		slog.Warn("database ping failed — continuing without DB", "error", err)
+	if err := pingWithBackoff(context.Background(), db, cfg.pingAttempts, cfg.pingInterval); err != nil {
+		db.Close()
+		return nil, err
 	}
 
 	return db, nil
 }
+
+// pinger is the part of *sql.DB that pingWithBackoff needs.
+type pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// pingWithBackoff pings db until it answers or attempts run out, doubling the
+// wait between attempts. It returns the last ping error.
+func pingWithBackoff(ctx context.Context, db pinger, attempts int, interval time.Duration) error {
+	attempts = max(attempts, 1)
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = db.PingContext(ctx); err == nil {
+			if attempt > 1 {
+				slog.Info("database reachable", "attempt", attempt)
+			}
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+
+		slog.Warn("database ping failed, retrying", "attempt", attempt, "of", attempts, "retry_in", interval, "error", err)
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		interval = min(interval*2, maxPingInterval)
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// flakyPinger fails its first failures pings, then succeeds.
+type flakyPinger struct {
+	failures int
+	calls    int
+}
+
+func (p *flakyPinger) PingContext(ctx context.Context) error {
+	p.calls++
+	if p.calls <= p.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestPingWithBackoff_SucceedsAfterFailures(t *testing.T) {
+	p := &flakyPinger{failures: 3}
+
+	if err := pingWithBackoff(context.Background(), p, 5, time.Millisecond); err != nil {
+		t.Fatalf("pingWithBackoff() unexpected error: %v", err)
+	}
+	if p.calls != 4 {
+		t.Errorf("expected 4 pings, got %d", p.calls)
+	}
+}
+
+func TestPingWithBackoff_GivesUp(t *testing.T) {
+	p := &flakyPinger{failures: 10}
+
+	if err := pingWithBackoff(context.Background(), p, 3, time.Millisecond); err == nil {
+		t.Fatal("expected an error once attempts run out")
+	}
+	if p.calls != 3 {
+		t.Errorf("expected 3 pings, got %d", p.calls)
+	}
+}
+
+func TestPingWithBackoff_StopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := pingWithBackoff(ctx, &flakyPinger{failures: 10}, 5, time.Hour)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"bad connection", fmt.Errorf("query: %w", driver.ErrBadConn), true},
+		{"invalid connection", mysql.ErrInvalidConn, true},
+		{"connection done", sql.ErrConnDone, true},
+		{"deadline", context.DeadlineExceeded, true},
+		{"dial", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{"deadlock", &mysql.MySQLError{Number: 1213}, true},
+		{"lock wait timeout", &mysql.MySQLError{Number: 1205}, true},
+		{"duplicate key", &mysql.MySQLError{Number: 1062}, false},
+		{"syntax", &mysql.MySQLError{Number: 1064}, false},
+		{"no rows", sql.ErrNoRows, false},
+		{"not found", ErrEntryNotFound, false},
+		{"canceled", context.Canceled, false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		if got := IsTransient(tt.err); got != tt.want {
+			t.Errorf("%s: IsTransient() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/model"
+)
+
+var (
+	ErrInviteNotFound = errors.New("invite code not found")
+	ErrInviteUsed     = errors.New("invite code already used")
+	ErrInviteExpired  = errors.New("invite code expired")
+)
+
+// InviteRepository handles invite code persistence operations.
+type InviteRepository struct {
+	db *sql.DB
+}
+
+// NewInviteRepository creates a new InviteRepository.
+func NewInviteRepository(db *sql.DB) *InviteRepository {
+	return &InviteRepository{db: db}
+}
+
+// Create inserts a new, unused invite code.
+func (r *InviteRepository) Create(ctx context.Context, invite *model.Invite) error {
+	query := `INSERT INTO invite_codes (code_hash, created_at, expires_at) VALUES (?, ?, ?)`
+
+	_, err := r.db.ExecContext(ctx, query, invite.CodeHash, invite.CreatedAt, invite.ExpiresAt)
+	return err
+}
+
+// Claim marks an unused, unexpired code as used by email. If no row qualifies,
+// the code is looked up again to report why.
+func (r *InviteRepository) Claim(ctx context.Context, codeHash, email string, now time.Time) error {
+	query := `UPDATE invite_codes SET used_at = ?, used_by = ?
+		WHERE code_hash = ? AND used_at IS NULL AND (expires_at IS NULL OR expires_at > ?)`
+
+	result, err := r.db.ExecContext(ctx, query, now, email, codeHash, now)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 1 {
+		return nil
+	}
+
+	var usedAt sql.NullTime
+	err = r.db.QueryRowContext(ctx, `SELECT used_at FROM invite_codes WHERE code_hash = ?`, codeHash).Scan(&usedAt)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrInviteNotFound
+	case err != nil:
+		return err
+	case usedAt.Valid:
+		return ErrInviteUsed
+	default:
+		return ErrInviteExpired
+	}
+}
+
+// Release returns a claimed code to the unused state.
+func (r *InviteRepository) Release(ctx context.Context, codeHash string) error {
+	query := `UPDATE invite_codes SET used_at = NULL, used_by = NULL WHERE code_hash = ?`
+
+	_, err := r.db.ExecContext(ctx, query, codeHash)
+	return err
+}
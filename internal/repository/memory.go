@@ -0,0 +1,477 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/model"
+)
+
+// MemoryUserRepository is a thread-safe in-memory UserStore.
+type MemoryUserRepository struct {
+	mu      sync.RWMutex
+	byID    map[int64]*model.User
+	byEmail map[string]int64
+	kdf     map[int64][]byte
+	nextID  int64
+}
+
+// NewMemoryUserRepository creates an empty MemoryUserRepository.
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{
+		byID:    make(map[int64]*model.User),
+		byEmail: make(map[string]int64),
+		kdf:     make(map[int64][]byte),
+	}
+}
+
+// Create inserts a new user and sets the generated ID on the user struct.
+func (r *MemoryUserRepository) Create(ctx context.Context, user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.byEmail[user.Email]; exists {
+		return ErrDuplicateEmail
+	}
+
+	r.nextID++
+	now := time.Now().UTC()
+	user.ID = r.nextID
+	user.CreatedAt = now
+	user.UpdatedAt = now
+
+	stored := *user
+	r.byID[user.ID] = &stored
+	r.byEmail[user.Email] = user.ID
+	return nil
+}
+
+// GetByEmail retrieves a user by their email address.
+func (r *MemoryUserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.byEmail[email]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	user := *r.byID[id]
+	return &user, nil
+}
+
+// GetByID retrieves a user by their ID.
+func (r *MemoryUserRepository) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.byID[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	user := *u
+	return &user, nil
+}
+
+// SetDeactivatedAt marks a user as deactivated at the given time, or reactivates them when at is nil.
+func (r *MemoryUserRepository) SetDeactivatedAt(ctx context.Context, id int64, at *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.byID[id]
+	if !ok {
+		return ErrUserNotFound
+	}
+	if at != nil {
+		t := *at
+		at = &t
+	}
+	u.DeactivatedAt = at
+	u.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// BumpTokenEpoch increments the user's token epoch and returns the new value.
+func (r *MemoryUserRepository) BumpTokenEpoch(ctx context.Context, id int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.byID[id]
+	if !ok {
+		return 0, ErrUserNotFound
+	}
+	u.TokenEpoch++
+	u.UpdatedAt = time.Now().UTC()
+	return u.TokenEpoch, nil
+}
+
+// BumpRekeyVersion increments the user's rekey version and returns the new value.
+func (r *MemoryUserRepository) BumpRekeyVersion(ctx context.Context, id int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.byID[id]
+	if !ok {
+		return 0, ErrUserNotFound
+	}
+	u.RekeyVersion++
+	u.UpdatedAt = time.Now().UTC()
+	return u.RekeyVersion, nil
+}
+
+// GetKDFProfile returns the user's stored key derivation profile as JSON, or nil if none has been stored.
+func (r *MemoryUserRepository) GetKDFProfile(ctx context.Context, id int64) ([]byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.byID[id]; !ok {
+		return nil, ErrUserNotFound
+	}
+	return bytes.Clone(r.kdf[id]), nil
+}
+
+// SetKDFProfile replaces the user's key derivation profile.
+func (r *MemoryUserRepository) SetKDFProfile(ctx context.Context, id int64, profile []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.byID[id]
+	if !ok {
+		return ErrUserNotFound
+	}
+	r.kdf[id] = bytes.Clone(profile)
+	u.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// ListUsers retrieves a page of users ordered by creation time, then ID, with AuthHash cleared.
+func (r *MemoryUserRepository) ListUsers(ctx context.Context, filter model.UserListFilter) ([]model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var users []model.User
+	for _, u := range r.byID {
+		if c := filter.After; c != nil && (Cursor{At: c.CreatedAt, ID: c.ID}).Compare(u.CreatedAt, u.ID) <= 0 {
+			continue
+		}
+		user := *u
+		user.AuthHash = ""
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if !users[i].CreatedAt.Equal(users[j].CreatedAt) {
+			return users[i].CreatedAt.Before(users[j].CreatedAt)
+		}
+		return users[i].ID < users[j].ID
+	})
+	if len(users) > filter.Limit {
+		users = users[:filter.Limit]
+	}
+	return users, nil
+}
+
+// MemoryCollectionRepository is a thread-safe in-memory CollectionStore.
+// Unlike the SQL store, deleting a vault does not cascade to a MemoryVaultRepository;
+// its entries simply become unreachable because vault IDs are never reused.
+type MemoryCollectionRepository struct {
+	mu     sync.RWMutex
+	vaults map[int64]*model.Vault
+	nextID int64
+}
+
+// NewMemoryCollectionRepository creates an empty MemoryCollectionRepository.
+func NewMemoryCollectionRepository() *MemoryCollectionRepository {
+	return &MemoryCollectionRepository{vaults: make(map[int64]*model.Vault)}
+}
+
+// Create inserts a new, non-default vault and fills in its ID and timestamps.
+func (r *MemoryCollectionRepository) Create(ctx context.Context, vault *model.Vault) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	vault.IsDefault = false
+	r.insertLocked(vault)
+	return nil
+}
+
+// insertLocked assigns an ID and timestamps and stores a copy. Callers must hold r.mu for writing.
+func (r *MemoryCollectionRepository) insertLocked(vault *model.Vault) {
+	r.nextID++
+	now := time.Now().UTC()
+	vault.ID = r.nextID
+	vault.CreatedAt = now
+	vault.UpdatedAt = now
+	stored := *vault
+	r.vaults[vault.ID] = &stored
+}
+
+// EnsureDefault returns the user's default vault, creating it on first use.
+func (r *MemoryCollectionRepository) EnsureDefault(ctx context.Context, userID int64) (*model.Vault, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, v := range r.vaults {
+		if v.UserID == userID && v.IsDefault {
+			vault := *v
+			return &vault, nil
+		}
+	}
+
+	vault := &model.Vault{UserID: userID, Name: model.DefaultVaultName, IsDefault: true}
+	r.insertLocked(vault)
+	return vault, nil
+}
+
+// GetByID retrieves one of the user's vaults.
+func (r *MemoryCollectionRepository) GetByID(ctx context.Context, userID, id int64) (*model.Vault, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	v, ok := r.vaults[id]
+	if !ok || v.UserID != userID {
+		return nil, ErrVaultNotFound
+	}
+	vault := *v
+	return &vault, nil
+}
+
+// ListByUser retrieves the user's vaults, default first and then in creation order.
+func (r *MemoryCollectionRepository) ListByUser(ctx context.Context, userID int64) ([]model.Vault, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var vaults []model.Vault
+	for _, v := range r.vaults {
+		if v.UserID == userID {
+			vaults = append(vaults, *v)
+		}
+	}
+	sort.Slice(vaults, func(i, j int) bool {
+		if vaults[i].IsDefault != vaults[j].IsDefault {
+			return vaults[i].IsDefault
+		}
+		return vaults[i].ID < vaults[j].ID
+	})
+	return vaults, nil
+}
+
+// Rename changes the name of one of the user's vaults.
+func (r *MemoryCollectionRepository) Rename(ctx context.Context, userID, id int64, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v, ok := r.vaults[id]
+	if !ok || v.UserID != userID {
+		return ErrVaultNotFound
+	}
+	v.Name = name
+	v.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// Delete removes one of the user's non-default vaults.
+func (r *MemoryCollectionRepository) Delete(ctx context.Context, userID, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v, ok := r.vaults[id]
+	if !ok || v.UserID != userID || v.IsDefault {
+		return ErrVaultNotFound
+	}
+	delete(r.vaults, id)
+	return nil
+}
+
+// MemoryAuditRepository is a thread-safe in-memory AuditStore.
+type MemoryAuditRepository struct {
+	mu     sync.RWMutex
+	events []model.LoginEvent
+}
+
+// NewMemoryAuditRepository creates an empty MemoryAuditRepository.
+func NewMemoryAuditRepository() *MemoryAuditRepository {
+	return &MemoryAuditRepository{}
+}
+
+// RecordLogin appends a login event and sets the generated ID on the event struct.
+func (r *MemoryAuditRepository) RecordLogin(ctx context.Context, event *model.LoginEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event.ID = int64(len(r.events) + 1)
+	event.CreatedAt = time.Now().UTC()
+	r.events = append(r.events, *event)
+	return nil
+}
+
+// ListLoginsByUser retrieves a page of login events for a user, most recent first.
+func (r *MemoryAuditRepository) ListLoginsByUser(ctx context.Context, userID int64, filter model.LoginEventFilter) ([]model.LoginEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var events []model.LoginEvent
+	for i := len(r.events) - 1; i >= 0; i-- {
+		e := r.events[i]
+		if e.UserID == nil || *e.UserID != userID {
+			continue
+		}
+		if filter.Success != nil && e.Success != *filter.Success {
+			continue
+		}
+		if c := filter.Before; c != nil && (Cursor{At: c.CreatedAt, ID: c.ID}).Compare(e.CreatedAt, e.ID) >= 0 {
+			continue
+		}
+		events = append(events, e)
+	}
+
+	if filter.Offset >= len(events) {
+		return nil, nil
+	}
+	events = events[filter.Offset:]
+	if len(events) > filter.Limit {
+		events = events[:filter.Limit]
+	}
+	return events, nil
+}
+
+// MemorySessionRepository is a thread-safe in-memory SessionStore.
+type MemorySessionRepository struct {
+	mu       sync.RWMutex
+	sessions map[string]*model.Session
+}
+
+// NewMemorySessionRepository creates an empty MemorySessionRepository.
+func NewMemorySessionRepository() *MemorySessionRepository {
+	return &MemorySessionRepository{sessions: make(map[string]*model.Session)}
+}
+
+// Create inserts a new session. The session ID is generated by the caller.
+func (r *MemorySessionRepository) Create(ctx context.Context, session *model.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	session.CreatedAt = now
+	session.LastSeenAt = now
+	stored := *session
+	r.sessions[session.ID] = &stored
+	return nil
+}
+
+// GetByID retrieves a session by its ID, including revoked and expired sessions.
+func (r *MemorySessionRepository) GetByID(ctx context.Context, id string) (*model.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	session := *s
+	return &session, nil
+}
+
+// ListActiveByUser retrieves all unrevoked, unexpired sessions for a user, most recently seen first.
+func (r *MemorySessionRepository) ListActiveByUser(ctx context.Context, userID int64) ([]model.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now().UTC()
+	var sessions []model.Session
+	for _, s := range r.sessions {
+		if s.UserID == userID && s.RevokedAt == nil && s.ExpiresAt.After(now) {
+			sessions = append(sessions, *s)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+	return sessions, nil
+}
+
+// Touch updates the last-seen timestamp of a session.
+func (r *MemorySessionRepository) Touch(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s, ok := r.sessions[id]; ok {
+		s.LastSeenAt = time.Now().UTC()
+	}
+	return nil
+}
+
+// Revoke marks a user's session as revoked.
+func (r *MemorySessionRepository) Revoke(ctx context.Context, userID int64, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.sessions[id]
+	if !ok || s.UserID != userID || s.RevokedAt != nil {
+		return ErrSessionNotFound
+	}
+	now := time.Now().UTC()
+	s.RevokedAt = &now
+	return nil
+}
+
+var (
+	_ UserStore       = (*MemoryUserRepository)(nil)
+	_ CollectionStore = (*MemoryCollectionRepository)(nil)
+	_ VaultStore      = (*MemoryVaultRepository)(nil)
+	_ AuditStore      = (*MemoryAuditRepository)(nil)
+	_ SessionStore    = (*MemorySessionRepository)(nil)
+)
+
+// MemoryInviteRepository is a thread-safe in-memory InviteStore.
+type MemoryInviteRepository struct {
+	mu      sync.Mutex
+	invites map[string]*model.Invite
+}
+
+// NewMemoryInviteRepository creates an empty MemoryInviteRepository.
+func NewMemoryInviteRepository() *MemoryInviteRepository {
+	return &MemoryInviteRepository{invites: make(map[string]*model.Invite)}
+}
+
+// Create stores a new, unused invite code.
+func (r *MemoryInviteRepository) Create(ctx context.Context, invite *model.Invite) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *invite
+	r.invites[invite.CodeHash] = &stored
+	return nil
+}
+
+// Claim marks an unused, unexpired code as used by email.
+func (r *MemoryInviteRepository) Claim(ctx context.Context, codeHash, email string, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	inv, ok := r.invites[codeHash]
+	switch {
+	case !ok:
+		return ErrInviteNotFound
+	case inv.UsedAt != nil:
+		return ErrInviteUsed
+	case inv.ExpiresAt != nil && !inv.ExpiresAt.After(now):
+		return ErrInviteExpired
+	}
+	inv.UsedAt = &now
+	inv.UsedBy = email
+	return nil
+}
+
+// Release returns a claimed code to the unused state.
+func (r *MemoryInviteRepository) Release(ctx context.Context, codeHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if inv, ok := r.invites[codeHash]; ok {
+		inv.UsedAt = nil
+		inv.UsedBy = ""
+	}
+	return nil
+}
@@ -0,0 +1,611 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/model"
+)
+
+// MemoryVaultRepository is a thread-safe in-memory VaultStore with the same
+// last-write-wins and soft-delete semantics as VaultRepository. It is intended
+// for tests and zero-dependency demos; data is lost on restart.
+type MemoryVaultRepository struct {
+	mu             sync.RWMutex
+	entries        map[vaultKey]map[string]*model.VaultEntry
+	conflicts      map[vaultKey][]model.VaultConflict
+	acks           map[vaultKey]map[string]time.Time
+	nextID         int64
+	nextConflictID int64
+	eraseOnDelete  bool
+}
+
+// MemoryVaultOption configures a MemoryVaultRepository.
+type MemoryVaultOption func(*MemoryVaultRepository)
+
+// WithMemoryEraseOnDelete is WithEraseOnDelete for MemoryVaultRepository.
+func WithMemoryEraseOnDelete() MemoryVaultOption {
+	return func(r *MemoryVaultRepository) {
+		r.eraseOnDelete = true
+	}
+}
+
+// vaultKey scopes entries and conflicts to one of a user's vaults.
+type vaultKey struct {
+	userID, vaultID int64
+}
+
+// NewMemoryVaultRepository creates an empty MemoryVaultRepository.
+func NewMemoryVaultRepository(opts ...MemoryVaultOption) *MemoryVaultRepository {
+	r := &MemoryVaultRepository{
+		entries:   make(map[vaultKey]map[string]*model.VaultEntry),
+		conflicts: make(map[vaultKey][]model.VaultConflict),
+		acks:      make(map[vaultKey]map[string]time.Time),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// markDeleted tombstones e, discarding its blob when eraseOnDelete is set.
+// The caller must hold r.mu.
+func (r *MemoryVaultRepository) markDeleted(e *model.VaultEntry, now time.Time) {
+	e.Deleted = true
+	e.Version++
+	e.UpdatedAt = now
+	e.EncryptedData = r.storedData(e)
+}
+
+// storedData returns a copy of the blob to store for e, which is empty for a
+// tombstone when eraseOnDelete is set.
+func (r *MemoryVaultRepository) storedData(e *model.VaultEntry) []byte {
+	if r.eraseOnDelete && e.Deleted {
+		return []byte{}
+	}
+	return append([]byte(nil), e.EncryptedData...)
+}
+
+// memoryTx buffers writes until Commit so a rolled-back sync leaves no trace.
+// Each pending write runs with the repository lock held.
+type memoryTx struct {
+	repo    *MemoryVaultRepository
+	pending []func()
+	done    bool
+}
+
+func (tx *memoryTx) Commit() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+
+	tx.repo.mu.Lock()
+	defer tx.repo.mu.Unlock()
+	for _, apply := range tx.pending {
+		apply()
+	}
+	tx.pending = nil
+	return nil
+}
+
+func (tx *memoryTx) Rollback() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	tx.pending = nil
+	return nil
+}
+
+// BeginTx starts a new buffered transaction.
+func (r *MemoryVaultRepository) BeginTx(ctx context.Context) (Tx, error) {
+	return &memoryTx{repo: r}, nil
+}
+
+// Upsert inserts or updates a vault entry using last-write-wins conflict resolution.
+func (r *MemoryVaultRepository) Upsert(ctx context.Context, entry *model.VaultEntry) error {
+	e := *entry
+	e.CreatedAt = time.Time{}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.upsertLocked(e)
+	return nil
+}
+
+// UpsertWithCreatedAt is Upsert, except that a newly inserted entry keeps entry.CreatedAt.
+func (r *MemoryVaultRepository) UpsertWithCreatedAt(ctx context.Context, entry *model.VaultEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.upsertLocked(*entry)
+	return nil
+}
+
+// UpsertTx queues an upsert to be applied when the transaction commits.
+func (r *MemoryVaultRepository) UpsertTx(ctx context.Context, tx Tx, entry *model.VaultEntry) error {
+	e := *entry
+	e.CreatedAt = time.Time{}
+	return r.queue(tx, func() { r.upsertLocked(e) })
+}
+
+// queue appends a write to a transaction begun by this repository.
+func (r *MemoryVaultRepository) queue(tx Tx, apply func()) error {
+	mtx, ok := tx.(*memoryTx)
+	if !ok || mtx.repo != r {
+		return ErrForeignTx
+	}
+	if mtx.done {
+		return ErrTxDone
+	}
+	mtx.pending = append(mtx.pending, apply)
+	return nil
+}
+
+// UpdateIfVersion overwrites a live entry only if its stored version equals expectedVersion.
+func (r *MemoryVaultRepository) UpdateIfVersion(ctx context.Context, entry *model.VaultEntry, expectedVersion int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.entries[vaultKey{entry.UserID, entry.VaultID}][entry.EntryID]
+	if !ok || existing.Deleted {
+		return ErrEntryNotFound
+	}
+	if existing.Version != expectedVersion {
+		return ErrVersionMismatch
+	}
+
+	existing.EncryptedData = r.storedData(entry)
+	existing.Version = entry.Version
+	existing.Favorite = entry.Favorite
+	existing.LastDeviceID = entry.LastDeviceID
+	existing.Deleted = entry.Deleted
+	existing.DeleteReason = entry.DeleteReason
+	existing.ExpiresAt = copyTime(entry.ExpiresAt)
+	existing.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// upsertLocked applies a single LWW upsert. A new entry's creation time is
+// e.CreatedAt, or now if unset. Callers must hold r.mu for writing.
+func (r *MemoryVaultRepository) upsertLocked(e model.VaultEntry) {
+	key := vaultKey{e.UserID, e.VaultID}
+	vault := r.entries[key]
+	if vault == nil {
+		vault = make(map[string]*model.VaultEntry)
+		r.entries[key] = vault
+	}
+
+	now := time.Now().UTC()
+	existing, ok := vault[e.EntryID]
+	if !ok {
+		r.nextID++
+		e.ID = r.nextID
+		e.EncryptedData = r.storedData(&e)
+		e.ExpiresAt = copyTime(e.ExpiresAt)
+		e.LastAccessedAt = nil
+		if e.CreatedAt.IsZero() {
+			e.CreatedAt = now
+		}
+		e.UpdatedAt = now
+		vault[e.EntryID] = &e
+		return
+	}
+
+	if e.Version > existing.Version {
+		existing.EncryptedData = r.storedData(&e)
+		existing.Version = e.Version
+		existing.Favorite = e.Favorite
+		existing.LastDeviceID = e.LastDeviceID
+		existing.Deleted = e.Deleted
+		existing.DeleteReason = e.DeleteReason
+		existing.ExpiresAt = copyTime(e.ExpiresAt)
+		existing.UpdatedAt = now
+	}
+}
+
+// GetByEntryID retrieves a vault entry by user ID, vault ID and client-generated entry ID.
+func (r *MemoryVaultRepository) GetByEntryID(ctx context.Context, userID, vaultID int64, entryID string) (*model.VaultEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	e, ok := r.entries[vaultKey{userID, vaultID}][entryID]
+	if !ok {
+		return nil, ErrEntryNotFound
+	}
+	copied := *e
+	return &copied, nil
+}
+
+// GetByEntryIDForUpdateTx is GetByEntryID for a transaction begun by this
+// repository. It reads committed state; writes are serialized at Commit.
+func (r *MemoryVaultRepository) GetByEntryIDForUpdateTx(ctx context.Context, tx Tx, userID, vaultID int64, entryID string) (*model.VaultEntry, error) {
+	if mtx, ok := tx.(*memoryTx); !ok || mtx.repo != r {
+		return nil, ErrForeignTx
+	}
+	return r.GetByEntryID(ctx, userID, vaultID, entryID)
+}
+
+// GetByEntryIDs retrieves the vault's live entries among entryIDs.
+func (r *MemoryVaultRepository) GetByEntryIDs(ctx context.Context, userID, vaultID int64, entryIDs []string) ([]model.VaultEntry, error) {
+	now := time.Now().UTC()
+	return r.collect(vaultKey{userID, vaultID}, func(e *model.VaultEntry) bool {
+		return live(e, now) && slices.Contains(entryIDs, e.EntryID)
+	}), nil
+}
+
+// ListByUser retrieves all live (non-deleted, unexpired) entries in one of the user's vaults,
+// ordered by most recently updated.
+func (r *MemoryVaultRepository) ListByUser(ctx context.Context, userID, vaultID int64) ([]model.VaultEntry, error) {
+	now := time.Now().UTC()
+	entries := r.collect(vaultKey{userID, vaultID}, func(e *model.VaultEntry) bool { return live(e, now) })
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[j].UpdatedAt.Before(entries[i].UpdatedAt)
+	})
+	return entries, nil
+}
+
+// ListFavorites retrieves the vault's live favorite entries, ordered by most recently updated.
+func (r *MemoryVaultRepository) ListFavorites(ctx context.Context, userID, vaultID int64) ([]model.VaultEntry, error) {
+	now := time.Now().UTC()
+	entries := r.collect(vaultKey{userID, vaultID}, func(e *model.VaultEntry) bool { return e.Favorite && live(e, now) })
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[j].UpdatedAt.Before(entries[i].UpdatedAt)
+	})
+	return entries, nil
+}
+
+// ListDeleted retrieves the vault's soft-deleted entries that have not been purged,
+// most recently deleted first.
+func (r *MemoryVaultRepository) ListDeleted(ctx context.Context, userID, vaultID int64) ([]model.VaultEntry, error) {
+	entries := r.collect(vaultKey{userID, vaultID}, func(e *model.VaultEntry) bool { return e.Deleted })
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[j].UpdatedAt.Before(entries[i].UpdatedAt)
+	})
+	return entries, nil
+}
+
+// GetChangedSince retrieves a page of vault entries (including deleted) modified after filter.Since,
+// plus entries that expired since then, ordered by change time then entry ID.
+func (r *MemoryVaultRepository) GetChangedSince(ctx context.Context, userID, vaultID int64, filter model.ChangeFilter) ([]model.VaultEntry, error) {
+	since, now := filter.Since, filter.Now
+	entries := r.collect(vaultKey{userID, vaultID}, func(e *model.VaultEntry) bool {
+		changed := e.UpdatedAt.After(since) || (e.ExpiresAt != nil && e.ExpiresAt.After(since) && e.Expired(now))
+		if c := filter.After; changed && c != nil {
+			return (Cursor{At: c.ChangedAt, Key: c.EntryID}).CompareKey(e.ChangedAt(now), e.EntryID) > 0
+		}
+		return changed
+	})
+	sort.Slice(entries, func(i, j int) bool {
+		return (Cursor{At: entries[j].ChangedAt(now), Key: entries[j].EntryID}).CompareKey(entries[i].ChangedAt(now), entries[i].EntryID) < 0
+	})
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	return entries, nil
+}
+
+// ListAllByUser retrieves every stored entry in all of the user's vaults,
+// including deleted and expired ones, ordered by vault.
+func (r *MemoryVaultRepository) ListAllByUser(ctx context.Context, userID int64) ([]model.VaultEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var entries []model.VaultEntry
+	for key, vault := range r.entries {
+		if key.userID != userID {
+			continue
+		}
+		for _, e := range vault {
+			entries = append(entries, *e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].VaultID != entries[j].VaultID {
+			return entries[i].VaultID < entries[j].VaultID
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries, nil
+}
+
+// ListManifest retrieves the metadata of every entry in one of the user's vaults,
+// including deleted and expired ones, ordered by entry ID.
+func (r *MemoryVaultRepository) ListManifest(ctx context.Context, userID, vaultID int64) ([]model.ManifestEntry, error) {
+	now := time.Now().UTC()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var manifest []model.ManifestEntry
+	for _, e := range r.entries[vaultKey{userID, vaultID}] {
+		manifest = append(manifest, model.ManifestEntry{
+			EntryID:   e.EntryID,
+			Version:   e.Version,
+			UpdatedAt: e.UpdatedAt,
+			Deleted:   !live(e, now),
+		})
+	}
+	sort.Slice(manifest, func(i, j int) bool { return manifest[i].EntryID < manifest[j].EntryID })
+	return manifest, nil
+}
+
+// TouchAccessed records at as the last access time of the given entries,
+// leaving UpdatedAt alone.
+func (r *MemoryVaultRepository) TouchAccessed(ctx context.Context, userID, vaultID int64, entryIDs []string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	vault := r.entries[vaultKey{userID, vaultID}]
+	for _, id := range entryIDs {
+		if e, ok := vault[id]; ok {
+			e.LastAccessedAt = &at
+		}
+	}
+	return nil
+}
+
+// SoftDelete marks a vault entry as deleted and increments its version for sync propagation.
+func (r *MemoryVaultRepository) SoftDelete(ctx context.Context, userID, vaultID int64, entryID string) error {
+	return r.SoftDeleteWithReason(ctx, userID, vaultID, entryID, "")
+}
+
+// SoftDeleteWithReason is SoftDelete that also records why the entry was deleted.
+func (r *MemoryVaultRepository) SoftDeleteWithReason(ctx context.Context, userID, vaultID int64, entryID, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.entries[vaultKey{userID, vaultID}][entryID]
+	if !ok {
+		return ErrEntryNotFound
+	}
+	r.markDeleted(e, time.Now().UTC())
+	e.DeleteReason = reason
+	return nil
+}
+
+// Restore clears the deleted flag on a soft-deleted vault entry and increments its version.
+// A tombstone whose blob was erased is refused with ErrEntryErased.
+func (r *MemoryVaultRepository) Restore(ctx context.Context, userID, vaultID int64, entryID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.entries[vaultKey{userID, vaultID}][entryID]
+	if !ok || !e.Deleted {
+		return ErrEntryNotFound
+	}
+	if len(e.EncryptedData) == 0 {
+		return ErrEntryErased
+	}
+	e.Deleted = false
+	e.DeleteReason = ""
+	e.Version++
+	e.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// WipeByUser deletes every entry in all of the user's vaults and drops their
+// staged conflicts. Soft wipes mark live entries deleted and bump their versions;
+// hard wipes remove them. It returns the number of entries wiped.
+func (r *MemoryVaultRepository) WipeByUser(ctx context.Context, userID int64, hard bool) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key := range r.conflicts {
+		if key.userID == userID {
+			delete(r.conflicts, key)
+		}
+	}
+
+	now := time.Now().UTC()
+	var n int64
+	for key, vault := range r.entries {
+		if key.userID != userID {
+			continue
+		}
+		for id, e := range vault {
+			switch {
+			case hard:
+				delete(vault, id)
+			case e.Deleted:
+				continue
+			default:
+				r.markDeleted(e, now)
+			}
+			n++
+		}
+	}
+	return n, nil
+}
+
+// live reports whether an entry is neither deleted nor expired.
+func live(e *model.VaultEntry, now time.Time) bool {
+	return !e.Deleted && !e.Expired(now)
+}
+
+// copyTime returns a copy of t so stored entries don't alias caller memory.
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+// collect copies the vault's entries matching keep, ordered by ID for stable sorting.
+func (r *MemoryVaultRepository) collect(key vaultKey, keep func(*model.VaultEntry) bool) []model.VaultEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var entries []model.VaultEntry
+	for _, e := range r.entries[key] {
+		if keep(e) {
+			entries = append(entries, *e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries
+}
+
+// StageConflictTx queues a losing write to be staged when the transaction commits,
+// unless a conflict with the same entry ID and version is already staged.
+func (r *MemoryVaultRepository) StageConflictTx(ctx context.Context, tx Tx, conflict *model.VaultConflict) error {
+	c := *conflict
+	c.EncryptedData = append([]byte(nil), conflict.EncryptedData...)
+	return r.queue(tx, func() {
+		key := vaultKey{c.UserID, c.VaultID}
+		if slices.ContainsFunc(r.conflicts[key], func(s model.VaultConflict) bool {
+			return s.EntryID == c.EntryID && s.Version == c.Version
+		}) {
+			return
+		}
+		r.nextConflictID++
+		c.ID = r.nextConflictID
+		c.CreatedAt = time.Now().UTC()
+		r.conflicts[key] = append(r.conflicts[key], c)
+	})
+}
+
+// ClearConflictsTx queues removal of an entry's staged conflicts.
+func (r *MemoryVaultRepository) ClearConflictsTx(ctx context.Context, tx Tx, userID, vaultID int64, entryID string) error {
+	key := vaultKey{userID, vaultID}
+	return r.queue(tx, func() {
+		r.conflicts[key] = slices.DeleteFunc(r.conflicts[key], func(c model.VaultConflict) bool {
+			return c.EntryID == entryID
+		})
+	})
+}
+
+// ListConflicts retrieves the vault's unresolved conflicts, oldest first.
+func (r *MemoryVaultRepository) ListConflicts(ctx context.Context, userID, vaultID int64) ([]model.VaultConflict, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.conflicts[vaultKey{userID, vaultID}]), nil
+}
+
+// AckSync records that a device synced the vault and, when cursor is set,
+// that it has applied every change up to it. A device that has never
+// acknowledged a cursor is stored with the zero time. Each device's stored
+// cursor only moves forward.
+func (r *MemoryVaultRepository) AckSync(ctx context.Context, userID, vaultID int64, deviceID string, cursor *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := vaultKey{userID, vaultID}
+	if r.acks[key] == nil {
+		r.acks[key] = make(map[string]time.Time)
+	}
+	acked := r.acks[key][deviceID]
+	if cursor != nil && cursor.After(acked) {
+		acked = *cursor
+	}
+	r.acks[key][deviceID] = acked
+	return nil
+}
+
+// MinSyncAck returns the oldest cursor acknowledged by any device that has
+// synced the vault. It returns the zero time if no device has synced, or if
+// any device has never acknowledged a cursor.
+func (r *MemoryVaultRepository) MinSyncAck(ctx context.Context, userID, vaultID int64) (time.Time, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var oldest time.Time
+	for _, at := range r.acks[vaultKey{userID, vaultID}] {
+		if at.IsZero() {
+			return time.Time{}, nil
+		}
+		if oldest.IsZero() || at.Before(oldest) {
+			oldest = at
+		}
+	}
+	return oldest, nil
+}
+
+// PurgeTombstones permanently removes the vault's deleted entries last changed at or before through.
+func (r *MemoryVaultRepository) PurgeTombstones(ctx context.Context, userID, vaultID int64, through time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	vault := r.entries[vaultKey{userID, vaultID}]
+	for id, e := range vault {
+		if e.Deleted && !e.UpdatedAt.After(through) {
+			delete(vault, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// PurgeExpired permanently removes every user's entries that expired at or before now.
+func (r *MemoryVaultRepository) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	for _, vault := range r.entries {
+		for id, e := range vault {
+			if e.Expired(now) {
+				delete(vault, id)
+				n++
+			}
+		}
+	}
+	return n, nil
+}
+
+// DuplicateStats counts live entries whose blob is byte-identical to another
+// entry of the same user, hashing blobs as the SQL store does on write.
+func (r *MemoryVaultRepository) DuplicateStats(ctx context.Context) (model.DuplicateStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	type group struct {
+		userID int64
+		hash   string
+	}
+	counts := make(map[group]int64)
+	for key, vault := range r.entries {
+		for _, e := range vault {
+			if !e.Deleted {
+				counts[group{key.userID, contentHash(key.userID, e.EncryptedData)}]++
+			}
+		}
+	}
+
+	var stats model.DuplicateStats
+	users := make(map[int64]bool)
+	for g, n := range counts {
+		if n > 1 {
+			stats.Groups++
+			stats.Entries += n
+			users[g.userID] = true
+		}
+	}
+	stats.Users = int64(len(users))
+	stats.Redundant = stats.Entries - stats.Groups
+	return stats, nil
+}
+
+// UsageStats counts the user's live (non-deleted, unexpired) entries and their blob bytes.
+func (r *MemoryVaultRepository) UsageStats(ctx context.Context, userID int64) (model.VaultUsage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	var usage model.VaultUsage
+	for key, vault := range r.entries {
+		if key.userID != userID {
+			continue
+		}
+		for _, e := range vault {
+			if live(e, now) {
+				usage.Entries++
+				usage.Bytes += int64(len(e.EncryptedData))
+			}
+		}
+	}
+	return usage, nil
+}
@@ -0,0 +1,373 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/model"
+)
+
+func TestMemoryVault_LastWriteWins(t *testing.T) {
+	repo := NewMemoryVaultRepository()
+	ctx := context.Background()
+
+	repo.Upsert(ctx, &model.VaultEntry{UserID: 1, VaultID: 1, EntryID: "e1", EncryptedData: []byte("v2"), Version: 2})
+	repo.Upsert(ctx, &model.VaultEntry{UserID: 1, VaultID: 1, EntryID: "e1", EncryptedData: []byte("v1"), Version: 1})
+	repo.Upsert(ctx, &model.VaultEntry{UserID: 1, VaultID: 1, EntryID: "e1", EncryptedData: []byte("v2-dup"), Version: 2})
+
+	got, err := repo.GetByEntryID(ctx, 1, 1, "e1")
+	if err != nil {
+		t.Fatalf("GetByEntryID() unexpected error: %v", err)
+	}
+	if string(got.EncryptedData) != "v2" || got.Version != 2 {
+		t.Errorf("expected stale and equal versions to be ignored, got %q v%d", got.EncryptedData, got.Version)
+	}
+
+	repo.Upsert(ctx, &model.VaultEntry{UserID: 1, VaultID: 1, EntryID: "e1", EncryptedData: []byte("v3"), Version: 3})
+	got, _ = repo.GetByEntryID(ctx, 1, 1, "e1")
+	if string(got.EncryptedData) != "v3" || got.Version != 3 {
+		t.Errorf("expected newer version to win, got %q v%d", got.EncryptedData, got.Version)
+	}
+}
+
+func TestMemoryVault_SoftDelete(t *testing.T) {
+	repo := NewMemoryVaultRepository()
+	ctx := context.Background()
+
+	repo.Upsert(ctx, &model.VaultEntry{UserID: 1, VaultID: 1, EntryID: "e1", EncryptedData: []byte("x"), Version: 4})
+	if err := repo.SoftDelete(ctx, 1, 1, "e1"); err != nil {
+		t.Fatalf("SoftDelete() unexpected error: %v", err)
+	}
+	if err := repo.SoftDelete(ctx, 2, 1, "e1"); err != ErrEntryNotFound {
+		t.Errorf("expected ErrEntryNotFound for another user's entry, got %v", err)
+	}
+
+	got, _ := repo.GetByEntryID(ctx, 1, 1, "e1")
+	if !got.Deleted || got.Version != 5 {
+		t.Errorf("expected deleted entry at version 5, got deleted=%v v%d", got.Deleted, got.Version)
+	}
+
+	list, _ := repo.ListByUser(ctx, 1, 1)
+	if len(list) != 0 {
+		t.Errorf("expected soft-deleted entry to be excluded from ListByUser, got %d", len(list))
+	}
+}
+
+func TestMemoryVault_EraseOnDelete(t *testing.T) {
+	for _, erase := range []bool{false, true} {
+		var opts []MemoryVaultOption
+		if erase {
+			opts = append(opts, WithMemoryEraseOnDelete())
+		}
+		repo := NewMemoryVaultRepository(opts...)
+		ctx := context.Background()
+		before := time.Now().UTC().Add(-time.Second)
+
+		repo.Upsert(ctx, &model.VaultEntry{UserID: 1, VaultID: 1, EntryID: "e1", EncryptedData: []byte("secret"), Version: 1})
+		repo.Upsert(ctx, &model.VaultEntry{UserID: 1, VaultID: 1, EntryID: "e2", EncryptedData: []byte("secret"), Version: 1})
+		if err := repo.SoftDelete(ctx, 1, 1, "e1"); err != nil {
+			t.Fatalf("erase=%v: SoftDelete() unexpected error: %v", erase, err)
+		}
+		if _, err := repo.WipeByUser(ctx, 1, false); err != nil {
+			t.Fatalf("erase=%v: WipeByUser() unexpected error: %v", erase, err)
+		}
+
+		changes, _ := repo.GetChangedSince(ctx, 1, 1, model.ChangeFilter{Since: before, Now: time.Now().UTC(), Limit: 10})
+		if len(changes) != 2 {
+			t.Fatalf("erase=%v: expected 2 tombstones, got %d", erase, len(changes))
+		}
+		for _, e := range changes {
+			switch {
+			case !e.Deleted || e.Version != 2:
+				t.Errorf("erase=%v: %s: expected tombstone at version 2, got deleted=%v v%d", erase, e.EntryID, e.Deleted, e.Version)
+			case erase && len(e.EncryptedData) != 0:
+				t.Errorf("%s: expected blob erased, got %q", e.EntryID, e.EncryptedData)
+			case !erase && string(e.EncryptedData) != "secret":
+				t.Errorf("%s: expected blob retained, got %q", e.EntryID, e.EncryptedData)
+			}
+		}
+	}
+}
+
+func TestMemoryVault_EraseOnDeleteAppliesToEveryTombstone(t *testing.T) {
+	repo := NewMemoryVaultRepository(WithMemoryEraseOnDelete())
+	ctx := context.Background()
+
+	for _, id := range []string{"synced", "tx", "if-match"} {
+		repo.Upsert(ctx, &model.VaultEntry{UserID: 1, VaultID: 1, EntryID: id, EncryptedData: []byte("secret"), Version: 1})
+	}
+	tombstone := func(id string) *model.VaultEntry {
+		return &model.VaultEntry{UserID: 1, VaultID: 1, EntryID: id, EncryptedData: []byte("secret"), Version: 2, Deleted: true}
+	}
+
+	repo.Upsert(ctx, tombstone("synced"))
+	tx, _ := repo.BeginTx(ctx)
+	repo.UpsertTx(ctx, tx, tombstone("tx"))
+	tx.Commit()
+	if err := repo.UpdateIfVersion(ctx, tombstone("if-match"), 1); err != nil {
+		t.Fatalf("UpdateIfVersion() unexpected error: %v", err)
+	}
+
+	for _, id := range []string{"synced", "tx", "if-match"} {
+		got, _ := repo.GetByEntryID(ctx, 1, 1, id)
+		if !got.Deleted || len(got.EncryptedData) != 0 {
+			t.Errorf("%s: expected erased tombstone, got deleted=%v data=%q", id, got.Deleted, got.EncryptedData)
+		}
+		if err := repo.Restore(ctx, 1, 1, id); err != ErrEntryErased {
+			t.Errorf("%s: Restore() expected ErrEntryErased, got %v", id, err)
+		}
+	}
+}
+
+func TestMemoryVault_GetChangedSinceOrdering(t *testing.T) {
+	repo := NewMemoryVaultRepository()
+	ctx := context.Background()
+
+	start := time.Now().UTC()
+	for _, id := range []string{"a", "b", "c"} {
+		time.Sleep(time.Millisecond)
+		repo.Upsert(ctx, &model.VaultEntry{UserID: 1, VaultID: 1, EntryID: id, EncryptedData: []byte(id), Version: 1})
+	}
+	time.Sleep(time.Millisecond)
+	repo.SoftDelete(ctx, 1, 1, "a")
+
+	changed, err := repo.GetChangedSince(ctx, 1, 1, model.ChangeFilter{Since: start, Now: time.Now().UTC(), Limit: 10})
+	if err != nil {
+		t.Fatalf("GetChangedSince() unexpected error: %v", err)
+	}
+	var order []string
+	for _, e := range changed {
+		order = append(order, e.EntryID)
+	}
+	if len(order) != 3 || order[0] != "b" || order[1] != "c" || order[2] != "a" {
+		t.Errorf("expected oldest change first [b c a], got %v", order)
+	}
+	if !changed[2].Deleted {
+		t.Error("expected deleted entries to be included in changes")
+	}
+
+	later, _ := repo.GetChangedSince(ctx, 1, 1, model.ChangeFilter{Since: changed[2].UpdatedAt, Now: time.Now().UTC(), Limit: 10})
+	if len(later) != 0 {
+		t.Errorf("expected no changes after the latest update, got %d", len(later))
+	}
+}
+
+func TestMemoryVault_GetChangedSincePages(t *testing.T) {
+	repo := NewMemoryVaultRepository()
+	ctx := context.Background()
+
+	// Entries written in the same instant are ordered by entry ID.
+	for _, id := range []string{"d", "b", "c", "a"} {
+		repo.Upsert(ctx, &model.VaultEntry{UserID: 1, VaultID: 1, EntryID: id, EncryptedData: []byte(id), Version: 1})
+	}
+	at := time.Now().UTC()
+	for _, e := range repo.entries[vaultKey{1, 1}] {
+		e.UpdatedAt = at
+	}
+
+	var got []string
+	filter := model.ChangeFilter{Now: time.Now().UTC(), Limit: 3}
+	for range 3 {
+		page, err := repo.GetChangedSince(ctx, 1, 1, filter)
+		if err != nil {
+			t.Fatalf("GetChangedSince() unexpected error: %v", err)
+		}
+		if len(page) > filter.Limit {
+			t.Fatalf("expected at most %d entries, got %d", filter.Limit, len(page))
+		}
+		if len(page) == 0 {
+			break
+		}
+		for _, e := range page {
+			got = append(got, e.EntryID)
+		}
+		last := page[len(page)-1]
+		filter.After = &model.ChangeCursor{ChangedAt: last.ChangedAt(filter.Now), EntryID: last.EntryID}
+	}
+	if !slices.Equal(got, []string{"a", "b", "c", "d"}) {
+		t.Errorf("expected pages to continue after the cursor in entry ID order, got %v", got)
+	}
+}
+
+func TestMemoryVault_ListAllByUser(t *testing.T) {
+	repo := NewMemoryVaultRepository()
+	ctx := context.Background()
+
+	repo.Upsert(ctx, &model.VaultEntry{UserID: 1, VaultID: 2, EntryID: "b", Version: 1})
+	repo.Upsert(ctx, &model.VaultEntry{UserID: 1, VaultID: 1, EntryID: "a", Version: 1})
+	repo.Upsert(ctx, &model.VaultEntry{UserID: 1, VaultID: 1, EntryID: "gone", Version: 1})
+	repo.Upsert(ctx, &model.VaultEntry{UserID: 2, VaultID: 3, EntryID: "other", Version: 1})
+	repo.SoftDelete(ctx, 1, 1, "gone")
+
+	entries, err := repo.ListAllByUser(ctx, 1)
+	if err != nil {
+		t.Fatalf("ListAllByUser() unexpected error: %v", err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.EntryID)
+	}
+	if len(got) != 3 || got[0] != "a" || got[1] != "gone" || got[2] != "b" {
+		t.Errorf("expected every entry of user 1 ordered by vault, got %v", got)
+	}
+}
+
+func TestMemoryVault_UsageStats(t *testing.T) {
+	repo := NewMemoryVaultRepository()
+	ctx := context.Background()
+
+	repo.Upsert(ctx, &model.VaultEntry{UserID: 1, VaultID: 1, EntryID: "a", EncryptedData: []byte("12345"), Version: 1})
+	repo.Upsert(ctx, &model.VaultEntry{UserID: 1, VaultID: 2, EntryID: "b", EncryptedData: []byte("123"), Version: 1})
+	repo.Upsert(ctx, &model.VaultEntry{UserID: 1, VaultID: 1, EntryID: "gone", EncryptedData: []byte("1234567"), Version: 1})
+	repo.Upsert(ctx, &model.VaultEntry{UserID: 2, VaultID: 3, EntryID: "other", EncryptedData: []byte("12"), Version: 1})
+	repo.SoftDelete(ctx, 1, 1, "gone")
+	past := time.Now().Add(-time.Minute)
+	repo.Upsert(ctx, &model.VaultEntry{UserID: 1, VaultID: 2, EntryID: "expired", EncryptedData: []byte("123456789"), Version: 1, ExpiresAt: &past})
+
+	usage, err := repo.UsageStats(ctx, 1)
+	if err != nil {
+		t.Fatalf("UsageStats() unexpected error: %v", err)
+	}
+	if want := (model.VaultUsage{Entries: 2, Bytes: 8}); usage != want {
+		t.Errorf("expected %+v for user 1's live entries across vaults, got %+v", want, usage)
+	}
+
+	if usage, _ := repo.UsageStats(ctx, 9); usage != (model.VaultUsage{}) {
+		t.Errorf("expected zero usage for a user with no entries, got %+v", usage)
+	}
+}
+
+func TestMemoryVault_ListManifestIncludesDeleted(t *testing.T) {
+	repo := NewMemoryVaultRepository()
+	ctx := context.Background()
+	past := time.Now().UTC().Add(-time.Minute)
+
+	repo.Upsert(ctx, &model.VaultEntry{UserID: 1, VaultID: 1, EntryID: "b", EncryptedData: []byte("x"), Version: 1})
+	repo.Upsert(ctx, &model.VaultEntry{UserID: 1, VaultID: 1, EntryID: "a", EncryptedData: []byte("x"), Version: 3})
+	repo.Upsert(ctx, &model.VaultEntry{UserID: 1, VaultID: 1, EntryID: "c", EncryptedData: []byte("x"), Version: 1, ExpiresAt: &past})
+	repo.Upsert(ctx, &model.VaultEntry{UserID: 1, VaultID: 2, EntryID: "other", EncryptedData: []byte("x"), Version: 1})
+	repo.SoftDelete(ctx, 1, 1, "b")
+
+	manifest, err := repo.ListManifest(ctx, 1, 1)
+	if err != nil {
+		t.Fatalf("ListManifest() unexpected error: %v", err)
+	}
+	want := []struct {
+		id      string
+		version int
+		deleted bool
+	}{{"a", 3, false}, {"b", 2, true}, {"c", 1, true}}
+	if len(manifest) != len(want) {
+		t.Fatalf("expected %d manifest entries, got %+v", len(want), manifest)
+	}
+	for i, w := range want {
+		if m := manifest[i]; m.EntryID != w.id || m.Version != w.version || m.Deleted != w.deleted {
+			t.Errorf("manifest[%d] = %+v, want %s v%d deleted=%v", i, m, w.id, w.version, w.deleted)
+		}
+	}
+}
+
+func TestMemoryVault_WipeByUserOnlyAffectsThatUser(t *testing.T) {
+	for _, hard := range []bool{false, true} {
+		repo := NewMemoryVaultRepository()
+		ctx := context.Background()
+
+		repo.Upsert(ctx, &model.VaultEntry{UserID: 1, VaultID: 1, EntryID: "a", EncryptedData: []byte("x"), Version: 1})
+		repo.Upsert(ctx, &model.VaultEntry{UserID: 1, VaultID: 2, EntryID: "b", EncryptedData: []byte("x"), Version: 4})
+		repo.Upsert(ctx, &model.VaultEntry{UserID: 2, VaultID: 3, EntryID: "a", EncryptedData: []byte("x"), Version: 1})
+
+		n, err := repo.WipeByUser(ctx, 1, hard)
+		if err != nil {
+			t.Fatalf("WipeByUser(hard=%v) unexpected error: %v", hard, err)
+		}
+		if n != 2 {
+			t.Errorf("WipeByUser(hard=%v) = %d, want 2", hard, n)
+		}
+
+		got, err := repo.GetByEntryID(ctx, 1, 2, "b")
+		switch {
+		case hard && err != ErrEntryNotFound:
+			t.Errorf("hard wipe: expected entry removed, got %v", err)
+		case !hard && (err != nil || !got.Deleted || got.Version != 5):
+			t.Errorf("soft wipe: expected deleted entry at version 5, got %+v, %v", got, err)
+		}
+
+		other, err := repo.GetByEntryID(ctx, 2, 3, "a")
+		if err != nil || other.Deleted || other.Version != 1 {
+			t.Errorf("hard=%v: another user's entry must be untouched, got %+v, %v", hard, other, err)
+		}
+	}
+}
+
+func TestMemoryVault_TxRollbackDiscardsWrites(t *testing.T) {
+	repo := NewMemoryVaultRepository()
+	ctx := context.Background()
+
+	tx, _ := repo.BeginTx(ctx)
+	if err := repo.UpsertTx(ctx, tx, &model.VaultEntry{UserID: 1, VaultID: 1, EntryID: "e1", EncryptedData: []byte("x"), Version: 1}); err != nil {
+		t.Fatalf("UpsertTx() unexpected error: %v", err)
+	}
+	tx.Rollback()
+
+	if _, err := repo.GetByEntryID(ctx, 1, 1, "e1"); err != ErrEntryNotFound {
+		t.Errorf("expected rolled-back write to be discarded, got %v", err)
+	}
+
+	tx, _ = repo.BeginTx(ctx)
+	repo.UpsertTx(ctx, tx, &model.VaultEntry{UserID: 1, VaultID: 1, EntryID: "e1", EncryptedData: []byte("x"), Version: 1})
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit() unexpected error: %v", err)
+	}
+	if _, err := repo.GetByEntryID(ctx, 1, 1, "e1"); err != nil {
+		t.Errorf("expected committed write to be visible, got %v", err)
+	}
+	if err := tx.Rollback(); err != ErrTxDone {
+		t.Errorf("expected ErrTxDone after commit, got %v", err)
+	}
+}
+
+func TestMemoryVault_DuplicateStats(t *testing.T) {
+	repo := NewMemoryVaultRepository()
+	ctx := context.Background()
+
+	for _, e := range []model.VaultEntry{
+		{UserID: 1, VaultID: 1, EntryID: "a", EncryptedData: []byte("same")},
+		{UserID: 1, VaultID: 2, EntryID: "b", EncryptedData: []byte("same")},
+		{UserID: 1, VaultID: 1, EntryID: "c", EncryptedData: []byte("same")},
+		{UserID: 1, VaultID: 1, EntryID: "d", EncryptedData: []byte("unique")},
+		{UserID: 1, VaultID: 1, EntryID: "e", EncryptedData: []byte("unique-deleted")},
+		{UserID: 1, VaultID: 1, EntryID: "f", EncryptedData: []byte("unique-deleted")},
+		// The same blob under another user is not a duplicate of user 1's.
+		{UserID: 2, VaultID: 3, EntryID: "a", EncryptedData: []byte("same")},
+	} {
+		e.Version = 1
+		repo.Upsert(ctx, &e)
+	}
+	repo.SoftDelete(ctx, 1, 1, "f")
+
+	stats, err := repo.DuplicateStats(ctx)
+	if err != nil {
+		t.Fatalf("DuplicateStats() unexpected error: %v", err)
+	}
+	want := model.DuplicateStats{Groups: 1, Entries: 3, Redundant: 2, Users: 1}
+	if stats != want {
+		t.Errorf("expected %+v, got %+v", want, stats)
+	}
+}
+
+func TestMemoryUser_DuplicateEmail(t *testing.T) {
+	repo := NewMemoryUserRepository()
+	ctx := context.Background()
+
+	if err := repo.Create(ctx, &model.User{Email: "a@example.com", AuthHash: "h"}); err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+	if err := repo.Create(ctx, &model.User{Email: "a@example.com", AuthHash: "h"}); err != ErrDuplicateEmail {
+		t.Errorf("expected ErrDuplicateEmail, got %v", err)
+	}
+	if _, err := repo.GetByEmail(ctx, "missing@example.com"); err != ErrUserNotFound {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/model"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionRepository handles login session persistence operations.
+type SessionRepository struct {
+	db *sql.DB
+}
+
+// NewSessionRepository creates a new SessionRepository.
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// Create inserts a new session. The session ID is generated by the caller.
+func (r *SessionRepository) Create(ctx context.Context, session *model.Session) error {
+	query := `INSERT INTO sessions (id, user_id, ip_address, user_agent, expires_at) VALUES (?, ?, ?, ?, ?)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		session.ID, session.UserID, session.IPAddress, session.UserAgent, session.ExpiresAt,
+	)
+	return err
+}
+
+// GetByID retrieves a session by its ID, including revoked and expired sessions.
+func (r *SessionRepository) GetByID(ctx context.Context, id string) (*model.Session, error) {
+	query := `SELECT id, user_id, ip_address, user_agent, created_at, last_seen_at, expires_at, revoked_at
+		FROM sessions WHERE id = ?`
+
+	session, err := scanSession(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// ListActiveByUser retrieves all unrevoked, unexpired sessions for a user, most recently seen first.
+func (r *SessionRepository) ListActiveByUser(ctx context.Context, userID int64) ([]model.Session, error) {
+	query := `SELECT id, user_id, ip_address, user_agent, created_at, last_seen_at, expires_at, revoked_at
+		FROM sessions WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+		ORDER BY last_seen_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []model.Session
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *s)
+	}
+
+	return sessions, rows.Err()
+}
+
+// Touch updates the last-seen timestamp of a session.
+func (r *SessionRepository) Touch(ctx context.Context, id string) error {
+	query := `UPDATE sessions SET last_seen_at = CURRENT_TIMESTAMP WHERE id = ?`
+
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// Revoke marks a user's session as revoked. Returns ErrSessionNotFound if the
+// session does not exist, belongs to another user, or is already revoked.
+func (r *SessionRepository) Revoke(ctx context.Context, userID int64, id string) error {
+	query := `UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ? AND revoked_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanSession scans a single session row.
+func scanSession(row rowScanner) (*model.Session, error) {
+	s := &model.Session{}
+	var revokedAt sql.NullTime
+	if err := row.Scan(
+		&s.ID, &s.UserID, &s.IPAddress, &s.UserAgent,
+		&s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt, &revokedAt,
+	); err != nil {
+		return nil, err
+	}
+	if revokedAt.Valid {
+		s.RevokedAt = &revokedAt.Time
+	}
+	return s, nil
+}
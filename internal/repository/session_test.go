@@ -0,0 +1,19 @@
+package repository
+
+import "testing"
+
+func TestNewSessionRepository(t *testing.T) {
+	repo := NewSessionRepository(nil)
+	if repo == nil {
+		t.Fatal("expected non-nil SessionRepository")
+	}
+	if repo.db != nil {
+		t.Fatal("expected nil db when constructed with nil")
+	}
+}
+
+func TestErrSessionNotFound(t *testing.T) {
+	if ErrSessionNotFound.Error() != "session not found" {
+		t.Fatalf("unexpected error message: %s", ErrSessionNotFound.Error())
+	}
+}
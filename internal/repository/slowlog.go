@@ -0,0 +1,377 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/model"
+)
+
+// SlowQueryLog wraps stores so that any call running longer than a threshold
+// is logged with its name and duration. Only the method name is logged, never
+// the SQL or its arguments, so vault data can't leak into logs.
+type SlowQueryLog struct {
+	threshold time.Duration
+}
+
+// NewSlowQueryLog returns a SlowQueryLog that warns about calls taking at
+// least threshold. A threshold <= 0 leaves stores unwrapped.
+func NewSlowQueryLog(threshold time.Duration) *SlowQueryLog {
+	return &SlowQueryLog{threshold: threshold}
+}
+
+// observe logs the call named query if it has run for at least the threshold
+// since start. Use it as: defer l.observe(ctx, "vault.Upsert", time.Now()).
+func (l *SlowQueryLog) observe(ctx context.Context, query string, start time.Time) {
+	if d := time.Since(start); d >= l.threshold {
+		slog.WarnContext(ctx, "slow query", "query", query, "duration", d, "threshold", l.threshold)
+	}
+}
+
+func (l *SlowQueryLog) enabled() bool {
+	return l != nil && l.threshold > 0
+}
+
+// Users wraps s with slow-call logging.
+func (l *SlowQueryLog) Users(s UserStore) UserStore {
+	if !l.enabled() {
+		return s
+	}
+	return &slowUserStore{next: s, log: l}
+}
+
+// Collections wraps s with slow-call logging.
+func (l *SlowQueryLog) Collections(s CollectionStore) CollectionStore {
+	if !l.enabled() {
+		return s
+	}
+	return &slowCollectionStore{next: s, log: l}
+}
+
+// Vault wraps s with slow-call logging.
+func (l *SlowQueryLog) Vault(s VaultStore) VaultStore {
+	if !l.enabled() {
+		return s
+	}
+	return &slowVaultStore{next: s, log: l}
+}
+
+// Audit wraps s with slow-call logging.
+func (l *SlowQueryLog) Audit(s AuditStore) AuditStore {
+	if !l.enabled() {
+		return s
+	}
+	return &slowAuditStore{next: s, log: l}
+}
+
+// Sessions wraps s with slow-call logging.
+func (l *SlowQueryLog) Sessions(s SessionStore) SessionStore {
+	if !l.enabled() {
+		return s
+	}
+	return &slowSessionStore{next: s, log: l}
+}
+
+// Invites wraps s with slow-call logging.
+func (l *SlowQueryLog) Invites(s InviteStore) InviteStore {
+	if !l.enabled() {
+		return s
+	}
+	return &slowInviteStore{next: s, log: l}
+}
+
+type slowUserStore struct {
+	next UserStore
+	log  *SlowQueryLog
+}
+
+func (s *slowUserStore) Create(ctx context.Context, user *model.User) error {
+	defer s.log.observe(ctx, "users.Create", time.Now())
+	return s.next.Create(ctx, user)
+}
+
+func (s *slowUserStore) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	defer s.log.observe(ctx, "users.GetByEmail", time.Now())
+	return s.next.GetByEmail(ctx, email)
+}
+
+func (s *slowUserStore) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	defer s.log.observe(ctx, "users.GetByID", time.Now())
+	return s.next.GetByID(ctx, id)
+}
+
+func (s *slowUserStore) SetDeactivatedAt(ctx context.Context, id int64, at *time.Time) error {
+	defer s.log.observe(ctx, "users.SetDeactivatedAt", time.Now())
+	return s.next.SetDeactivatedAt(ctx, id, at)
+}
+
+func (s *slowUserStore) BumpTokenEpoch(ctx context.Context, id int64) (int64, error) {
+	defer s.log.observe(ctx, "users.BumpTokenEpoch", time.Now())
+	return s.next.BumpTokenEpoch(ctx, id)
+}
+
+func (s *slowUserStore) BumpRekeyVersion(ctx context.Context, id int64) (int64, error) {
+	defer s.log.observe(ctx, "users.BumpRekeyVersion", time.Now())
+	return s.next.BumpRekeyVersion(ctx, id)
+}
+
+func (s *slowUserStore) GetKDFProfile(ctx context.Context, id int64) ([]byte, error) {
+	defer s.log.observe(ctx, "users.GetKDFProfile", time.Now())
+	return s.next.GetKDFProfile(ctx, id)
+}
+
+func (s *slowUserStore) SetKDFProfile(ctx context.Context, id int64, profile []byte) error {
+	defer s.log.observe(ctx, "users.SetKDFProfile", time.Now())
+	return s.next.SetKDFProfile(ctx, id, profile)
+}
+
+func (s *slowUserStore) ListUsers(ctx context.Context, filter model.UserListFilter) ([]model.User, error) {
+	defer s.log.observe(ctx, "users.ListUsers", time.Now())
+	return s.next.ListUsers(ctx, filter)
+}
+
+type slowCollectionStore struct {
+	next CollectionStore
+	log  *SlowQueryLog
+}
+
+func (s *slowCollectionStore) Create(ctx context.Context, vault *model.Vault) error {
+	defer s.log.observe(ctx, "vaults.Create", time.Now())
+	return s.next.Create(ctx, vault)
+}
+
+func (s *slowCollectionStore) EnsureDefault(ctx context.Context, userID int64) (*model.Vault, error) {
+	defer s.log.observe(ctx, "vaults.EnsureDefault", time.Now())
+	return s.next.EnsureDefault(ctx, userID)
+}
+
+func (s *slowCollectionStore) GetByID(ctx context.Context, userID, id int64) (*model.Vault, error) {
+	defer s.log.observe(ctx, "vaults.GetByID", time.Now())
+	return s.next.GetByID(ctx, userID, id)
+}
+
+func (s *slowCollectionStore) ListByUser(ctx context.Context, userID int64) ([]model.Vault, error) {
+	defer s.log.observe(ctx, "vaults.ListByUser", time.Now())
+	return s.next.ListByUser(ctx, userID)
+}
+
+func (s *slowCollectionStore) Rename(ctx context.Context, userID, id int64, name string) error {
+	defer s.log.observe(ctx, "vaults.Rename", time.Now())
+	return s.next.Rename(ctx, userID, id, name)
+}
+
+func (s *slowCollectionStore) Delete(ctx context.Context, userID, id int64) error {
+	defer s.log.observe(ctx, "vaults.Delete", time.Now())
+	return s.next.Delete(ctx, userID, id)
+}
+
+type slowVaultStore struct {
+	next VaultStore
+	log  *SlowQueryLog
+}
+
+func (s *slowVaultStore) BeginTx(ctx context.Context) (Tx, error) {
+	defer s.log.observe(ctx, "vault.BeginTx", time.Now())
+	return s.next.BeginTx(ctx)
+}
+
+func (s *slowVaultStore) Upsert(ctx context.Context, entry *model.VaultEntry) error {
+	defer s.log.observe(ctx, "vault.Upsert", time.Now())
+	return s.next.Upsert(ctx, entry)
+}
+
+func (s *slowVaultStore) UpsertTx(ctx context.Context, tx Tx, entry *model.VaultEntry) error {
+	defer s.log.observe(ctx, "vault.UpsertTx", time.Now())
+	return s.next.UpsertTx(ctx, tx, entry)
+}
+
+func (s *slowVaultStore) UpsertWithCreatedAt(ctx context.Context, entry *model.VaultEntry) error {
+	defer s.log.observe(ctx, "vault.UpsertWithCreatedAt", time.Now())
+	return s.next.UpsertWithCreatedAt(ctx, entry)
+}
+
+func (s *slowVaultStore) UpdateIfVersion(ctx context.Context, entry *model.VaultEntry, expectedVersion int) error {
+	defer s.log.observe(ctx, "vault.UpdateIfVersion", time.Now())
+	return s.next.UpdateIfVersion(ctx, entry, expectedVersion)
+}
+
+func (s *slowVaultStore) GetByEntryID(ctx context.Context, userID, vaultID int64, entryID string) (*model.VaultEntry, error) {
+	defer s.log.observe(ctx, "vault.GetByEntryID", time.Now())
+	return s.next.GetByEntryID(ctx, userID, vaultID, entryID)
+}
+
+func (s *slowVaultStore) GetByEntryIDForUpdateTx(ctx context.Context, tx Tx, userID, vaultID int64, entryID string) (*model.VaultEntry, error) {
+	defer s.log.observe(ctx, "vault.GetByEntryIDForUpdateTx", time.Now())
+	return s.next.GetByEntryIDForUpdateTx(ctx, tx, userID, vaultID, entryID)
+}
+
+func (s *slowVaultStore) GetByEntryIDs(ctx context.Context, userID, vaultID int64, entryIDs []string) ([]model.VaultEntry, error) {
+	defer s.log.observe(ctx, "vault.GetByEntryIDs", time.Now())
+	return s.next.GetByEntryIDs(ctx, userID, vaultID, entryIDs)
+}
+
+func (s *slowVaultStore) ListByUser(ctx context.Context, userID, vaultID int64) ([]model.VaultEntry, error) {
+	defer s.log.observe(ctx, "vault.ListByUser", time.Now())
+	return s.next.ListByUser(ctx, userID, vaultID)
+}
+
+func (s *slowVaultStore) ListFavorites(ctx context.Context, userID, vaultID int64) ([]model.VaultEntry, error) {
+	defer s.log.observe(ctx, "vault.ListFavorites", time.Now())
+	return s.next.ListFavorites(ctx, userID, vaultID)
+}
+
+func (s *slowVaultStore) ListDeleted(ctx context.Context, userID, vaultID int64) ([]model.VaultEntry, error) {
+	defer s.log.observe(ctx, "vault.ListDeleted", time.Now())
+	return s.next.ListDeleted(ctx, userID, vaultID)
+}
+
+func (s *slowVaultStore) GetChangedSince(ctx context.Context, userID, vaultID int64, filter model.ChangeFilter) ([]model.VaultEntry, error) {
+	defer s.log.observe(ctx, "vault.GetChangedSince", time.Now())
+	return s.next.GetChangedSince(ctx, userID, vaultID, filter)
+}
+
+func (s *slowVaultStore) ListManifest(ctx context.Context, userID, vaultID int64) ([]model.ManifestEntry, error) {
+	defer s.log.observe(ctx, "vault.ListManifest", time.Now())
+	return s.next.ListManifest(ctx, userID, vaultID)
+}
+
+func (s *slowVaultStore) ListAllByUser(ctx context.Context, userID int64) ([]model.VaultEntry, error) {
+	defer s.log.observe(ctx, "vault.ListAllByUser", time.Now())
+	return s.next.ListAllByUser(ctx, userID)
+}
+
+func (s *slowVaultStore) TouchAccessed(ctx context.Context, userID, vaultID int64, entryIDs []string, at time.Time) error {
+	defer s.log.observe(ctx, "vault.TouchAccessed", time.Now())
+	return s.next.TouchAccessed(ctx, userID, vaultID, entryIDs, at)
+}
+
+func (s *slowVaultStore) SoftDelete(ctx context.Context, userID, vaultID int64, entryID string) error {
+	defer s.log.observe(ctx, "vault.SoftDelete", time.Now())
+	return s.next.SoftDelete(ctx, userID, vaultID, entryID)
+}
+
+func (s *slowVaultStore) SoftDeleteWithReason(ctx context.Context, userID, vaultID int64, entryID, reason string) error {
+	defer s.log.observe(ctx, "vault.SoftDeleteWithReason", time.Now())
+	return s.next.SoftDeleteWithReason(ctx, userID, vaultID, entryID, reason)
+}
+
+func (s *slowVaultStore) Restore(ctx context.Context, userID, vaultID int64, entryID string) error {
+	defer s.log.observe(ctx, "vault.Restore", time.Now())
+	return s.next.Restore(ctx, userID, vaultID, entryID)
+}
+
+func (s *slowVaultStore) WipeByUser(ctx context.Context, userID int64, hard bool) (int64, error) {
+	defer s.log.observe(ctx, "vault.WipeByUser", time.Now())
+	return s.next.WipeByUser(ctx, userID, hard)
+}
+
+func (s *slowVaultStore) StageConflictTx(ctx context.Context, tx Tx, conflict *model.VaultConflict) error {
+	defer s.log.observe(ctx, "vault.StageConflictTx", time.Now())
+	return s.next.StageConflictTx(ctx, tx, conflict)
+}
+
+func (s *slowVaultStore) ClearConflictsTx(ctx context.Context, tx Tx, userID, vaultID int64, entryID string) error {
+	defer s.log.observe(ctx, "vault.ClearConflictsTx", time.Now())
+	return s.next.ClearConflictsTx(ctx, tx, userID, vaultID, entryID)
+}
+
+func (s *slowVaultStore) ListConflicts(ctx context.Context, userID, vaultID int64) ([]model.VaultConflict, error) {
+	defer s.log.observe(ctx, "vault.ListConflicts", time.Now())
+	return s.next.ListConflicts(ctx, userID, vaultID)
+}
+
+func (s *slowVaultStore) AckSync(ctx context.Context, userID, vaultID int64, deviceID string, cursor *time.Time) error {
+	defer s.log.observe(ctx, "vault.AckSync", time.Now())
+	return s.next.AckSync(ctx, userID, vaultID, deviceID, cursor)
+}
+
+func (s *slowVaultStore) MinSyncAck(ctx context.Context, userID, vaultID int64) (time.Time, error) {
+	defer s.log.observe(ctx, "vault.MinSyncAck", time.Now())
+	return s.next.MinSyncAck(ctx, userID, vaultID)
+}
+
+func (s *slowVaultStore) PurgeTombstones(ctx context.Context, userID, vaultID int64, through time.Time) (int64, error) {
+	defer s.log.observe(ctx, "vault.PurgeTombstones", time.Now())
+	return s.next.PurgeTombstones(ctx, userID, vaultID, through)
+}
+
+func (s *slowVaultStore) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	defer s.log.observe(ctx, "vault.PurgeExpired", time.Now())
+	return s.next.PurgeExpired(ctx, now)
+}
+
+func (s *slowVaultStore) DuplicateStats(ctx context.Context) (model.DuplicateStats, error) {
+	defer s.log.observe(ctx, "vault.DuplicateStats", time.Now())
+	return s.next.DuplicateStats(ctx)
+}
+
+func (s *slowVaultStore) UsageStats(ctx context.Context, userID int64) (model.VaultUsage, error) {
+	defer s.log.observe(ctx, "vault.UsageStats", time.Now())
+	return s.next.UsageStats(ctx, userID)
+}
+
+type slowAuditStore struct {
+	next AuditStore
+	log  *SlowQueryLog
+}
+
+func (s *slowAuditStore) RecordLogin(ctx context.Context, event *model.LoginEvent) error {
+	defer s.log.observe(ctx, "audit.RecordLogin", time.Now())
+	return s.next.RecordLogin(ctx, event)
+}
+
+func (s *slowAuditStore) ListLoginsByUser(ctx context.Context, userID int64, filter model.LoginEventFilter) ([]model.LoginEvent, error) {
+	defer s.log.observe(ctx, "audit.ListLoginsByUser", time.Now())
+	return s.next.ListLoginsByUser(ctx, userID, filter)
+}
+
+type slowSessionStore struct {
+	next SessionStore
+	log  *SlowQueryLog
+}
+
+func (s *slowSessionStore) Create(ctx context.Context, session *model.Session) error {
+	defer s.log.observe(ctx, "sessions.Create", time.Now())
+	return s.next.Create(ctx, session)
+}
+
+func (s *slowSessionStore) GetByID(ctx context.Context, id string) (*model.Session, error) {
+	defer s.log.observe(ctx, "sessions.GetByID", time.Now())
+	return s.next.GetByID(ctx, id)
+}
+
+func (s *slowSessionStore) ListActiveByUser(ctx context.Context, userID int64) ([]model.Session, error) {
+	defer s.log.observe(ctx, "sessions.ListActiveByUser", time.Now())
+	return s.next.ListActiveByUser(ctx, userID)
+}
+
+func (s *slowSessionStore) Touch(ctx context.Context, id string) error {
+	defer s.log.observe(ctx, "sessions.Touch", time.Now())
+	return s.next.Touch(ctx, id)
+}
+
+func (s *slowSessionStore) Revoke(ctx context.Context, userID int64, id string) error {
+	defer s.log.observe(ctx, "sessions.Revoke", time.Now())
+	return s.next.Revoke(ctx, userID, id)
+}
+
+type slowInviteStore struct {
+	next InviteStore
+	log  *SlowQueryLog
+}
+
+func (s *slowInviteStore) Create(ctx context.Context, invite *model.Invite) error {
+	defer s.log.observe(ctx, "invites.Create", time.Now())
+	return s.next.Create(ctx, invite)
+}
+
+func (s *slowInviteStore) Claim(ctx context.Context, codeHash, email string, now time.Time) error {
+	defer s.log.observe(ctx, "invites.Claim", time.Now())
+	return s.next.Claim(ctx, codeHash, email, now)
+}
+
+func (s *slowInviteStore) Release(ctx context.Context, codeHash string) error {
+	defer s.log.observe(ctx, "invites.Release", time.Now())
+	return s.next.Release(ctx, codeHash)
+}
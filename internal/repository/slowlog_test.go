@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/model"
+)
+
+// sleepyUserStore is a UserStore whose GetByID takes delay.
+type sleepyUserStore struct {
+	UserStore
+	delay time.Duration
+}
+
+func (s *sleepyUserStore) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	time.Sleep(s.delay)
+	return &model.User{ID: id, Email: "secret@example.com"}, nil
+}
+
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	original := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(original) })
+	return &buf
+}
+
+func TestSlowQueryLog_LogsSlowCall(t *testing.T) {
+	logs := captureLogs(t)
+	users := NewSlowQueryLog(10 * time.Millisecond).Users(&sleepyUserStore{delay: 30 * time.Millisecond})
+
+	if _, err := users.GetByID(context.Background(), 42); err != nil {
+		t.Fatalf("GetByID() unexpected error: %v", err)
+	}
+
+	out := logs.String()
+	if !strings.Contains(out, "slow query") || !strings.Contains(out, "query=users.GetByID") || !strings.Contains(out, "duration=") {
+		t.Errorf("expected a slow query warning with name and duration, got %q", out)
+	}
+	if strings.Contains(out, "secret@example.com") {
+		t.Errorf("expected no results in the log, got %q", out)
+	}
+}
+
+func TestSlowQueryLog_IgnoresFastCall(t *testing.T) {
+	logs := captureLogs(t)
+	users := NewSlowQueryLog(time.Second).Users(&sleepyUserStore{})
+
+	if _, err := users.GetByID(context.Background(), 1); err != nil {
+		t.Fatalf("GetByID() unexpected error: %v", err)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("expected no log for a fast call, got %q", logs.String())
+	}
+}
+
+func TestSlowQueryLog_DisabledLeavesStoreUnwrapped(t *testing.T) {
+	store := NewMemoryVaultRepository()
+	if got := NewSlowQueryLog(0).Vault(store); got != VaultStore(store) {
+		t.Errorf("expected a zero threshold to return the store as is, got %T", got)
+	}
+}
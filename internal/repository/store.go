@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/model"
+)
+
+var (
+	// ErrForeignTx is returned when a store is handed a transaction it did not begin.
+	ErrForeignTx = errors.New("transaction was not started by this store")
+	// ErrTxDone is returned when a transaction is used after Commit or Rollback.
+	ErrTxDone = errors.New("transaction has already been committed or rolled back")
+)
+
+// Tx is a unit of work started by a store's BeginTx.
+type Tx interface {
+	Commit() error
+	Rollback() error
+}
+
+// UserStore persists user accounts.
+type UserStore interface {
+	Create(ctx context.Context, user *model.User) error
+	GetByEmail(ctx context.Context, email string) (*model.User, error)
+	GetByID(ctx context.Context, id int64) (*model.User, error)
+	SetDeactivatedAt(ctx context.Context, id int64, at *time.Time) error
+	BumpTokenEpoch(ctx context.Context, id int64) (int64, error)
+	BumpRekeyVersion(ctx context.Context, id int64) (int64, error)
+	// GetKDFProfile returns the user's stored key derivation profile as JSON,
+	// or nil if none has been stored.
+	GetKDFProfile(ctx context.Context, id int64) ([]byte, error)
+	SetKDFProfile(ctx context.Context, id int64, profile []byte) error
+	// ListUsers returns a page of users, oldest first. AuthHash is never loaded.
+	ListUsers(ctx context.Context, filter model.UserListFilter) ([]model.User, error)
+}
+
+// CollectionStore persists a user's vaults, the named collections that group entries.
+type CollectionStore interface {
+	Create(ctx context.Context, vault *model.Vault) error
+	EnsureDefault(ctx context.Context, userID int64) (*model.Vault, error)
+	GetByID(ctx context.Context, userID, id int64) (*model.Vault, error)
+	ListByUser(ctx context.Context, userID int64) ([]model.Vault, error)
+	Rename(ctx context.Context, userID, id int64, name string) error
+	Delete(ctx context.Context, userID, id int64) error
+}
+
+// VaultStore persists encrypted vault entries with last-write-wins versioning.
+// Entries are scoped by (user ID, vault ID).
+type VaultStore interface {
+	BeginTx(ctx context.Context) (Tx, error)
+	Upsert(ctx context.Context, entry *model.VaultEntry) error
+	UpsertTx(ctx context.Context, tx Tx, entry *model.VaultEntry) error
+	UpsertWithCreatedAt(ctx context.Context, entry *model.VaultEntry) error
+	UpdateIfVersion(ctx context.Context, entry *model.VaultEntry, expectedVersion int) error
+	GetByEntryID(ctx context.Context, userID, vaultID int64, entryID string) (*model.VaultEntry, error)
+	// GetByEntryIDForUpdateTx is GetByEntryID within tx, locking the row until tx ends.
+	GetByEntryIDForUpdateTx(ctx context.Context, tx Tx, userID, vaultID int64, entryID string) (*model.VaultEntry, error)
+	GetByEntryIDs(ctx context.Context, userID, vaultID int64, entryIDs []string) ([]model.VaultEntry, error)
+	ListByUser(ctx context.Context, userID, vaultID int64) ([]model.VaultEntry, error)
+	ListFavorites(ctx context.Context, userID, vaultID int64) ([]model.VaultEntry, error)
+	ListDeleted(ctx context.Context, userID, vaultID int64) ([]model.VaultEntry, error)
+	GetChangedSince(ctx context.Context, userID, vaultID int64, filter model.ChangeFilter) ([]model.VaultEntry, error)
+	ListManifest(ctx context.Context, userID, vaultID int64) ([]model.ManifestEntry, error)
+	// ListAllByUser returns every stored entry across all of the user's vaults.
+	ListAllByUser(ctx context.Context, userID int64) ([]model.VaultEntry, error)
+	// TouchAccessed records when entries were last read without changing updated_at.
+	TouchAccessed(ctx context.Context, userID, vaultID int64, entryIDs []string, at time.Time) error
+	SoftDelete(ctx context.Context, userID, vaultID int64, entryID string) error
+	SoftDeleteWithReason(ctx context.Context, userID, vaultID int64, entryID, reason string) error
+	Restore(ctx context.Context, userID, vaultID int64, entryID string) error
+	WipeByUser(ctx context.Context, userID int64, hard bool) (int64, error)
+
+	// StageConflictTx stages a losing write unless one with the same entry ID and
+	// version is already staged.
+	StageConflictTx(ctx context.Context, tx Tx, conflict *model.VaultConflict) error
+	ClearConflictsTx(ctx context.Context, tx Tx, userID, vaultID int64, entryID string) error
+	ListConflicts(ctx context.Context, userID, vaultID int64) ([]model.VaultConflict, error)
+
+	// AckSync records that a device synced the vault and, when cursor is set,
+	// that it has applied every change up to it.
+	AckSync(ctx context.Context, userID, vaultID int64, deviceID string, cursor *time.Time) error
+	// MinSyncAck returns the oldest cursor acknowledged by any device that has
+	// synced the vault, or the zero time if none has, or if any device has
+	// never acknowledged one.
+	MinSyncAck(ctx context.Context, userID, vaultID int64) (time.Time, error)
+	PurgeTombstones(ctx context.Context, userID, vaultID int64, through time.Time) (int64, error)
+	PurgeExpired(ctx context.Context, now time.Time) (int64, error)
+	DuplicateStats(ctx context.Context) (model.DuplicateStats, error)
+	// UsageStats counts the user's live entries and their stored blob bytes across all vaults.
+	UsageStats(ctx context.Context, userID int64) (model.VaultUsage, error)
+}
+
+// AuditStore persists the login audit log.
+type AuditStore interface {
+	RecordLogin(ctx context.Context, event *model.LoginEvent) error
+	ListLoginsByUser(ctx context.Context, userID int64, filter model.LoginEventFilter) ([]model.LoginEvent, error)
+}
+
+// InviteStore persists single-use registration invite codes, keyed by code hash.
+// Claim marks a code used atomically, so two registrations can't share one.
+// Release undoes a claim whose registration failed.
+type InviteStore interface {
+	Create(ctx context.Context, invite *model.Invite) error
+	Claim(ctx context.Context, codeHash, email string, now time.Time) error
+	Release(ctx context.Context, codeHash string) error
+}
+
+// SessionStore persists server-side login sessions.
+type SessionStore interface {
+	Create(ctx context.Context, session *model.Session) error
+	GetByID(ctx context.Context, id string) (*model.Session, error)
+	ListActiveByUser(ctx context.Context, userID int64) ([]model.Session, error)
+	Touch(ctx context.Context, id string) error
+	Revoke(ctx context.Context, userID int64, id string) error
+}
+
+var (
+	_ UserStore       = (*UserRepository)(nil)
+	_ CollectionStore = (*CollectionRepository)(nil)
+	_ VaultStore      = (*VaultRepository)(nil)
+	_ AuditStore      = (*AuditRepository)(nil)
+	_ SessionStore    = (*SessionRepository)(nil)
+)
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"strings"
+	"time"
 
 	"github.com/vaultpass/vaultpass-go/internal/model"
 )
@@ -47,11 +48,12 @@ func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
 
 // GetByEmail retrieves a user by their email address.
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
-	query := `SELECT id, email, auth_hash, created_at, updated_at FROM users WHERE email = ?`
+	query := `SELECT id, email, auth_hash, created_at, updated_at, deactivated_at, token_epoch, rekey_version FROM users WHERE email = ?`
 
 	user := &model.User{}
+	var deactivatedAt sql.NullTime
 	err := r.db.QueryRowContext(ctx, query, email).Scan(
-		&user.ID, &user.Email, &user.AuthHash, &user.CreatedAt, &user.UpdatedAt,
+		&user.ID, &user.Email, &user.AuthHash, &user.CreatedAt, &user.UpdatedAt, &deactivatedAt, &user.TokenEpoch, &user.RekeyVersion,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -59,17 +61,21 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*model.U
 		}
 		return nil, err
 	}
+	if deactivatedAt.Valid {
+		user.DeactivatedAt = &deactivatedAt.Time
+	}
 
 	return user, nil
 }
 
 // GetByID retrieves a user by their ID.
 func (r *UserRepository) GetByID(ctx context.Context, id int64) (*model.User, error) {
-	query := `SELECT id, email, auth_hash, created_at, updated_at FROM users WHERE id = ?`
+	query := `SELECT id, email, auth_hash, created_at, updated_at, deactivated_at, token_epoch, rekey_version FROM users WHERE id = ?`
 
 	user := &model.User{}
+	var deactivatedAt sql.NullTime
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&user.ID, &user.Email, &user.AuthHash, &user.CreatedAt, &user.UpdatedAt,
+		&user.ID, &user.Email, &user.AuthHash, &user.CreatedAt, &user.UpdatedAt, &deactivatedAt, &user.TokenEpoch, &user.RekeyVersion,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -77,10 +83,152 @@ func (r *UserRepository) GetByID(ctx context.Context, id int64) (*model.User, er
 		}
 		return nil, err
 	}
+	if deactivatedAt.Valid {
+		user.DeactivatedAt = &deactivatedAt.Time
+	}
 
 	return user, nil
 }
 
+// SetDeactivatedAt marks a user as deactivated at the given time, or reactivates them when at is nil.
+func (r *UserRepository) SetDeactivatedAt(ctx context.Context, id int64, at *time.Time) error {
+	query := `UPDATE users SET deactivated_at = ? WHERE id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, at, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		// MySQL reports zero rows when the value is unchanged, so confirm the user exists.
+		_, err := r.GetByID(ctx, id)
+		return err
+	}
+	return nil
+}
+
+// BumpTokenEpoch increments the user's token epoch and returns the new value.
+func (r *UserRepository) BumpTokenEpoch(ctx context.Context, id int64) (int64, error) {
+	query := `UPDATE users SET token_epoch = token_epoch + 1 WHERE id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, ErrUserNotFound
+	}
+
+	user, err := r.GetByID(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	return user.TokenEpoch, nil
+}
+
+// BumpRekeyVersion increments the user's rekey version and returns the new value.
+func (r *UserRepository) BumpRekeyVersion(ctx context.Context, id int64) (int64, error) {
+	query := `UPDATE users SET rekey_version = rekey_version + 1 WHERE id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, ErrUserNotFound
+	}
+
+	user, err := r.GetByID(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	return user.RekeyVersion, nil
+}
+
+// GetKDFProfile returns the user's stored key derivation profile as JSON, or nil if none has been stored.
+func (r *UserRepository) GetKDFProfile(ctx context.Context, id int64) ([]byte, error) {
+	query := `SELECT kdf_profile FROM users WHERE id = ?`
+
+	var profile []byte
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&profile)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return profile, nil
+}
+
+// SetKDFProfile replaces the user's key derivation profile.
+func (r *UserRepository) SetKDFProfile(ctx context.Context, id int64, profile []byte) error {
+	query := `UPDATE users SET kdf_profile = ? WHERE id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, profile, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		// MySQL reports zero rows when the value is unchanged, so confirm the user exists.
+		_, err := r.GetByID(ctx, id)
+		return err
+	}
+	return nil
+}
+
+// ListUsers retrieves a page of users ordered by creation time, then ID. The
+// auth hash column is deliberately not selected.
+func (r *UserRepository) ListUsers(ctx context.Context, filter model.UserListFilter) ([]model.User, error) {
+	query := `SELECT id, email, created_at, updated_at, deactivated_at, token_epoch, rekey_version FROM users`
+	var args []any
+
+	if c := filter.After; c != nil {
+		query += ` WHERE created_at > ? OR (created_at = ? AND id > ?)`
+		args = append(args, c.CreatedAt, c.CreatedAt, c.ID)
+	}
+	query += ` ORDER BY created_at ASC, id ASC LIMIT ?`
+	args = append(args, filter.Limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []model.User
+	for rows.Next() {
+		var u model.User
+		var deactivatedAt sql.NullTime
+		if err := rows.Scan(&u.ID, &u.Email, &u.CreatedAt, &u.UpdatedAt, &deactivatedAt, &u.TokenEpoch, &u.RekeyVersion); err != nil {
+			return nil, err
+		}
+		if deactivatedAt.Valid {
+			u.DeactivatedAt = &deactivatedAt.Time
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
 // isDuplicateEntryError checks if a MySQL error is a duplicate entry error (code 1062).
 func isDuplicateEntryError(err error) bool {
 	return err != nil && strings.Contains(err.Error(), "Duplicate entry")
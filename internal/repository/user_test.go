@@ -1,7 +1,11 @@
 package repository
 
 import (
+	"context"
 	"testing"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/model"
 )
 
 func TestNewUserRepository(t *testing.T) {
@@ -37,3 +41,96 @@ func TestIsDuplicateEntryError(t *testing.T) {
 		t.Fatal("ErrUserNotFound should not be a duplicate entry error")
 	}
 }
+
+func TestMemoryUserRepository_SetDeactivatedAt(t *testing.T) {
+	repo := NewMemoryUserRepository()
+	ctx := context.Background()
+
+	user := &model.User{Email: "a@example.com", AuthHash: "hash"}
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+
+	at := time.Now().UTC()
+	if err := repo.SetDeactivatedAt(ctx, user.ID, &at); err != nil {
+		t.Fatalf("SetDeactivatedAt() unexpected error: %v", err)
+	}
+	got, err := repo.GetByEmail(ctx, "a@example.com")
+	if err != nil {
+		t.Fatalf("GetByEmail() unexpected error: %v", err)
+	}
+	if got.Active() || !got.DeactivatedAt.Equal(at) {
+		t.Fatalf("expected user deactivated at %v, got %v", at, got.DeactivatedAt)
+	}
+
+	if err := repo.SetDeactivatedAt(ctx, user.ID, nil); err != nil {
+		t.Fatalf("SetDeactivatedAt(nil) unexpected error: %v", err)
+	}
+	if got, _ := repo.GetByID(ctx, user.ID); !got.Active() {
+		t.Errorf("expected user to be active after reactivation, got %v", got.DeactivatedAt)
+	}
+
+	if err := repo.SetDeactivatedAt(ctx, 999, &at); err != ErrUserNotFound {
+		t.Errorf("expected ErrUserNotFound for unknown user, got %v", err)
+	}
+}
+
+func TestMemoryUserRepository_BumpTokenEpoch(t *testing.T) {
+	repo := NewMemoryUserRepository()
+	ctx := context.Background()
+
+	user := &model.User{Email: "a@example.com", AuthHash: "hash"}
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+
+	for want := int64(1); want <= 2; want++ {
+		epoch, err := repo.BumpTokenEpoch(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("BumpTokenEpoch() unexpected error: %v", err)
+		}
+		if epoch != want {
+			t.Errorf("expected epoch %d, got %d", want, epoch)
+		}
+	}
+	if got, _ := repo.GetByEmail(ctx, "a@example.com"); got.TokenEpoch != 2 {
+		t.Errorf("expected stored epoch 2, got %d", got.TokenEpoch)
+	}
+
+	if _, err := repo.BumpTokenEpoch(ctx, 999); err != ErrUserNotFound {
+		t.Errorf("expected ErrUserNotFound for unknown user, got %v", err)
+	}
+}
+
+func TestMemoryUserRepository_ListUsers(t *testing.T) {
+	repo := NewMemoryUserRepository()
+	ctx := context.Background()
+
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		if err := repo.Create(ctx, &model.User{Email: email, AuthHash: "hash"}); err != nil {
+			t.Fatalf("Create() unexpected error: %v", err)
+		}
+	}
+
+	first, err := repo.ListUsers(ctx, model.UserListFilter{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListUsers() unexpected error: %v", err)
+	}
+	if len(first) != 2 || first[0].Email != "a@example.com" || first[1].Email != "b@example.com" {
+		t.Fatalf("expected the two oldest users, got %+v", first)
+	}
+	for _, u := range first {
+		if u.AuthHash != "" {
+			t.Errorf("expected AuthHash to be left out, got %q", u.AuthHash)
+		}
+	}
+
+	last := first[1]
+	rest, err := repo.ListUsers(ctx, model.UserListFilter{Limit: 2, After: &model.UserCursor{CreatedAt: last.CreatedAt, ID: last.ID}})
+	if err != nil {
+		t.Fatalf("ListUsers() unexpected error: %v", err)
+	}
+	if len(rest) != 1 || rest[0].Email != "c@example.com" {
+		t.Errorf("expected only the user after the cursor, got %+v", rest)
+	}
+}
@@ -2,75 +2,245 @@ package repository
 
 import (
 	"context"
+	"crypto/cipher"
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/vaultpass/vaultpass-go/internal/model"
 )
 
-var ErrEntryNotFound = errors.New("vault entry not found")
+var (
+	ErrEntryNotFound   = errors.New("vault entry not found")
+	ErrVersionMismatch = errors.New("vault entry version does not match")
+	ErrEntryErased     = errors.New("vault entry was erased when deleted")
+)
 
 // VaultRepository handles vault entry persistence operations.
 type VaultRepository struct {
-	db *sql.DB
+	db            *sql.DB
+	codec         blobCodec
+	eraseOnDelete bool
+}
+
+// VaultOption configures how a VaultRepository stores encrypted blobs.
+type VaultOption func(*VaultRepository)
+
+// WithCompression gzip-compresses encrypted_data before it is written.
+// Rows written without compression continue to read back unchanged.
+func WithCompression() VaultOption {
+	return func(r *VaultRepository) {
+		r.codec.compress = true
+	}
+}
+
+// WithEncryption seals encrypted_data with a server-held key before it is
+// written. Rows written without a key continue to read back unchanged.
+func WithEncryption(aead cipher.AEAD) VaultOption {
+	return func(r *VaultRepository) {
+		r.codec.aead = aead
+	}
 }
 
+// WithEraseOnDelete makes every write that deletes an entry, including sync
+// tombstones, empty encrypted_data and drop its nonce and content hash. The
+// tombstone row is kept so the deletion still syncs, but Restore refuses it
+// with ErrEntryErased.
+func WithEraseOnDelete() VaultOption {
+	return func(r *VaultRepository) {
+		r.eraseOnDelete = true
+	}
+}
+
+// eraseColumns is the SET clause that discards a deleted entry's blob.
+const eraseColumns = `, encrypted_data = '', compressed = FALSE, server_encrypted = FALSE, nonce = NULL, content_hash = NULL`
+
 // NewVaultRepository creates a new VaultRepository.
-func NewVaultRepository(db *sql.DB) *VaultRepository {
-	return &VaultRepository{db: db}
+func NewVaultRepository(db *sql.DB, opts ...VaultOption) *VaultRepository {
+	r := &VaultRepository{db: db}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
+// vaultColumns lists the columns read by scanEntry, in scan order.
+const vaultColumns = `id, user_id, vault_id, entry_id, encrypted_data, compressed, server_encrypted, nonce, version, favorite, last_device_id, created_at, updated_at, deleted, delete_reason, expires_at, last_accessed_at`
+
 // upsertQuery is the shared SQL for insert-or-update with LWW conflict resolution.
 const upsertQuery = `
-	INSERT INTO vault_entries (user_id, entry_id, encrypted_data, version, deleted)
-	VALUES (?, ?, ?, ?, ?)
+	INSERT INTO vault_entries (user_id, vault_id, entry_id, encrypted_data, compressed, server_encrypted, nonce, content_hash, version, favorite, last_device_id, deleted, delete_reason, expires_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)` + upsertOnDuplicate
+
+// upsertWithCreatedAtQuery is upsertQuery with an explicit created_at for new rows.
+const upsertWithCreatedAtQuery = `
+	INSERT INTO vault_entries (user_id, vault_id, entry_id, encrypted_data, compressed, server_encrypted, nonce, content_hash, version, favorite, last_device_id, deleted, delete_reason, expires_at, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)` + upsertOnDuplicate
+
+// upsertOnDuplicate applies LWW when the entry already exists. created_at is never overwritten.
+// MySQL assigns left to right, so every column compared against the stored
+// version must come before version itself is overwritten.
+const upsertOnDuplicate = `
 	ON DUPLICATE KEY UPDATE
-		encrypted_data = IF(VALUES(version) > version, VALUES(encrypted_data), encrypted_data),
-		version        = IF(VALUES(version) > version, VALUES(version), version),
-		deleted        = IF(VALUES(version) > version, VALUES(deleted), deleted),
-		updated_at     = IF(VALUES(version) > version, CURRENT_TIMESTAMP, updated_at)`
+		encrypted_data   = IF(VALUES(version) > version, VALUES(encrypted_data), encrypted_data),
+		compressed       = IF(VALUES(version) > version, VALUES(compressed), compressed),
+		server_encrypted = IF(VALUES(version) > version, VALUES(server_encrypted), server_encrypted),
+		nonce            = IF(VALUES(version) > version, VALUES(nonce), nonce),
+		content_hash     = IF(VALUES(version) > version, VALUES(content_hash), content_hash),
+		favorite         = IF(VALUES(version) > version, VALUES(favorite), favorite),
+		updated_at       = IF(VALUES(version) > version, CURRENT_TIMESTAMP, updated_at),
+		expires_at       = IF(VALUES(version) > version, VALUES(expires_at), expires_at),
+		last_device_id   = IF(VALUES(version) > version, VALUES(last_device_id), last_device_id),
+		deleted          = IF(VALUES(version) > version, VALUES(deleted), deleted),
+		delete_reason    = IF(VALUES(version) > version, VALUES(delete_reason), delete_reason),
+		version          = IF(VALUES(version) > version, VALUES(version), version)`
 
 // BeginTx starts a new database transaction.
-func (r *VaultRepository) BeginTx(ctx context.Context) (*sql.Tx, error) {
+func (r *VaultRepository) BeginTx(ctx context.Context) (Tx, error) {
 	return r.db.BeginTx(ctx, nil)
 }
 
 // Upsert inserts or updates a vault entry using last-write-wins conflict resolution.
 // The entry is only updated if the incoming version is greater than the existing version.
 func (r *VaultRepository) Upsert(ctx context.Context, entry *model.VaultEntry) error {
-	_, err := r.db.ExecContext(ctx, upsertQuery,
-		entry.UserID,
-		entry.EntryID,
-		entry.EncryptedData,
-		entry.Version,
-		entry.Deleted,
-	)
+	args, err := r.upsertArgs(entry)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, upsertQuery, args...)
 	return err
 }
 
-// UpsertTx inserts or updates a vault entry within the provided transaction.
-func (r *VaultRepository) UpsertTx(ctx context.Context, tx *sql.Tx, entry *model.VaultEntry) error {
-	_, err := tx.ExecContext(ctx, upsertQuery,
-		entry.UserID,
-		entry.EntryID,
-		entry.EncryptedData,
-		entry.Version,
-		entry.Deleted,
-	)
+// UpsertWithCreatedAt is Upsert, except that a newly inserted entry takes
+// entry.CreatedAt as its creation time instead of now. It is used by imports
+// that preserve timestamps from another product; updated_at is still now.
+func (r *VaultRepository) UpsertWithCreatedAt(ctx context.Context, entry *model.VaultEntry) error {
+	args, err := r.upsertArgs(entry)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, upsertWithCreatedAtQuery, append(args, entry.CreatedAt)...)
 	return err
 }
 
-// GetByEntryID retrieves a vault entry by user ID and client-generated entry ID.
-func (r *VaultRepository) GetByEntryID(ctx context.Context, userID int64, entryID string) (*model.VaultEntry, error) {
-	query := `SELECT id, user_id, entry_id, encrypted_data, version, created_at, updated_at, deleted
-		FROM vault_entries WHERE user_id = ? AND entry_id = ?`
+// UpsertTx inserts or updates a vault entry within a transaction started by BeginTx.
+func (r *VaultRepository) UpsertTx(ctx context.Context, tx Tx, entry *model.VaultEntry) error {
+	sqlTx, ok := tx.(*sql.Tx)
+	if !ok {
+		return ErrForeignTx
+	}
+	args, err := r.upsertArgs(entry)
+	if err != nil {
+		return err
+	}
+	_, err = sqlTx.ExecContext(ctx, upsertQuery, args...)
+	return err
+}
 
-	entry := &model.VaultEntry{}
-	err := r.db.QueryRowContext(ctx, query, userID, entryID).Scan(
-		&entry.ID, &entry.UserID, &entry.EntryID, &entry.EncryptedData,
-		&entry.Version, &entry.CreatedAt, &entry.UpdatedAt, &entry.Deleted,
+// UpdateIfVersion overwrites a live entry only if its stored version equals
+// expectedVersion, giving callers optimistic concurrency control. It returns
+// ErrVersionMismatch if another write got there first, and ErrEntryNotFound
+// for a missing or deleted entry.
+func (r *VaultRepository) UpdateIfVersion(ctx context.Context, entry *model.VaultEntry, expectedVersion int) error {
+	blob, hash, err := r.encodeEntry(entry)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE vault_entries
+		SET encrypted_data = ?, compressed = ?, server_encrypted = ?, nonce = ?, content_hash = ?,
+			version = ?, favorite = ?, last_device_id = ?, deleted = ?, delete_reason = ?, expires_at = ?
+		WHERE user_id = ? AND vault_id = ? AND entry_id = ? AND version = ? AND deleted = FALSE`
+
+	result, err := r.db.ExecContext(ctx, query,
+		blob.data, blob.compressed, blob.encrypted, blob.nonce, hash,
+		entry.Version, entry.Favorite, entry.LastDeviceID, entry.Deleted, entry.DeleteReason, entry.ExpiresAt,
+		entry.UserID, entry.VaultID, entry.EntryID, expectedVersion,
 	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	// Nothing matched: tell a missing or deleted entry apart from a stale version.
+	existing, err := r.GetByEntryID(ctx, entry.UserID, entry.VaultID, entry.EntryID)
+	if err != nil {
+		return err
+	}
+	if existing.Deleted {
+		return ErrEntryNotFound
+	}
+	return ErrVersionMismatch
+}
+
+// upsertArgs encodes the entry's blob and returns the arguments for upsertQuery.
+func (r *VaultRepository) upsertArgs(entry *model.VaultEntry) ([]any, error) {
+	blob, hash, err := r.encodeEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+	return []any{
+		entry.UserID, entry.VaultID, entry.EntryID, blob.data, blob.compressed, blob.encrypted, blob.nonce,
+		hash,
+		entry.Version, entry.Favorite, entry.LastDeviceID, entry.Deleted, entry.DeleteReason, entry.ExpiresAt,
+	}, nil
+}
+
+// encodeEntry returns the stored form of entry's blob and its content hash. With
+// WithEraseOnDelete a tombstone stores an empty blob and no hash, the same as
+// eraseColumns leaves behind.
+func (r *VaultRepository) encodeEntry(entry *model.VaultEntry) (storedBlob, *string, error) {
+	if r.eraseOnDelete && entry.Deleted {
+		return storedBlob{data: []byte{}}, nil, nil
+	}
+	blob, err := r.codec.encode(entry.EncryptedData, blobAAD(entry.UserID, entry.EntryID))
+	if err != nil {
+		return storedBlob{}, nil, err
+	}
+	hash := contentHash(entry.UserID, entry.EncryptedData)
+	return blob, &hash, nil
+}
+
+// scanEntry reads a row selected with vaultColumns and decodes its blob.
+func (r *VaultRepository) scanEntry(row rowScanner) (*model.VaultEntry, error) {
+	entry := &model.VaultEntry{}
+	var blob storedBlob
+	var expiresAt, lastAccessedAt sql.NullTime
+	if err := row.Scan(
+		&entry.ID, &entry.UserID, &entry.VaultID, &entry.EntryID, &blob.data, &blob.compressed, &blob.encrypted, &blob.nonce,
+		&entry.Version, &entry.Favorite, &entry.LastDeviceID, &entry.CreatedAt, &entry.UpdatedAt, &entry.Deleted, &entry.DeleteReason, &expiresAt, &lastAccessedAt,
+	); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		entry.ExpiresAt = &expiresAt.Time
+	}
+	if lastAccessedAt.Valid {
+		entry.LastAccessedAt = &lastAccessedAt.Time
+	}
+
+	data, err := r.codec.decode(blob, blobAAD(entry.UserID, entry.EntryID))
+	if err != nil {
+		return nil, err
+	}
+	entry.EncryptedData = data
+	return entry, nil
+}
+
+// GetByEntryID retrieves a vault entry by user ID, vault ID and client-generated entry ID.
+func (r *VaultRepository) GetByEntryID(ctx context.Context, userID, vaultID int64, entryID string) (*model.VaultEntry, error) {
+	query := `SELECT ` + vaultColumns + `
+		FROM vault_entries WHERE user_id = ? AND vault_id = ? AND entry_id = ?`
+
+	entry, err := r.scanEntry(r.db.QueryRowContext(ctx, query, userID, vaultID, entryID))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrEntryNotFound
@@ -81,39 +251,175 @@ func (r *VaultRepository) GetByEntryID(ctx context.Context, userID int64, entryI
 	return entry, nil
 }
 
-// ListByUser retrieves all non-deleted vault entries for a user, ordered by most recently updated.
-func (r *VaultRepository) ListByUser(ctx context.Context, userID int64) ([]model.VaultEntry, error) {
-	query := `SELECT id, user_id, entry_id, encrypted_data, version, created_at, updated_at, deleted
-		FROM vault_entries WHERE user_id = ? AND deleted = FALSE ORDER BY updated_at DESC`
+// GetByEntryIDForUpdateTx is GetByEntryID within tx. The row is locked with
+// SELECT ... FOR UPDATE, so concurrent writers of the entry serialize on it.
+func (r *VaultRepository) GetByEntryIDForUpdateTx(ctx context.Context, tx Tx, userID, vaultID int64, entryID string) (*model.VaultEntry, error) {
+	sqlTx, ok := tx.(*sql.Tx)
+	if !ok {
+		return nil, ErrForeignTx
+	}
+
+	query := `SELECT ` + vaultColumns + `
+		FROM vault_entries WHERE user_id = ? AND vault_id = ? AND entry_id = ? FOR UPDATE`
+
+	entry, err := r.scanEntry(sqlTx.QueryRowContext(ctx, query, userID, vaultID, entryID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrEntryNotFound
+		}
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// GetByEntryIDs retrieves the vault's non-deleted entries among entryIDs in a single query.
+// IDs that don't exist are simply absent from the result.
+func (r *VaultRepository) GetByEntryIDs(ctx context.Context, userID, vaultID int64, entryIDs []string) ([]model.VaultEntry, error) {
+	if len(entryIDs) == 0 {
+		return nil, nil
+	}
+
+	args := make([]any, 0, len(entryIDs)+3)
+	args = append(args, userID, vaultID, time.Now().UTC())
+	for _, id := range entryIDs {
+		args = append(args, id)
+	}
+
+	query := `SELECT ` + vaultColumns + `
+		FROM vault_entries WHERE user_id = ? AND vault_id = ? AND deleted = FALSE
+		AND (expires_at IS NULL OR expires_at > ?)
+		AND entry_id IN (?` + strings.Repeat(", ?", len(entryIDs)-1) + `)`
+
+	return r.queryEntries(ctx, query, args...)
+}
+
+// ListByUser retrieves all live (non-deleted, unexpired) entries in one of the user's vaults,
+// ordered by most recently updated.
+func (r *VaultRepository) ListByUser(ctx context.Context, userID, vaultID int64) ([]model.VaultEntry, error) {
+	query := `SELECT ` + vaultColumns + `
+		FROM vault_entries WHERE user_id = ? AND vault_id = ? AND deleted = FALSE
+		AND (expires_at IS NULL OR expires_at > ?) ORDER BY updated_at DESC`
+
+	return r.queryEntries(ctx, query, userID, vaultID, time.Now().UTC())
+}
+
+// ListFavorites retrieves the vault's live favorite entries, ordered by most recently updated.
+func (r *VaultRepository) ListFavorites(ctx context.Context, userID, vaultID int64) ([]model.VaultEntry, error) {
+	query := `SELECT ` + vaultColumns + `
+		FROM vault_entries WHERE user_id = ? AND vault_id = ? AND favorite = TRUE AND deleted = FALSE
+		AND (expires_at IS NULL OR expires_at > ?) ORDER BY updated_at DESC`
+
+	return r.queryEntries(ctx, query, userID, vaultID, time.Now().UTC())
+}
+
+// ListDeleted retrieves the soft-deleted entries in one of the user's vaults that have not
+// been purged yet, most recently deleted first. Deletion bumps updated_at, so it doubles as
+// the deletion time.
+func (r *VaultRepository) ListDeleted(ctx context.Context, userID, vaultID int64) ([]model.VaultEntry, error) {
+	query := `SELECT ` + vaultColumns + `
+		FROM vault_entries WHERE user_id = ? AND vault_id = ? AND deleted = TRUE ORDER BY updated_at DESC`
+
+	return r.queryEntries(ctx, query, userID, vaultID)
+}
+
+// GetChangedSince retrieves a page of vault entries (including deleted) modified after filter.Since,
+// plus entries that expired since then, ordered by change time then entry ID. This is used during
+// sync to send changed entries back to the client; the cursor and limit are applied in the query.
+func (r *VaultRepository) GetChangedSince(ctx context.Context, userID, vaultID int64, filter model.ChangeFilter) ([]model.VaultEntry, error) {
+	query := `SELECT ` + vaultColumns + ` FROM (
+		SELECT ` + vaultColumns + `,
+			CASE WHEN expires_at <= ? AND expires_at > updated_at THEN expires_at ELSE updated_at END AS changed_at
+		FROM vault_entries WHERE user_id = ? AND vault_id = ?
+		AND (updated_at > ? OR (expires_at > ? AND expires_at <= ?))
+	) AS changes`
+	args := []any{filter.Now, userID, vaultID, filter.Since, filter.Since, filter.Now}
+
+	if c := filter.After; c != nil {
+		query += ` WHERE changed_at > ? OR (changed_at = ? AND entry_id > ?)`
+		args = append(args, c.ChangedAt, c.ChangedAt, c.EntryID)
+	}
+	query += ` ORDER BY changed_at ASC, entry_id ASC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+
+	return r.queryEntries(ctx, query, args...)
+}
+
+// ListAllByUser retrieves every stored entry in all of the user's vaults,
+// including deleted and expired ones, ordered by vault.
+func (r *VaultRepository) ListAllByUser(ctx context.Context, userID int64) ([]model.VaultEntry, error) {
+	query := `SELECT ` + vaultColumns + `
+		FROM vault_entries WHERE user_id = ? ORDER BY vault_id, id`
+
+	return r.queryEntries(ctx, query, userID)
+}
+
+// ListManifest retrieves the metadata of every entry in one of the user's vaults,
+// including deleted and expired ones, ordered by entry ID. Blobs are not read.
+func (r *VaultRepository) ListManifest(ctx context.Context, userID, vaultID int64) ([]model.ManifestEntry, error) {
+	query := `SELECT entry_id, version, updated_at, deleted OR (expires_at IS NOT NULL AND expires_at <= ?)
+		FROM vault_entries WHERE user_id = ? AND vault_id = ? ORDER BY entry_id ASC`
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	rows, err := r.db.QueryContext(ctx, query, time.Now().UTC(), userID, vaultID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var entries []model.VaultEntry
+	var manifest []model.ManifestEntry
 	for rows.Next() {
-		var e model.VaultEntry
-		if err := rows.Scan(
-			&e.ID, &e.UserID, &e.EntryID, &e.EncryptedData,
-			&e.Version, &e.CreatedAt, &e.UpdatedAt, &e.Deleted,
-		); err != nil {
+		var m model.ManifestEntry
+		if err := rows.Scan(&m.EntryID, &m.Version, &m.UpdatedAt, &m.Deleted); err != nil {
 			return nil, err
 		}
-		entries = append(entries, e)
+		manifest = append(manifest, m)
 	}
 
-	return entries, rows.Err()
+	return manifest, rows.Err()
 }
 
-// GetChangedSince retrieves all vault entries (including deleted) modified after the given timestamp.
-// This is used during sync to send changed entries back to the client.
-func (r *VaultRepository) GetChangedSince(ctx context.Context, userID int64, since time.Time) ([]model.VaultEntry, error) {
-	query := `SELECT id, user_id, entry_id, encrypted_data, version, created_at, updated_at, deleted
-		FROM vault_entries WHERE user_id = ? AND updated_at > ? ORDER BY updated_at ASC`
+// DuplicateStats counts live entries whose blob is byte-identical to another
+// entry of the same user, across all users. Rows written before content hashes
+// were recorded are reported as unhashed.
+func (r *VaultRepository) DuplicateStats(ctx context.Context) (model.DuplicateStats, error) {
+	var stats model.DuplicateStats
+	query := `SELECT COUNT(*), COUNT(DISTINCT user_id), COALESCE(SUM(n), 0) FROM (
+			SELECT user_id, COUNT(*) AS n FROM vault_entries
+			WHERE deleted = FALSE AND content_hash IS NOT NULL
+			GROUP BY user_id, content_hash HAVING COUNT(*) > 1
+		) AS dup`
+	err := r.db.QueryRowContext(ctx, query).Scan(&stats.Groups, &stats.Users, &stats.Entries)
+	if err != nil {
+		return model.DuplicateStats{}, err
+	}
+
+	query = `SELECT COUNT(*) FROM vault_entries WHERE deleted = FALSE AND content_hash IS NULL`
+	if err := r.db.QueryRowContext(ctx, query).Scan(&stats.Unhashed); err != nil {
+		return model.DuplicateStats{}, err
+	}
 
-	rows, err := r.db.QueryContext(ctx, query, userID, since)
+	stats.Redundant = stats.Entries - stats.Groups
+	return stats, nil
+}
+
+// UsageStats counts the user's live (non-deleted, unexpired) entries and the stored size of their
+// blobs, which is after compression and server-side encryption when enabled.
+func (r *VaultRepository) UsageStats(ctx context.Context, userID int64) (model.VaultUsage, error) {
+	var usage model.VaultUsage
+	query := `SELECT COUNT(*), COALESCE(SUM(LENGTH(encrypted_data)), 0) FROM vault_entries
+		WHERE user_id = ? AND deleted = FALSE AND (expires_at IS NULL OR expires_at > ?)`
+	if err := r.db.QueryRowContext(ctx, query, userID, time.Now().UTC()).Scan(&usage.Entries, &usage.Bytes); err != nil {
+		return model.VaultUsage{}, err
+	}
+	return usage, nil
+}
+
+// queryEntries runs a SELECT of vaultColumns and scans every row.
+func (r *VaultRepository) queryEntries(ctx context.Context, query string, args ...any) ([]model.VaultEntry, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -121,25 +427,53 @@ func (r *VaultRepository) GetChangedSince(ctx context.Context, userID int64, sin
 
 	var entries []model.VaultEntry
 	for rows.Next() {
-		var e model.VaultEntry
-		if err := rows.Scan(
-			&e.ID, &e.UserID, &e.EntryID, &e.EncryptedData,
-			&e.Version, &e.CreatedAt, &e.UpdatedAt, &e.Deleted,
-		); err != nil {
+		e, err := r.scanEntry(rows)
+		if err != nil {
 			return nil, err
 		}
-		entries = append(entries, e)
+		entries = append(entries, *e)
 	}
 
 	return entries, rows.Err()
 }
 
+// TouchAccessed records at as the last access time of the given entries.
+// updated_at is pinned so an access never looks like a change to sync.
+func (r *VaultRepository) TouchAccessed(ctx context.Context, userID, vaultID int64, entryIDs []string, at time.Time) error {
+	if len(entryIDs) == 0 {
+		return nil
+	}
+
+	args := make([]any, 0, len(entryIDs)+3)
+	args = append(args, at, userID, vaultID)
+	for _, id := range entryIDs {
+		args = append(args, id)
+	}
+
+	query := `UPDATE vault_entries SET last_accessed_at = ?, updated_at = updated_at
+		WHERE user_id = ? AND vault_id = ? AND entry_id IN (?` + strings.Repeat(", ?", len(entryIDs)-1) + `)`
+
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
 // SoftDelete marks a vault entry as deleted and increments its version for sync propagation.
-func (r *VaultRepository) SoftDelete(ctx context.Context, userID int64, entryID string) error {
-	query := `UPDATE vault_entries SET deleted = TRUE, version = version + 1
-		WHERE user_id = ? AND entry_id = ?`
+func (r *VaultRepository) SoftDelete(ctx context.Context, userID, vaultID int64, entryID string) error {
+	return r.SoftDeleteWithReason(ctx, userID, vaultID, entryID, "")
+}
+
+// SoftDeleteWithReason is SoftDelete that also records why the entry was
+// deleted. An empty reason clears any earlier one. With WithEraseOnDelete the
+// blob is discarded too.
+func (r *VaultRepository) SoftDeleteWithReason(ctx context.Context, userID, vaultID int64, entryID, reason string) error {
+	set := `deleted = TRUE, delete_reason = ?, version = version + 1`
+	if r.eraseOnDelete {
+		set += eraseColumns
+	}
+	query := `UPDATE vault_entries SET ` + set + `
+		WHERE user_id = ? AND vault_id = ? AND entry_id = ?`
 
-	result, err := r.db.ExecContext(ctx, query, userID, entryID)
+	result, err := r.db.ExecContext(ctx, query, reason, userID, vaultID, entryID)
 	if err != nil {
 		return err
 	}
@@ -155,3 +489,195 @@ func (r *VaultRepository) SoftDelete(ctx context.Context, userID int64, entryID
 
 	return nil
 }
+
+// Restore clears the deleted flag on a soft-deleted vault entry and increments its
+// version so the restore syncs. It returns ErrEntryNotFound if no deleted entry matches,
+// and ErrEntryErased if the tombstone's blob was erased.
+func (r *VaultRepository) Restore(ctx context.Context, userID, vaultID int64, entryID string) error {
+	query := `UPDATE vault_entries SET deleted = FALSE, delete_reason = '', version = version + 1
+		WHERE user_id = ? AND vault_id = ? AND entry_id = ? AND deleted = TRUE AND encrypted_data <> ''`
+
+	result, err := r.db.ExecContext(ctx, query, userID, vaultID, entryID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		entry, err := r.GetByEntryID(ctx, userID, vaultID, entryID)
+		if err == nil && entry.Deleted {
+			return ErrEntryErased
+		}
+		return ErrEntryNotFound
+	}
+
+	return nil
+}
+
+// WipeByUser deletes every entry in all of the user's vaults in one transaction
+// and drops their staged conflicts. Soft wipes mark live entries deleted and bump
+// their versions so the wipe syncs; hard wipes remove the rows. It returns the
+// number of entries wiped.
+func (r *VaultRepository) WipeByUser(ctx context.Context, userID int64, hard bool) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM vault_conflicts WHERE user_id = ?`, userID); err != nil {
+		return 0, err
+	}
+
+	set := `deleted = TRUE, version = version + 1`
+	if r.eraseOnDelete {
+		set += eraseColumns
+	}
+	query := `UPDATE vault_entries SET ` + set + `
+		WHERE user_id = ? AND deleted = FALSE`
+	if hard {
+		query = `DELETE FROM vault_entries WHERE user_id = ?`
+	}
+	result, err := tx.ExecContext(ctx, query, userID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return n, tx.Commit()
+}
+
+// StageConflictTx records an incoming write that lost to the stored entry. A write
+// already staged at the same version is not staged again, so replaying a losing
+// sync leaves one conflict; conflict.ID is then left unset.
+func (r *VaultRepository) StageConflictTx(ctx context.Context, tx Tx, conflict *model.VaultConflict) error {
+	sqlTx, ok := tx.(*sql.Tx)
+	if !ok {
+		return ErrForeignTx
+	}
+
+	blob, err := r.codec.encode(conflict.EncryptedData, blobAAD(conflict.UserID, conflict.EntryID))
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO vault_conflicts
+		(user_id, vault_id, entry_id, encrypted_data, compressed, server_encrypted, nonce, version, favorite, deleted, device_id)
+		SELECT ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ? FROM DUAL
+		WHERE NOT EXISTS (SELECT 1 FROM vault_conflicts
+			WHERE user_id = ? AND vault_id = ? AND entry_id = ? AND version = ?)`
+
+	result, err := sqlTx.ExecContext(ctx, query,
+		conflict.UserID, conflict.VaultID, conflict.EntryID, blob.data, blob.compressed, blob.encrypted, blob.nonce,
+		conflict.Version, conflict.Favorite, conflict.Deleted, conflict.DeviceID,
+		conflict.UserID, conflict.VaultID, conflict.EntryID, conflict.Version,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	conflict.ID = id
+	return nil
+}
+
+// ClearConflictsTx removes staged conflicts for an entry once a newer write has resolved them.
+func (r *VaultRepository) ClearConflictsTx(ctx context.Context, tx Tx, userID, vaultID int64, entryID string) error {
+	sqlTx, ok := tx.(*sql.Tx)
+	if !ok {
+		return ErrForeignTx
+	}
+	query := `DELETE FROM vault_conflicts WHERE user_id = ? AND vault_id = ? AND entry_id = ?`
+	_, err := sqlTx.ExecContext(ctx, query, userID, vaultID, entryID)
+	return err
+}
+
+// ListConflicts retrieves the vault's unresolved conflicts, oldest first.
+func (r *VaultRepository) ListConflicts(ctx context.Context, userID, vaultID int64) ([]model.VaultConflict, error) {
+	query := `SELECT id, user_id, vault_id, entry_id, encrypted_data, compressed, server_encrypted, nonce,
+			version, favorite, deleted, device_id, created_at
+		FROM vault_conflicts WHERE user_id = ? AND vault_id = ? ORDER BY id ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, vaultID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conflicts []model.VaultConflict
+	for rows.Next() {
+		var c model.VaultConflict
+		var blob storedBlob
+		if err := rows.Scan(
+			&c.ID, &c.UserID, &c.VaultID, &c.EntryID, &blob.data, &blob.compressed, &blob.encrypted, &blob.nonce,
+			&c.Version, &c.Favorite, &c.Deleted, &c.DeviceID, &c.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if c.EncryptedData, err = r.codec.decode(blob, blobAAD(c.UserID, c.EntryID)); err != nil {
+			return nil, err
+		}
+		conflicts = append(conflicts, c)
+	}
+
+	return conflicts, rows.Err()
+}
+
+// AckSync records that a device synced the vault and, when cursor is set,
+// that it has applied every change up to it. A device that has never
+// acknowledged a cursor is stored with a NULL one. Each device's stored
+// cursor only moves forward.
+func (r *VaultRepository) AckSync(ctx context.Context, userID, vaultID int64, deviceID string, cursor *time.Time) error {
+	// GREATEST is NULL if either side is, so each side falls back to the other.
+	query := `INSERT INTO sync_acks (user_id, vault_id, device_id, acked_at) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE acked_at = GREATEST(COALESCE(acked_at, VALUES(acked_at)), COALESCE(VALUES(acked_at), acked_at))`
+
+	_, err := r.db.ExecContext(ctx, query, userID, vaultID, deviceID, cursor)
+	return err
+}
+
+// MinSyncAck returns the oldest cursor acknowledged by any device that has
+// synced the vault. It returns the zero time if no device has synced, or if
+// any device has never acknowledged a cursor.
+func (r *VaultRepository) MinSyncAck(ctx context.Context, userID, vaultID int64) (time.Time, error) {
+	var ackedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx,
+		`SELECT CASE WHEN COUNT(*) = COUNT(acked_at) THEN MIN(acked_at) END
+		FROM sync_acks WHERE user_id = ? AND vault_id = ?`, userID, vaultID,
+	).Scan(&ackedAt)
+	return ackedAt.Time, err
+}
+
+// PurgeTombstones permanently removes the vault's deleted entries last changed at or before through.
+func (r *VaultRepository) PurgeTombstones(ctx context.Context, userID, vaultID int64, through time.Time) (int64, error) {
+	query := `DELETE FROM vault_entries
+		WHERE user_id = ? AND vault_id = ? AND deleted = TRUE AND updated_at <= ?`
+
+	result, err := r.db.ExecContext(ctx, query, userID, vaultID, through)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// PurgeExpired permanently removes every user's entries that expired at or before now.
+func (r *VaultRepository) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM vault_entries WHERE expires_at <= ?`, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+)
+
+// assignmentIndex returns the position of column's assignment in the
+// ON DUPLICATE KEY UPDATE clause, or -1 if it is not assigned.
+func assignmentIndex(column string) int {
+	for i, line := range strings.Split(upsertOnDuplicate, "\n") {
+		if name, _, ok := strings.Cut(strings.TrimSpace(line), "="); ok && strings.TrimSpace(name) == column {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestUpsertOnDuplicate_AssignsVersionLast(t *testing.T) {
+	version := assignmentIndex("version")
+	if version < 0 {
+		t.Fatal("upsertOnDuplicate does not assign version")
+	}
+	for _, column := range []string{
+		"encrypted_data", "compressed", "server_encrypted", "nonce", "content_hash",
+		"favorite", "updated_at", "expires_at", "last_device_id",
+		"deleted", "delete_reason",
+	} {
+		if i := assignmentIndex(column); i < 0 || i > version {
+			t.Errorf("%s is assigned at line %d, want before version at line %d", column, i, version)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/vaultpass/vaultpass-go/internal/config"
+	"github.com/vaultpass/vaultpass-go/internal/middleware"
+)
+
+// NewRedirect returns a plain HTTP server on HTTP_REDIRECT_PORT that sends
+// every request to the HTTPS server on PORT. Hosts outside ALLOWED_HOSTS are
+// refused, so the redirect can't be aimed at another site.
+func NewRedirect(cfg config.Config) *http.Server {
+	return &http.Server{
+		Addr:              ":" + cfg.HTTPRedirectPort,
+		Handler:           middleware.AllowedHosts(cfg.AllowedHosts)(RedirectHandler(cfg.Port)),
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+}
+
+// RedirectHandler answers every request with a 301 to the same host and URI
+// over HTTPS on httpsPort. The port is left out of the Location when it is 443.
+func RedirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+		if host == "" {
+			http.Error(w, "missing host", http.StatusBadRequest)
+			return
+		}
+
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vaultpass/vaultpass-go/internal/config"
+)
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		port, host, target, want string
+	}{
+		{"443", "api.example.com", "/api/v1/vault?favorites=true", "https://api.example.com/api/v1/vault?favorites=true"},
+		{"443", "api.example.com:80", "/health", "https://api.example.com/health"},
+		{"8443", "api.example.com:8080", "/health", "https://api.example.com:8443/health"},
+		{"8443", "[::1]:8080", "/", "https://[::1]:8443/"},
+		{"443", "[::1]", "/", "https://[::1]/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host+tt.target, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.Host = tt.host
+			rec := httptest.NewRecorder()
+			RedirectHandler(tt.port).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusMovedPermanently {
+				t.Fatalf("expected 301, got %d", rec.Code)
+			}
+			if got := rec.Header().Get("Location"); got != tt.want {
+				t.Errorf("Location = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRedirect_RejectsUnknownHosts(t *testing.T) {
+	srv := NewRedirect(config.Config{Port: "443", HTTPRedirectPort: "80", AllowedHosts: []string{"api.example.com"}})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "evil.example"
+	rec := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a host outside ALLOWED_HOSTS, got %d", rec.Code)
+	}
+	if loc := rec.Header().Get("Location"); loc != "" {
+		t.Errorf("expected no redirect, got Location %q", loc)
+	}
+}
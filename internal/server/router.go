@@ -0,0 +1,292 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vaultpass/vaultpass-go/internal/config"
+	"github.com/vaultpass/vaultpass-go/internal/crypto"
+	"github.com/vaultpass/vaultpass-go/internal/handler"
+	"github.com/vaultpass/vaultpass-go/internal/middleware"
+)
+
+// corsMaxAge is how long browsers may cache a preflight result.
+const corsMaxAge = 10 * time.Minute
+
+// bulkSuffixes end the paths of sync, import and export routes. Their bodies can
+// be large, so they are exempt from the request timeout and bounded by the
+// server's read and write timeouts instead. A client's X-Request-Timeout still
+// applies to them.
+var bulkSuffixes = []string{"/sync", "/import", "/export"}
+
+var (
+	corsAllowedHeaders = []string{"Authorization", "Content-Type", "If-Match", "X-Request-ID", "X-Request-Timeout"}
+	corsExposedHeaders = []string{"ETag", "Retry-After", "X-Request-ID"}
+)
+
+// Deps holds everything NewRouter needs to mount the API.
+// Auth and Vault are nil when storage is unavailable, which leaves their routes unmounted.
+// Keys is nil unless tokens are signed with RS256. Invites and Admin are nil unless an
+// admin token is configured, and the admin routes, including metrics, are only mounted when it is set.
+type Deps struct {
+	Config    config.Config
+	Tokens    *crypto.TokenManager
+	Sessions  middleware.SessionValidator
+	Health    *handler.HealthHandler
+	Generator *handler.GeneratorHandler
+	Keys      *handler.KeysHandler
+	Auth      *handler.AuthHandler
+	Vault     *handler.VaultHandler
+	Invites   *handler.InviteHandler
+	Admin     *handler.AdminHandler
+	// RateLimiters, if set, collects each route group's rate limiter so the
+	// admin metrics can report how many visitors they track.
+	RateLimiters *middleware.RateLimiterSet
+}
+
+// rateLimits is the rate limit state shared by every route group: the clients
+// that are never limited, and the set each group's limiter joins.
+type rateLimits struct {
+	exempt []*net.IPNet
+	set    *middleware.RateLimiterSet
+}
+
+// route is a single method and pattern served by a handler.
+type route struct {
+	method  string
+	pattern string
+	handler http.HandlerFunc
+}
+
+// NewRouter builds the API router. Each route group gets its own rate limit and
+// CORS policy from the config.
+func NewRouter(deps Deps) http.Handler {
+	cfg := deps.Config
+	limits := rateLimits{exempt: cfg.RateLimitExempt, set: deps.RateLimiters}
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(middleware.SampledLogger(cfg.LogSampleRate))
+	r.Use(middleware.Recover)
+	r.Use(middleware.AllowedHosts(cfg.AllowedHosts))
+	r.Use(middleware.MaxInFlight(cfg.MaxInFlight))
+	if cfg.RequestTimeout > 0 {
+		r.Use(exceptBulk(middleware.Timeout(cfg.RequestTimeout)))
+	}
+	r.Use(middleware.ClientTimeout(cfg.ClientTimeoutMax))
+	if cfg.StrictJSON {
+		r.Use(middleware.StrictJSON)
+	}
+
+	r.Get("/health", deps.Health.HandleHealth)
+	r.Head("/health", deps.Health.HandleHealth)
+	if deps.Keys != nil {
+		r.Get("/.well-known/jwks.json", deps.Keys.HandleJWKS)
+	}
+
+	r.Group(func(r chi.Router) {
+		// Signed-in callers are identified before rate limiting so they get the
+		// per-user generate limit; anyone else is served anonymously.
+		if deps.Tokens != nil {
+			r.Use(middleware.OptionalJWTAuth(deps.Tokens, deps.Sessions))
+		}
+		mount(r, cfg.GenerateRoutes, limits, []route{
+			{http.MethodPost, "/api/v1/generate", deps.Generator.HandleGenerate},
+			{http.MethodGet, "/api/v1/generate/defaults", deps.Generator.HandleDefaults},
+			{http.MethodGet, "/api/v1/generate/capabilities", deps.Generator.HandleCapabilities},
+			{http.MethodPost, "/api/v1/generate/redeem", deps.Generator.HandleRedeem},
+			{http.MethodPost, "/api/v1/password/strength", deps.Generator.HandleStrength},
+			{http.MethodPost, "/api/v1/strength/batch", deps.Generator.HandleStrengthBatch},
+		})
+	})
+
+	// Usage metrics need no storage, so they are served whenever an admin token is set.
+	if cfg.AdminToken != "" {
+		mount(r, cfg.AuthRoutes, limits, []route{
+			{http.MethodGet, "/api/v1/admin/metrics", deps.Generator.HandleMetrics},
+		}, middleware.AdminToken(cfg.AdminToken))
+	}
+
+	if deps.Auth == nil || deps.Vault == nil {
+		return r
+	}
+
+	mount(r, cfg.AuthRoutes, limits, []route{
+		{http.MethodPost, "/api/v1/auth/register", deps.Auth.HandleRegister},
+		{http.MethodPost, "/api/v1/auth/login", deps.Auth.HandleLogin},
+		{http.MethodPost, "/api/v1/auth/reactivate", deps.Auth.HandleReactivate},
+	})
+
+	mount(r, cfg.CheckEmailRoutes, limits, []route{
+		{http.MethodPost, "/api/v1/auth/check-email", deps.Auth.HandleCheckEmail},
+	})
+
+	if deps.Invites != nil {
+		mount(r, cfg.AuthRoutes, limits, []route{
+			{http.MethodPost, "/api/v1/admin/invites", deps.Invites.HandleCreate},
+			{http.MethodGet, "/api/v1/admin/stats/duplicates", deps.Vault.HandleDuplicateStats},
+		}, middleware.AdminToken(cfg.AdminToken))
+	}
+	if deps.Admin != nil {
+		mount(r, cfg.AuthRoutes, limits, []route{
+			{http.MethodGet, "/api/v1/admin/users", deps.Admin.HandleListUsers},
+			{http.MethodGet, "/api/v1/admin/users/{id}/export", deps.Admin.HandleExportUser},
+		}, middleware.AdminToken(cfg.AdminToken))
+	}
+
+	mount(r, cfg.VaultRoutes, limits, []route{
+		{http.MethodGet, "/api/v1/auth/me", deps.Auth.HandleMe},
+		{http.MethodGet, "/api/v1/auth/login-history", deps.Auth.HandleLoginHistory},
+		{http.MethodGet, "/api/v1/auth/sessions", deps.Auth.HandleListSessions},
+		{http.MethodDelete, "/api/v1/auth/sessions/{id}", deps.Auth.HandleRevokeSession},
+		{http.MethodPost, "/api/v1/auth/logout-all", deps.Auth.HandleLogoutAll},
+		{http.MethodGet, "/api/v1/auth/kdf-profile", deps.Auth.HandleGetKDFProfile},
+		{http.MethodPut, "/api/v1/auth/kdf-profile", deps.Auth.HandlePutKDFProfile},
+		{http.MethodPost, "/api/v1/auth/deactivate", deps.Auth.HandleDeactivate},
+
+		{http.MethodGet, "/api/v1/vault", deps.Vault.HandleListEntries},
+		{http.MethodPost, "/api/v1/vault", deps.Vault.HandleCreateEntry},
+		{http.MethodGet, "/api/v1/vault/{entry_id}", deps.Vault.HandleGetEntry},
+		{http.MethodPut, "/api/v1/vault/{entry_id}", deps.Vault.HandleUpdateEntry},
+		{http.MethodDelete, "/api/v1/vault/{entry_id}", deps.Vault.HandleDeleteEntry},
+		{http.MethodPost, "/api/v1/vault/{entry_id}/restore", deps.Vault.HandleRestoreEntry},
+		{http.MethodPost, "/api/v1/vault/sync", deps.Vault.HandleSync},
+		{http.MethodPost, "/api/v1/vault/batch-get", deps.Vault.HandleBatchGet},
+		{http.MethodGet, "/api/v1/vault/manifest", deps.Vault.HandleManifest},
+		{http.MethodGet, "/api/v1/vault/quota", deps.Vault.HandleQuota},
+		{http.MethodGet, "/api/v1/vault/trash", deps.Vault.HandleTrash},
+		{http.MethodPost, "/api/v1/vault/wipe", deps.Vault.HandleWipe},
+		{http.MethodPost, "/api/v1/vault/rekey", deps.Vault.HandleRekey},
+		{http.MethodGet, "/api/v1/vault/export", deps.Vault.HandleExport},
+		{http.MethodHead, "/api/v1/vault/export", deps.Vault.HandleExport},
+		{http.MethodPost, "/api/v1/vault/import", deps.Vault.HandleImport},
+
+		{http.MethodGet, "/api/v1/vaults", deps.Vault.HandleListVaults},
+		{http.MethodPost, "/api/v1/vaults", deps.Vault.HandleCreateVault},
+		{http.MethodGet, "/api/v1/vaults/{vault_id}", deps.Vault.HandleGetVault},
+		{http.MethodPatch, "/api/v1/vaults/{vault_id}", deps.Vault.HandleRenameVault},
+		{http.MethodDelete, "/api/v1/vaults/{vault_id}", deps.Vault.HandleDeleteVault},
+		{http.MethodGet, "/api/v1/vaults/{vault_id}/entries", deps.Vault.HandleListEntries},
+		{http.MethodPost, "/api/v1/vaults/{vault_id}/entries", deps.Vault.HandleCreateEntry},
+		{http.MethodGet, "/api/v1/vaults/{vault_id}/entries/{entry_id}", deps.Vault.HandleGetEntry},
+		{http.MethodPut, "/api/v1/vaults/{vault_id}/entries/{entry_id}", deps.Vault.HandleUpdateEntry},
+		{http.MethodDelete, "/api/v1/vaults/{vault_id}/entries/{entry_id}", deps.Vault.HandleDeleteEntry},
+		{http.MethodPost, "/api/v1/vaults/{vault_id}/entries/{entry_id}/restore", deps.Vault.HandleRestoreEntry},
+		{http.MethodPost, "/api/v1/vaults/{vault_id}/sync", deps.Vault.HandleSync},
+		{http.MethodPost, "/api/v1/vaults/{vault_id}/batch-get", deps.Vault.HandleBatchGet},
+		{http.MethodGet, "/api/v1/vaults/{vault_id}/manifest", deps.Vault.HandleManifest},
+		{http.MethodGet, "/api/v1/vaults/{vault_id}/trash", deps.Vault.HandleTrash},
+		{http.MethodGet, "/api/v1/vaults/{vault_id}/export", deps.Vault.HandleExport},
+		{http.MethodHead, "/api/v1/vaults/{vault_id}/export", deps.Vault.HandleExport},
+		{http.MethodPost, "/api/v1/vaults/{vault_id}/import", deps.Vault.HandleImport},
+	}, middleware.JWTAuth(deps.Tokens, deps.Sessions))
+
+	return r
+}
+
+// mount registers routes as a group behind policy's CORS and rate limit, then
+// any extra middleware such as authentication. Request bodies must be JSON.
+// CORS runs first so preflight
+// requests, which carry no credentials, are answered before they can be rejected.
+// When policy has a per-user limit, the rate limit runs after extra so it can
+// tell authenticated callers apart; preflights are still limited per IP.
+// Clients in limits.exempt are never rate-limited.
+func mount(r chi.Router, policy config.RoutePolicy, limits rateLimits, routes []route, extra ...func(http.Handler) http.Handler) {
+	var methods, patterns []string
+	allowed := make(map[string][]string) // pattern -> methods
+	for _, rt := range routes {
+		if !slices.Contains(methods, rt.method) {
+			methods = append(methods, rt.method)
+		}
+		if !slices.Contains(patterns, rt.pattern) {
+			patterns = append(patterns, rt.pattern)
+		}
+		allowed[rt.pattern] = append(allowed[rt.pattern], rt.method)
+	}
+
+	var limit func(http.Handler) http.Handler
+	// The per-user limit applies even when the per-IP one is off.
+	if policy.RateLimitRPS > 0 || policy.UserRateLimitRPS > 0 {
+		opts := []middleware.RateLimitOption{middleware.WithExemptNets(limits.exempt)}
+		if policy.RateLimitWindow > 0 {
+			opts = append(opts, middleware.WithFixedWindow(policy.RateLimitWindow))
+		}
+		if policy.UserRateLimitRPS > 0 {
+			opts = append(opts, middleware.WithUserLimit(policy.UserRateLimitRPS, policy.UserRateLimitBurst))
+		}
+		limiter := middleware.NewRateLimiter(policy.RateLimitRPS, policy.RateLimitBurst, middleware.DefaultMaxVisitors, opts...)
+		limits.set.Add(limiter)
+		limit = limiter.Middleware
+	}
+	afterAuth := limit != nil && len(extra) > 0 && policy.UserRateLimitRPS > 0
+
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.CORS(middleware.CORSConfig{
+			AllowedOrigins:   policy.CORSOrigins,
+			AllowedMethods:   methods,
+			AllowedHeaders:   corsAllowedHeaders,
+			ExposedHeaders:   exposedHeaders(policy.CORSExposeHeaders),
+			MaxAge:           corsMaxAge,
+			AllowCredentials: policy.CORSAllowCredentials,
+		}))
+		preflight := r
+		if afterAuth {
+			preflight = r.With(limit)
+		} else if limit != nil {
+			r.Use(limit)
+		}
+
+		// Every route answers OPTIONS with its allowed methods. Preflights also
+		// need the route to reach the CORS middleware, and must not require auth.
+		for _, pattern := range patterns {
+			allow := strings.Join(append(allowed[pattern], http.MethodOptions), ", ")
+			preflight.Options(pattern, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Allow", allow)
+				w.WriteHeader(http.StatusNoContent)
+			})
+		}
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireJSON)
+			r.Use(extra...)
+			if afterAuth {
+				r.Use(limit)
+			}
+			for _, rt := range routes {
+				r.Method(rt.method, rt.pattern, rt.handler)
+			}
+		})
+	})
+}
+
+// exceptBulk applies mw to every request except those for bulk routes.
+func exceptBulk(mw func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		limited := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, suffix := range bulkSuffixes {
+				if strings.HasSuffix(r.URL.Path, suffix) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			limited.ServeHTTP(w, r)
+		})
+	}
+}
+
+// exposedHeaders returns corsExposedHeaders followed by the configured extras,
+// without duplicates.
+func exposedHeaders(extra []string) []string {
+	headers := slices.Clone(corsExposedHeaders)
+	for _, h := range extra {
+		if !slices.ContainsFunc(headers, func(s string) bool { return strings.EqualFold(s, h) }) {
+			headers = append(headers, http.CanonicalHeaderKey(h))
+		}
+	}
+	return headers
+}
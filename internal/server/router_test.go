@@ -0,0 +1,728 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/config"
+	"github.com/vaultpass/vaultpass-go/internal/crypto"
+	"github.com/vaultpass/vaultpass-go/internal/handler"
+	"github.com/vaultpass/vaultpass-go/internal/middleware"
+	"github.com/vaultpass/vaultpass-go/internal/model"
+	"github.com/vaultpass/vaultpass-go/internal/repository"
+	"github.com/vaultpass/vaultpass-go/internal/service"
+)
+
+func newTestRouter(cfg config.Config) http.Handler {
+	tokens := crypto.NewTokenManager(crypto.TokenConfig{Secret: "test-secret", Expiry: time.Hour})
+	invites := repository.NewMemoryInviteRepository()
+	users := repository.NewMemoryUserRepository()
+	vaults := repository.NewMemoryCollectionRepository()
+	entries := repository.NewMemoryVaultRepository()
+	var authOpts []service.AuthOption
+	if cfg.InviteOnly {
+		authOpts = append(authOpts, service.WithInviteOnly(invites))
+	}
+	auth := service.NewAuthService(
+		users,
+		repository.NewMemoryAuditRepository(),
+		repository.NewMemorySessionRepository(),
+		tokens,
+		authOpts...,
+	)
+
+	var inviteHandler *handler.InviteHandler
+	var adminHandler *handler.AdminHandler
+	if cfg.AdminToken != "" {
+		inviteHandler = handler.NewInviteHandler(service.NewInviteService(invites))
+		adminHandler = handler.NewAdminHandler(service.NewAccountExportService(users, vaults, entries), service.NewUserListService(users))
+	}
+
+	limiters := &middleware.RateLimiterSet{}
+	return NewRouter(Deps{
+		Config:       cfg,
+		Tokens:       tokens,
+		Sessions:     auth,
+		Health:       handler.NewHealthHandler(crypto.HashTiming{}),
+		Generator:    handler.NewGeneratorHandler(service.NewGeneratorService(), handler.WithRateLimitVisitors(limiters.Visitors)),
+		Auth:         handler.NewAuthHandler(auth),
+		Vault:        handler.NewVaultHandler(service.NewVaultService(entries, vaults)),
+		Invites:      inviteHandler,
+		Admin:        adminHandler,
+		RateLimiters: limiters,
+	})
+}
+
+func send(h http.Handler, method, path, body string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestNewRouter_GroupRateLimits(t *testing.T) {
+	r := newTestRouter(config.Config{
+		GenerateRoutes: config.RoutePolicy{RateLimitRPS: 100, RateLimitBurst: 5},
+		AuthRoutes:     config.RoutePolicy{RateLimitRPS: 0.001, RateLimitBurst: 2},
+	})
+
+	login := `{"email":"nobody@example.com","password":"x"}`
+	for i := range 2 {
+		if rec := send(r, http.MethodPost, "/api/v1/auth/login", login, nil); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("login %d: expected 401 within burst, got %d", i+1, rec.Code)
+		}
+	}
+	if rec := send(r, http.MethodPost, "/api/v1/auth/login", login, nil); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected auth group to return 429 past its burst, got %d", rec.Code)
+	}
+
+	// The generator group has its own, larger bucket.
+	for i := range 5 {
+		if rec := send(r, http.MethodPost, "/api/v1/generate", `{}`, nil); rec.Code != http.StatusOK {
+			t.Fatalf("generate %d: expected 200 within burst, got %d", i+1, rec.Code)
+		}
+	}
+	if rec := send(r, http.MethodPost, "/api/v1/generate", `{}`, nil); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected generate group to return 429 past its burst, got %d", rec.Code)
+	}
+
+	// Groups without a limit are never throttled.
+	for range 20 {
+		if rec := send(r, http.MethodGet, "/api/v1/vault", "", nil); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("expected unlimited vault group to reach JWT auth, got %d", rec.Code)
+		}
+	}
+}
+
+func TestNewRouter_LoginRestoresRateLimit(t *testing.T) {
+	r := newTestRouter(config.Config{
+		AuthRoutes: config.RoutePolicy{RateLimitRPS: 0.001, RateLimitBurst: 4},
+	})
+
+	creds := `{"email":"a@example.com","password":"password123"}`
+	typo := `{"email":"a@example.com","password":"password124"}`
+	if rec := send(r, http.MethodPost, "/api/v1/auth/register", creds, nil); rec.Code != http.StatusCreated {
+		t.Fatalf("register: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	for range 2 {
+		if rec := send(r, http.MethodPost, "/api/v1/auth/login", typo, nil); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("typo: expected 401, got %d", rec.Code)
+		}
+	}
+	// The last request in the burst succeeds and restores the full burst.
+	if rec := send(r, http.MethodPost, "/api/v1/auth/login", creds, nil); rec.Code != http.StatusOK {
+		t.Fatalf("login: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	for i := range 3 {
+		if rec := send(r, http.MethodPost, "/api/v1/auth/login", typo, nil); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("typo %d after login: expected 401, got %d", i+1, rec.Code)
+		}
+	}
+
+	// Resets are rate limited too, so this login doesn't restore capacity again.
+	if rec := send(r, http.MethodPost, "/api/v1/auth/login", creds, nil); rec.Code != http.StatusOK {
+		t.Fatalf("second login: expected 200, got %d", rec.Code)
+	}
+	if rec := send(r, http.MethodPost, "/api/v1/auth/login", typo, nil); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 past the burst, got %d", rec.Code)
+	}
+}
+
+func TestNewRouter_GenerateUserRateLimit(t *testing.T) {
+	r := newTestRouter(config.Config{
+		GenerateRoutes: config.RoutePolicy{
+			RateLimitRPS: 0.001, RateLimitBurst: 1,
+			UserRateLimitRPS: 0.001, UserRateLimitBurst: 3,
+		},
+	})
+
+	rec := send(r, http.MethodPost, "/api/v1/auth/register", `{"email":"a@example.com","password":"password123"}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("register: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var reg struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &reg)
+	authed := http.Header{"Authorization": {"Bearer " + reg.Token}}
+
+	// Anonymous clients share the per-IP limit.
+	if rec := send(r, http.MethodPost, "/api/v1/generate", `{}`, nil); rec.Code != http.StatusOK {
+		t.Fatalf("anonymous: expected 200 within burst, got %d", rec.Code)
+	}
+	if rec := send(r, http.MethodPost, "/api/v1/generate", `{}`, nil); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("anonymous: expected 429 past burst, got %d", rec.Code)
+	}
+	// An invalid token falls back to anonymous, so it hits the exhausted IP limit.
+	bogus := http.Header{"Authorization": {"Bearer not-a-jwt"}}
+	if rec := send(r, http.MethodPost, "/api/v1/generate", `{}`, bogus); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("invalid token: expected anonymous 429, got %d", rec.Code)
+	}
+
+	// A signed-in user from the same IP gets their own, larger allowance.
+	for i := range 3 {
+		if rec := send(r, http.MethodPost, "/api/v1/generate", `{}`, authed); rec.Code != http.StatusOK {
+			t.Fatalf("authenticated %d: expected 200 within user burst, got %d", i+1, rec.Code)
+		}
+	}
+	if rec := send(r, http.MethodPost, "/api/v1/generate", `{}`, authed); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("authenticated: expected 429 past user burst, got %d", rec.Code)
+	}
+}
+
+func TestNewRouter_VaultUserRateLimitPerUser(t *testing.T) {
+	r := newTestRouter(config.Config{
+		VaultRoutes: config.RoutePolicy{
+			RateLimitRPS: 0.001, RateLimitBurst: 1,
+			UserRateLimitRPS: 0.001, UserRateLimitBurst: 2,
+		},
+	})
+
+	var users []http.Header
+	for _, email := range []string{"a@example.com", "b@example.com"} {
+		rec := send(r, http.MethodPost, "/api/v1/auth/register", `{"email":"`+email+`","password":"password123"}`, nil)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("register %s: expected 201, got %d: %s", email, rec.Code, rec.Body)
+		}
+		var reg struct {
+			Token string `json:"token"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &reg)
+		users = append(users, http.Header{"Authorization": {"Bearer " + reg.Token}})
+	}
+
+	// Both users share one IP, but the vault limit runs after authentication,
+	// so each gets their own bucket rather than the exhausted per-IP one.
+	for i, authed := range users {
+		for j := range 2 {
+			if rec := send(r, http.MethodGet, "/api/v1/auth/me", "", authed); rec.Code != http.StatusOK {
+				t.Fatalf("user %d request %d: expected 200 within user burst, got %d", i+1, j+1, rec.Code)
+			}
+		}
+		if rec := send(r, http.MethodGet, "/api/v1/auth/me", "", authed); rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("user %d: expected 429 past user burst, got %d", i+1, rec.Code)
+		}
+	}
+}
+
+func TestNewRouter_UserRateLimitWithoutIPLimit(t *testing.T) {
+	r := newTestRouter(config.Config{
+		GenerateRoutes: config.RoutePolicy{UserRateLimitRPS: 0.001, UserRateLimitBurst: 2},
+	})
+
+	rec := send(r, http.MethodPost, "/api/v1/auth/register", `{"email":"a@example.com","password":"password123"}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("register: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var reg struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &reg)
+	authed := http.Header{"Authorization": {"Bearer " + reg.Token}}
+
+	// With no per-IP limit, anonymous clients are not limited at all.
+	for i := range 5 {
+		if rec := send(r, http.MethodPost, "/api/v1/generate", `{}`, nil); rec.Code != http.StatusOK {
+			t.Fatalf("anonymous %d: expected 200, got %d", i+1, rec.Code)
+		}
+	}
+
+	for i := range 2 {
+		if rec := send(r, http.MethodPost, "/api/v1/generate", `{}`, authed); rec.Code != http.StatusOK {
+			t.Fatalf("authenticated %d: expected 200 within user burst, got %d", i+1, rec.Code)
+		}
+	}
+	if rec := send(r, http.MethodPost, "/api/v1/generate", `{}`, authed); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("authenticated: expected 429 past user burst, got %d", rec.Code)
+	}
+}
+
+func TestNewRouter_GroupCORS(t *testing.T) {
+	r := newTestRouter(config.Config{
+		GenerateRoutes: config.RoutePolicy{CORSOrigins: []string{"*"}},
+		VaultRoutes: config.RoutePolicy{
+			CORSOrigins:          []string{"https://app.example.com"},
+			CORSAllowCredentials: true,
+			CORSExposeHeaders:    []string{"x-total-count", "ETag"},
+		},
+	})
+
+	preflight := func(path, origin string) *httptest.ResponseRecorder {
+		return send(r, http.MethodOptions, path, "", http.Header{
+			"Origin":                        {origin},
+			"Access-Control-Request-Method": {http.MethodGet},
+		})
+	}
+
+	rec := preflight("/api/v1/generate", "https://anywhere.example")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "https://anywhere.example" {
+		t.Errorf("expected generator to allow any origin, got %d %q", rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	// Preflights carry no token, so they must be answered before JWT auth.
+	rec = preflight("/api/v1/vault/abc", "https://app.example.com")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Errorf("expected vault preflight to succeed for allowed origin, got %d %q", rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); got != "GET, DELETE, POST, PUT, HEAD, PATCH" {
+		t.Errorf("unexpected allowed methods %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Expose-Headers"); got != "ETag, Retry-After, X-Request-ID, X-Total-Count" {
+		t.Errorf("unexpected exposed headers %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("expected vault group to allow credentials, got %q", got)
+	}
+
+	rec = preflight("/api/v1/vault", "https://evil.example")
+	if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("expected no CORS headers for a disallowed origin")
+	}
+
+	// The auth group has no CORS policy: OPTIONS is answered, but without CORS headers.
+	rec = preflight("/api/v1/auth/login", "https://app.example.com")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("expected auth group to ignore CORS, got %d %q", rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestNewRouter_HeadAndOptions(t *testing.T) {
+	r := newTestRouter(config.Config{})
+
+	rec := send(r, http.MethodHead, "/health", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Errorf("HEAD /health: expected 200, got %d", rec.Code)
+	}
+
+	// Without CORS origins, OPTIONS still lists the route's methods, and needs no token.
+	rec = send(r, http.MethodOptions, "/api/v1/vault/abc", "", nil)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Allow") != "GET, PUT, DELETE, OPTIONS" {
+		t.Errorf("OPTIONS /api/v1/vault/abc: expected 204 with Allow, got %d %q", rec.Code, rec.Header().Get("Allow"))
+	}
+	rec = send(r, http.MethodOptions, "/api/v1/vault/export", "", nil)
+	if rec.Header().Get("Allow") != "GET, HEAD, OPTIONS" {
+		t.Errorf("OPTIONS /api/v1/vault/export: unexpected Allow %q", rec.Header().Get("Allow"))
+	}
+
+	if rec := send(r, http.MethodOptions, "/api/v1/nope", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("OPTIONS on an unknown path: expected 404, got %d", rec.Code)
+	}
+}
+
+func TestNewRouter_WithoutStorage(t *testing.T) {
+	r := NewRouter(Deps{
+		Health:    handler.NewHealthHandler(crypto.HashTiming{}),
+		Generator: handler.NewGeneratorHandler(service.NewGeneratorService()),
+	})
+
+	if rec := send(r, http.MethodGet, "/health", "", nil); rec.Code != http.StatusOK {
+		t.Errorf("expected /health to be served, got %d", rec.Code)
+	}
+	if rec := send(r, http.MethodPost, "/api/v1/auth/login", `{}`, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected auth routes to be unmounted, got %d", rec.Code)
+	}
+}
+
+func TestNewRouter_RequiresJSONBodies(t *testing.T) {
+	r := newTestRouter(config.Config{})
+
+	rec := send(r, http.MethodPost, "/api/v1/generate", "length=16", http.Header{"Content-Type": {"application/x-www-form-urlencoded"}})
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("form body: expected 415, got %d", rec.Code)
+	}
+
+	rec = send(r, http.MethodPost, "/api/v1/generate", `{"length": 20}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Errorf("JSON body: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = send(r, http.MethodPost, "/api/v1/generate", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Errorf("empty body: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestNewRouter_AdminInvites(t *testing.T) {
+	const adminToken = "test-admin-token-0123456789abcdef"
+	r := newTestRouter(config.Config{InviteOnly: true, AdminToken: adminToken})
+
+	if rec := send(r, http.MethodPost, "/api/v1/admin/invites", "", nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("without admin token: expected 401, got %d", rec.Code)
+	}
+
+	rec := send(r, http.MethodPost, "/api/v1/admin/invites", "", http.Header{"Authorization": {"Bearer " + adminToken}})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("mint: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var invite struct {
+		Code string `json:"code"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &invite)
+
+	body := `{"email":"a@example.com","password":"password123","invite_code":"` + invite.Code + `"}`
+	if rec := send(r, http.MethodPost, "/api/v1/auth/register", body, nil); rec.Code != http.StatusCreated {
+		t.Errorf("register with code: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+
+	if rec := send(newTestRouter(config.Config{}), http.MethodPost, "/api/v1/admin/invites", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("no admin token configured: expected 404, got %d", rec.Code)
+	}
+}
+
+func TestNewRouter_CheckEmail(t *testing.T) {
+	r := newTestRouter(config.Config{
+		CheckEmailRoutes: config.RoutePolicy{RateLimitRPS: 0.001, RateLimitBurst: 2},
+	})
+
+	if rec := send(r, http.MethodPost, "/api/v1/auth/register", `{"email":"a@example.com","password":"password123"}`, nil); rec.Code != http.StatusCreated {
+		t.Fatalf("register: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+
+	for email, want := range map[string]bool{"a@example.com": false, "b@example.com": true} {
+		rec := send(r, http.MethodPost, "/api/v1/auth/check-email", `{"email":"`+email+`"}`, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", email, rec.Code, rec.Body)
+		}
+		var resp struct {
+			Available bool `json:"available"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if resp.Available != want {
+			t.Errorf("%s: expected available=%v, got %v", email, want, resp.Available)
+		}
+	}
+
+	rec := send(r, http.MethodPost, "/api/v1/auth/check-email", `{"email":"c@example.com"}`, nil)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 past the burst, got %d", rec.Code)
+	}
+
+	// The strict limit is separate from the rest of the auth group.
+	if rec := send(r, http.MethodPost, "/api/v1/auth/login", `{"email":"a@example.com","password":"password123"}`, nil); rec.Code != http.StatusOK {
+		t.Errorf("login: expected 200, got %d", rec.Code)
+	}
+}
+
+func TestNewRouter_AdminUserExport(t *testing.T) {
+	const adminToken = "test-admin-token-0123456789abcdef"
+	r := newTestRouter(config.Config{AdminToken: adminToken})
+	admin := http.Header{"Authorization": {"Bearer " + adminToken}}
+
+	rec := send(r, http.MethodPost, "/api/v1/auth/register", `{"email":"a@example.com","password":"password123"}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("register: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var reg struct {
+		Token string `json:"token"`
+		User  struct {
+			ID int64 `json:"id"`
+		} `json:"user"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &reg)
+	authed := http.Header{"Authorization": {"Bearer " + reg.Token}}
+
+	entry := `{"entry_id":"e1","encrypted_data":"c2VjcmV0"}`
+	if rec := send(r, http.MethodPost, "/api/v1/vault", entry, authed); rec.Code != http.StatusCreated {
+		t.Fatalf("create entry: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+
+	path := fmt.Sprintf("/api/v1/admin/users/%d/export", reg.User.ID)
+	if rec := send(r, http.MethodGet, path, "", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without admin token: expected 401, got %d", rec.Code)
+	}
+	if rec := send(r, http.MethodGet, path, "", authed); rec.Code != http.StatusUnauthorized {
+		t.Errorf("with the user's own token: expected 401, got %d", rec.Code)
+	}
+
+	rec = send(r, http.MethodGet, path, "", admin)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "no-store" {
+		t.Errorf("expected Cache-Control no-store, got %q", cc)
+	}
+	if strings.Contains(rec.Body.String(), "auth_hash") || strings.Contains(rec.Body.String(), "$argon2") {
+		t.Errorf("export must not include the auth hash: %s", rec.Body)
+	}
+	var export struct {
+		User struct {
+			Email string `json:"email"`
+		} `json:"user"`
+		Vaults []struct {
+			Default bool `json:"default"`
+			Entries []struct {
+				EntryID       string `json:"entry_id"`
+				EncryptedData string `json:"encrypted_data"`
+			} `json:"entries"`
+		} `json:"vaults"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &export)
+	if export.User.Email != "a@example.com" {
+		t.Errorf("expected the user's email, got %q", export.User.Email)
+	}
+	if len(export.Vaults) != 1 || len(export.Vaults[0].Entries) != 1 || export.Vaults[0].Entries[0].EncryptedData != "c2VjcmV0" {
+		t.Errorf("expected the default vault with the encrypted entry, got %+v", export.Vaults)
+	}
+
+	if rec := send(r, http.MethodGet, "/api/v1/admin/users/999/export", "", admin); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown user: expected 404, got %d", rec.Code)
+	}
+	if rec := send(r, http.MethodGet, "/api/v1/admin/users/abc/export", "", admin); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid id: expected 400, got %d", rec.Code)
+	}
+
+	if rec := send(newTestRouter(config.Config{}), http.MethodGet, path, "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("no admin token configured: expected 404, got %d", rec.Code)
+	}
+}
+
+func TestNewRouter_AdminListUsers(t *testing.T) {
+	const adminToken = "test-admin-token-0123456789abcdef"
+	r := newTestRouter(config.Config{AdminToken: adminToken})
+	admin := http.Header{"Authorization": {"Bearer " + adminToken}}
+
+	emails := []string{"a@example.com", "b@example.com", "c@example.com"}
+	for _, email := range emails {
+		body := `{"email":"` + email + `","password":"password123"}`
+		if rec := send(r, http.MethodPost, "/api/v1/auth/register", body, nil); rec.Code != http.StatusCreated {
+			t.Fatalf("register %s: expected 201, got %d: %s", email, rec.Code, rec.Body)
+		}
+	}
+
+	if rec := send(r, http.MethodGet, "/api/v1/admin/users", "", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without admin token: expected 401, got %d", rec.Code)
+	}
+
+	var got []string
+	path := "/api/v1/admin/users?limit=2"
+	for range 3 {
+		rec := send(r, http.MethodGet, path, "", admin)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+		}
+		if strings.Contains(rec.Body.String(), "auth_hash") || strings.Contains(rec.Body.String(), "$argon2") {
+			t.Fatalf("user list must not include auth hashes: %s", rec.Body)
+		}
+		var page model.UserListResponse
+		json.Unmarshal(rec.Body.Bytes(), &page)
+		for _, u := range page.Users {
+			got = append(got, u.Email)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		path = "/api/v1/admin/users?limit=2&cursor=" + page.NextCursor
+	}
+	if !slices.Equal(got, emails) {
+		t.Errorf("expected every user once in creation order, got %v", got)
+	}
+
+	if rec := send(r, http.MethodGet, "/api/v1/admin/users?cursor=bogus", "", admin); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid cursor: expected 400, got %d", rec.Code)
+	}
+	if rec := send(r, http.MethodGet, "/api/v1/admin/users?limit=x", "", admin); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid limit: expected 400, got %d", rec.Code)
+	}
+}
+
+func TestNewRouter_AdminMetrics(t *testing.T) {
+	const adminToken = "test-admin-token-0123456789abcdef"
+	r := newTestRouter(config.Config{
+		AdminToken:     adminToken,
+		GenerateRoutes: config.RoutePolicy{RateLimitRPS: 100, RateLimitBurst: 5},
+	})
+
+	send(r, http.MethodPost, "/api/v1/generate", "", nil)
+
+	if rec := send(r, http.MethodGet, "/api/v1/admin/metrics", "", nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("without admin token: expected 401, got %d", rec.Code)
+	}
+
+	rec := send(r, http.MethodGet, "/api/v1/admin/metrics", "", http.Header{"Authorization": {"Bearer " + adminToken}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Generator struct {
+			Total int64 `json:"total"`
+		} `json:"generator"`
+		RateLimit struct {
+			Visitors int `json:"visitors"`
+		} `json:"rate_limit"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.Generator.Total != 1 {
+		t.Errorf("expected 1 generated password, got %d", resp.Generator.Total)
+	}
+	// Only the generate group is rate limited, and it has seen one client.
+	if resp.RateLimit.Visitors != 1 {
+		t.Errorf("expected 1 rate limiter visitor, got %d", resp.RateLimit.Visitors)
+	}
+
+	if rec := send(newTestRouter(config.Config{}), http.MethodGet, "/api/v1/admin/metrics", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("no admin token configured: expected 404, got %d", rec.Code)
+	}
+}
+
+func TestNewRouter_AllowedHosts(t *testing.T) {
+	r := newTestRouter(config.Config{AllowedHosts: []string{"api.example.com", "*.example.net"}})
+
+	for host, want := range map[string]int{
+		"api.example.com":    http.StatusOK,
+		"eu.example.net:443": http.StatusOK,
+		"attacker.example":   http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("Host %q: expected %d, got %d", host, want, rec.Code)
+		}
+	}
+}
+
+func TestNewRouter_KDFProfile(t *testing.T) {
+	r := newTestRouter(config.Config{})
+
+	rec := send(r, http.MethodPost, "/api/v1/auth/register", `{"email":"a@example.com","password":"password123"}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("register: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var reg struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &reg)
+	authed := http.Header{"Authorization": {"Bearer " + reg.Token}}
+
+	rec = send(r, http.MethodGet, "/api/v1/auth/kdf-profile", "", authed)
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "{}" {
+		t.Fatalf("unset profile: expected 200 {}, got %d: %s", rec.Code, rec.Body)
+	}
+
+	profile := `{"kdf":"argon2id","iterations":3,"memory_kib":65536,"parallelism":4,"salt":"c2FsdHNhbHRzYWx0c2FsdA=="}`
+	if rec := send(r, http.MethodPut, "/api/v1/auth/kdf-profile", profile, authed); rec.Code != http.StatusOK {
+		t.Fatalf("put: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	rec = send(r, http.MethodGet, "/api/v1/auth/kdf-profile", "", authed)
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != profile {
+		t.Errorf("get: expected the stored profile, got %d: %s", rec.Code, rec.Body)
+	}
+
+	for name, body := range map[string]string{
+		"invalid fields": `{"kdf":"Argon2!","iterations":0,"salt":"short"}`,
+		"unknown field":  `{"kdf":"argon2id","iterations":3,"salt":"c2FsdHNhbHRzYWx0c2FsdA==","key":"secret"}`,
+		"too large":      `{"kdf":"` + strings.Repeat("a", 5000) + `"}`,
+	} {
+		if rec := send(r, http.MethodPut, "/api/v1/auth/kdf-profile", body, authed); rec.Code != http.StatusBadRequest && rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("%s: expected rejection, got %d: %s", name, rec.Code, rec.Body)
+		}
+	}
+	rec = send(r, http.MethodGet, "/api/v1/auth/kdf-profile", "", authed)
+	if strings.TrimSpace(rec.Body.String()) != profile {
+		t.Errorf("rejected updates must leave the profile unchanged, got %s", rec.Body)
+	}
+
+	if rec := send(r, http.MethodGet, "/api/v1/auth/kdf-profile", "", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous: expected 401, got %d", rec.Code)
+	}
+}
+
+func TestExceptBulk_ExemptsSyncImportAndExport(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+	h := exceptBulk(middleware.Timeout(10 * time.Millisecond))(slow)
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/api/v1/vault", http.StatusServiceUnavailable},
+		{"/api/v1/vault/batch-get", http.StatusServiceUnavailable},
+		{"/api/v1/vault/sync", http.StatusOK},
+		{"/api/v1/vaults/2/import", http.StatusOK},
+		{"/api/v1/vaults/2/export", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if rec := send(h, http.MethodPost, tt.path, "", nil); rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.path, tt.want, rec.Code)
+		}
+	}
+}
+
+func TestNewRouter_ClientTimeoutOnBulkRoutes(t *testing.T) {
+	r := newTestRouter(config.Config{RequestTimeout: time.Minute, ClientTimeoutMax: time.Minute})
+
+	rec := send(r, http.MethodPost, "/api/v1/auth/register", `{"email":"a@example.com","password":"password123"}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("register: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var reg struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &reg)
+	authed := http.Header{"Authorization": {"Bearer " + reg.Token}}
+
+	if rec := send(r, http.MethodPost, "/api/v1/vault/sync", `{"entries":[]}`, authed); rec.Code != http.StatusOK {
+		t.Fatalf("sync without a deadline: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	// The request timeout skips bulk routes, but the client's deadline doesn't.
+	authed.Set(middleware.RequestTimeoutHeader, "1ns")
+	for _, req := range []struct{ method, path, body string }{
+		{http.MethodPost, "/api/v1/vault/sync", `{"entries":[]}`},
+		{http.MethodGet, "/api/v1/vault/export", ""},
+	} {
+		rec := send(r, req.method, req.path, req.body, authed)
+		if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "request timed out") {
+			t.Errorf("%s: expected 503 once the client's deadline passed, got %d: %s", req.path, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestNewRouter_VaultQuota(t *testing.T) {
+	r := newTestRouter(config.Config{})
+
+	rec := send(r, http.MethodPost, "/api/v1/auth/register", `{"email":"a@example.com","password":"password123"}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("register: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var reg struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &reg)
+	authed := http.Header{"Authorization": {"Bearer " + reg.Token}}
+
+	if rec := send(r, http.MethodPost, "/api/v1/vault", `{"entry_id":"e1","encrypted_data":"c2VjcmV0"}`, authed); rec.Code != http.StatusCreated {
+		t.Fatalf("create entry: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+
+	if rec := send(r, http.MethodGet, "/api/v1/vault/quota", "", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without a token: expected 401, got %d", rec.Code)
+	}
+	rec = send(r, http.MethodGet, "/api/v1/vault/quota", "", authed)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("quota: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var quota model.VaultQuota
+	if err := json.Unmarshal(rec.Body.Bytes(), &quota); err != nil {
+		t.Fatalf("decoding quota: %v", err)
+	}
+	if quota.Entries != 1 || quota.Bytes != int64(len("secret")) || quota.MaxRequestBytes != handler.DefaultMaxVaultBody {
+		t.Errorf("unexpected quota %+v", quota)
+	}
+}
@@ -0,0 +1,31 @@
+// Package server builds the HTTP server that fronts the API router.
+package server
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"github.com/vaultpass/vaultpass-go/internal/config"
+)
+
+// New returns an http.Server for handler with the timeouts from cfg applied.
+// Every timeout is set so slow or idle clients cannot hold connections open
+// indefinitely. When TLS is configured, connections below TLS_MIN_VERSION are
+// refused.
+func New(cfg config.Config, handler http.Handler, opts ...Option) *http.Server {
+	srv := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+	if cfg.TLSCertFile != "" {
+		srv.TLSConfig = &tls.Config{MinVersion: cfg.TLSMinVersion}
+	}
+	for _, opt := range opts {
+		opt(srv)
+	}
+	return srv
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/config"
+)
+
+func TestNewSetsTimeouts(t *testing.T) {
+	cfg := config.Config{
+		Port:              "9090",
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+	handler := http.NewServeMux()
+
+	srv := New(cfg, handler)
+
+	if srv.Addr != ":9090" {
+		t.Errorf("Addr = %q, want %q", srv.Addr, ":9090")
+	}
+	if srv.Handler != handler {
+		t.Error("expected handler to be wired through")
+	}
+	if srv.ReadHeaderTimeout != 5*time.Second {
+		t.Errorf("ReadHeaderTimeout = %v, want 5s", srv.ReadHeaderTimeout)
+	}
+	if srv.ReadTimeout != 15*time.Second {
+		t.Errorf("ReadTimeout = %v, want 15s", srv.ReadTimeout)
+	}
+	if srv.WriteTimeout != 30*time.Second {
+		t.Errorf("WriteTimeout = %v, want 30s", srv.WriteTimeout)
+	}
+	if srv.IdleTimeout != 60*time.Second {
+		t.Errorf("IdleTimeout = %v, want 60s", srv.IdleTimeout)
+	}
+}
+
+func TestNewWithLoadedDefaults(t *testing.T) {
+	srv := New(config.Load(), http.NewServeMux())
+
+	for name, d := range map[string]time.Duration{
+		"ReadHeaderTimeout": srv.ReadHeaderTimeout,
+		"ReadTimeout":       srv.ReadTimeout,
+		"WriteTimeout":      srv.WriteTimeout,
+		"IdleTimeout":       srv.IdleTimeout,
+	} {
+		if d <= 0 {
+			t.Errorf("%s is unset; a zero timeout never expires", name)
+		}
+	}
+}
+
+func TestNewSetsTLSMinVersion(t *testing.T) {
+	if srv := New(config.Config{}, http.NewServeMux()); srv.TLSConfig != nil {
+		t.Error("expected no TLS config without a certificate")
+	}
+
+	srv := New(config.Config{TLSCertFile: "cert.pem", TLSKeyFile: "key.pem", TLSMinVersion: tls.VersionTLS13}, http.NewServeMux())
+	if srv.TLSConfig == nil || srv.TLSConfig.MinVersion != tls.VersionTLS13 {
+		t.Errorf("expected TLS 1.3 minimum, got %+v", srv.TLSConfig)
+	}
+}
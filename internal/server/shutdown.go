@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// Option configures the http.Server built by New.
+type Option func(*http.Server)
+
+// WithBaseContext makes ctx the parent of every request context, so cancelling it
+// tells in-flight handlers to stop. Shutdown does this once its deadline passes.
+func WithBaseContext(ctx context.Context) Option {
+	return func(srv *http.Server) {
+		srv.BaseContext = func(net.Listener) context.Context { return ctx }
+	}
+}
+
+// WithStreams closes every registered stream when the server starts shutting down.
+func WithStreams(streams *Streams) Option {
+	return func(srv *http.Server) {
+		srv.RegisterOnShutdown(streams.CloseAll)
+	}
+}
+
+// Streams tracks long-lived connections, such as hijacked WebSockets, that
+// http.Server.Shutdown neither waits for nor closes. Each stream registers a
+// close function that should send a close frame and end the stream promptly.
+type Streams struct {
+	mu      sync.Mutex
+	nextID  int
+	closers map[int]func()
+}
+
+// NewStreams creates an empty stream registry.
+func NewStreams() *Streams {
+	return &Streams{closers: make(map[int]func())}
+}
+
+// Add registers a stream's close function. The returned func removes it again
+// and must be called when the stream ends on its own.
+func (s *Streams) Add(closeFn func()) (remove func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	id := s.nextID
+	s.closers[id] = closeFn
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.closers, id)
+	}
+}
+
+// CloseAll asks every registered stream to close and forgets them.
+func (s *Streams) CloseAll() {
+	s.mu.Lock()
+	closers := s.closers
+	s.closers = make(map[int]func())
+	s.mu.Unlock()
+
+	for _, closeFn := range closers {
+		closeFn()
+	}
+}
+
+// Len returns the number of registered streams.
+func (s *Streams) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.closers)
+}
+
+// Shutdown stops srv gracefully, letting in-flight requests finish until ctx is
+// done. If they are still running then, cancelRequests cancels their contexts
+// (see WithBaseContext) and the remaining connections are closed.
+func Shutdown(ctx context.Context, srv *http.Server, cancelRequests context.CancelFunc) error {
+	err := srv.Shutdown(ctx)
+	if err == nil {
+		return nil
+	}
+
+	cancelRequests()
+	srv.Close()
+	return err
+}
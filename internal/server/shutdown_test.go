@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/config"
+)
+
+// startServer serves handler on a loopback port and returns the server and its base URL.
+func startServer(t *testing.T, handler http.Handler, opts ...Option) (*http.Server, string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() unexpected error: %v", err)
+	}
+	srv := New(config.Config{}, handler, opts...)
+	go srv.Serve(ln)
+	return srv, "http://" + ln.Addr().String()
+}
+
+func TestShutdown_InFlightRequestCompletes(t *testing.T) {
+	started := make(chan struct{})
+	srv, url := startServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		io.WriteString(w, "done")
+	}))
+
+	result := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			result <- "error: " + err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		result <- string(body)
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := Shutdown(ctx, srv, func() {}); err != nil {
+		t.Fatalf("Shutdown() unexpected error: %v", err)
+	}
+
+	if got := <-result; got != "done" {
+		t.Errorf("expected in-flight request to finish, got %q", got)
+	}
+}
+
+func TestShutdown_CancelsRequestsPastDeadline(t *testing.T) {
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+
+	started := make(chan struct{})
+	stopped := make(chan error, 1)
+	srv, url := startServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		select {
+		case <-r.Context().Done():
+			stopped <- r.Context().Err()
+		case <-time.After(10 * time.Second):
+			stopped <- nil
+		}
+	}), WithBaseContext(baseCtx))
+
+	go func() {
+		if resp, err := http.Get(url); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := Shutdown(ctx, srv, cancelRequests); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+
+	select {
+	case err := <-stopped:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected handler to observe cancellation, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler was not cancelled")
+	}
+}
+
+func TestShutdown_ClosesStreams(t *testing.T) {
+	streams := NewStreams()
+	closed := make(chan struct{})
+	streams.Add(func() { close(closed) })
+	remove := streams.Add(func() { t.Error("removed stream should not be closed") })
+	remove()
+
+	srv, _ := startServer(t, http.NotFoundHandler(), WithStreams(streams))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := Shutdown(ctx, srv, func() {}); err != nil {
+		t.Fatalf("Shutdown() unexpected error: %v", err)
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("expected registered stream to be closed on shutdown")
+	}
+	if streams.Len() != 0 {
+		t.Errorf("expected registry to be empty after shutdown, got %d", streams.Len())
+	}
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/apperr"
+	"github.com/vaultpass/vaultpass-go/internal/model"
+	"github.com/vaultpass/vaultpass-go/internal/repository"
+)
+
+var ErrUserNotFound = apperr.New(http.StatusNotFound, "user_not_found", "user not found")
+
+// AccountExportService exports whole accounts for admins, for support and data
+// portability requests.
+type AccountExportService struct {
+	users   repository.UserStore
+	vaults  repository.CollectionStore
+	entries repository.VaultStore
+}
+
+// NewAccountExportService creates a new AccountExportService.
+func NewAccountExportService(users repository.UserStore, vaults repository.CollectionStore, entries repository.VaultStore) *AccountExportService {
+	return &AccountExportService{users: users, vaults: vaults, entries: entries}
+}
+
+// Export returns the user's profile, key derivation profile and every vault
+// with all of its entries. Deactivated accounts are exported too.
+func (s *AccountExportService) Export(ctx context.Context, userID int64) (model.AccountExport, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return model.AccountExport{}, ErrUserNotFound
+	}
+	if err != nil {
+		return model.AccountExport{}, err
+	}
+
+	export := model.AccountExport{
+		ExportedAt: time.Now().UTC(),
+		User: model.AccountExportUser{
+			ID:            user.ID,
+			Email:         user.Email,
+			CreatedAt:     user.CreatedAt,
+			UpdatedAt:     user.UpdatedAt,
+			DeactivatedAt: user.DeactivatedAt,
+		},
+		Vaults: []model.AccountExportVault{},
+	}
+
+	raw, err := s.users.GetKDFProfile(ctx, userID)
+	if err != nil {
+		return model.AccountExport{}, err
+	}
+	if raw != nil {
+		export.KDFProfile = &model.KDFProfile{}
+		if err := json.Unmarshal(raw, export.KDFProfile); err != nil {
+			return model.AccountExport{}, err
+		}
+	}
+
+	vaults, err := s.vaults.ListByUser(ctx, userID)
+	if err != nil {
+		return model.AccountExport{}, err
+	}
+	entries, err := s.entries.ListAllByUser(ctx, userID)
+	if err != nil {
+		return model.AccountExport{}, err
+	}
+
+	byVault := make(map[int64][]model.VaultEntry, len(vaults))
+	for _, e := range entries {
+		byVault[e.VaultID] = append(byVault[e.VaultID], e)
+	}
+	for i := range vaults {
+		export.Vaults = append(export.Vaults, model.AccountExportVault{
+			VaultResponse: vaultToResponse(&vaults[i]),
+			Entries:       entriesToResponse(byVault[vaults[i].ID]),
+		})
+	}
+
+	return export, nil
+}
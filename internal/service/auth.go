@@ -3,63 +3,164 @@ package service
 import (
 	"context"
 	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
 	"time"
+	"unicode/utf8"
 
+	"github.com/vaultpass/vaultpass-go/internal/apperr"
 	"github.com/vaultpass/vaultpass-go/internal/crypto"
 	"github.com/vaultpass/vaultpass-go/internal/model"
 	"github.com/vaultpass/vaultpass-go/internal/repository"
 )
 
 var (
-	ErrInvalidCredentials = errors.New("invalid email or password")
-	ErrEmailRequired      = errors.New("email is required")
-	ErrPasswordRequired   = errors.New("password is required")
-	ErrEmailTaken         = errors.New("email already taken")
+	ErrInvalidCredentials = apperr.New(http.StatusUnauthorized, "invalid_credentials", "invalid email or password")
+	ErrEmailRequired      = model.ErrEmailRequired
+	ErrPasswordRequired   = model.ErrPasswordRequired
+	ErrPasswordBreached   = model.ErrPasswordBreached
+	ErrWeakPassword       = model.ErrWeakPassword
+	ErrEmailTaken         = apperr.New(http.StatusConflict, "email_taken", "email already taken")
+	ErrInvalidCursor      = apperr.Wrap(http.StatusBadRequest, "invalid_cursor", repository.ErrInvalidCursor)
+	ErrAccountDeactivated = apperr.Wrap(http.StatusForbidden, "account_deactivated", model.ErrAccountDeactivated)
+)
+
+// verifyPassword is the password check used by Login; tests may replace it.
+var verifyPassword = crypto.VerifyPasswordWith
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 100
 )
 
 // AuthService handles authentication business logic.
 type AuthService struct {
-	repo      *repository.UserRepository
-	jwtSecret string
-	jwtExpiry time.Duration
+	repo     repository.UserStore
+	audit    repository.AuditStore
+	sessions repository.SessionStore
+	tokens   *crypto.TokenManager
+	breaches *crypto.BreachChecker
+	common   *crypto.CommonPasswords
+	invites  repository.InviteStore
+	epochs   *epochCache
+
+	hashParams crypto.HashParams
+	hashLimits crypto.HashLimits
+	// dummyHash is a fixed hash, made with hashParams, verified against when a
+	// login email is unknown, so both paths spend comparable time in Argon2
+	// and timing doesn't reveal registration.
+	dummyHash func() string
+
+	emailCheckFloor time.Duration
+}
+
+// AuthOption configures an AuthService.
+type AuthOption func(*AuthService)
+
+// WithRegistrationBreachCheck rejects registrations whose password appears in a
+// known breach. If the lookup fails, registration proceeds unchecked.
+func WithRegistrationBreachCheck(checker *crypto.BreachChecker) AuthOption {
+	return func(s *AuthService) {
+		s.breaches = checker
+	}
+}
+
+// WithCommonPasswordCheck rejects registrations whose password is in common.
+func WithCommonPasswordCheck(common *crypto.CommonPasswords) AuthOption {
+	return func(s *AuthService) {
+		s.common = common
+	}
+}
+
+// WithInviteOnly requires every registration to claim an unused, unexpired
+// invite code from invites.
+func WithInviteOnly(invites repository.InviteStore) AuthOption {
+	return func(s *AuthService) {
+		s.invites = invites
+	}
+}
+
+// WithHashParams sets the Argon2id cost of new password hashes, which
+// defaults to crypto.DefaultHashParams.
+func WithHashParams(params crypto.HashParams) AuthOption {
+	return func(s *AuthService) {
+		s.hashParams = params
+	}
+}
+
+// WithHashLimits bounds the Argon2id parameters accepted from stored hashes
+// at login, which defaults to crypto.DefaultHashLimits. A hash outside them is
+// rejected before any hashing work is done.
+func WithHashLimits(limits crypto.HashLimits) AuthOption {
+	return func(s *AuthService) {
+		s.hashLimits = limits
+	}
 }
 
 // NewAuthService creates a new AuthService.
-func NewAuthService(repo *repository.UserRepository, secret string, expiry time.Duration) *AuthService {
-	return &AuthService{
-		repo:      repo,
-		jwtSecret: secret,
-		jwtExpiry: expiry,
+func NewAuthService(repo repository.UserStore, audit repository.AuditStore, sessions repository.SessionStore, tokens *crypto.TokenManager, opts ...AuthOption) *AuthService {
+	s := &AuthService{
+		repo:     repo,
+		audit:    audit,
+		sessions: sessions,
+		tokens:   tokens,
+		epochs:   newEpochCache(tokenEpochTTL),
+
+		hashParams:      crypto.DefaultHashParams(),
+		hashLimits:      crypto.DefaultHashLimits(),
+		emailCheckFloor: defaultEmailCheckFloor,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	s.dummyHash = sync.OnceValue(func() string {
+		hash, err := crypto.HashPasswordWith("vaultpass-dummy-password", s.hashParams)
+		if err != nil {
+			panic("generating dummy hash: " + err.Error())
+		}
+		return hash
+	})
+	return s
 }
 
 // Register creates a new user account and returns an auth token.
-func (s *AuthService) Register(ctx context.Context, req model.CreateUserRequest) (model.AuthResponse, error) {
-	if req.Email == "" {
-		return model.AuthResponse{}, ErrEmailRequired
+func (s *AuthService) Register(ctx context.Context, req model.CreateUserRequest, client model.ClientInfo) (model.AuthResponse, error) {
+	if err := req.Validate(); err != nil {
+		return model.AuthResponse{}, err
 	}
-	if req.Password == "" {
-		return model.AuthResponse{}, ErrPasswordRequired
+	if err := s.checkCommon(req.Password); err != nil {
+		return model.AuthResponse{}, err
+	}
+	if err := s.checkBreached(ctx, req.Password); err != nil {
+		return model.AuthResponse{}, err
 	}
 
-	hash, err := crypto.HashPassword(req.Password)
+	release, err := s.claimInvite(ctx, req)
 	if err != nil {
 		return model.AuthResponse{}, err
 	}
 
+	hash, err := crypto.HashPasswordWith(req.Password, s.hashParams)
+	if err != nil {
+		release()
+		return model.AuthResponse{}, err
+	}
+
 	user := &model.User{
 		Email:    req.Email,
 		AuthHash: hash,
 	}
 
 	if err := s.repo.Create(ctx, user); err != nil {
+		release()
 		if errors.Is(err, repository.ErrDuplicateEmail) {
 			return model.AuthResponse{}, ErrEmailTaken
 		}
 		return model.AuthResponse{}, err
 	}
 
-	token, err := crypto.GenerateToken(user.ID, s.jwtSecret, s.jwtExpiry)
+	token, err := s.issueToken(ctx, user, client, false)
 	if err != nil {
 		return model.AuthResponse{}, err
 	}
@@ -74,25 +175,118 @@ func (s *AuthService) Register(ctx context.Context, req model.CreateUserRequest)
 	}, nil
 }
 
+// checkCommon returns a validation error if password is a common password.
+// Flows that set a new password should call it alongside checkBreached.
+func (s *AuthService) checkCommon(password string) error {
+	if !s.common.Contains(password) {
+		return nil
+	}
+	var verr model.ValidationError
+	verr.Add("password", "weak", ErrWeakPassword)
+	return verr.Err()
+}
+
+// checkBreached returns a validation error if password is known to be breached.
+// Lookup failures are logged and treated as not breached.
+func (s *AuthService) checkBreached(ctx context.Context, password string) error {
+	if s.breaches == nil {
+		return nil
+	}
+	count, err := s.breaches.Count(ctx, password)
+	if err != nil {
+		slog.Warn("breach check unavailable, registering unchecked", "error", err)
+		return nil
+	}
+	if count == 0 {
+		return nil
+	}
+	var verr model.ValidationError
+	verr.Add("password", "breached", ErrPasswordBreached)
+	return verr.Err()
+}
+
 // Login authenticates a user and returns an auth token.
-func (s *AuthService) Login(ctx context.Context, req model.LoginRequest) (model.AuthResponse, error) {
+// Every attempt, successful or not, is recorded in the login audit log.
+// Deactivated accounts are refused with ErrAccountDeactivated once their credentials check out.
+func (s *AuthService) Login(ctx context.Context, req model.LoginRequest, client model.ClientInfo) (model.AuthResponse, error) {
+	user, err := s.authenticate(ctx, req, client)
+	if err != nil {
+		return model.AuthResponse{}, err
+	}
+	if !user.Active() {
+		s.recordLogin(ctx, newLoginEvent(&user.ID, req.Email, false, client))
+		return model.AuthResponse{}, ErrAccountDeactivated
+	}
+	s.recordLogin(ctx, newLoginEvent(&user.ID, req.Email, true, client))
+
+	return s.signIn(ctx, user, client, req.Remember)
+}
+
+// Deactivate disables the user's account without deleting its data and revokes
+// every active session. The account can be restored with Reactivate until it is purged.
+func (s *AuthService) Deactivate(ctx context.Context, userID int64) error {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.Active() {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	if err := s.repo.SetDeactivatedAt(ctx, userID, &now); err != nil {
+		return err
+	}
+	s.epochs.set(userID, user.TokenEpoch, false, now)
+
+	return s.revokeSessions(ctx, userID)
+}
+
+// Reactivate restores a deactivated account after re-checking its credentials
+// and signs the user in. Reactivating an active account behaves like Login.
+func (s *AuthService) Reactivate(ctx context.Context, req model.LoginRequest, client model.ClientInfo) (model.AuthResponse, error) {
+	user, err := s.authenticate(ctx, req, client)
+	if err != nil {
+		return model.AuthResponse{}, err
+	}
+	if !user.Active() {
+		if err := s.repo.SetDeactivatedAt(ctx, user.ID, nil); err != nil {
+			return model.AuthResponse{}, err
+		}
+		user.DeactivatedAt = nil
+		s.epochs.set(user.ID, user.TokenEpoch, true, time.Now())
+	}
+	s.recordLogin(ctx, newLoginEvent(&user.ID, req.Email, true, client))
+
+	return s.signIn(ctx, user, client, req.Remember)
+}
+
+// authenticate looks up the user and verifies their password, recording failed attempts.
+func (s *AuthService) authenticate(ctx context.Context, req model.LoginRequest, client model.ClientInfo) (*model.User, error) {
 	user, err := s.repo.GetByEmail(ctx, req.Email)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
-			return model.AuthResponse{}, ErrInvalidCredentials
+			s.checkCredentials(nil, req.Password)
+			s.recordLogin(ctx, newLoginEvent(nil, req.Email, false, client))
+			return nil, ErrInvalidCredentials
 		}
-		return model.AuthResponse{}, err
+		return nil, err
 	}
 
-	match, err := crypto.VerifyPassword(req.Password, user.AuthHash)
+	match, err := s.checkCredentials(user, req.Password)
 	if err != nil {
-		return model.AuthResponse{}, err
+		return nil, err
 	}
 	if !match {
-		return model.AuthResponse{}, ErrInvalidCredentials
+		s.recordLogin(ctx, newLoginEvent(&user.ID, req.Email, false, client))
+		return nil, ErrInvalidCredentials
 	}
+	return user, nil
+}
 
-	token, err := crypto.GenerateToken(user.ID, s.jwtSecret, s.jwtExpiry)
+// signIn issues a token for an authenticated user, long-lived if remember is set.
+func (s *AuthService) signIn(ctx context.Context, user *model.User, client model.ClientInfo, remember bool) (model.AuthResponse, error) {
+	token, err := s.issueToken(ctx, user, client, remember)
 	if err != nil {
 		return model.AuthResponse{}, err
 	}
@@ -120,3 +314,109 @@ func (s *AuthService) GetUser(ctx context.Context, userID int64) (model.UserResp
 		CreatedAt: user.CreatedAt,
 	}, nil
 }
+
+// LoginHistory returns a page of the user's login events, most recent first.
+// Pages can be walked with NextCursor, which stays stable as new events arrive.
+func (s *AuthService) LoginHistory(ctx context.Context, userID int64, q model.LoginHistoryQuery) (model.LoginHistoryResponse, error) {
+	limit, offset := normalizePage(q.Limit, q.Offset)
+
+	filter := model.LoginEventFilter{
+		Limit:   limit + 1, // one extra row tells us whether another page exists
+		Offset:  offset,
+		Success: q.Success,
+	}
+	if q.Cursor != "" {
+		c, err := repository.DecodeCursor(q.Cursor)
+		if err != nil {
+			return model.LoginHistoryResponse{}, ErrInvalidCursor
+		}
+		filter.Before = &model.LoginCursor{CreatedAt: c.At, ID: c.ID}
+	}
+
+	events, err := s.audit.ListLoginsByUser(ctx, userID, filter)
+	if err != nil {
+		return model.LoginHistoryResponse{}, err
+	}
+
+	events, nextCursor, hasMore := repository.Paginate(events, limit, func(e model.LoginEvent) repository.Cursor {
+		return repository.Cursor{At: e.CreatedAt, ID: e.ID}
+	})
+
+	result := make([]model.LoginEventResponse, len(events))
+	for i, e := range events {
+		result[i] = model.LoginEventResponse{
+			ID:        e.ID,
+			Success:   e.Success,
+			IPAddress: e.IPAddress,
+			UserAgent: e.UserAgent,
+			CreatedAt: e.CreatedAt,
+		}
+	}
+
+	return model.LoginHistoryResponse{
+		Events:     result,
+		Limit:      limit,
+		Offset:     offset,
+		NextCursor: nextCursor,
+		HasMore:    hasMore,
+	}, nil
+}
+
+// checkCredentials verifies password against the user's stored hash. For a nil user
+// it verifies against s.dummyHash and always reports no match, keeping the timing of
+// unknown-email logins close to that of wrong-password logins.
+func (s *AuthService) checkCredentials(user *model.User, password string) (bool, error) {
+	if user == nil {
+		verifyPassword(password, s.dummyHash(), s.hashLimits)
+		return false, nil
+	}
+	return verifyPassword(password, user.AuthHash, s.hashLimits)
+}
+
+// recordLogin writes a login event to the audit log. Failures are logged but
+// never block authentication.
+func (s *AuthService) recordLogin(ctx context.Context, event *model.LoginEvent) {
+	if s.audit == nil {
+		return
+	}
+	if err := s.audit.RecordLogin(ctx, event); err != nil {
+		slog.Warn("failed to record login event", "success", event.Success, "error", err)
+	}
+}
+
+// newLoginEvent builds an audit event for a login attempt. userID is nil when
+// the email does not belong to any account, so the event is never visible to a user.
+func newLoginEvent(userID *int64, email string, success bool, client model.ClientInfo) *model.LoginEvent {
+	return &model.LoginEvent{
+		UserID:    userID,
+		Email:     truncate(email, 255),
+		Success:   success,
+		IPAddress: truncate(client.IPAddress, 45),
+		UserAgent: truncate(client.UserAgent, 255),
+	}
+}
+
+// normalizePage clamps pagination parameters to sane bounds.
+func normalizePage(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -2,9 +2,16 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
+	"github.com/vaultpass/vaultpass-go/internal/crypto"
 	"github.com/vaultpass/vaultpass-go/internal/model"
 	"github.com/vaultpass/vaultpass-go/internal/repository"
 )
@@ -12,8 +19,9 @@ import (
 func newTestAuthService() *AuthService {
 	return NewAuthService(
 		repository.NewUserRepository(nil),
-		"test-secret",
-		time.Hour,
+		repository.NewAuditRepository(nil),
+		repository.NewSessionRepository(nil),
+		crypto.NewTokenManager(crypto.TokenConfig{Secret: "test-secret", Expiry: time.Hour}),
 	)
 }
 
@@ -23,9 +31,9 @@ func TestRegister_EmptyEmail(t *testing.T) {
 	_, err := svc.Register(context.Background(), model.CreateUserRequest{
 		Email:    "",
 		Password: "password123",
-	})
+	}, model.ClientInfo{})
 
-	if err != ErrEmailRequired {
+	if !errors.Is(err, ErrEmailRequired) {
 		t.Errorf("expected ErrEmailRequired, got %v", err)
 	}
 }
@@ -36,9 +44,542 @@ func TestRegister_EmptyPassword(t *testing.T) {
 	_, err := svc.Register(context.Background(), model.CreateUserRequest{
 		Email:    "test@example.com",
 		Password: "",
-	})
+	}, model.ClientInfo{})
 
-	if err != ErrPasswordRequired {
+	if !errors.Is(err, ErrPasswordRequired) {
 		t.Errorf("expected ErrPasswordRequired, got %v", err)
 	}
 }
+
+func TestNewLoginEvent_UnknownEmail(t *testing.T) {
+	event := newLoginEvent(nil, "nobody@example.com", false, model.ClientInfo{
+		IPAddress: "203.0.113.7",
+		UserAgent: "curl/8.0",
+	})
+
+	if event.UserID != nil {
+		t.Errorf("expected nil user ID for unknown email, got %d", *event.UserID)
+	}
+	if event.Email != "nobody@example.com" {
+		t.Errorf("expected attempted email to be recorded, got %q", event.Email)
+	}
+	if event.Success {
+		t.Error("expected failed login event")
+	}
+	if event.IPAddress != "203.0.113.7" || event.UserAgent != "curl/8.0" {
+		t.Errorf("unexpected client info: %q %q", event.IPAddress, event.UserAgent)
+	}
+}
+
+func TestNewLoginEvent_TruncatesUserAgent(t *testing.T) {
+	userID := int64(7)
+	event := newLoginEvent(&userID, "test@example.com", true, model.ClientInfo{
+		UserAgent: strings.Repeat("a", 1000),
+	})
+
+	if event.UserID == nil || *event.UserID != 7 {
+		t.Fatal("expected user ID to be recorded")
+	}
+	if len(event.UserAgent) != 255 {
+		t.Errorf("expected user agent truncated to 255, got %d", len(event.UserAgent))
+	}
+}
+
+func TestTruncate_KeepsRunesWhole(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"abcdef", 3, "abc"},
+		{"añb", 2, "a"},  // ñ is two bytes
+		{"añb", 3, "añ"}, // cut right after ñ
+		{"日本", 4, "日"},   // 日 is three bytes
+		{"日本", 2, ""},
+	}
+	for _, tt := range tests {
+		got := truncate(tt.s, tt.n)
+		if got != tt.want || !utf8.ValidString(got) {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+
+	event := newLoginEvent(nil, "test@example.com", false, model.ClientInfo{
+		UserAgent: strings.Repeat("é", 200),
+	})
+	// Byte 255 falls inside an é, so the cut steps back one byte.
+	if len(event.UserAgent) != 254 || !utf8.ValidString(event.UserAgent) {
+		t.Errorf("expected a valid user agent of 254 bytes, got %d bytes, valid=%v", len(event.UserAgent), utf8.ValidString(event.UserAgent))
+	}
+}
+
+func TestNormalizePage(t *testing.T) {
+	tests := []struct {
+		limit, offset         int
+		wantLimit, wantOffset int
+	}{
+		{0, 0, defaultHistoryLimit, 0},
+		{-5, -1, defaultHistoryLimit, 0},
+		{10, 20, 10, 20},
+		{1000, 0, maxHistoryLimit, 0},
+	}
+
+	for _, tt := range tests {
+		limit, offset := normalizePage(tt.limit, tt.offset)
+		if limit != tt.wantLimit || offset != tt.wantOffset {
+			t.Errorf("normalizePage(%d, %d) = (%d, %d), want (%d, %d)",
+				tt.limit, tt.offset, limit, offset, tt.wantLimit, tt.wantOffset)
+		}
+	}
+}
+
+func TestRecordLogin_NilAuditRepository(t *testing.T) {
+	svc := NewAuthService(repository.NewUserRepository(nil), nil, nil,
+		crypto.NewTokenManager(crypto.TokenConfig{Secret: "test-secret", Expiry: time.Hour}))
+
+	// Must not panic when auditing is not configured.
+	svc.recordLogin(context.Background(), newLoginEvent(nil, "test@example.com", false, model.ClientInfo{}))
+}
+
+func TestCheckCredentials_UnknownUserRunsDummyVerify(t *testing.T) {
+	var verified []string
+	original := verifyPassword
+	verifyPassword = func(password, encodedHash string, limits crypto.HashLimits) (bool, error) {
+		verified = append(verified, encodedHash)
+		return original(password, encodedHash, limits)
+	}
+	defer func() { verifyPassword = original }()
+
+	svc := newTestAuthService()
+	match, err := svc.checkCredentials(nil, "password123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if match {
+		t.Fatal("unknown user must never match")
+	}
+	if len(verified) != 1 || verified[0] != svc.dummyHash() {
+		t.Fatalf("expected one Argon2 verification against the dummy hash, got %d", len(verified))
+	}
+}
+
+func TestCheckCredentials_DummyHashNeverMatches(t *testing.T) {
+	// Even the password used to build the dummy hash must not authenticate an unknown user.
+	match, err := newTestAuthService().checkCredentials(nil, "vaultpass-dummy-password")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if match {
+		t.Fatal("unknown user must never match")
+	}
+}
+
+func TestCheckCredentials_KnownUser(t *testing.T) {
+	hash, err := crypto.HashPassword("password123")
+	if err != nil {
+		t.Fatalf("HashPassword() unexpected error: %v", err)
+	}
+	user := &model.User{ID: 1, AuthHash: hash}
+	svc := newTestAuthService()
+
+	if match, _ := svc.checkCredentials(user, "password123"); !match {
+		t.Error("expected correct password to match")
+	}
+	if match, _ := svc.checkCredentials(user, "wrong"); match {
+		t.Error("expected wrong password not to match")
+	}
+}
+
+func newMemoryAuthService() (*AuthService, *repository.MemoryAuditRepository) {
+	audit := repository.NewMemoryAuditRepository()
+	return NewAuthService(
+		repository.NewMemoryUserRepository(),
+		audit,
+		repository.NewMemorySessionRepository(),
+		crypto.NewTokenManager(crypto.TokenConfig{Secret: "test-secret", Expiry: time.Hour}),
+	), audit
+}
+
+func TestLogin_RecordsAuditEvents(t *testing.T) {
+	svc, audit := newMemoryAuthService()
+	ctx := context.Background()
+	client := model.ClientInfo{IPAddress: "198.51.100.1", UserAgent: "test"}
+
+	reg, err := svc.Register(ctx, model.CreateUserRequest{Email: "a@example.com", Password: "password123"}, client)
+	if err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+
+	if _, err := svc.Login(ctx, model.LoginRequest{Email: "a@example.com", Password: "wrong"}, client); err != ErrInvalidCredentials {
+		t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+	}
+	if _, err := svc.Login(ctx, model.LoginRequest{Email: "nobody@example.com", Password: "x"}, client); err != ErrInvalidCredentials {
+		t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+	}
+	if _, err := svc.Login(ctx, model.LoginRequest{Email: "a@example.com", Password: "password123"}, client); err != nil {
+		t.Fatalf("Login() unexpected error: %v", err)
+	}
+
+	history, err := svc.LoginHistory(ctx, reg.User.ID, model.LoginHistoryQuery{})
+	if err != nil {
+		t.Fatalf("LoginHistory() unexpected error: %v", err)
+	}
+	if len(history.Events) != 2 || !history.Events[0].Success || history.Events[1].Success {
+		t.Fatalf("expected [success, failure] for the user, got %+v", history.Events)
+	}
+
+	// The unknown-email attempt is stored but belongs to no user.
+	if all, _ := audit.ListLoginsByUser(ctx, 0, model.LoginEventFilter{Limit: 100}); len(all) != 0 {
+		t.Errorf("expected unknown-email events to be unattributed, got %d", len(all))
+	}
+}
+
+func TestRegister_UsesConfiguredHashParams(t *testing.T) {
+	params := crypto.DefaultHashParams()
+	params.Memory, params.Iterations, params.Parallelism = 8*1024, 1, 1
+	users := repository.NewMemoryUserRepository()
+	tokens := crypto.NewTokenManager(crypto.TokenConfig{Secret: "test-secret", Expiry: time.Hour})
+	svc := NewAuthService(users, repository.NewMemoryAuditRepository(), repository.NewMemorySessionRepository(), tokens,
+		WithHashParams(params))
+	ctx := context.Background()
+
+	if _, err := svc.Register(ctx, model.CreateUserRequest{Email: "a@example.com", Password: "password123"}, model.ClientInfo{}); err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+	user, err := users.GetByEmail(ctx, "a@example.com")
+	if err != nil {
+		t.Fatalf("GetByEmail() unexpected error: %v", err)
+	}
+	const want = "$m=8192,t=1,p=1$"
+	if !strings.Contains(user.AuthHash, want) {
+		t.Errorf("expected the stored hash to use %q, got %q", want, user.AuthHash)
+	}
+	if !strings.Contains(svc.dummyHash(), want) {
+		t.Errorf("expected the dummy hash to use the same parameters, got %q", svc.dummyHash())
+	}
+	if _, err := svc.Login(ctx, model.LoginRequest{Email: "a@example.com", Password: "password123"}, model.ClientInfo{}); err != nil {
+		t.Errorf("Login() unexpected error: %v", err)
+	}
+}
+
+func TestLogin_RejectsHashOverConfiguredLimits(t *testing.T) {
+	users := repository.NewMemoryUserRepository()
+	tokens := crypto.NewTokenManager(crypto.TokenConfig{Secret: "test-secret", Expiry: time.Hour})
+	ctx := context.Background()
+
+	register := NewAuthService(users, repository.NewMemoryAuditRepository(), repository.NewMemorySessionRepository(), tokens)
+	if _, err := register.Register(ctx, model.CreateUserRequest{Email: "a@example.com", Password: "password123"}, model.ClientInfo{}); err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+
+	limits := crypto.DefaultHashLimits()
+	limits.MaxMemory = crypto.DefaultHashParams().Memory - 1
+	svc := NewAuthService(users, repository.NewMemoryAuditRepository(), repository.NewMemorySessionRepository(), tokens,
+		WithHashParams(crypto.HashParams{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}),
+		WithHashLimits(limits))
+	if _, err := svc.Login(ctx, model.LoginRequest{Email: "a@example.com", Password: "password123"}, model.ClientInfo{}); !errors.Is(err, crypto.ErrHashParamsOutOfRange) {
+		t.Errorf("expected a hash over the configured limits to be rejected, got %v", err)
+	}
+}
+
+func TestLogin_RememberIssuesLongerToken(t *testing.T) {
+	tokens := crypto.NewTokenManager(crypto.TokenConfig{Secret: "test-secret", Expiry: time.Hour, RememberExpiry: 30 * 24 * time.Hour})
+	svc := NewAuthService(repository.NewMemoryUserRepository(), repository.NewMemoryAuditRepository(), repository.NewMemorySessionRepository(), tokens)
+	ctx := context.Background()
+
+	reg, err := svc.Register(ctx, model.CreateUserRequest{Email: "a@example.com", Password: "password123"}, model.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+
+	lifetime := func(remember bool) time.Duration {
+		t.Helper()
+		resp, err := svc.Login(ctx, model.LoginRequest{Email: "a@example.com", Password: "password123", Remember: remember}, model.ClientInfo{})
+		if err != nil {
+			t.Fatalf("Login(remember=%v) unexpected error: %v", remember, err)
+		}
+		claims, err := tokens.Validate(resp.Token)
+		if err != nil {
+			t.Fatalf("Validate() unexpected error: %v", err)
+		}
+		return claims.ExpiresAt.Sub(claims.IssuedAt.Time)
+	}
+
+	if got := lifetime(false); got != time.Hour {
+		t.Errorf("default login token lasts %v, want 1h", got)
+	}
+	if got := lifetime(true); got != 30*24*time.Hour {
+		t.Errorf("remembered login token lasts %v, want 720h", got)
+	}
+
+	// The server-side session must outlive the token it backs, or it would be
+	// rejected early.
+	sessions, err := svc.ListSessions(ctx, reg.User.ID, "")
+	if err != nil {
+		t.Fatalf("ListSessions() unexpected error: %v", err)
+	}
+	var longest time.Time
+	for _, sess := range sessions {
+		if sess.ExpiresAt.After(longest) {
+			longest = sess.ExpiresAt
+		}
+	}
+	if time.Until(longest) < 29*24*time.Hour {
+		t.Errorf("expected the remembered session to last about 30 days, expires at %v", longest)
+	}
+}
+
+func TestRevokeSession_TokenStopsValidating(t *testing.T) {
+	svc, _ := newMemoryAuthService()
+	ctx := context.Background()
+
+	resp, err := svc.Register(ctx, model.CreateUserRequest{Email: "a@example.com", Password: "password123"}, model.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+	claims, err := svc.tokens.Validate(resp.Token)
+	if err != nil {
+		t.Fatalf("Validate() unexpected error: %v", err)
+	}
+	if err := svc.ValidateSession(ctx, claims); err != nil {
+		t.Fatalf("expected fresh session to validate, got %v", err)
+	}
+
+	sessions, err := svc.ListSessions(ctx, resp.User.ID, claims.ID)
+	if err != nil || len(sessions) != 1 || !sessions[0].Current {
+		t.Fatalf("expected one current session, got %+v (err %v)", sessions, err)
+	}
+
+	if err := svc.RevokeSession(ctx, resp.User.ID, claims.ID); err != nil {
+		t.Fatalf("RevokeSession() unexpected error: %v", err)
+	}
+	if err := svc.ValidateSession(ctx, claims); err != ErrSessionRevoked {
+		t.Errorf("expected ErrSessionRevoked after revocation, got %v", err)
+	}
+	if err := svc.RevokeSession(ctx, resp.User.ID, claims.ID); err != ErrSessionNotFound {
+		t.Errorf("expected ErrSessionNotFound revoking twice, got %v", err)
+	}
+}
+
+func TestDeactivate_BlocksLoginUntilReactivated(t *testing.T) {
+	svc, _ := newMemoryAuthService()
+	ctx := context.Background()
+	creds := model.LoginRequest{Email: "a@example.com", Password: "password123"}
+
+	reg, err := svc.Register(ctx, model.CreateUserRequest{Email: creds.Email, Password: creds.Password}, model.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+	claims, err := svc.tokens.Validate(reg.Token)
+	if err != nil {
+		t.Fatalf("Validate() unexpected error: %v", err)
+	}
+
+	if err := svc.Deactivate(ctx, reg.User.ID); err != nil {
+		t.Fatalf("Deactivate() unexpected error: %v", err)
+	}
+	if err := svc.ValidateSession(ctx, claims); err != ErrAccountDeactivated {
+		t.Errorf("expected ErrAccountDeactivated for existing token, got %v", err)
+	}
+	if _, err := svc.Login(ctx, creds, model.ClientInfo{}); err != ErrAccountDeactivated {
+		t.Fatalf("expected ErrAccountDeactivated from Login, got %v", err)
+	}
+	if _, err := svc.Login(ctx, model.LoginRequest{Email: creds.Email, Password: "wrong"}, model.ClientInfo{}); err != ErrInvalidCredentials {
+		t.Errorf("expected wrong password to report ErrInvalidCredentials, got %v", err)
+	}
+	if _, err := svc.Reactivate(ctx, model.LoginRequest{Email: creds.Email, Password: "wrong"}, model.ClientInfo{}); err != ErrInvalidCredentials {
+		t.Errorf("expected Reactivate to require valid credentials, got %v", err)
+	}
+
+	resp, err := svc.Reactivate(ctx, creds, model.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Reactivate() unexpected error: %v", err)
+	}
+	if resp.Token == "" {
+		t.Error("expected Reactivate to issue a token")
+	}
+	if _, err := svc.Login(ctx, creds, model.ClientInfo{}); err != nil {
+		t.Errorf("expected Login to succeed after reactivation, got %v", err)
+	}
+
+	// Deactivation revoked the old session, so its token stays invalid.
+	if err := svc.ValidateSession(ctx, claims); err != ErrSessionRevoked {
+		t.Errorf("expected pre-deactivation session to stay revoked, got %v", err)
+	}
+}
+
+// seedLoginEvents records n events for user 1, where every third attempt failed.
+func seedLoginEvents(t *testing.T, audit *repository.MemoryAuditRepository, n int) {
+	t.Helper()
+	userID := int64(1)
+	for i := range n {
+		event := &model.LoginEvent{UserID: &userID, Email: "a@example.com", Success: i%3 != 0}
+		if err := audit.RecordLogin(context.Background(), event); err != nil {
+			t.Fatalf("RecordLogin() unexpected error: %v", err)
+		}
+	}
+}
+
+func TestLoginHistory_CursorPaging(t *testing.T) {
+	svc, audit := newMemoryAuthService()
+	seedLoginEvents(t, audit, 7)
+	ctx := context.Background()
+
+	var ids []int64
+	q := model.LoginHistoryQuery{Limit: 3}
+	for page := 0; ; page++ {
+		if page > 5 {
+			t.Fatal("cursor paging did not terminate")
+		}
+		resp, err := svc.LoginHistory(ctx, 1, q)
+		if err != nil {
+			t.Fatalf("LoginHistory() unexpected error: %v", err)
+		}
+		for _, e := range resp.Events {
+			ids = append(ids, e.ID)
+		}
+		if resp.NextCursor == "" {
+			break
+		}
+		q.Cursor = resp.NextCursor
+	}
+
+	want := []int64{7, 6, 5, 4, 3, 2, 1}
+	if fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("expected every event once, newest first: got %v, want %v", ids, want)
+	}
+}
+
+func TestLoginHistory_FailedOnly(t *testing.T) {
+	svc, audit := newMemoryAuthService()
+	seedLoginEvents(t, audit, 7)
+	failed := false
+
+	resp, err := svc.LoginHistory(context.Background(), 1, model.LoginHistoryQuery{Success: &failed})
+	if err != nil {
+		t.Fatalf("LoginHistory() unexpected error: %v", err)
+	}
+
+	var ids []int64
+	for _, e := range resp.Events {
+		if e.Success {
+			t.Errorf("expected only failed attempts, got success event %d", e.ID)
+		}
+		ids = append(ids, e.ID)
+	}
+	if fmt.Sprint(ids) != "[7 4 1]" {
+		t.Errorf("expected failed events [7 4 1], got %v", ids)
+	}
+	if resp.NextCursor != "" {
+		t.Errorf("expected no further page, got cursor %q", resp.NextCursor)
+	}
+}
+
+func TestLoginHistory_InvalidCursor(t *testing.T) {
+	svc, _ := newMemoryAuthService()
+
+	for _, cursor := range []string{"!!!", "bm8tY29sb24", "YWJjOjEy"} {
+		_, err := svc.LoginHistory(context.Background(), 1, model.LoginHistoryQuery{Cursor: cursor})
+		if !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("cursor %q: expected ErrInvalidCursor, got %v", cursor, err)
+		}
+	}
+}
+
+// stubBreaches returns a BreachChecker whose range lookups answer with body,
+// or fail if body is empty.
+func stubBreaches(body string) *crypto.BreachChecker {
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if body == "" {
+			return nil, errors.New("range API down")
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+	})}
+	return crypto.NewBreachChecker(crypto.WithBreachClient(client))
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// SHA-1("password123") suffix after the 5-character prefix "CBFDA".
+const password123Suffix = "C6008F9CAB4083784CBD1874F76618D2A97:251682"
+
+func TestRegister_BreachCheck(t *testing.T) {
+	newSvc := func(body string) *AuthService {
+		return NewAuthService(
+			repository.NewMemoryUserRepository(),
+			repository.NewMemoryAuditRepository(),
+			repository.NewMemorySessionRepository(),
+			crypto.NewTokenManager(crypto.TokenConfig{Secret: "test-secret", Expiry: time.Hour}),
+			WithRegistrationBreachCheck(stubBreaches(body)),
+		)
+	}
+	req := model.CreateUserRequest{Email: "a@example.com", Password: "password123"}
+
+	_, err := newSvc(password123Suffix).Register(context.Background(), req, model.ClientInfo{})
+	if !errors.Is(err, ErrPasswordBreached) {
+		t.Fatalf("breached password: expected ErrPasswordBreached, got %v", err)
+	}
+
+	if _, err := newSvc("0018A45C4D1DEF81644B54AB7F969B88D65:1").Register(context.Background(), req, model.ClientInfo{}); err != nil {
+		t.Fatalf("unbreached password: unexpected error: %v", err)
+	}
+
+	// An unreachable range API must not block registration.
+	if _, err := newSvc("").Register(context.Background(), req, model.ClientInfo{}); err != nil {
+		t.Fatalf("range API down: unexpected error: %v", err)
+	}
+}
+
+func TestRegister_CommonPasswordCheck(t *testing.T) {
+	svc := NewAuthService(
+		repository.NewMemoryUserRepository(),
+		repository.NewMemoryAuditRepository(),
+		repository.NewMemorySessionRepository(),
+		crypto.NewTokenManager(crypto.TokenConfig{Secret: "test-secret", Expiry: time.Hour}),
+		WithCommonPasswordCheck(crypto.NewCommonPasswords([]string{"correcthorse"})),
+	)
+	ctx := context.Background()
+
+	for _, password := range []string{"password123", " Password123 ", "CorrectHorse"} {
+		_, err := svc.Register(ctx, model.CreateUserRequest{Email: "a@example.com", Password: password}, model.ClientInfo{})
+		if !errors.Is(err, ErrWeakPassword) {
+			t.Errorf("%q: expected ErrWeakPassword, got %v", password, err)
+		}
+	}
+
+	if _, err := svc.Register(ctx, model.CreateUserRequest{Email: "a@example.com", Password: "v7#Lq9!zRt2@wX"}, model.ClientInfo{}); err != nil {
+		t.Fatalf("strong password: unexpected error: %v", err)
+	}
+}
+
+func TestCheckEmail(t *testing.T) {
+	svc, _ := newMemoryAuthService()
+	WithEmailCheckFloor(20 * time.Millisecond)(svc)
+	ctx := context.Background()
+
+	if _, err := svc.Register(ctx, model.CreateUserRequest{Email: "taken@example.com", Password: "password123"}, model.ClientInfo{}); err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+
+	for email, want := range map[string]bool{"taken@example.com": false, "free@example.com": true} {
+		start := time.Now()
+		resp, err := svc.CheckEmail(ctx, model.CheckEmailRequest{Email: email})
+		if err != nil {
+			t.Fatalf("CheckEmail(%q) unexpected error: %v", email, err)
+		}
+		if resp.Available != want {
+			t.Errorf("CheckEmail(%q) available = %v, want %v", email, resp.Available, want)
+		}
+		if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+			t.Errorf("CheckEmail(%q) answered after %v, before the floor", email, elapsed)
+		}
+	}
+
+	var verr *model.ValidationError
+	if _, err := svc.CheckEmail(ctx, model.CheckEmailRequest{Email: "not-an-email"}); !errors.As(err, &verr) {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vaultpass/vaultpass-go/internal/apperr"
+	"github.com/vaultpass/vaultpass-go/internal/model"
+	"github.com/vaultpass/vaultpass-go/internal/repository"
+)
+
+var (
+	ErrVaultNotFound     = apperr.New(http.StatusNotFound, "vault_not_found", "vault not found")
+	ErrVaultNameRequired = apperr.New(http.StatusBadRequest, "name_required", "name is required")
+	ErrVaultNameTooLong  = apperr.New(http.StatusBadRequest, "name_too_long", "name must be at most 100 characters")
+	ErrDefaultVault      = apperr.New(http.StatusConflict, "default_vault", "the default vault cannot be deleted")
+)
+
+// maxVaultNameLength matches the vaults.name column.
+const maxVaultNameLength = 100
+
+// ListVaults returns the user's vaults, creating the default vault on first use.
+func (s *VaultService) ListVaults(ctx context.Context, userID int64) ([]model.VaultResponse, error) {
+	if _, err := s.vaults.EnsureDefault(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	vaults, err := s.vaults.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]model.VaultResponse, len(vaults))
+	for i, v := range vaults {
+		result[i] = vaultToResponse(&v)
+	}
+	return result, nil
+}
+
+// CreateVault adds a named vault for the user.
+func (s *VaultService) CreateVault(ctx context.Context, userID int64, req model.VaultRequest) (model.VaultResponse, error) {
+	name, err := vaultName(req.Name)
+	if err != nil {
+		return model.VaultResponse{}, err
+	}
+
+	// Create the default vault first so the new vault can never take its place.
+	if _, err := s.vaults.EnsureDefault(ctx, userID); err != nil {
+		return model.VaultResponse{}, err
+	}
+
+	vault := &model.Vault{UserID: userID, Name: name}
+	if err := s.vaults.Create(ctx, vault); err != nil {
+		return model.VaultResponse{}, err
+	}
+	return vaultToResponse(vault), nil
+}
+
+// GetVault returns one of the user's vaults; vaultID 0 selects the default vault.
+func (s *VaultService) GetVault(ctx context.Context, userID, vaultID int64) (model.VaultResponse, error) {
+	vault, err := s.getVault(ctx, userID, vaultID)
+	if err != nil {
+		return model.VaultResponse{}, err
+	}
+	return vaultToResponse(vault), nil
+}
+
+// RenameVault changes the name of one of the user's vaults.
+func (s *VaultService) RenameVault(ctx context.Context, userID, vaultID int64, req model.VaultRequest) (model.VaultResponse, error) {
+	name, err := vaultName(req.Name)
+	if err != nil {
+		return model.VaultResponse{}, err
+	}
+
+	vault, err := s.getVault(ctx, userID, vaultID)
+	if err != nil {
+		return model.VaultResponse{}, err
+	}
+	if err := s.vaults.Rename(ctx, userID, vault.ID, name); err != nil {
+		if errors.Is(err, repository.ErrVaultNotFound) {
+			return model.VaultResponse{}, ErrVaultNotFound
+		}
+		return model.VaultResponse{}, err
+	}
+
+	if vault, err = s.getVault(ctx, userID, vault.ID); err != nil {
+		return model.VaultResponse{}, err
+	}
+	return vaultToResponse(vault), nil
+}
+
+// DeleteVault removes one of the user's vaults together with its entries.
+// The default vault cannot be deleted.
+func (s *VaultService) DeleteVault(ctx context.Context, userID, vaultID int64) error {
+	vault, err := s.getVault(ctx, userID, vaultID)
+	if err != nil {
+		return err
+	}
+	if vault.IsDefault {
+		return ErrDefaultVault
+	}
+
+	err = s.vaults.Delete(ctx, userID, vault.ID)
+	if errors.Is(err, repository.ErrVaultNotFound) {
+		return ErrVaultNotFound
+	}
+	return err
+}
+
+// resolveVault returns the ID of the user's vault, resolving 0 to the default
+// vault. It fails with ErrVaultNotFound if the vault belongs to someone else.
+func (s *VaultService) resolveVault(ctx context.Context, userID, vaultID int64) (int64, error) {
+	vault, err := s.getVault(ctx, userID, vaultID)
+	if err != nil {
+		return 0, err
+	}
+	return vault.ID, nil
+}
+
+// getVault loads one of the user's vaults, resolving 0 to the default vault.
+func (s *VaultService) getVault(ctx context.Context, userID, vaultID int64) (*model.Vault, error) {
+	if vaultID == 0 {
+		return s.vaults.EnsureDefault(ctx, userID)
+	}
+
+	vault, err := s.vaults.GetByID(ctx, userID, vaultID)
+	if errors.Is(err, repository.ErrVaultNotFound) {
+		return nil, ErrVaultNotFound
+	}
+	return vault, err
+}
+
+// vaultName trims and validates a requested vault name.
+func vaultName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return "", ErrVaultNameRequired
+	case utf8.RuneCountInString(name) > maxVaultNameLength:
+		return "", ErrVaultNameTooLong
+	}
+	return name, nil
+}
+
+// vaultToResponse converts a Vault to its API representation.
+func vaultToResponse(v *model.Vault) model.VaultResponse {
+	return model.VaultResponse{
+		ID:        v.ID,
+		Name:      v.Name,
+		Default:   v.IsDefault,
+		CreatedAt: v.CreatedAt,
+		UpdatedAt: v.UpdatedAt,
+	}
+}
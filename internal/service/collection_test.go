@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/model"
+	"github.com/vaultpass/vaultpass-go/internal/repository"
+)
+
+func newMemoryVaultService() *VaultService {
+	return NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+}
+
+func TestVaultService_EntriesDoNotLeakAcrossVaults(t *testing.T) {
+	svc := newMemoryVaultService()
+	ctx := context.Background()
+
+	work, err := svc.CreateVault(ctx, 1, model.VaultRequest{Name: " Work "})
+	if err != nil {
+		t.Fatalf("CreateVault() unexpected error: %v", err)
+	}
+	if work.Name != "Work" || work.Default {
+		t.Fatalf("unexpected vault %+v", work)
+	}
+
+	// The same entry ID may exist independently in each vault.
+	if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: "e1", EncryptedData: b64("personal")}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+	if _, err := svc.CreateEntry(ctx, 1, work.ID, model.VaultEntryRequest{EntryID: "e1", EncryptedData: b64("work")}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+	if _, err := svc.CreateEntry(ctx, 1, work.ID, model.VaultEntryRequest{EntryID: "e2", EncryptedData: b64("work-only")}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+
+	personal, _ := svc.ListEntries(ctx, 1, 0)
+	if len(personal) != 1 || personal[0].EncryptedData != b64("personal") {
+		t.Fatalf("expected only the personal entry in the default vault, got %+v", personal)
+	}
+	batch, _ := svc.BatchGet(ctx, 1, 0, []string{"e1", "e2"})
+	if len(batch) != 1 || batch[0].EncryptedData != b64("personal") {
+		t.Fatalf("expected batch-get to stay within the default vault, got %+v", batch)
+	}
+
+	if err := svc.DeleteEntry(ctx, 1, 0, "e2"); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("expected ErrEntryNotFound deleting another vault's entry, got %v", err)
+	}
+	if err := svc.DeleteEntry(ctx, 1, work.ID, "e1"); err != nil {
+		t.Fatalf("DeleteEntry() unexpected error: %v", err)
+	}
+	if personal, _ := svc.ListEntries(ctx, 1, 0); len(personal) != 1 {
+		t.Errorf("deleting from the work vault affected the default vault: %+v", personal)
+	}
+
+	// Other users can neither read nor write someone else's vault.
+	if _, err := svc.ListEntries(ctx, 2, work.ID); !errors.Is(err, ErrVaultNotFound) {
+		t.Errorf("expected ErrVaultNotFound for another user's vault, got %v", err)
+	}
+	if _, err := svc.CreateEntry(ctx, 2, work.ID, model.VaultEntryRequest{EntryID: "x", EncryptedData: b64("x")}); !errors.Is(err, ErrVaultNotFound) {
+		t.Errorf("expected ErrVaultNotFound writing to another user's vault, got %v", err)
+	}
+}
+
+func TestVaultService_SyncIsPerVault(t *testing.T) {
+	svc := newMemoryVaultService()
+	ctx := context.Background()
+
+	work, err := svc.CreateVault(ctx, 1, model.VaultRequest{Name: "Work"})
+	if err != nil {
+		t.Fatalf("CreateVault() unexpected error: %v", err)
+	}
+
+	if _, err := svc.CreateEntry(ctx, 1, work.ID, model.VaultEntryRequest{EntryID: "w1", EncryptedData: b64("work")}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+	first, err := svc.Sync(ctx, 1, work.ID, model.SyncRequest{})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	if len(first.Entries) != 1 || first.Entries[0].EntryID != "w1" {
+		t.Fatalf("expected the work vault's entry, got %+v", first.Entries)
+	}
+
+	// A change in the default vault doesn't show up in the work vault's delta.
+	time.Sleep(time.Millisecond)
+	if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: "p1", EncryptedData: b64("personal")}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+	delta, err := svc.Sync(ctx, 1, work.ID, model.SyncRequest{LastSyncedAt: &first.SyncedAt})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	if len(delta.Entries) != 0 {
+		t.Errorf("expected no changes in the work vault, got %+v", delta.Entries)
+	}
+
+	full, err := svc.Sync(ctx, 1, 0, model.SyncRequest{})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	if len(full.Entries) != 1 || full.Entries[0].EntryID != "p1" {
+		t.Errorf("expected only the default vault's entry, got %+v", full.Entries)
+	}
+}
+
+func TestVaultService_VaultCRUD(t *testing.T) {
+	svc := newMemoryVaultService()
+	ctx := context.Background()
+
+	if _, err := svc.CreateVault(ctx, 1, model.VaultRequest{Name: "  "}); !errors.Is(err, ErrVaultNameRequired) {
+		t.Errorf("expected ErrVaultNameRequired, got %v", err)
+	}
+
+	work, _ := svc.CreateVault(ctx, 1, model.VaultRequest{Name: "Work"})
+	vaults, err := svc.ListVaults(ctx, 1)
+	if err != nil {
+		t.Fatalf("ListVaults() unexpected error: %v", err)
+	}
+	if len(vaults) != 2 || !vaults[0].Default || vaults[0].Name != model.DefaultVaultName || vaults[1].ID != work.ID {
+		t.Fatalf("expected default vault then Work, got %+v", vaults)
+	}
+
+	renamed, err := svc.RenameVault(ctx, 1, work.ID, model.VaultRequest{Name: "Office"})
+	if err != nil || renamed.Name != "Office" {
+		t.Fatalf("RenameVault() = %+v, %v", renamed, err)
+	}
+
+	if err := svc.DeleteVault(ctx, 1, vaults[0].ID); !errors.Is(err, ErrDefaultVault) {
+		t.Errorf("expected ErrDefaultVault, got %v", err)
+	}
+	if err := svc.DeleteVault(ctx, 2, work.ID); !errors.Is(err, ErrVaultNotFound) {
+		t.Errorf("expected ErrVaultNotFound deleting another user's vault, got %v", err)
+	}
+	if err := svc.DeleteVault(ctx, 1, work.ID); err != nil {
+		t.Fatalf("DeleteVault() unexpected error: %v", err)
+	}
+	if _, err := svc.GetVault(ctx, 1, work.ID); !errors.Is(err, ErrVaultNotFound) {
+		t.Errorf("expected deleted vault to be gone, got %v", err)
+	}
+}
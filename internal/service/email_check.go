@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/model"
+	"github.com/vaultpass/vaultpass-go/internal/repository"
+)
+
+// defaultEmailCheckFloor is the least time CheckEmail takes, so whether an
+// address is registered can't be read from how quickly the answer arrives.
+const defaultEmailCheckFloor = 300 * time.Millisecond
+
+// WithEmailCheckFloor overrides how long CheckEmail takes at minimum.
+func WithEmailCheckFloor(d time.Duration) AuthOption {
+	return func(s *AuthService) {
+		s.emailCheckFloor = d
+	}
+}
+
+// CheckEmail reports whether req.Email is free to register. Deactivated
+// accounts still hold their address. Every answer takes at least the email
+// check floor, whether or not the address exists.
+func (s *AuthService) CheckEmail(ctx context.Context, req model.CheckEmailRequest) (model.CheckEmailResponse, error) {
+	if err := req.Validate(); err != nil {
+		return model.CheckEmailResponse{}, err
+	}
+
+	timer := time.NewTimer(s.emailCheckFloor)
+	defer timer.Stop()
+
+	_, err := s.repo.GetByEmail(ctx, req.Email)
+	available := errors.Is(err, repository.ErrUserNotFound)
+	if err != nil && !available {
+		return model.CheckEmailResponse{}, err
+	}
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return model.CheckEmailResponse{}, ctx.Err()
+	}
+	return model.CheckEmailResponse{Available: available}, nil
+}
@@ -0,0 +1,11 @@
+package service
+
+import "github.com/vaultpass/vaultpass-go/internal/repository"
+
+// IsTransient reports whether an error returned by a service is a temporary
+// infrastructure failure, such as a lost database connection or a deadlock,
+// that the caller may retry. Anything else is permanent: retrying the same
+// request will fail the same way.
+func IsTransient(err error) bool {
+	return repository.IsTransient(err)
+}
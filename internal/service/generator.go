@@ -1,40 +1,396 @@
 package service
 
 import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+
+	"github.com/vaultpass/vaultpass-go/internal/apperr"
 	"github.com/vaultpass/vaultpass-go/internal/crypto"
 	"github.com/vaultpass/vaultpass-go/internal/model"
 )
 
+var (
+	ErrUnknownMode   = apperr.New(http.StatusBadRequest, "unknown_mode", "mode must be random, pronounceable, hex, base32 or base64url")
+	ErrUnknownPreset = apperr.New(http.StatusBadRequest, "unknown_preset", "preset must be nist, pin, max-compatibility or mobile")
+	ErrPolicyMode    = apperr.New(http.StatusBadRequest, "policy_mode", "policy_regex applies only to random and pronounceable modes")
+)
+
+// optionErrors are the crypto errors caused by a request's options rather than
+// by the server.
+var optionErrors = []error{
+	crypto.ErrLengthTooShort,
+	crypto.ErrLengthTooLong,
+	crypto.ErrNoCharacterTypes,
+	crypto.ErrLengthInsufficient,
+	crypto.ErrTokenBytes,
+	crypto.ErrDenylistExhausted,
+	crypto.ErrPolicyInvalid,
+	crypto.ErrPolicyTooComplex,
+	crypto.ErrPolicyUnsatisfied,
+}
+
+// invalidOptions marks err as a 400 when it is one of optionErrors.
+func invalidOptions(err error) error {
+	for _, target := range optionErrors {
+		if errors.Is(err, target) {
+			return apperr.Wrap(http.StatusBadRequest, "invalid_options", err)
+		}
+	}
+	return err
+}
+
+// generatePreset is a named bundle of generation options. A zero length
+// leaves the length to the request or the server default.
+type generatePreset struct {
+	mode                                   string
+	length                                 int
+	uppercase, lowercase, numbers, symbols bool
+	mobileFriendly                         bool
+}
+
+// generatePresets maps GenerateRequest.Preset names to their options.
+var generatePresets = map[string]generatePreset{
+	// Long and drawn from every character class, in the spirit of NIST SP 800-63B.
+	"nist": {mode: model.GenerateModeRandom, length: 20, uppercase: true, lowercase: true, numbers: true, symbols: true},
+	// Digits only, for PINs and numeric passcodes.
+	"pin": {mode: model.GenerateModeRandom, length: 6, numbers: true},
+	// Letters and digits only, for sites that reject symbols.
+	"max-compatibility": {mode: model.GenerateModeRandom, length: 16, uppercase: true, lowercase: true, numbers: true},
+	// Every class, but only symbols that are easy to reach on phone keyboards.
+	"mobile": {mode: model.GenerateModeRandom, uppercase: true, lowercase: true, numbers: true, symbols: true, mobileFriendly: true},
+}
+
 // GeneratorService handles password generation business logic.
-type GeneratorService struct{}
+type GeneratorService struct {
+	bounds   crypto.LengthBounds
+	defaults crypto.GeneratorOptions
+	recovery *RecoveryCache
+	denylist *crypto.Denylist
+	breaches *crypto.BreachChecker
+	metrics  *generatorMetrics
+}
+
+// GeneratorOption configures a GeneratorService.
+type GeneratorOption func(*GeneratorService)
+
+// WithLengthBounds overrides the default 8-128 length range.
+// Bounds should be checked with LengthBounds.Validate first.
+func WithLengthBounds(bounds crypto.LengthBounds) GeneratorOption {
+	return func(s *GeneratorService) {
+		s.bounds = bounds
+	}
+}
+
+// WithDefaults overrides the length and character classes used for fields a
+// request leaves unset. The length is clamped to the configured bounds.
+func WithDefaults(defaults crypto.GeneratorOptions) GeneratorOption {
+	return func(s *GeneratorService) {
+		s.defaults = defaults
+	}
+}
+
+// WithRecovery lets clients opt in to a one-time recovery handle for each
+// generated password. Without it, recoverable requests fail with ErrRecoveryDisabled.
+func WithRecovery(cache *RecoveryCache) GeneratorOption {
+	return func(s *GeneratorService) {
+		s.recovery = cache
+	}
+}
+
+// WithDenylist makes random and pronounceable passwords avoid the words in d,
+// regenerating any password that contains one.
+func WithDenylist(d *crypto.Denylist) GeneratorOption {
+	return func(s *GeneratorService) {
+		s.denylist = d
+	}
+}
 
 // NewGeneratorService creates a new GeneratorService.
-func NewGeneratorService() *GeneratorService {
-	return &GeneratorService{}
+func NewGeneratorService(opts ...GeneratorOption) *GeneratorService {
+	s := &GeneratorService{
+		bounds:   crypto.DefaultLengthBounds(),
+		defaults: crypto.DefaultOptions(),
+		metrics:  newGeneratorMetrics(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-// Generate produces a password based on the given request.
+// Generate produces a password based on the given request. When the request is
+// recoverable, the response also carries a handle that can redeem the password once.
 func (s *GeneratorService) Generate(req model.GenerateRequest) (model.GenerateResponse, error) {
+	if req.Recoverable && s.recovery == nil {
+		return model.GenerateResponse{}, ErrRecoveryDisabled
+	}
+
+	resp, classes, err := s.generate(req)
+	if err != nil {
+		return model.GenerateResponse{}, invalidOptions(err)
+	}
+
+	if req.Recoverable {
+		handle, expiresAt, err := s.recovery.Store(resp.Password)
+		if err != nil {
+			return model.GenerateResponse{}, err
+		}
+		resp.RecoveryHandle = handle
+		resp.RecoveryExpiresAt = &expiresAt
+	}
+
+	s.metrics.record(resp.Mode, resp.Length, classes)
+	return resp, nil
+}
+
+// Metrics reports how many passwords have been generated, by mode, length
+// bucket and enabled character classes. The passwords are never recorded.
+func (s *GeneratorService) Metrics() model.GeneratorMetrics {
+	return s.metrics.snapshot()
+}
+
+// Redeem returns a password generated with a recovery handle. Each handle works once.
+func (s *GeneratorService) Redeem(handle string) (model.RedeemResponse, error) {
+	if s.recovery == nil {
+		return model.RedeemResponse{}, ErrRecoveryNotFound
+	}
+
+	password, err := s.recovery.Redeem(handle)
+	if err != nil {
+		return model.RedeemResponse{}, err
+	}
+	return model.RedeemResponse{Password: password}, nil
+}
+
+// generate produces the password for req, along with the combination of
+// character classes it was drawn from, which is empty for token modes.
+func (s *GeneratorService) generate(req model.GenerateRequest) (model.GenerateResponse, string, error) {
+	req, err := s.applyPreset(req)
+	if err != nil {
+		return model.GenerateResponse{}, "", err
+	}
+
 	opts := crypto.GeneratorOptions{
-		Length:    req.Length,
-		Uppercase: boolOrDefault(req.Uppercase, true),
-		Lowercase: boolOrDefault(req.Lowercase, true),
-		Numbers:   boolOrDefault(req.Numbers, true),
-		Symbols:   boolOrDefault(req.Symbols, true),
+		Length:         req.Length,
+		Uppercase:      boolOrDefault(req.Uppercase, s.defaults.Uppercase),
+		Lowercase:      boolOrDefault(req.Lowercase, s.defaults.Lowercase),
+		Numbers:        boolOrDefault(req.Numbers, s.defaults.Numbers),
+		Symbols:        boolOrDefault(req.Symbols, s.defaults.Symbols),
+		MobileFriendly: req.MobileFriendly,
+		Bounds:         s.bounds,
+		Denylist:       s.denylist,
+	}
+
+	mode := req.Mode
+	if mode == "" {
+		mode = model.GenerateModeRandom
 	}
 
 	if opts.Length == 0 {
-		opts.Length = 16
+		opts.Length = s.clampLength(s.defaults.Length)
+		if opts.MobileFriendly && mode == model.GenerateModeRandom {
+			opts.Length = s.mobileLength(opts)
+		}
+	}
+
+	switch mode {
+	case model.GenerateModeHex, model.GenerateModeBase32, model.GenerateModeBase64URL:
+		if req.PolicyRegex != "" {
+			return model.GenerateResponse{}, "", ErrPolicyMode
+		}
+		resp, err := generateToken(mode, req.Bytes)
+		return resp, "", err
+	}
+
+	if req.PolicyRegex != "" {
+		if opts.Policy, err = crypto.NewPolicy(req.PolicyRegex); err != nil {
+			return model.GenerateResponse{}, "", err
+		}
+	}
+
+	var password string
+	var entropy float64
+	switch mode {
+	case model.GenerateModeRandom:
+		password, err = crypto.Generate(opts)
+		entropy = crypto.Entropy(opts)
+	case model.GenerateModePronounceable:
+		password, entropy, err = crypto.GeneratePronounceable(opts)
+	default:
+		return model.GenerateResponse{}, "", ErrUnknownMode
+	}
+	if err != nil {
+		if errors.Is(err, crypto.ErrLengthTooShort) || errors.Is(err, crypto.ErrLengthTooLong) {
+			return model.GenerateResponse{}, "", fmt.Errorf("%w (allowed %d-%d)", err, s.bounds.Min, s.bounds.Max)
+		}
+		return model.GenerateResponse{}, "", err
+	}
+
+	return model.GenerateResponse{
+		Password:       password,
+		Length:         len(password),
+		Mode:           mode,
+		EntropyBits:    math.Round(entropy*10) / 10,
+		MobileFriendly: opts.MobileFriendly,
+	}, classCombination(opts), nil
+}
+
+// applyPreset fills the fields req leaves unset from its named preset, so
+// explicit fields always win. The preset length is clamped to the configured bounds.
+func (s *GeneratorService) applyPreset(req model.GenerateRequest) (model.GenerateRequest, error) {
+	if req.Preset == "" {
+		return req, nil
+	}
+	p, ok := generatePresets[req.Preset]
+	if !ok {
+		return req, ErrUnknownPreset
+	}
+
+	if req.Mode == "" {
+		req.Mode = p.mode
+	}
+	if req.Length == 0 && p.length > 0 {
+		req.Length = s.clampLength(p.length)
+	}
+	if p.mobileFriendly {
+		req.MobileFriendly = true
+	}
+	if req.Uppercase == nil {
+		req.Uppercase = &p.uppercase
+	}
+	if req.Lowercase == nil {
+		req.Lowercase = &p.lowercase
+	}
+	if req.Numbers == nil {
+		req.Numbers = &p.numbers
+	}
+	if req.Symbols == nil {
+		req.Symbols = &p.symbols
+	}
+	return req, nil
+}
+
+// Defaults reports the options applied to fields a generate request leaves
+// unset, along with the length bounds and presets, as the server will apply them.
+func (s *GeneratorService) Defaults() model.GenerateDefaultsResponse {
+	presets := make(map[string]model.GeneratePreset, len(generatePresets))
+	for name, p := range generatePresets {
+		preset := model.GeneratePreset{
+			Mode:           p.mode,
+			Length:         s.clampLength(p.length),
+			Uppercase:      p.uppercase,
+			Lowercase:      p.lowercase,
+			Numbers:        p.numbers,
+			Symbols:        p.symbols,
+			MobileFriendly: p.mobileFriendly,
+		}
+		if p.length == 0 {
+			preset.Length = s.mobileLength(crypto.GeneratorOptions{
+				Uppercase:      p.uppercase,
+				Lowercase:      p.lowercase,
+				Numbers:        p.numbers,
+				Symbols:        p.symbols,
+				MobileFriendly: p.mobileFriendly,
+			})
+		}
+		presets[name] = preset
+	}
+
+	return model.GenerateDefaultsResponse{
+		Mode:      model.GenerateModeRandom,
+		Length:    s.clampLength(s.defaults.Length),
+		Uppercase: s.defaults.Uppercase,
+		Lowercase: s.defaults.Lowercase,
+		Numbers:   s.defaults.Numbers,
+		Symbols:   s.defaults.Symbols,
+		MinLength: s.bounds.Min,
+		MaxLength: s.bounds.Max,
+		Presets:   presets,
+	}
+}
+
+// Capabilities reports the modes, their options and bounds, the presets and
+// the optional features this server's generator supports.
+func (s *GeneratorService) Capabilities() model.GenerateCapabilities {
+	var common []string
+	if s.recovery != nil {
+		common = append(common, "recoverable")
+	}
+	passwordModes := map[string][]string{
+		model.GenerateModeRandom:        {"length", "uppercase", "lowercase", "numbers", "symbols", "mobile_friendly", "policy_regex"},
+		model.GenerateModePronounceable: {"length", "uppercase", "numbers", "symbols", "mobile_friendly", "policy_regex"},
+	}
+
+	var modes []model.GenerateModeCapability
+	for _, mode := range []string{model.GenerateModeRandom, model.GenerateModePronounceable} {
+		modes = append(modes, model.GenerateModeCapability{
+			Mode:      mode,
+			Options:   append(passwordModes[mode], common...),
+			MinLength: s.bounds.Min,
+			MaxLength: s.bounds.Max,
+		})
+	}
+	for _, mode := range []string{model.GenerateModeHex, model.GenerateModeBase32, model.GenerateModeBase64URL} {
+		modes = append(modes, model.GenerateModeCapability{
+			Mode:     mode,
+			Options:  append([]string{"bytes"}, common...),
+			MinBytes: crypto.MinTokenBytes,
+			MaxBytes: crypto.MaxTokenBytes,
+		})
+	}
+
+	return model.GenerateCapabilities{
+		Modes:   modes,
+		Presets: slices.Sorted(maps.Keys(generatePresets)),
+		Features: model.GenerateFeatures{
+			Recovery:     s.recovery != nil,
+			Denylist:     s.denylist != nil,
+			BreachCheck:  s.breaches != nil,
+			FileDownload: true,
+		},
+	}
+}
+
+// mobileLength returns the length at which a password drawn from opts' smaller
+// mobile-friendly pool reaches the entropy of the default options at the default
+// length, fitted into the configured bounds.
+func (s *GeneratorService) mobileLength(opts crypto.GeneratorOptions) int {
+	target := s.defaults
+	target.Length = s.clampLength(s.defaults.Length)
+	perChar := crypto.EntropyPerChar(opts)
+	if perChar == 0 {
+		return target.Length
+	}
+	return s.clampLength(int(math.Ceil(crypto.Entropy(target) / perChar)))
+}
+
+// clampLength fits n into the configured length bounds.
+func (s *GeneratorService) clampLength(n int) int {
+	return min(max(n, s.bounds.Min), s.bounds.Max)
+}
+
+// generateToken encodes raw random bytes; the mode names the encoding.
+func generateToken(encoding string, n int) (model.GenerateResponse, error) {
+	if n == 0 {
+		n = crypto.DefaultTokenBytes
 	}
 
-	password, err := crypto.Generate(opts)
+	token, err := crypto.GenerateRandomToken(encoding, n)
 	if err != nil {
 		return model.GenerateResponse{}, err
 	}
 
 	return model.GenerateResponse{
-		Password: password,
-		Length:   len(password),
+		Password:    token,
+		Length:      len(token),
+		Mode:        encoding,
+		Encoding:    encoding,
+		Bytes:       n,
+		EntropyBits: float64(n * 8),
 	}, nil
 }
 
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/vaultpass/vaultpass-go/internal/crypto"
+	"github.com/vaultpass/vaultpass-go/internal/model"
+)
+
+// lengthBuckets are the upper bounds of the generated-length buckets; longer
+// passwords fall into the last, open-ended bucket.
+var lengthBuckets = []struct {
+	max   int
+	label string
+}{
+	{11, "1-11"},
+	{15, "12-15"},
+	{19, "16-19"},
+	{31, "20-31"},
+	{63, "32-63"},
+}
+
+// generatorMetrics counts generated passwords by mode, length bucket and
+// enabled character classes. It never sees the passwords themselves.
+type generatorMetrics struct {
+	mu      sync.Mutex
+	total   int64
+	modes   map[string]int64
+	lengths map[string]int64
+	classes map[string]int64
+}
+
+func newGeneratorMetrics() *generatorMetrics {
+	return &generatorMetrics{
+		modes:   make(map[string]int64),
+		lengths: make(map[string]int64),
+		classes: make(map[string]int64),
+	}
+}
+
+// record counts one generated password. classes is empty for token modes,
+// which draw from an encoding rather than character classes.
+func (m *generatorMetrics) record(mode string, length int, classes string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.total++
+	m.modes[mode]++
+	m.lengths[lengthBucket(length)]++
+	if classes != "" {
+		m.classes[classes]++
+	}
+}
+
+// snapshot returns a copy of the counters.
+func (m *generatorMetrics) snapshot() model.GeneratorMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return model.GeneratorMetrics{
+		Total:   m.total,
+		Modes:   copyCounts(m.modes),
+		Lengths: copyCounts(m.lengths),
+		Classes: copyCounts(m.classes),
+	}
+}
+
+// lengthBucket returns the label of the bucket n falls into.
+func lengthBucket(n int) string {
+	for _, b := range lengthBuckets {
+		if n <= b.max {
+			return b.label
+		}
+	}
+	return "64+"
+}
+
+// classCombination names the character classes enabled in opts, joined with
+// "+" in a fixed order, e.g. "upper+lower+numbers".
+func classCombination(opts crypto.GeneratorOptions) string {
+	var classes []string
+	if opts.Uppercase {
+		classes = append(classes, "upper")
+	}
+	if opts.Lowercase {
+		classes = append(classes, "lower")
+	}
+	if opts.Numbers {
+		classes = append(classes, "numbers")
+	}
+	if opts.Symbols {
+		if opts.MobileFriendly {
+			classes = append(classes, "mobile_symbols")
+		} else {
+			classes = append(classes, "symbols")
+		}
+	}
+	if len(classes) == 0 {
+		return "none"
+	}
+	return strings.Join(classes, "+")
+}
+
+func copyCounts(src map[string]int64) map[string]int64 {
+	dst := make(map[string]int64, len(src))
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
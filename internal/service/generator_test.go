@@ -1,8 +1,14 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"strings"
 	"testing"
 
+	"github.com/vaultpass/vaultpass-go/internal/crypto"
 	"github.com/vaultpass/vaultpass-go/internal/model"
 )
 
@@ -73,3 +79,388 @@ func TestGenerate_NoCharacterTypes(t *testing.T) {
 		t.Fatal("expected error when no character types selected")
 	}
 }
+
+func TestGenerate_CustomBounds(t *testing.T) {
+	svc := NewGeneratorService(WithLengthBounds(crypto.LengthBounds{Min: 20, Max: 256}))
+
+	resp, err := svc.Generate(model.GenerateRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Length != 20 {
+		t.Errorf("expected default length raised to min 20, got %d", resp.Length)
+	}
+
+	resp, err = svc.Generate(model.GenerateRequest{Length: 256})
+	if err != nil {
+		t.Fatalf("unexpected error at custom max: %v", err)
+	}
+	if resp.Length != 256 {
+		t.Errorf("expected length 256, got %d", resp.Length)
+	}
+
+	_, err = svc.Generate(model.GenerateRequest{Length: 16})
+	if !errors.Is(err, crypto.ErrLengthTooShort) {
+		t.Fatalf("expected ErrLengthTooShort, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "20-256") {
+		t.Errorf("expected configured bounds in error, got %q", err)
+	}
+
+	if _, err := svc.Generate(model.GenerateRequest{Length: 257}); !errors.Is(err, crypto.ErrLengthTooLong) {
+		t.Errorf("expected ErrLengthTooLong, got %v", err)
+	}
+}
+
+func TestGenerate_PronounceableMode(t *testing.T) {
+	svc := NewGeneratorService()
+
+	resp, err := svc.Generate(model.GenerateRequest{Mode: model.GenerateModePronounceable, Length: 14})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Mode != model.GenerateModePronounceable || resp.Length != 14 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	random, _ := svc.Generate(model.GenerateRequest{Length: 14})
+	if random.Mode != model.GenerateModeRandom {
+		t.Errorf("expected random mode by default, got %q", random.Mode)
+	}
+	if resp.EntropyBits >= random.EntropyBits {
+		t.Errorf("expected pronounceable entropy %.1f below random %.1f", resp.EntropyBits, random.EntropyBits)
+	}
+}
+
+func TestGenerate_UnknownMode(t *testing.T) {
+	svc := NewGeneratorService()
+
+	if _, err := svc.Generate(model.GenerateRequest{Mode: "diceware"}); !errors.Is(err, ErrUnknownMode) {
+		t.Errorf("expected ErrUnknownMode, got %v", err)
+	}
+}
+
+func TestGenerate_Presets(t *testing.T) {
+	svc := NewGeneratorService(WithLengthBounds(crypto.LengthBounds{Min: 4, Max: 128}))
+	const symbols = "!@#$%^&*()-_=+[]{}|;:,.<>?"
+
+	nist, err := svc.Generate(model.GenerateRequest{Preset: "nist"})
+	if err != nil {
+		t.Fatalf("nist: unexpected error: %v", err)
+	}
+	if nist.Length != 20 || nist.Mode != model.GenerateModeRandom {
+		t.Errorf("nist: expected 20 random characters, got %+v", nist)
+	}
+
+	pin, err := svc.Generate(model.GenerateRequest{Preset: "pin"})
+	if err != nil {
+		t.Fatalf("pin: unexpected error: %v", err)
+	}
+	if pin.Length != 6 || strings.Trim(pin.Password, "0123456789") != "" {
+		t.Errorf("pin: expected 6 digits, got %q", pin.Password)
+	}
+
+	compat, err := svc.Generate(model.GenerateRequest{Preset: "max-compatibility"})
+	if err != nil {
+		t.Fatalf("max-compatibility: unexpected error: %v", err)
+	}
+	if compat.Length != 16 || strings.ContainsAny(compat.Password, symbols) {
+		t.Errorf("max-compatibility: expected 16 characters without symbols, got %q", compat.Password)
+	}
+
+	// The PIN length is raised to the configured minimum rather than rejected.
+	strict, err := NewGeneratorService().Generate(model.GenerateRequest{Preset: "pin"})
+	if err != nil {
+		t.Fatalf("pin with default bounds: unexpected error: %v", err)
+	}
+	if strict.Length != crypto.DefaultLengthBounds().Min {
+		t.Errorf("expected pin length clamped to %d, got %d", crypto.DefaultLengthBounds().Min, strict.Length)
+	}
+}
+
+func TestGenerate_MobileFriendly(t *testing.T) {
+	svc := NewGeneratorService()
+	const allowed = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-.@!?&$"
+	target := crypto.Entropy(crypto.DefaultOptions())
+
+	for _, req := range []model.GenerateRequest{{MobileFriendly: true}, {Preset: "mobile"}} {
+		resp, err := svc.Generate(req)
+		if err != nil {
+			t.Fatalf("%+v: unexpected error: %v", req, err)
+		}
+		if strings.Trim(resp.Password, allowed) != "" {
+			t.Errorf("%+v: %q contains characters outside the mobile-friendly set", req, resp.Password)
+		}
+		if !resp.MobileFriendly || resp.Length != 17 || resp.EntropyBits < math.Floor(target) {
+			t.Errorf("%+v: expected 17 characters reaching %.1f bits, got %+v", req, target, resp)
+		}
+	}
+
+	// An explicit length is kept, and the lower entropy is reported as is.
+	resp, err := svc.Generate(model.GenerateRequest{MobileFriendly: true, Length: 12})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := math.Round(12*math.Log2(69)*10) / 10; resp.Length != 12 || resp.EntropyBits != want {
+		t.Errorf("expected 12 characters with %.1f bits, got %+v", want, resp)
+	}
+
+	if got := svc.Defaults().Presets["mobile"]; got.Length != 17 || !got.MobileFriendly {
+		t.Errorf("mobile preset: expected length 17, got %+v", got)
+	}
+}
+
+func TestGenerate_PresetOverrides(t *testing.T) {
+	svc := NewGeneratorService()
+
+	resp, err := svc.Generate(model.GenerateRequest{Preset: "pin", Length: 12, Uppercase: boolPtr(true), Numbers: boolPtr(false)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Length != 12 || strings.Trim(resp.Password, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		t.Errorf("expected 12 uppercase letters, got %q", resp.Password)
+	}
+
+	resp, err = svc.Generate(model.GenerateRequest{Preset: "nist", Mode: model.GenerateModePronounceable})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Mode != model.GenerateModePronounceable || resp.Length != 20 {
+		t.Errorf("expected explicit mode with the preset length, got %+v", resp)
+	}
+
+	if _, err := svc.Generate(model.GenerateRequest{Preset: "fort-knox"}); !errors.Is(err, ErrUnknownPreset) {
+		t.Errorf("expected ErrUnknownPreset, got %v", err)
+	}
+}
+
+func TestGenerate_ConfiguredDefaults(t *testing.T) {
+	svc := NewGeneratorService(
+		WithLengthBounds(crypto.LengthBounds{Min: 20, Max: 64}),
+		WithDefaults(crypto.GeneratorOptions{Length: 24, Lowercase: true, Numbers: true}),
+	)
+
+	resp, err := svc.Generate(model.GenerateRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Length != 24 || strings.Trim(resp.Password, "abcdefghijklmnopqrstuvwxyz0123456789") != "" {
+		t.Errorf("expected 24 lowercase letters and digits, got %q", resp.Password)
+	}
+
+	defaults := svc.Defaults()
+	if defaults.Length != 24 || defaults.Uppercase || !defaults.Lowercase || !defaults.Numbers || defaults.Symbols {
+		t.Errorf("unexpected defaults: %+v", defaults)
+	}
+	if defaults.MinLength != 20 || defaults.MaxLength != 64 {
+		t.Errorf("expected bounds 20-64, got %d-%d", defaults.MinLength, defaults.MaxLength)
+	}
+	if pin := defaults.Presets["pin"]; pin.Length != 20 || !pin.Numbers || pin.Lowercase {
+		t.Errorf("expected pin preset clamped to the minimum length, got %+v", pin)
+	}
+
+	// Explicit fields win over the configured defaults in both directions.
+	on, off := true, false
+	resp, err = svc.Generate(model.GenerateRequest{Symbols: &on, Numbers: &off})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.ContainsAny(resp.Password, "0123456789") || !strings.ContainsAny(resp.Password, "!@#$%^&*()_+-=[]{}|;:,.<>?") {
+		t.Errorf("expected symbols on and digits off as requested, got %q", resp.Password)
+	}
+}
+
+func TestGenerate_TokenModes(t *testing.T) {
+	svc := NewGeneratorService()
+
+	for _, mode := range []string{model.GenerateModeHex, model.GenerateModeBase32, model.GenerateModeBase64URL} {
+		resp, err := svc.Generate(model.GenerateRequest{Mode: mode, Bytes: 24})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", mode, err)
+		}
+		if resp.Encoding != mode || resp.Bytes != 24 || resp.EntropyBits != 192 {
+			t.Errorf("%s: unexpected response: %+v", mode, resp)
+		}
+		if resp.Length != len(resp.Password) {
+			t.Errorf("%s: length %d does not match token %q", mode, resp.Length, resp.Password)
+		}
+	}
+
+	resp, err := svc.Generate(model.GenerateRequest{Mode: model.GenerateModeHex})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Bytes != crypto.DefaultTokenBytes || len(resp.Password) != 2*crypto.DefaultTokenBytes {
+		t.Errorf("expected %d default bytes, got %+v", crypto.DefaultTokenBytes, resp)
+	}
+
+	if _, err := svc.Generate(model.GenerateRequest{Mode: model.GenerateModeHex, Bytes: 8}); !errors.Is(err, crypto.ErrTokenBytes) {
+		t.Errorf("expected ErrTokenBytes, got %v", err)
+	}
+}
+
+func TestCapabilities_ReflectEnabledFeatures(t *testing.T) {
+	caps := NewGeneratorService(WithLengthBounds(crypto.LengthBounds{Min: 12, Max: 64})).Capabilities()
+
+	var modes []string
+	for _, m := range caps.Modes {
+		modes = append(modes, m.Mode)
+		if slices.Contains(m.Options, "recoverable") {
+			t.Errorf("%s: recoverable listed while recovery is disabled", m.Mode)
+		}
+	}
+	if want := []string{"random", "pronounceable", "hex", "base32", "base64url"}; !slices.Equal(modes, want) {
+		t.Errorf("modes = %v, want %v", modes, want)
+	}
+	if random := caps.Modes[0]; random.MinLength != 12 || random.MaxLength != 64 || !slices.Contains(random.Options, "mobile_friendly") {
+		t.Errorf("unexpected random mode capability %+v", random)
+	}
+	if hex := caps.Modes[2]; hex.MinBytes != crypto.MinTokenBytes || hex.MaxBytes != crypto.MaxTokenBytes || !slices.Equal(hex.Options, []string{"bytes"}) {
+		t.Errorf("unexpected hex mode capability %+v", hex)
+	}
+	if want := []string{"max-compatibility", "mobile", "nist", "pin"}; !slices.Equal(caps.Presets, want) {
+		t.Errorf("presets = %v, want %v", caps.Presets, want)
+	}
+	if want := (model.GenerateFeatures{FileDownload: true}); caps.Features != want {
+		t.Errorf("features = %+v, want %+v", caps.Features, want)
+	}
+
+	denylist, _ := crypto.NewDenylist([]string{"bad"})
+	caps = NewGeneratorService(
+		WithRecovery(newTestRecoveryCache(t)),
+		WithDenylist(denylist),
+		WithBreachChecker(crypto.NewBreachChecker()),
+	).Capabilities()
+	if want := (model.GenerateFeatures{Recovery: true, Denylist: true, BreachCheck: true, FileDownload: true}); caps.Features != want {
+		t.Errorf("features = %+v, want %+v", caps.Features, want)
+	}
+	for _, m := range caps.Modes {
+		if !slices.Contains(m.Options, "recoverable") {
+			t.Errorf("%s: expected recoverable once recovery is enabled, got %v", m.Mode, m.Options)
+		}
+	}
+}
+
+func TestStrength_BreachStatus(t *testing.T) {
+	ctx := context.Background()
+
+	resp, err := NewGeneratorService().Strength(ctx, "password123")
+	if err != nil {
+		t.Fatalf("Strength() unexpected error: %v", err)
+	}
+	if resp.Breach != model.BreachDisabled || resp.EntropyBits <= 0 {
+		t.Errorf("without checker: got %+v, want breach disabled and positive entropy", resp)
+	}
+	wantFindings := []string{crypto.FindingTooShort, crypto.FindingNoUppercase, crypto.FindingNoSymbols, crypto.FindingSequence, crypto.FindingCommonWord}
+	if resp.Score != 0 || !slices.Equal(resp.Findings, wantFindings) {
+		t.Errorf("expected score 0 with findings %q, got %d %q", wantFindings, resp.Score, resp.Findings)
+	}
+
+	resp, _ = NewGeneratorService(WithBreachChecker(stubBreaches(password123Suffix))).Strength(ctx, "password123")
+	if resp.Breach != model.BreachFound || resp.BreachCount != 251682 {
+		t.Errorf("breached: got %+v", resp)
+	}
+
+	resp, _ = NewGeneratorService(WithBreachChecker(stubBreaches(""))).Strength(ctx, "password123")
+	if resp.Breach != model.BreachUnavailable {
+		t.Errorf("range API down: breach = %q, want %q", resp.Breach, model.BreachUnavailable)
+	}
+
+	if _, err := NewGeneratorService().Strength(ctx, ""); !errors.Is(err, ErrPasswordRequired) {
+		t.Errorf("empty password: expected ErrPasswordRequired, got %v", err)
+	}
+}
+
+func TestStrengthBatch_MixedPreservesOrder(t *testing.T) {
+	svc := NewGeneratorService(WithBreachChecker(stubBreaches(password123Suffix)))
+
+	passwords := []string{"password123", "", "correct horse battery staple", "Tr0ub4dor&3"}
+	for range 20 {
+		passwords = append(passwords, "password123")
+	}
+
+	results := svc.StrengthBatch(context.Background(), passwords)
+	if len(results) != len(passwords) {
+		t.Fatalf("expected %d results, got %d", len(passwords), len(results))
+	}
+	if results[0].Breach != model.BreachFound || results[0].BreachCount != 251682 {
+		t.Errorf("results[0]: expected breached, got %+v", results[0])
+	}
+	if results[1].Error != ErrPasswordRequired.Error() || results[1].Breach != "" {
+		t.Errorf("results[1]: expected a per-item error, got %+v", results[1])
+	}
+	for i, pw := range passwords[2:4] {
+		got := results[i+2]
+		if got.Breach != model.BreachNotFound || got.Length != len(pw) {
+			t.Errorf("results[%d]: expected %d characters not found, got %+v", i+2, len(pw), got)
+		}
+	}
+	for i, got := range results[4:] {
+		if got.Breach != model.BreachFound {
+			t.Errorf("results[%d]: expected breached, got %+v", i+4, got)
+		}
+	}
+}
+
+func TestGenerate_Metrics(t *testing.T) {
+	svc := NewGeneratorService()
+
+	for range 2 {
+		if _, err := svc.Generate(model.GenerateRequest{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	resp, err := svc.Generate(model.GenerateRequest{
+		Length:    40,
+		Uppercase: boolPtr(false),
+		Lowercase: boolPtr(true),
+		Numbers:   boolPtr(true),
+		Symbols:   boolPtr(false),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.Generate(model.GenerateRequest{Mode: model.GenerateModeHex}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.Generate(model.GenerateRequest{Length: 3}); err == nil {
+		t.Fatal("expected length error")
+	}
+
+	m := svc.Metrics()
+	if m.Total != 4 {
+		t.Errorf("expected 4 generated, got %d", m.Total)
+	}
+	if m.Modes[model.GenerateModeRandom] != 3 || m.Modes[model.GenerateModeHex] != 1 {
+		t.Errorf("unexpected modes: %v", m.Modes)
+	}
+	if m.Lengths["16-19"] != 2 || m.Lengths["32-63"] != 1 || m.Lengths["64+"] != 1 {
+		t.Errorf("unexpected lengths: %v", m.Lengths)
+	}
+	want := map[string]int64{"upper+lower+numbers+symbols": 2, "lower+numbers": 1}
+	if len(m.Classes) != len(want) {
+		t.Errorf("unexpected classes: %v", m.Classes)
+	}
+	for k, v := range want {
+		if m.Classes[k] != v {
+			t.Errorf("classes[%q]: expected %d, got %d", k, v, m.Classes[k])
+		}
+	}
+
+	for _, counts := range []map[string]int64{m.Modes, m.Lengths, m.Classes} {
+		for k := range counts {
+			if strings.Contains(k, resp.Password) {
+				t.Fatalf("metrics key %q contains a generated password", k)
+			}
+		}
+	}
+}
+
+func TestLengthBucket(t *testing.T) {
+	tests := map[int]string{8: "1-11", 12: "12-15", 16: "16-19", 20: "20-31", 63: "32-63", 64: "64+", 128: "64+"}
+	for n, want := range tests {
+		if got := lengthBucket(n); got != want {
+			t.Errorf("lengthBucket(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
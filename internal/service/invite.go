@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/apperr"
+	"github.com/vaultpass/vaultpass-go/internal/crypto"
+	"github.com/vaultpass/vaultpass-go/internal/model"
+	"github.com/vaultpass/vaultpass-go/internal/repository"
+)
+
+// inviteCodeBytes is the amount of randomness in an invite code.
+const inviteCodeBytes = 16
+
+var ErrInviteExpiryPast = apperr.New(http.StatusBadRequest, "invalid_expires_at", "expires_at must be in the future")
+
+// InviteService mints single-use registration invite codes.
+type InviteService struct {
+	store repository.InviteStore
+}
+
+// NewInviteService creates a new InviteService.
+func NewInviteService(store repository.InviteStore) *InviteService {
+	return &InviteService{store: store}
+}
+
+// Create mints a new invite code. The plaintext code is returned once and only
+// its hash is stored.
+func (s *InviteService) Create(ctx context.Context, req model.CreateInviteRequest) (model.InviteResponse, error) {
+	now := time.Now().UTC()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return model.InviteResponse{}, ErrInviteExpiryPast
+	}
+
+	code, err := crypto.GenerateRandomToken(crypto.EncodingBase32, inviteCodeBytes)
+	if err != nil {
+		return model.InviteResponse{}, err
+	}
+
+	invite := &model.Invite{
+		CodeHash:  hashInviteCode(code),
+		CreatedAt: now,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := s.store.Create(ctx, invite); err != nil {
+		return model.InviteResponse{}, err
+	}
+
+	return model.InviteResponse{
+		Code:      code,
+		CreatedAt: invite.CreatedAt,
+		ExpiresAt: invite.ExpiresAt,
+	}, nil
+}
+
+// hashInviteCode returns the hex SHA-256 of a code. Codes are base32, so they
+// are matched case-insensitively to forgive hand-typed lowercase.
+func hashInviteCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToUpper(strings.TrimSpace(code))))
+	return hex.EncodeToString(sum[:])
+}
+
+// claimInvite claims the request's invite code when registration is invite-only.
+// The returned release func hands the code back if registration then fails.
+func (s *AuthService) claimInvite(ctx context.Context, req model.CreateUserRequest) (func(), error) {
+	if s.invites == nil {
+		return func() {}, nil
+	}
+
+	var verr model.ValidationError
+	if req.InviteCode == "" {
+		verr.Add("invite_code", "required", model.ErrInviteRequired)
+		return nil, verr.Err()
+	}
+
+	codeHash := hashInviteCode(req.InviteCode)
+	err := s.invites.Claim(ctx, codeHash, req.Email, time.Now().UTC())
+	switch {
+	case errors.Is(err, repository.ErrInviteNotFound):
+		verr.Add("invite_code", "invalid", model.ErrInviteInvalid)
+		return nil, verr.Err()
+	case errors.Is(err, repository.ErrInviteUsed):
+		verr.Add("invite_code", "used", model.ErrInviteUsed)
+		return nil, verr.Err()
+	case errors.Is(err, repository.ErrInviteExpired):
+		verr.Add("invite_code", "expired", model.ErrInviteExpired)
+		return nil, verr.Err()
+	case err != nil:
+		return nil, err
+	}
+
+	return func() {
+		if err := s.invites.Release(context.WithoutCancel(ctx), codeHash); err != nil {
+			slog.Error("failed to release invite code after failed registration", "error", err)
+		}
+	}, nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/crypto"
+	"github.com/vaultpass/vaultpass-go/internal/model"
+	"github.com/vaultpass/vaultpass-go/internal/repository"
+)
+
+func TestRegister_InviteOnly(t *testing.T) {
+	ctx := context.Background()
+	invites := repository.NewMemoryInviteRepository()
+	svc := NewAuthService(
+		repository.NewMemoryUserRepository(),
+		repository.NewMemoryAuditRepository(),
+		repository.NewMemorySessionRepository(),
+		crypto.NewTokenManager(crypto.TokenConfig{Secret: "test-secret", Expiry: time.Hour}),
+		WithInviteOnly(invites),
+	)
+	minter := NewInviteService(invites)
+	register := func(email, code string) error {
+		_, err := svc.Register(ctx, model.CreateUserRequest{Email: email, Password: "password123", InviteCode: code}, model.ClientInfo{})
+		return err
+	}
+
+	invite, err := minter.Create(ctx, model.CreateInviteRequest{})
+	if err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+
+	if err := register("a@example.com", ""); !errors.Is(err, model.ErrInviteRequired) {
+		t.Errorf("missing code: expected ErrInviteRequired, got %v", err)
+	}
+	if err := register("a@example.com", "NOT-A-REAL-CODE"); !errors.Is(err, model.ErrInviteInvalid) {
+		t.Errorf("unknown code: expected ErrInviteInvalid, got %v", err)
+	}
+
+	if err := register("a@example.com", strings.ToLower(invite.Code)); err != nil {
+		t.Fatalf("valid code: unexpected error: %v", err)
+	}
+	if err := register("b@example.com", invite.Code); !errors.Is(err, model.ErrInviteUsed) {
+		t.Errorf("reused code: expected ErrInviteUsed, got %v", err)
+	}
+
+	past := time.Now().UTC().Add(-time.Minute)
+	expired := &model.Invite{CodeHash: hashInviteCode("EXPIREDCODE"), CreatedAt: past.Add(-time.Hour), ExpiresAt: &past}
+	if err := invites.Create(ctx, expired); err != nil {
+		t.Fatalf("seed expired invite: %v", err)
+	}
+	if err := register("c@example.com", "EXPIREDCODE"); !errors.Is(err, model.ErrInviteExpired) {
+		t.Errorf("expired code: expected ErrInviteExpired, got %v", err)
+	}
+}
+
+func TestRegister_InviteReleasedWhenRegistrationFails(t *testing.T) {
+	ctx := context.Background()
+	invites := repository.NewMemoryInviteRepository()
+	svc := NewAuthService(
+		repository.NewMemoryUserRepository(),
+		repository.NewMemoryAuditRepository(),
+		repository.NewMemorySessionRepository(),
+		crypto.NewTokenManager(crypto.TokenConfig{Secret: "test-secret", Expiry: time.Hour}),
+		WithInviteOnly(invites),
+	)
+	minter := NewInviteService(invites)
+
+	first, _ := minter.Create(ctx, model.CreateInviteRequest{})
+	second, _ := minter.Create(ctx, model.CreateInviteRequest{})
+	req := model.CreateUserRequest{Email: "a@example.com", Password: "password123", InviteCode: first.Code}
+	if _, err := svc.Register(ctx, req, model.ClientInfo{}); err != nil {
+		t.Fatalf("first registration: %v", err)
+	}
+
+	req.InviteCode = second.Code
+	if _, err := svc.Register(ctx, req, model.ClientInfo{}); !errors.Is(err, ErrEmailTaken) {
+		t.Fatalf("duplicate email: expected ErrEmailTaken, got %v", err)
+	}
+
+	req.Email = "b@example.com"
+	if _, err := svc.Register(ctx, req, model.ClientInfo{}); err != nil {
+		t.Errorf("code should be usable again after the failed registration, got %v", err)
+	}
+}
+
+func TestInviteService_CreateRejectsPastExpiry(t *testing.T) {
+	past := time.Now().Add(-time.Second)
+	_, err := NewInviteService(repository.NewMemoryInviteRepository()).Create(context.Background(), model.CreateInviteRequest{ExpiresAt: &past})
+	if !errors.Is(err, ErrInviteExpiryPast) {
+		t.Errorf("expected ErrInviteExpiryPast, got %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/vaultpass/vaultpass-go/internal/model"
+)
+
+// KDFProfile returns the user's key derivation profile, or the zero profile if
+// none has been stored yet.
+func (s *AuthService) KDFProfile(ctx context.Context, userID int64) (model.KDFProfile, error) {
+	raw, err := s.repo.GetKDFProfile(ctx, userID)
+	if err != nil || raw == nil {
+		return model.KDFProfile{}, err
+	}
+
+	var profile model.KDFProfile
+	if err := json.Unmarshal(raw, &profile); err != nil {
+		return model.KDFProfile{}, err
+	}
+	return profile, nil
+}
+
+// SetKDFProfile validates and stores the user's key derivation profile,
+// replacing any previous one.
+func (s *AuthService) SetKDFProfile(ctx context.Context, userID int64, profile model.KDFProfile) (model.KDFProfile, error) {
+	if err := profile.Validate(); err != nil {
+		return model.KDFProfile{}, err
+	}
+
+	raw, err := json.Marshal(profile)
+	if err != nil {
+		return model.KDFProfile{}, err
+	}
+	if err := s.repo.SetKDFProfile(ctx, userID, raw); err != nil {
+		return model.KDFProfile{}, err
+	}
+	return profile, nil
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"log/slog"
+
+	"github.com/vaultpass/vaultpass-go/internal/model"
+)
+
+// entryLogAttrs returns the non-secret metadata of an incoming vault entry that is
+// safe to log. The encrypted payload itself is reduced to its encoded size.
+func entryLogAttrs(re model.VaultEntryRequest) []any {
+	return []any{
+		"entry_id", re.EntryID,
+		"version", re.Version,
+		"deleted", re.Deleted,
+		"encoded_size", len(re.EncryptedData),
+	}
+}
+
+// logSkippedEntry logs a sync entry that could not be applied, without its payload.
+func logSkippedEntry(re model.VaultEntryRequest, reason string, err error) {
+	attrs := append(entryLogAttrs(re), "reason", reason, "error", err)
+	slog.Warn("skipping sync entry", attrs...)
+}
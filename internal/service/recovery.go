@@ -0,0 +1,200 @@
+package service
+
+import (
+	"container/list"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/apperr"
+)
+
+var (
+	ErrRecoveryDisabled = apperr.New(http.StatusBadRequest, "recovery_disabled", "password recovery is not enabled")
+	ErrRecoveryNotFound = apperr.New(http.StatusNotFound, "recovery_not_found", "recovery handle not found or expired")
+)
+
+const (
+	recoveryIDSize  = 16
+	recoveryKeySize = 32
+)
+
+// DefaultMaxRecoveryEntries bounds the handles a RecoveryCache holds so a flood
+// of recoverable requests can't grow it without limit.
+const DefaultMaxRecoveryEntries = 10000
+
+type recoveryEntry struct {
+	id        string
+	sealed    []byte // nonce || AES-GCM ciphertext
+	expiresAt time.Time
+}
+
+// RecoveryCache holds recently generated passwords so a client can re-fetch one
+// exactly once before it expires. Each password is sealed with a fresh key that
+// only travels inside the handle returned to the client, so the cache never
+// holds anything it can decrypt on its own. Once maxEntries handles are held,
+// storing another evicts the oldest.
+type RecoveryCache struct {
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	order      *list.List // front = newest; handles share one TTL, so the back expires first
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+// RecoveryOption configures a RecoveryCache.
+type RecoveryOption func(*RecoveryCache)
+
+// WithMaxRecoveryEntries caps the handles held at once. n <= 0 keeps
+// DefaultMaxRecoveryEntries.
+func WithMaxRecoveryEntries(n int) RecoveryOption {
+	return func(c *RecoveryCache) {
+		if n > 0 {
+			c.maxEntries = n
+		}
+	}
+}
+
+// NewRecoveryCache creates a RecoveryCache whose handles expire after ttl and
+// starts its background cleanup, which runs until Close.
+func NewRecoveryCache(ttl time.Duration, opts ...RecoveryOption) *RecoveryCache {
+	c := &RecoveryCache{
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		ttl:        ttl,
+		maxEntries: DefaultMaxRecoveryEntries,
+		now:        time.Now,
+		done:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	go c.cleanup()
+	return c
+}
+
+// Close stops the background cleanup. Handles already stored can still be redeemed.
+func (c *RecoveryCache) Close() {
+	c.closeOnce.Do(func() { close(c.done) })
+}
+
+// Store seals password and returns the handle that redeems it, along with its expiry.
+func (c *RecoveryCache) Store(password string) (string, time.Time, error) {
+	raw := make([]byte, recoveryIDSize+recoveryKeySize)
+	if _, err := rand.Read(raw); err != nil {
+		return "", time.Time{}, err
+	}
+	id, key := raw[:recoveryIDSize], raw[recoveryIDSize:]
+
+	aead, err := newRecoveryAEAD(key)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", time.Time{}, err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(password), id)
+
+	expiresAt := c.now().Add(c.ttl)
+
+	c.mu.Lock()
+	for len(c.entries) >= c.maxEntries {
+		c.remove(c.order.Back())
+	}
+	c.entries[string(id)] = c.order.PushFront(&recoveryEntry{id: string(id), sealed: sealed, expiresAt: expiresAt})
+	c.mu.Unlock()
+
+	return base64.RawURLEncoding.EncodeToString(raw), expiresAt, nil
+}
+
+// Redeem returns the password behind handle and forgets it, so each handle works once.
+func (c *RecoveryCache) Redeem(handle string) (string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(handle)
+	if err != nil || len(raw) != recoveryIDSize+recoveryKeySize {
+		return "", ErrRecoveryNotFound
+	}
+	id, key := raw[:recoveryIDSize], raw[recoveryIDSize:]
+
+	c.mu.Lock()
+	el, ok := c.entries[string(id)]
+	if ok {
+		c.remove(el)
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		return "", ErrRecoveryNotFound
+	}
+	entry := el.Value.(*recoveryEntry)
+	if !c.now().Before(entry.expiresAt) {
+		return "", ErrRecoveryNotFound
+	}
+
+	aead, err := newRecoveryAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonceSize := aead.NonceSize()
+	plaintext, err := aead.Open(nil, entry.sealed[:nonceSize], entry.sealed[nonceSize:], id)
+	if err != nil {
+		return "", ErrRecoveryNotFound
+	}
+	return string(plaintext), nil
+}
+
+// Len returns the number of handles currently held, including expired ones not yet swept.
+func (c *RecoveryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// remove forgets the handle at el. Callers must hold c.mu.
+func (c *RecoveryCache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*recoveryEntry).id)
+}
+
+// sweep drops every expired handle. They are ordered by age, so the expired
+// ones are all at the back.
+func (c *RecoveryCache) sweep() {
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for el := c.order.Back(); el != nil; el = c.order.Back() {
+		if now.Before(el.Value.(*recoveryEntry).expiresAt) {
+			break
+		}
+		c.remove(el)
+	}
+}
+
+func (c *RecoveryCache) cleanup() {
+	ticker := time.NewTicker(c.ttl)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.sweep()
+		}
+	}
+}
+
+func newRecoveryAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
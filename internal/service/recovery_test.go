@@ -0,0 +1,175 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/model"
+)
+
+// newTestRecoveryCache returns a one-minute cache that is closed when t ends.
+func newTestRecoveryCache(t *testing.T, opts ...RecoveryOption) *RecoveryCache {
+	t.Helper()
+	cache := NewRecoveryCache(time.Minute, opts...)
+	t.Cleanup(cache.Close)
+	return cache
+}
+
+func TestRecoveryCache_RedeemOnce(t *testing.T) {
+	cache := newTestRecoveryCache(t)
+
+	handle, expiresAt, err := cache.Store("s3cret-password")
+	if err != nil {
+		t.Fatalf("Store() unexpected error: %v", err)
+	}
+	if !expiresAt.After(time.Now()) {
+		t.Errorf("expected expiry in the future, got %v", expiresAt)
+	}
+
+	got, err := cache.Redeem(handle)
+	if err != nil {
+		t.Fatalf("Redeem() unexpected error: %v", err)
+	}
+	if got != "s3cret-password" {
+		t.Errorf("expected original password, got %q", got)
+	}
+
+	if _, err := cache.Redeem(handle); err != ErrRecoveryNotFound {
+		t.Errorf("expected ErrRecoveryNotFound on second redemption, got %v", err)
+	}
+	if cache.Len() != 0 {
+		t.Errorf("expected cache to be empty after redemption, got %d", cache.Len())
+	}
+}
+
+func TestRecoveryCache_Expiry(t *testing.T) {
+	cache := newTestRecoveryCache(t)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	expired, _, err := cache.Store("first")
+	if err != nil {
+		t.Fatalf("Store() unexpected error: %v", err)
+	}
+	now = now.Add(30 * time.Second)
+	live, _, err := cache.Store("second")
+	if err != nil {
+		t.Fatalf("Store() unexpected error: %v", err)
+	}
+
+	now = now.Add(31 * time.Second)
+	cache.sweep()
+	if cache.Len() != 1 {
+		t.Fatalf("expected sweep to drop only the expired handle, got %d left", cache.Len())
+	}
+	if _, err := cache.Redeem(expired); err != ErrRecoveryNotFound {
+		t.Errorf("expected ErrRecoveryNotFound for expired handle, got %v", err)
+	}
+
+	now = now.Add(30 * time.Second)
+	if _, err := cache.Redeem(live); err != ErrRecoveryNotFound {
+		t.Errorf("expected ErrRecoveryNotFound at the TTL boundary, got %v", err)
+	}
+}
+
+func TestRecoveryCache_TamperedHandle(t *testing.T) {
+	cache := newTestRecoveryCache(t)
+
+	handle, _, err := cache.Store("password")
+	if err != nil {
+		t.Fatalf("Store() unexpected error: %v", err)
+	}
+
+	// Flip the last key character: the entry is found but cannot be opened.
+	flipped := "A"
+	if strings.HasSuffix(handle, "A") {
+		flipped = "B"
+	}
+	tampered := handle[:len(handle)-1] + flipped
+	if _, err := cache.Redeem(tampered); err != ErrRecoveryNotFound {
+		t.Errorf("expected ErrRecoveryNotFound for tampered handle, got %v", err)
+	}
+	for _, bad := range []string{"", "not base64!", strings.Repeat("A", 10)} {
+		if _, err := cache.Redeem(bad); err != ErrRecoveryNotFound {
+			t.Errorf("Redeem(%q): expected ErrRecoveryNotFound, got %v", bad, err)
+		}
+	}
+}
+
+func TestRecoveryCache_MaxEntries(t *testing.T) {
+	cache := newTestRecoveryCache(t, WithMaxRecoveryEntries(2))
+
+	var handles []string
+	for _, pw := range []string{"first", "second", "third"} {
+		handle, _, err := cache.Store(pw)
+		if err != nil {
+			t.Fatalf("Store() unexpected error: %v", err)
+		}
+		handles = append(handles, handle)
+	}
+
+	if cache.Len() != 2 {
+		t.Fatalf("expected the cache capped at 2 handles, got %d", cache.Len())
+	}
+	if _, err := cache.Redeem(handles[0]); err != ErrRecoveryNotFound {
+		t.Errorf("expected the oldest handle evicted, got %v", err)
+	}
+	if got, err := cache.Redeem(handles[2]); err != nil || got != "third" {
+		t.Errorf("Redeem(newest) = %q, %v; want third", got, err)
+	}
+}
+
+func TestRecoveryCache_Close(t *testing.T) {
+	cache := NewRecoveryCache(time.Minute)
+	handle, _, _ := cache.Store("password")
+
+	cache.Close()
+	cache.Close() // idempotent
+
+	if got, err := cache.Redeem(handle); err != nil || got != "password" {
+		t.Errorf("Redeem() after Close = %q, %v; want the stored password", got, err)
+	}
+}
+
+func TestGenerate_Recoverable(t *testing.T) {
+	svc := NewGeneratorService(WithRecovery(newTestRecoveryCache(t)))
+
+	resp, err := svc.Generate(model.GenerateRequest{Recoverable: true})
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+	if resp.RecoveryHandle == "" || resp.RecoveryExpiresAt == nil {
+		t.Fatalf("expected recovery handle and expiry, got %+v", resp)
+	}
+
+	redeemed, err := svc.Redeem(resp.RecoveryHandle)
+	if err != nil {
+		t.Fatalf("Redeem() unexpected error: %v", err)
+	}
+	if redeemed.Password != resp.Password {
+		t.Errorf("expected redeemed password %q, got %q", resp.Password, redeemed.Password)
+	}
+	if _, err := svc.Redeem(resp.RecoveryHandle); err != ErrRecoveryNotFound {
+		t.Errorf("expected second redemption to fail, got %v", err)
+	}
+
+	plain, err := svc.Generate(model.GenerateRequest{})
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+	if plain.RecoveryHandle != "" {
+		t.Error("expected no recovery handle unless requested")
+	}
+}
+
+func TestGenerate_RecoverableDisabled(t *testing.T) {
+	svc := NewGeneratorService()
+
+	if _, err := svc.Generate(model.GenerateRequest{Recoverable: true}); err != ErrRecoveryDisabled {
+		t.Errorf("expected ErrRecoveryDisabled, got %v", err)
+	}
+	if _, err := svc.Redeem("anything"); err != ErrRecoveryNotFound {
+		t.Errorf("expected ErrRecoveryNotFound when disabled, got %v", err)
+	}
+}
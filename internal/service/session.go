@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/apperr"
+	"github.com/vaultpass/vaultpass-go/internal/crypto"
+	"github.com/vaultpass/vaultpass-go/internal/model"
+	"github.com/vaultpass/vaultpass-go/internal/repository"
+)
+
+var (
+	ErrSessionNotFound = apperr.New(http.StatusNotFound, "session_not_found", "session not found")
+	ErrSessionRevoked  = apperr.New(http.StatusUnauthorized, "session_revoked", "session has been revoked")
+	ErrTokenRevoked    = apperr.New(http.StatusUnauthorized, "token_revoked", "token was issued before the user logged out everywhere")
+	ErrSessionIdle     = apperr.New(http.StatusUnauthorized, "session_idle", "session expired after inactivity")
+)
+
+// tokenEpochTTL bounds how long a user's token epoch and active state are
+// cached for tokens without a session. Bumps and deactivations made by this
+// instance take effect immediately.
+const tokenEpochTTL = 30 * time.Second
+
+// issueToken creates a server-side session for the user and returns a token bound to it.
+// When no session repository is configured, an unbound token is issued instead.
+// Either way the token carries the user's current token epoch. Remembered
+// sign-ins get the longer "remember me" lifetime for both token and session.
+func (s *AuthService) issueToken(ctx context.Context, user *model.User, client model.ClientInfo, remember bool) (string, error) {
+	expiry := s.tokens.Expiry()
+	if remember {
+		expiry = s.tokens.RememberExpiry()
+	}
+	if s.sessions == nil {
+		return s.tokens.GenerateWithExpiry(user.ID, "", user.TokenEpoch, expiry)
+	}
+
+	sessionID, err := crypto.NewSessionID()
+	if err != nil {
+		return "", err
+	}
+
+	session := &model.Session{
+		ID:        sessionID,
+		UserID:    user.ID,
+		IPAddress: truncate(client.IPAddress, 45),
+		UserAgent: truncate(client.UserAgent, 255),
+		ExpiresAt: time.Now().UTC().Add(expiry),
+	}
+	if err := s.sessions.Create(ctx, session); err != nil {
+		return "", err
+	}
+
+	return s.tokens.GenerateWithExpiry(user.ID, sessionID, user.TokenEpoch, expiry)
+}
+
+// ListSessions returns the user's active sessions, flagging the one making the request.
+func (s *AuthService) ListSessions(ctx context.Context, userID int64, currentSessionID string) ([]model.SessionResponse, error) {
+	sessions, err := s.sessions.ListActiveByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]model.SessionResponse, len(sessions))
+	for i, sess := range sessions {
+		result[i] = model.SessionResponse{
+			ID:         sess.ID,
+			IPAddress:  sess.IPAddress,
+			UserAgent:  sess.UserAgent,
+			CreatedAt:  sess.CreatedAt,
+			LastSeenAt: sess.LastSeenAt,
+			ExpiresAt:  sess.ExpiresAt,
+			Current:    sess.ID == currentSessionID,
+		}
+	}
+	return result, nil
+}
+
+// RevokeSession revokes one of the user's sessions, invalidating its token.
+func (s *AuthService) RevokeSession(ctx context.Context, userID int64, sessionID string) error {
+	err := s.sessions.Revoke(ctx, userID, sessionID)
+	if errors.Is(err, repository.ErrSessionNotFound) {
+		return ErrSessionNotFound
+	}
+	return err
+}
+
+// LogoutAll invalidates every token the user holds by bumping their token epoch,
+// and revokes their sessions so they no longer show up as active.
+func (s *AuthService) LogoutAll(ctx context.Context, userID int64) error {
+	epoch, err := s.repo.BumpTokenEpoch(ctx, userID)
+	if err != nil {
+		return err
+	}
+	// Only an active user can log out everywhere.
+	s.epochs.set(userID, epoch, true, time.Now())
+	return s.revokeSessions(ctx, userID)
+}
+
+// revokeSessions revokes all of the user's active sessions.
+func (s *AuthService) revokeSessions(ctx context.Context, userID int64) error {
+	if s.sessions == nil {
+		return nil
+	}
+	sessions, err := s.sessions.ListActiveByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, sess := range sessions {
+		if err := s.sessions.Revoke(ctx, userID, sess.ID); err != nil && !errors.Is(err, repository.ErrSessionNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateSession checks that the session a token was issued for is still active
+// and records activity on it. A session unused for longer than the token's
+// MaxIdle is revoked and rejected with ErrSessionIdle. Tokens issued without a session are accepted until they expire.
+// Tokens belonging to a deactivated user, with or without a session, are rejected with ErrAccountDeactivated.
+// Any token issued before the user's current token epoch is rejected with ErrTokenRevoked.
+func (s *AuthService) ValidateSession(ctx context.Context, claims *crypto.Claims) error {
+	if s.sessions == nil || claims.ID == "" {
+		return s.checkTokenEpoch(ctx, claims)
+	}
+
+	user, err := s.repo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrSessionRevoked
+		}
+		return err
+	}
+	if !user.Active() {
+		return ErrAccountDeactivated
+	}
+	s.epochs.set(user.ID, user.TokenEpoch, true, time.Now())
+	if claims.TokenEpoch < user.TokenEpoch {
+		return ErrTokenRevoked
+	}
+
+	session, err := s.sessions.GetByID(ctx, claims.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			return ErrSessionRevoked
+		}
+		return err
+	}
+
+	now := time.Now().UTC()
+	if err := checkSession(session, claims.UserID, now); err != nil {
+		return err
+	}
+	if err := checkIdle(session, claims.MaxIdle, now); err != nil {
+		// Revoked so the session stops being listed as active.
+		if rerr := s.sessions.Revoke(ctx, session.UserID, session.ID); rerr != nil && !errors.Is(rerr, repository.ErrSessionNotFound) {
+			return rerr
+		}
+		return err
+	}
+
+	return s.sessions.Touch(ctx, session.ID)
+}
+
+// checkTokenEpoch rejects a token issued before its user's current token epoch,
+// or one whose user is deactivated. Both are read through a cache, since these
+// tokens otherwise need no lookup.
+func (s *AuthService) checkTokenEpoch(ctx context.Context, claims *crypto.Claims) error {
+	now := time.Now()
+	cached, ok := s.epochs.get(claims.UserID, now)
+	if !ok {
+		user, err := s.repo.GetByID(ctx, claims.UserID)
+		if err != nil {
+			if errors.Is(err, repository.ErrUserNotFound) {
+				return ErrTokenRevoked
+			}
+			return err
+		}
+		cached = cachedEpoch{epoch: user.TokenEpoch, active: user.Active()}
+		s.epochs.set(user.ID, cached.epoch, cached.active, now)
+	}
+
+	if !cached.active {
+		return ErrAccountDeactivated
+	}
+	if claims.TokenEpoch < cached.epoch {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// checkSession reports whether a stored session may still be used by the given user.
+func checkSession(session *model.Session, userID int64, now time.Time) error {
+	if session.UserID != userID || session.RevokedAt != nil || !now.Before(session.ExpiresAt) {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+// checkIdle rejects a session last seen more than maxIdle seconds before now.
+func checkIdle(session *model.Session, maxIdle int64, now time.Time) error {
+	if maxIdle > 0 && now.Sub(session.LastSeenAt) > time.Duration(maxIdle)*time.Second {
+		return ErrSessionIdle
+	}
+	return nil
+}
+
+// epochCache holds recently read token epochs and active states by user ID.
+type epochCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[int64]cachedEpoch
+}
+
+type cachedEpoch struct {
+	epoch     int64
+	active    bool
+	expiresAt time.Time
+}
+
+func newEpochCache(ttl time.Duration) *epochCache {
+	return &epochCache{ttl: ttl, entries: make(map[int64]cachedEpoch)}
+}
+
+// get returns the cached entry for userID unless it has expired.
+func (c *epochCache) get(userID int64, now time.Time) (cachedEpoch, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[userID]
+	if !ok {
+		return cachedEpoch{}, false
+	}
+	if !now.Before(e.expiresAt) {
+		delete(c.entries, userID)
+		return cachedEpoch{}, false
+	}
+	return e, true
+}
+
+// set caches epoch and active for userID. An older epoch never replaces a
+// newer one, so a slow read can't undo a bump.
+func (c *epochCache) set(userID, epoch int64, active bool, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[userID]; ok && e.epoch > epoch && now.Before(e.expiresAt) {
+		return
+	}
+	c.entries[userID] = cachedEpoch{epoch: epoch, active: active, expiresAt: now.Add(c.ttl)}
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/crypto"
+	"github.com/vaultpass/vaultpass-go/internal/model"
+	"github.com/vaultpass/vaultpass-go/internal/repository"
+)
+
+func TestCheckSession_Active(t *testing.T) {
+	now := time.Now().UTC()
+	session := &model.Session{ID: "abc", UserID: 1, ExpiresAt: now.Add(time.Hour)}
+
+	if err := checkSession(session, 1, now); err != nil {
+		t.Errorf("expected active session to validate, got %v", err)
+	}
+}
+
+func TestCheckSession_Revoked(t *testing.T) {
+	now := time.Now().UTC()
+	revokedAt := now.Add(-time.Minute)
+	session := &model.Session{ID: "abc", UserID: 1, ExpiresAt: now.Add(time.Hour), RevokedAt: &revokedAt}
+
+	if err := checkSession(session, 1, now); err != ErrSessionRevoked {
+		t.Errorf("expected ErrSessionRevoked for revoked session, got %v", err)
+	}
+}
+
+func TestCheckSession_Expired(t *testing.T) {
+	now := time.Now().UTC()
+	session := &model.Session{ID: "abc", UserID: 1, ExpiresAt: now.Add(-time.Second)}
+
+	if err := checkSession(session, 1, now); err != ErrSessionRevoked {
+		t.Errorf("expected ErrSessionRevoked for expired session, got %v", err)
+	}
+}
+
+func TestCheckSession_WrongUser(t *testing.T) {
+	now := time.Now().UTC()
+	session := &model.Session{ID: "abc", UserID: 1, ExpiresAt: now.Add(time.Hour)}
+
+	if err := checkSession(session, 2, now); err != ErrSessionRevoked {
+		t.Errorf("expected ErrSessionRevoked for another user's session, got %v", err)
+	}
+}
+
+func TestCheckIdle(t *testing.T) {
+	now := time.Now().UTC()
+	session := &model.Session{ID: "abc", UserID: 1, LastSeenAt: now.Add(-10 * time.Minute)}
+
+	if err := checkIdle(session, 0, now); err != nil {
+		t.Errorf("expected no idle limit without max_idle, got %v", err)
+	}
+	if err := checkIdle(session, 900, now); err != nil {
+		t.Errorf("expected a session seen 10m ago to pass a 15m limit, got %v", err)
+	}
+	if err := checkIdle(session, 300, now); err != ErrSessionIdle {
+		t.Errorf("expected ErrSessionIdle past a 5m limit, got %v", err)
+	}
+}
+
+// agedSessions reports every session as last seen age earlier than stored.
+type agedSessions struct {
+	repository.SessionStore
+	age time.Duration
+}
+
+func (a *agedSessions) GetByID(ctx context.Context, id string) (*model.Session, error) {
+	session, err := a.SessionStore.GetByID(ctx, id)
+	if session != nil {
+		session.LastSeenAt = session.LastSeenAt.Add(-a.age)
+	}
+	return session, err
+}
+
+func TestValidateSession_IdleSessionRejected(t *testing.T) {
+	sessions := &agedSessions{SessionStore: repository.NewMemorySessionRepository()}
+	svc := NewAuthService(
+		repository.NewMemoryUserRepository(),
+		repository.NewMemoryAuditRepository(),
+		sessions,
+		crypto.NewTokenManager(crypto.TokenConfig{Secret: "test-secret", Expiry: time.Hour, IdleTimeout: 15 * time.Minute}),
+	)
+	ctx := context.Background()
+
+	resp, err := svc.Register(ctx, model.CreateUserRequest{Email: "a@example.com", Password: "password123"}, model.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+	claims, err := svc.tokens.Validate(resp.Token)
+	if err != nil {
+		t.Fatalf("Validate() unexpected error: %v", err)
+	}
+
+	sessions.age = 14 * time.Minute
+	if err := svc.ValidateSession(ctx, claims); err != nil {
+		t.Fatalf("expected a session inside the idle window to validate, got %v", err)
+	}
+
+	sessions.age = 16 * time.Minute
+	if err := svc.ValidateSession(ctx, claims); err != ErrSessionIdle {
+		t.Fatalf("expected ErrSessionIdle past the idle window, got %v", err)
+	}
+	if active, _ := svc.ListSessions(ctx, resp.User.ID, ""); len(active) != 0 {
+		t.Errorf("expected the idle session to be revoked, got %d active", len(active))
+	}
+
+	sessions.age = 0
+	if err := svc.ValidateSession(ctx, claims); err != ErrSessionRevoked {
+		t.Errorf("expected the idle session to stay revoked, got %v", err)
+	}
+}
+
+func TestValidateSession_TokenWithoutSession(t *testing.T) {
+	svc, _ := newMemoryAuthService()
+	ctx := context.Background()
+
+	resp, err := svc.Register(ctx, model.CreateUserRequest{Email: "a@example.com", Password: "password123"}, model.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+
+	claims := &crypto.Claims{UserID: resp.User.ID}
+	if err := svc.ValidateSession(ctx, claims); err != nil {
+		t.Errorf("expected session-less token to be accepted, got %v", err)
+	}
+}
+
+func TestLogoutAll_InvalidatesIssuedTokens(t *testing.T) {
+	svc, _ := newMemoryAuthService()
+	ctx := context.Background()
+
+	resp, err := svc.Register(ctx, model.CreateUserRequest{Email: "a@example.com", Password: "password123"}, model.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+	claims, err := svc.tokens.Validate(resp.Token)
+	if err != nil {
+		t.Fatalf("Validate() unexpected error: %v", err)
+	}
+	sessionless := &crypto.Claims{UserID: resp.User.ID}
+	for _, c := range []*crypto.Claims{claims, sessionless} {
+		if err := svc.ValidateSession(ctx, c); err != nil {
+			t.Fatalf("expected token to validate before logout-all, got %v", err)
+		}
+	}
+
+	if err := svc.LogoutAll(ctx, resp.User.ID); err != nil {
+		t.Fatalf("LogoutAll() unexpected error: %v", err)
+	}
+	for _, c := range []*crypto.Claims{claims, sessionless} {
+		if err := svc.ValidateSession(ctx, c); err != ErrTokenRevoked {
+			t.Errorf("expected ErrTokenRevoked after logout-all, got %v", err)
+		}
+	}
+	if sessions, _ := svc.ListSessions(ctx, resp.User.ID, ""); len(sessions) != 0 {
+		t.Errorf("expected sessions to be revoked, got %d", len(sessions))
+	}
+
+	login, err := svc.Login(ctx, model.LoginRequest{Email: "a@example.com", Password: "password123"}, model.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login() unexpected error: %v", err)
+	}
+	fresh, err := svc.tokens.Validate(login.Token)
+	if err != nil {
+		t.Fatalf("Validate() unexpected error: %v", err)
+	}
+	if fresh.TokenEpoch != 1 {
+		t.Errorf("expected new token to carry epoch 1, got %d", fresh.TokenEpoch)
+	}
+	if err := svc.ValidateSession(ctx, fresh); err != nil {
+		t.Errorf("expected token issued after logout-all to validate, got %v", err)
+	}
+}
+
+func TestEpochCache(t *testing.T) {
+	c := newEpochCache(time.Minute)
+	now := time.Now()
+
+	if _, ok := c.get(1, now); ok {
+		t.Fatal("expected miss on empty cache")
+	}
+	c.set(1, 2, true, now)
+	c.set(1, 1, true, now) // a stale read must not undo a bump
+	if e, ok := c.get(1, now); !ok || e.epoch != 2 {
+		t.Errorf("expected epoch 2, got %d (ok %v)", e.epoch, ok)
+	}
+	c.set(1, 2, false, now)
+	if e, _ := c.get(1, now); e.active {
+		t.Error("expected a deactivation at the same epoch to be cached")
+	}
+	if _, ok := c.get(1, now.Add(time.Minute)); ok {
+		t.Error("expected entry to expire after the TTL")
+	}
+}
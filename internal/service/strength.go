@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/vaultpass/vaultpass-go/internal/apperr"
+	"github.com/vaultpass/vaultpass-go/internal/crypto"
+	"github.com/vaultpass/vaultpass-go/internal/model"
+)
+
+// strengthWorkers bounds how many passwords StrengthBatch scores concurrently,
+// and so how many breach lookups one batch can have in flight.
+const strengthWorkers = 8
+
+// WithBreachChecker makes Strength look passwords up in a breach corpus.
+// Without it, responses report the breach check as disabled.
+func WithBreachChecker(checker *crypto.BreachChecker) GeneratorOption {
+	return func(s *GeneratorService) {
+		s.breaches = checker
+	}
+}
+
+// Strength estimates the entropy and score of password, lists the findings
+// behind the score and, when a breach checker is configured, whether it
+// appears in a known breach. A failed lookup is reported as unavailable
+// rather than failing the request.
+func (s *GeneratorService) Strength(ctx context.Context, password string) (model.StrengthResponse, error) {
+	if password == "" {
+		return model.StrengthResponse{}, apperr.Wrap(http.StatusBadRequest, "password_required", ErrPasswordRequired)
+	}
+
+	report := crypto.AnalyzeStrength(password)
+	resp := model.StrengthResponse{
+		Length:      utf8.RuneCountInString(password),
+		EntropyBits: math.Round(report.EntropyBits*10) / 10,
+		Score:       report.Score,
+		Findings:    report.Findings,
+		Breach:      model.BreachDisabled,
+	}
+	if s.breaches == nil {
+		return resp, nil
+	}
+
+	count, err := s.breaches.Count(ctx, password)
+	switch {
+	case err != nil:
+		slog.Warn("breach check unavailable", "error", err)
+		resp.Breach = model.BreachUnavailable
+	case count > 0:
+		resp.Breach = model.BreachFound
+		resp.BreachCount = count
+	default:
+		resp.Breach = model.BreachNotFound
+	}
+	return resp, nil
+}
+
+// StrengthBatch scores each password like Strength, using a bounded pool of
+// workers. Results are returned in input order; a password that cannot be
+// scored gets an error message in its slot instead of failing the batch.
+func (s *GeneratorService) StrengthBatch(ctx context.Context, passwords []string) []model.StrengthResult {
+	results := make([]model.StrengthResult, len(passwords))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(strengthWorkers, len(passwords)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				resp, err := s.Strength(ctx, passwords[i])
+				if err != nil {
+					results[i] = model.StrengthResult{Error: err.Error()}
+					continue
+				}
+				results[i] = model.StrengthResult{StrengthResponse: resp}
+			}
+		}()
+	}
+
+	for i := range passwords {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
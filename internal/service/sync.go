@@ -0,0 +1,335 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/vaultpass/vaultpass-go/internal/apperr"
+	"github.com/vaultpass/vaultpass-go/internal/model"
+	"github.com/vaultpass/vaultpass-go/internal/repository"
+)
+
+// ErrLateConflictStrategy is returned when a sync request changes its conflict
+// strategy after entries were already applied under another one.
+var ErrLateConflictStrategy = apperr.New(http.StatusBadRequest, "late_conflict_strategy", "conflict_strategy cannot change once entries are applied")
+
+// SyncUpload applies a sync request's entries one at a time as they are
+// decoded, so a large upload never has to be held in memory at once. Entries
+// are written in a transaction that Finish commits; Close rolls back an
+// unfinished upload. The transaction runs under txCtx, which carries the
+// service's sync transaction deadline from the first entry on.
+type SyncUpload struct {
+	s        *VaultService
+	ctx      context.Context
+	txCtx    context.Context
+	cancel   context.CancelFunc
+	userID   int64
+	vaultID  int64
+	syncedAt time.Time
+	strategy string
+	tx       repository.Tx
+	applied  int
+	added    int
+	skipped  []model.SkippedEntry
+	done     bool
+}
+
+// BeginSync starts a sync of one of the user's vaults using the lww strategy
+// until SetStrategy says otherwise.
+func (s *VaultService) BeginSync(ctx context.Context, userID, vaultID int64) (*SyncUpload, error) {
+	syncedAt := time.Now().UTC()
+
+	vaultID, err := s.resolveVault(ctx, userID, vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SyncUpload{
+		s:        s,
+		ctx:      ctx,
+		userID:   userID,
+		vaultID:  vaultID,
+		syncedAt: syncedAt,
+		strategy: model.ConflictLWW,
+	}, nil
+}
+
+// SetStrategy sets the conflict strategy; empty means lww. Once entries have
+// been applied, only the strategy already in use is accepted.
+func (u *SyncUpload) SetStrategy(strategy string) error {
+	if strategy == "" {
+		strategy = model.ConflictLWW
+	}
+	if strategy != model.ConflictLWW && strategy != model.ConflictManual {
+		return ErrInvalidStrategy
+	}
+	if u.applied > 0 && strategy != u.strategy {
+		return ErrLateConflictStrategy
+	}
+	u.strategy = strategy
+	return nil
+}
+
+// Apply writes one incoming entry within the upload's transaction. Invalid
+// entries and failed writes are logged and listed as skipped; an error means
+// the upload can't continue.
+func (u *SyncUpload) Apply(re model.VaultEntryRequest) error {
+	if u.tx == nil {
+		u.txCtx, u.cancel = u.ctx, func() {}
+		if u.s.syncTxTimeout > 0 {
+			u.txCtx, u.cancel = context.WithTimeout(u.ctx, u.s.syncTxTimeout)
+		}
+		tx, err := u.s.repo.BeginTx(u.txCtx)
+		if err != nil {
+			u.cancel()
+			return err
+		}
+		u.tx = tx
+	}
+	if err := u.txErr(); err != nil {
+		return err
+	}
+	u.applied++
+
+	if !validDeviceID(re.DeviceID) {
+		u.skip(re, ErrInvalidDeviceID)
+		return nil
+	}
+
+	reason := re.DeleteReason
+	if !re.Deleted {
+		reason = ""
+	}
+	if !validDeleteReason(reason) {
+		u.skip(re, ErrInvalidDeleteReason)
+		return nil
+	}
+
+	data, err := u.s.decodeEntryData(re.EncryptedData)
+	if err != nil {
+		u.skip(re, err)
+		return nil
+	}
+
+	version := re.Version
+	if version < 1 {
+		version = 1
+	}
+
+	entry := model.VaultEntry{
+		UserID:        u.userID,
+		VaultID:       u.vaultID,
+		EntryID:       re.EntryID,
+		EncryptedData: data,
+		Version:       version,
+		Favorite:      re.Favorite,
+		LastDeviceID:  re.DeviceID,
+		Deleted:       re.Deleted,
+		DeleteReason:  reason,
+		ExpiresAt:     re.ExpiresAt,
+	}
+
+	adds, err := u.addsEntry(&entry)
+	if err != nil {
+		u.skip(re, err)
+		return nil
+	}
+	if adds {
+		if err := u.s.checkEntryLimit(u.txCtx, u.userID, u.added+1); err != nil {
+			u.skip(re, err)
+			return nil
+		}
+	}
+
+	if u.strategy == model.ConflictManual {
+		err = u.s.upsertManual(u.txCtx, u.tx, &entry)
+	} else {
+		err = u.s.repo.UpsertTx(u.txCtx, u.tx, &entry)
+	}
+	if err != nil {
+		u.skip(re, err)
+		return nil
+	}
+	if adds {
+		u.added++
+	}
+	return nil
+}
+
+// addsEntry reports whether writing entry would count against the entry limit:
+// a live entry that is new, or that wins over a tombstone. It always reports
+// false when no limit is set.
+func (u *SyncUpload) addsEntry(entry *model.VaultEntry) (bool, error) {
+	if u.s.maxEntries <= 0 || entry.Deleted {
+		return false, nil
+	}
+	existing, err := u.s.repo.GetByEntryIDForUpdateTx(u.txCtx, u.tx, entry.UserID, entry.VaultID, entry.EntryID)
+	if errors.Is(err, repository.ErrEntryNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return existing.Deleted && entry.Version > existing.Version, nil
+}
+
+// txErr returns ErrSyncTimeout once the transaction's deadline has passed, or
+// the request's own error if it was cancelled.
+func (u *SyncUpload) txErr() error {
+	err := u.txCtx.Err()
+	if errors.Is(err, context.DeadlineExceeded) && u.ctx.Err() == nil {
+		return ErrSyncTimeout
+	}
+	return err
+}
+
+// skip logs an entry that could not be applied and reports it in the
+// response. Client mistakes are reported by their error code; anything else
+// is a failed write.
+func (u *SyncUpload) skip(re model.VaultEntryRequest, err error) {
+	reason := model.SkipWriteFailed
+	if e, ok := apperr.As(err); ok {
+		reason = e.Code
+	}
+	logSkippedEntry(re, reason, err)
+	u.skipped = append(u.skipped, model.SkippedEntry{EntryID: re.EntryID, Reason: reason})
+}
+
+// Finish commits the applied entries, records req.AckCursor for req.DeviceID
+// and purges tombstones every device has acknowledged, and
+// returns the server-side changes since req.LastSyncedAt, or every entry when
+// it is nil. Changes come oldest first. Without req.Limit every change is
+// returned; with it, req.Limit and req.Cursor page through them.
+func (u *SyncUpload) Finish(req model.SyncRequest) (model.SyncResponse, error) {
+	if req.AckCursor != nil && req.AckCursor.After(u.syncedAt) {
+		return model.SyncResponse{}, ErrInvalidAckCursor
+	}
+	if !validDeviceID(req.DeviceID) {
+		return model.SyncResponse{}, ErrInvalidDeviceID
+	}
+	if req.Limit < 0 {
+		return model.SyncResponse{}, ErrInvalidSyncLimit
+	}
+	var after *model.ChangeCursor
+	if req.Cursor != "" {
+		c, err := repository.DecodeCursor(req.Cursor)
+		if err != nil || c.Key == "" {
+			return model.SyncResponse{}, ErrInvalidCursor
+		}
+		after = &model.ChangeCursor{ChangedAt: c.At, EntryID: c.Key}
+	}
+
+	if u.tx != nil {
+		if err := u.txErr(); err != nil {
+			return model.SyncResponse{}, err
+		}
+		if err := u.tx.Commit(); err != nil {
+			return model.SyncResponse{}, err
+		}
+		u.cancel()
+	}
+	u.done = true
+
+	// Every device that syncs is tracked, so one that hasn't acknowledged
+	// anything yet still holds back the tombstone purge.
+	var ack *time.Time
+	if req.AckCursor != nil {
+		at := req.AckCursor.UTC()
+		ack = &at
+	}
+	if err := u.s.repo.AckSync(u.ctx, u.userID, u.vaultID, req.DeviceID, ack); err != nil {
+		return model.SyncResponse{}, err
+	}
+	if ack != nil {
+		// A failed purge only delays it to the next acknowledgment.
+		if _, err := u.s.purgeAckedTombstones(u.ctx, u.userID, u.vaultID); err != nil {
+			slog.Error("tombstone purge failed", "user_id", u.userID, "vault_id", u.vaultID, "error", err)
+		}
+	}
+
+	var since time.Time
+	if req.LastSyncedAt != nil {
+		since = *req.LastSyncedAt
+	}
+	filter := model.ChangeFilter{Since: since, After: after, Now: u.syncedAt}
+	limit := min(req.Limit, MaxSyncPageSize)
+	if limit > 0 {
+		// One extra entry tells whether another page follows.
+		filter.Limit = limit + 1
+	}
+	serverEntries, err := u.s.repo.GetChangedSince(u.ctx, u.userID, u.vaultID, filter)
+	if err != nil {
+		return model.SyncResponse{}, err
+	}
+	var next string
+	var hasMore bool
+	if limit > 0 {
+		serverEntries, next, hasMore = repository.Paginate(serverEntries, limit, func(e model.VaultEntry) repository.Cursor {
+			return repository.Cursor{At: e.ChangedAt(u.syncedAt), Key: e.EntryID}
+		})
+	}
+
+	rekeyVersion, err := u.s.rekeyVersion(u.ctx, u.userID)
+	if err != nil {
+		return model.SyncResponse{}, err
+	}
+
+	resp := model.SyncResponse{
+		SyncedAt:     u.syncedAt,
+		Entries:      entriesToResponse(serverEntries),
+		Skipped:      u.skipped,
+		HasMore:      hasMore,
+		NextCursor:   next,
+		RekeyVersion: rekeyVersion,
+	}
+
+	if u.strategy == model.ConflictManual {
+		if resp.Conflicts, err = u.s.listConflicts(u.ctx, u.userID, u.vaultID); err != nil {
+			return model.SyncResponse{}, err
+		}
+	}
+
+	return resp, nil
+}
+
+// Rekey records that the user changed their master password and re-uploaded
+// their entries. It bumps the user's rekey version, which every later sync
+// reports, so other devices know to discard local state and pull fresh blobs.
+func (s *VaultService) Rekey(ctx context.Context, userID int64) (model.RekeyResponse, error) {
+	if s.users == nil {
+		return model.RekeyResponse{}, ErrRekeyUnavailable
+	}
+	version, err := s.users.BumpRekeyVersion(ctx, userID)
+	if err != nil {
+		return model.RekeyResponse{}, err
+	}
+	return model.RekeyResponse{RekeyVersion: version}, nil
+}
+
+// rekeyVersion returns the user's current rekey version, or zero when rekeys
+// aren't tracked.
+func (s *VaultService) rekeyVersion(ctx context.Context, userID int64) (int64, error) {
+	if s.users == nil {
+		return 0, nil
+	}
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	return user.RekeyVersion, nil
+}
+
+// Close rolls back the entries of an upload that did not finish. It is safe
+// to call after Finish.
+func (u *SyncUpload) Close() {
+	if u.tx != nil && !u.done {
+		u.tx.Rollback()
+		u.cancel()
+	}
+}
+
+// MaxSyncPageSize caps SyncRequest.Limit; larger limits are reduced to it.
+const MaxSyncPageSize = 500
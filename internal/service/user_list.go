@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+
+	"github.com/vaultpass/vaultpass-go/internal/model"
+	"github.com/vaultpass/vaultpass-go/internal/repository"
+)
+
+// UserListService pages through user accounts for admin support tooling.
+type UserListService struct {
+	users repository.UserStore
+}
+
+// NewUserListService creates a new UserListService.
+func NewUserListService(users repository.UserStore) *UserListService {
+	return &UserListService{users: users}
+}
+
+// List returns a page of users, oldest account first. The limit defaults to
+// 50 and is capped at 100; NextCursor is set when more users follow.
+func (s *UserListService) List(ctx context.Context, q model.UserListQuery) (model.UserListResponse, error) {
+	limit, _ := normalizePage(q.Limit, 0)
+
+	filter := model.UserListFilter{Limit: limit + 1} // one extra row tells us whether another page exists
+	if q.Cursor != "" {
+		c, err := repository.DecodeCursor(q.Cursor)
+		if err != nil {
+			return model.UserListResponse{}, ErrInvalidCursor
+		}
+		filter.After = &model.UserCursor{CreatedAt: c.At, ID: c.ID}
+	}
+
+	users, err := s.users.ListUsers(ctx, filter)
+	if err != nil {
+		return model.UserListResponse{}, err
+	}
+
+	users, nextCursor, hasMore := repository.Paginate(users, limit, func(u model.User) repository.Cursor {
+		return repository.Cursor{At: u.CreatedAt, ID: u.ID}
+	})
+
+	result := make([]model.UserResponse, len(users))
+	for i, u := range users {
+		result[i] = model.UserResponse{ID: u.ID, Email: u.Email, CreatedAt: u.CreatedAt}
+	}
+
+	return model.UserListResponse{Users: result, Limit: limit, NextCursor: nextCursor, HasMore: hasMore}, nil
+}
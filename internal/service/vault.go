@@ -1,117 +1,401 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"log/slog"
+	"net/http"
 	"time"
 
+	"github.com/vaultpass/vaultpass-go/internal/apperr"
+	"github.com/vaultpass/vaultpass-go/internal/crypto"
 	"github.com/vaultpass/vaultpass-go/internal/model"
 	"github.com/vaultpass/vaultpass-go/internal/repository"
 )
 
 var (
-	ErrEntryIDRequired      = errors.New("entry_id is required")
-	ErrEncryptedDataRequired = errors.New("encrypted_data is required")
-	ErrEntryNotFound         = errors.New("vault entry not found")
+	ErrEncryptedDataRequired = apperr.New(http.StatusBadRequest, "encrypted_data_required", "encrypted_data is required")
+	ErrEntryNotFound         = apperr.New(http.StatusNotFound, "entry_not_found", "vault entry not found")
+	ErrInvalidEncoding       = apperr.New(http.StatusBadRequest, "invalid_encoding", "encrypted_data is not valid base64")
+	ErrInvalidDeviceID       = apperr.New(http.StatusBadRequest, "invalid_device_id", "device_id must be at most 64 letters, digits, '-' or '_'")
+	ErrInvalidDeleteReason   = apperr.New(http.StatusBadRequest, "invalid_delete_reason", "delete_reason must be user, superseded or duplicate")
+	ErrInvalidStrategy       = apperr.New(http.StatusBadRequest, "invalid_conflict_strategy", "conflict_strategy must be lww or manual")
+	ErrTooManyEntryIDs       = apperr.New(http.StatusBadRequest, "too_many_entry_ids", "too many entry_ids (max 100)")
+	ErrVersionConflict       = apperr.New(http.StatusConflict, "version_conflict", "vault entry has been modified since the expected version")
+	ErrInvalidAckCursor      = apperr.New(http.StatusBadRequest, "invalid_ack_cursor", "ack_cursor cannot be in the future")
+	ErrInvalidSyncLimit      = apperr.New(http.StatusBadRequest, "invalid_limit", "limit must not be negative")
+	ErrWipeNotConfirmed      = apperr.New(http.StatusBadRequest, "wipe_not_confirmed", `confirm must be "`+model.WipeConfirmation+`"`)
+	ErrImportEmpty           = apperr.New(http.StatusBadRequest, "entries_required", "entries is required")
+	ErrInvalidCreatedAt      = apperr.New(http.StatusBadRequest, "invalid_created_at", "created_at must be after 1990-01-01 and not in the future")
+	ErrEntryTooLarge         = apperr.New(http.StatusRequestEntityTooLarge, "entry_too_large", "encrypted_data exceeds the maximum entry size")
+	ErrEntryLimit            = apperr.New(http.StatusForbidden, "entry_limit", "vault entry limit reached")
+	ErrEntryErased           = apperr.New(http.StatusGone, "entry_erased", "vault entry was erased when deleted and cannot be restored")
+	ErrEntryExists           = apperr.New(http.StatusConflict, "entry_exists", "a vault entry with this entry_id already exists")
+	ErrRekeyUnavailable      = apperr.New(http.StatusNotImplemented, "rekey_unavailable", "rekey tracking is not available")
+	ErrSyncTimeout           = apperr.New(http.StatusRequestTimeout, "sync_timeout", "sync upload took too long to send its entries")
 )
 
-// VaultService handles vault entry business logic.
+// maxBatchGetIDs caps the number of distinct entry IDs fetched per BatchGet.
+const maxBatchGetIDs = 100
+
+// maxDeviceIDLength bounds the client-supplied device identifier.
+const maxDeviceIDLength = 64
+
+// minImportCreatedAt is the earliest creation time an import may carry; anything
+// older is a corrupt or zero-valued timestamp rather than a real entry.
+var minImportCreatedAt = time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// maxCreatedAtSkew tolerates a client clock slightly ahead of the server's.
+const maxCreatedAtSkew = 5 * time.Minute
+
+// VaultService handles vault and vault entry business logic.
+// Entry methods take a vault ID; 0 selects the user's default vault.
 type VaultService struct {
-	repo *repository.VaultRepository
+	repo          repository.VaultStore
+	vaults        repository.CollectionStore
+	users         repository.UserStore // tracks rekeys; nil disables them
+	hardWipe      bool
+	maxEntries    int // live entries per user across all vaults; 0 means no limit
+	maxEntryBytes int // decoded encrypted_data per entry; 0 means no limit
+	syncTxTimeout time.Duration
 }
 
-// NewVaultService creates a new VaultService.
-func NewVaultService(repo *repository.VaultRepository) *VaultService {
-	return &VaultService{repo: repo}
+// VaultOption configures a VaultService.
+type VaultOption func(*VaultService)
+
+// WithHardWipe makes Wipe remove entries outright instead of soft-deleting
+// them. Hard-wiped entries no longer reach other devices through sync.
+func WithHardWipe() VaultOption {
+	return func(s *VaultService) {
+		s.hardWipe = true
+	}
 }
 
-// CreateEntry creates a new vault entry for a user.
-func (s *VaultService) CreateEntry(ctx context.Context, userID int64, req model.VaultEntryRequest) (model.VaultEntryResponse, error) {
-	if req.EntryID == "" {
-		return model.VaultEntryResponse{}, ErrEntryIDRequired
+// WithEntryLimits caps how many live entries a user may keep across all their
+// vaults and how large a single entry's decoded blob may be. Zero leaves
+// either unlimited.
+func WithEntryLimits(maxEntries, maxEntryBytes int) VaultOption {
+	return func(s *VaultService) {
+		s.maxEntries = maxEntries
+		s.maxEntryBytes = maxEntryBytes
+	}
+}
+
+// WithSyncTxTimeout bounds how long a sync upload may keep its transaction
+// open while the client sends the rest of its entries. Past it the
+// transaction is rolled back, releasing its row locks, and the sync fails
+// with ErrSyncTimeout. d <= 0 disables the limit.
+func WithSyncTxTimeout(d time.Duration) VaultOption {
+	return func(s *VaultService) {
+		s.syncTxTimeout = d
+	}
+}
+
+// WithUserStore lets the service record master password changes with Rekey
+// and report the user's rekey version in sync responses.
+func WithUserStore(users repository.UserStore) VaultOption {
+	return func(s *VaultService) {
+		s.users = users
+	}
+}
+
+// NewVaultService creates a new VaultService.
+func NewVaultService(repo repository.VaultStore, vaults repository.CollectionStore, opts ...VaultOption) *VaultService {
+	s := &VaultService{repo: repo, vaults: vaults}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
+}
+
+// CreateEntry creates a new entry in one of the user's vaults. Without a
+// client-supplied entry ID, the server assigns a random UUID and returns it.
+// A client ID already held by a live entry fails with ErrEntryExists; one held
+// by a tombstone or expired entry is replaced at the next version, so the
+// write wins last-write-wins and reaches other devices through sync.
+func (s *VaultService) CreateEntry(ctx context.Context, userID, vaultID int64, req model.VaultEntryRequest) (model.VaultEntryResponse, error) {
 	if req.EncryptedData == "" {
 		return model.VaultEntryResponse{}, ErrEncryptedDataRequired
 	}
 
-	data, err := base64.StdEncoding.DecodeString(req.EncryptedData)
+	if !validDeviceID(req.DeviceID) {
+		return model.VaultEntryResponse{}, ErrInvalidDeviceID
+	}
+
+	data, err := s.decodeEntryData(req.EncryptedData)
+	if err != nil {
+		return model.VaultEntryResponse{}, err
+	}
+
+	vaultID, err = s.resolveVault(ctx, userID, vaultID)
 	if err != nil {
 		return model.VaultEntryResponse{}, err
 	}
 
+	if err := s.checkEntryLimit(ctx, userID, 1); err != nil {
+		return model.VaultEntryResponse{}, err
+	}
+
+	version := 1
+	if req.EntryID == "" {
+		if req.EntryID, err = crypto.NewUUID(); err != nil {
+			return model.VaultEntryResponse{}, err
+		}
+	} else {
+		// Create never overwrites: updates go through UpdateEntry and sync.
+		existing, err := s.repo.GetByEntryID(ctx, userID, vaultID, req.EntryID)
+		switch {
+		case errors.Is(err, repository.ErrEntryNotFound):
+		case err != nil:
+			return model.VaultEntryResponse{}, err
+		case !existing.Deleted && !existing.Expired(time.Now().UTC()):
+			return model.VaultEntryResponse{}, ErrEntryExists
+		default:
+			version = existing.Version + 1
+		}
+	}
+
 	entry := model.VaultEntry{
 		UserID:        userID,
+		VaultID:       vaultID,
 		EntryID:       req.EntryID,
 		EncryptedData: data,
-		Version:       1,
+		Version:       version,
+		Favorite:      req.Favorite,
+		LastDeviceID:  req.DeviceID,
+		ExpiresAt:     req.ExpiresAt,
 	}
 
 	if err := s.repo.Upsert(ctx, &entry); err != nil {
 		return model.VaultEntryResponse{}, err
 	}
 	entry.UpdatedAt = time.Now().UTC()
+	entry.CreatedAt = entry.UpdatedAt
 
-	return model.VaultEntryResponse{
-		EntryID:       entry.EntryID,
-		EncryptedData: base64.StdEncoding.EncodeToString(entry.EncryptedData),
-		Version:       entry.Version,
-		UpdatedAt:     entry.UpdatedAt,
-	}, nil
+	return entriesToResponse([]model.VaultEntry{entry})[0], nil
+}
+
+// ImportEntries adds entries from a backup or another product to one of the
+// user's vaults, keeping each entry's original created_at when given. Every
+// entry is validated before any is written. Entries whose ID is already in the
+// vault, including deleted ones, are skipped rather than overwritten.
+func (s *VaultService) ImportEntries(ctx context.Context, userID, vaultID int64, req model.VaultImport) (model.ImportResponse, error) {
+	if len(req.Entries) == 0 {
+		return model.ImportResponse{}, ErrImportEmpty
+	}
+
+	now := time.Now().UTC()
+	entries := make([]model.VaultEntry, len(req.Entries))
+	for i, in := range req.Entries {
+		if in.EncryptedData == "" {
+			return model.ImportResponse{}, fmt.Errorf("entries[%d]: %w", i, ErrEncryptedDataRequired)
+		}
+		data, err := s.decodeEntryData(in.EncryptedData)
+		if err != nil {
+			return model.ImportResponse{}, fmt.Errorf("entries[%d]: %w", i, err)
+		}
+		createdAt := now
+		if in.CreatedAt != nil {
+			createdAt = in.CreatedAt.UTC()
+			if createdAt.Before(minImportCreatedAt) || createdAt.After(now.Add(maxCreatedAtSkew)) {
+				return model.ImportResponse{}, fmt.Errorf("entries[%d]: %w", i, ErrInvalidCreatedAt)
+			}
+		}
+		entries[i] = model.VaultEntry{
+			UserID:        userID,
+			EntryID:       in.EntryID,
+			EncryptedData: data,
+			Version:       1,
+			Favorite:      in.Favorite,
+			ExpiresAt:     in.ExpiresAt,
+			CreatedAt:     createdAt,
+		}
+	}
+
+	vaultID, err := s.resolveVault(ctx, userID, vaultID)
+	if err != nil {
+		return model.ImportResponse{}, err
+	}
+
+	manifest, err := s.repo.ListManifest(ctx, userID, vaultID)
+	if err != nil {
+		return model.ImportResponse{}, err
+	}
+	existing := make(map[string]bool, len(manifest))
+	for _, m := range manifest {
+		existing[m.EntryID] = true
+	}
+
+	resp := model.ImportResponse{Skipped: []string{}}
+	var added []*model.VaultEntry
+	for i := range entries {
+		e := &entries[i]
+		if e.EntryID == "" {
+			if e.EntryID, err = crypto.NewUUID(); err != nil {
+				return resp, err
+			}
+		} else if existing[e.EntryID] {
+			resp.Skipped = append(resp.Skipped, e.EntryID)
+			continue
+		}
+		existing[e.EntryID] = true
+		added = append(added, e)
+	}
+
+	// The whole import is refused rather than cut short at the limit.
+	if err := s.checkEntryLimit(ctx, userID, len(added)); err != nil {
+		return model.ImportResponse{}, err
+	}
+
+	for _, e := range added {
+		e.VaultID = vaultID
+		if err := s.repo.UpsertWithCreatedAt(ctx, e); err != nil {
+			return resp, err
+		}
+		resp.Imported++
+	}
+
+	return resp, nil
 }
 
 // UpdateEntry updates an existing vault entry.
-func (s *VaultService) UpdateEntry(ctx context.Context, userID int64, entryID string, req model.VaultEntryRequest) (model.VaultEntryResponse, error) {
+func (s *VaultService) UpdateEntry(ctx context.Context, userID, vaultID int64, entryID string, req model.VaultEntryRequest) (model.VaultEntryResponse, error) {
 	if req.EncryptedData == "" {
 		return model.VaultEntryResponse{}, ErrEncryptedDataRequired
 	}
 
-	data, err := base64.StdEncoding.DecodeString(req.EncryptedData)
+	if !validDeviceID(req.DeviceID) {
+		return model.VaultEntryResponse{}, ErrInvalidDeviceID
+	}
+
+	data, err := s.decodeEntryData(req.EncryptedData)
 	if err != nil {
 		return model.VaultEntryResponse{}, err
 	}
 
-	existing, err := s.repo.GetByEntryID(ctx, userID, entryID)
+	vaultID, err = s.resolveVault(ctx, userID, vaultID)
 	if err != nil {
-		if errors.Is(err, repository.ErrEntryNotFound) {
-			return model.VaultEntryResponse{}, ErrEntryNotFound
-		}
 		return model.VaultEntryResponse{}, err
 	}
 
 	entry := model.VaultEntry{
 		UserID:        userID,
+		VaultID:       vaultID,
 		EntryID:       entryID,
 		EncryptedData: data,
-		Version:       existing.Version + 1,
+		Favorite:      req.Favorite,
+		LastDeviceID:  req.DeviceID,
+		ExpiresAt:     req.ExpiresAt,
 	}
 
-	if err := s.repo.Upsert(ctx, &entry); err != nil {
+	if req.ExpectedVersion != nil {
+		// Conditional update: only apply on top of the version the client last saw.
+		entry.Version = *req.ExpectedVersion + 1
+		err = s.repo.UpdateIfVersion(ctx, &entry, *req.ExpectedVersion)
+	} else {
+		var existing *model.VaultEntry
+		existing, err = s.repo.GetByEntryID(ctx, userID, vaultID, entryID)
+		if err == nil && existing.Deleted {
+			// Deleted entries come back through RestoreEntry, not an update.
+			err = repository.ErrEntryNotFound
+		}
+		if err == nil {
+			entry.Version = existing.Version + 1
+			err = s.repo.Upsert(ctx, &entry)
+		}
+	}
+	switch {
+	case errors.Is(err, repository.ErrEntryNotFound):
+		return model.VaultEntryResponse{}, ErrEntryNotFound
+	case errors.Is(err, repository.ErrVersionMismatch):
+		return model.VaultEntryResponse{}, ErrVersionConflict
+	case err != nil:
 		return model.VaultEntryResponse{}, err
 	}
 	entry.UpdatedAt = time.Now().UTC()
 
-	return model.VaultEntryResponse{
-		EntryID:       entry.EntryID,
-		EncryptedData: base64.StdEncoding.EncodeToString(entry.EncryptedData),
-		Version:       entry.Version,
-		UpdatedAt:     entry.UpdatedAt,
-	}, nil
+	return entriesToResponse([]model.VaultEntry{entry})[0], nil
 }
 
 // DeleteEntry soft-deletes a vault entry.
-func (s *VaultService) DeleteEntry(ctx context.Context, userID int64, entryID string) error {
-	err := s.repo.SoftDelete(ctx, userID, entryID)
+func (s *VaultService) DeleteEntry(ctx context.Context, userID, vaultID int64, entryID string) error {
+	return s.DeleteEntryWithReason(ctx, userID, vaultID, entryID, "")
+}
+
+// DeleteEntryWithReason soft-deletes a vault entry, recording an optional
+// reason that is returned with its sync tombstone.
+func (s *VaultService) DeleteEntryWithReason(ctx context.Context, userID, vaultID int64, entryID, reason string) error {
+	if !validDeleteReason(reason) {
+		return ErrInvalidDeleteReason
+	}
+
+	vaultID, err := s.resolveVault(ctx, userID, vaultID)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.SoftDeleteWithReason(ctx, userID, vaultID, entryID, reason)
 	if errors.Is(err, repository.ErrEntryNotFound) {
 		return ErrEntryNotFound
 	}
 	return err
 }
 
-// ListEntries returns all non-deleted vault entries for a user.
-func (s *VaultService) ListEntries(ctx context.Context, userID int64) ([]model.VaultEntryResponse, error) {
-	entries, err := s.repo.ListByUser(ctx, userID)
+// RestoreEntry undeletes a soft-deleted vault entry and returns it.
+func (s *VaultService) RestoreEntry(ctx context.Context, userID, vaultID int64, entryID string) (model.VaultEntryResponse, error) {
+	vaultID, err := s.resolveVault(ctx, userID, vaultID)
+	if err != nil {
+		return model.VaultEntryResponse{}, err
+	}
+
+	if err := s.checkEntryLimit(ctx, userID, 1); err != nil {
+		return model.VaultEntryResponse{}, err
+	}
+
+	err = s.repo.Restore(ctx, userID, vaultID, entryID)
+	if errors.Is(err, repository.ErrEntryNotFound) {
+		return model.VaultEntryResponse{}, ErrEntryNotFound
+	}
+	if errors.Is(err, repository.ErrEntryErased) {
+		return model.VaultEntryResponse{}, ErrEntryErased
+	}
+	if err != nil {
+		return model.VaultEntryResponse{}, err
+	}
+
+	entry, err := s.repo.GetByEntryID(ctx, userID, vaultID, entryID)
+	if err != nil {
+		return model.VaultEntryResponse{}, err
+	}
+	return entriesToResponse([]model.VaultEntry{*entry})[0], nil
+}
+
+// Wipe deletes every entry in all of the user's vaults once the request carries
+// the confirmation phrase. By default entries are soft-deleted with bumped
+// versions so other devices drop them on their next sync.
+func (s *VaultService) Wipe(ctx context.Context, userID int64, req model.WipeRequest) (model.WipeResponse, error) {
+	if req.Confirm != model.WipeConfirmation {
+		return model.WipeResponse{}, ErrWipeNotConfirmed
+	}
+
+	n, err := s.repo.WipeByUser(ctx, userID, s.hardWipe)
+	if err != nil {
+		return model.WipeResponse{}, err
+	}
+	return model.WipeResponse{Wiped: n}, nil
+}
+
+// ListEntries returns all non-deleted entries in one of the user's vaults.
+func (s *VaultService) ListEntries(ctx context.Context, userID, vaultID int64) ([]model.VaultEntryResponse, error) {
+	vaultID, err := s.resolveVault(ctx, userID, vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.repo.ListByUser(ctx, userID, vaultID)
 	if err != nil {
 		return nil, err
 	}
@@ -119,83 +403,422 @@ func (s *VaultService) ListEntries(ctx context.Context, userID int64) ([]model.V
 	return entriesToResponse(entries), nil
 }
 
-// Sync processes incoming client entries and returns server-side changes.
-func (s *VaultService) Sync(ctx context.Context, userID int64, req model.SyncRequest) (model.SyncResponse, error) {
-	syncedAt := time.Now().UTC()
+// BatchGet returns the vault's non-deleted entries among entryIDs, in request order.
+// Duplicate IDs are collapsed; IDs with no live entry are omitted.
+func (s *VaultService) BatchGet(ctx context.Context, userID, vaultID int64, entryIDs []string) ([]model.VaultEntryResponse, error) {
+	seen := make(map[string]bool, len(entryIDs))
+	unique := make([]string, 0, len(entryIDs))
+	for _, id := range entryIDs {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) > maxBatchGetIDs {
+		return nil, ErrTooManyEntryIDs
+	}
 
-	// Process incoming client entries within a transaction.
-	var skipped int
-	if len(req.Entries) > 0 {
-		tx, err := s.repo.BeginTx(ctx)
-		if err != nil {
+	vaultID, err := s.resolveVault(ctx, userID, vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.repo.GetByEntryIDs(ctx, userID, vaultID, unique)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]model.VaultEntry, len(entries))
+	for _, e := range entries {
+		byID[e.EntryID] = e
+	}
+	ordered := make([]model.VaultEntry, 0, len(entries))
+	for _, id := range unique {
+		if e, ok := byID[id]; ok {
+			ordered = append(ordered, e)
+		}
+	}
+
+	s.touchAccessed(ctx, userID, vaultID, ordered)
+	return entriesToResponse(ordered), nil
+}
+
+// GetEntry returns one live entry and records the access. Deleted and expired
+// entries are not found.
+func (s *VaultService) GetEntry(ctx context.Context, userID, vaultID int64, entryID string) (model.VaultEntryResponse, error) {
+	vaultID, err := s.resolveVault(ctx, userID, vaultID)
+	if err != nil {
+		return model.VaultEntryResponse{}, err
+	}
+
+	entry, err := s.repo.GetByEntryID(ctx, userID, vaultID, entryID)
+	if errors.Is(err, repository.ErrEntryNotFound) {
+		return model.VaultEntryResponse{}, ErrEntryNotFound
+	}
+	if err != nil {
+		return model.VaultEntryResponse{}, err
+	}
+	if entry.Deleted || entry.Expired(time.Now().UTC()) {
+		return model.VaultEntryResponse{}, ErrEntryNotFound
+	}
+
+	entries := []model.VaultEntry{*entry}
+	s.touchAccessed(ctx, userID, vaultID, entries)
+	return entriesToResponse(entries)[0], nil
+}
+
+// touchAccessed records now as the last access time of entries and sets it on
+// them. Bulk listing and export don't record access, so a full download doesn't
+// write to every row. A failed write is logged rather than failing the read.
+func (s *VaultService) touchAccessed(ctx context.Context, userID, vaultID int64, entries []model.VaultEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	now := time.Now().UTC()
+	ids := make([]string, len(entries))
+	for i := range entries {
+		ids[i] = entries[i].EntryID
+	}
+	if err := s.repo.TouchAccessed(ctx, userID, vaultID, ids, now); err != nil {
+		slog.Warn("recording vault entry access failed", "user_id", userID, "vault_id", vaultID, "entries", len(ids), "error", err)
+		return
+	}
+	for i := range entries {
+		entries[i].LastAccessedAt = &now
+	}
+}
+
+// Manifest returns the entry IDs, versions and timestamps of every entry in one
+// of the user's vaults, including deleted ones, without their encrypted data.
+func (s *VaultService) Manifest(ctx context.Context, userID, vaultID int64) ([]model.ManifestEntry, error) {
+	vaultID, err := s.resolveVault(ctx, userID, vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := s.repo.ListManifest(ctx, userID, vaultID)
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		manifest = []model.ManifestEntry{}
+	}
+	return manifest, nil
+}
+
+// ListFavorites returns the vault's non-deleted favorite entries.
+func (s *VaultService) ListFavorites(ctx context.Context, userID, vaultID int64) ([]model.VaultEntryResponse, error) {
+	vaultID, err := s.resolveVault(ctx, userID, vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.repo.ListFavorites(ctx, userID, vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	return entriesToResponse(entries), nil
+}
+
+// Trash returns the vault's soft-deleted entries that have not been purged yet,
+// most recently deleted first.
+func (s *VaultService) Trash(ctx context.Context, userID, vaultID int64) ([]model.VaultEntryResponse, error) {
+	vaultID, err := s.resolveVault(ctx, userID, vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.repo.ListDeleted(ctx, userID, vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	return entriesToResponse(entries), nil
+}
+
+// Quota reports the user's live entry count and stored bytes across all
+// vaults, with the configured entry limits.
+func (s *VaultService) Quota(ctx context.Context, userID int64) (model.VaultQuota, error) {
+	usage, err := s.repo.UsageStats(ctx, userID)
+	if err != nil {
+		return model.VaultQuota{}, err
+	}
+	return model.VaultQuota{
+		Entries:       usage.Entries,
+		Bytes:         usage.Bytes,
+		MaxEntries:    s.maxEntries,
+		MaxEntryBytes: s.maxEntryBytes,
+	}, nil
+}
+
+// DuplicateStats reports how many live entries store a blob identical to
+// another entry of the same user, for admin storage insight.
+func (s *VaultService) DuplicateStats(ctx context.Context) (model.DuplicateStats, error) {
+	return s.repo.DuplicateStats(ctx)
+}
+
+// ExportEntries returns a backup of all non-deleted entries in one of the user's vaults.
+func (s *VaultService) ExportEntries(ctx context.Context, userID, vaultID int64) (model.VaultExport, error) {
+	vaultID, err := s.resolveVault(ctx, userID, vaultID)
+	if err != nil {
+		return model.VaultExport{}, err
+	}
+
+	entries, err := s.repo.ListByUser(ctx, userID, vaultID)
+	if err != nil {
+		return model.VaultExport{}, err
+	}
+
+	return model.VaultExport{
+		ExportedAt: time.Now().UTC(),
+		Entries:    entriesToResponse(entries),
+	}, nil
+}
+
+// Sync processes incoming client entries and returns server-side changes for one vault.
+// Each vault syncs independently: entries and last_synced_at apply only to that vault.
+func (s *VaultService) Sync(ctx context.Context, userID, vaultID int64, req model.SyncRequest) (model.SyncResponse, error) {
+	upload, err := s.BeginSync(ctx, userID, vaultID)
+	if err != nil {
+		return model.SyncResponse{}, err
+	}
+	defer upload.Close()
+
+	if err := upload.SetStrategy(req.ConflictStrategy); err != nil {
+		return model.SyncResponse{}, err
+	}
+	for _, re := range req.Entries {
+		if err := upload.Apply(re); err != nil {
 			return model.SyncResponse{}, err
 		}
-		defer tx.Rollback()
+	}
+	return upload.Finish(req)
+}
+
+// PurgeTombstones permanently removes deleted entries that every device of the
+// vault has acknowledged receiving, returning how many were removed. Sync runs
+// it after each acknowledgment.
+func (s *VaultService) PurgeTombstones(ctx context.Context, userID, vaultID int64) (int64, error) {
+	vaultID, err := s.resolveVault(ctx, userID, vaultID)
+	if err != nil {
+		return 0, err
+	}
+	return s.purgeAckedTombstones(ctx, userID, vaultID)
+}
+
+// purgeAckedTombstones removes the vault's tombstones changed at or before the
+// oldest device acknowledgment. Tombstones changed after it are kept so they
+// still reach the device that is furthest behind.
+func (s *VaultService) purgeAckedTombstones(ctx context.Context, userID, vaultID int64) (int64, error) {
+	acked, err := s.repo.MinSyncAck(ctx, userID, vaultID)
+	if err != nil || acked.IsZero() {
+		return 0, err
+	}
+	return s.repo.PurgeTombstones(ctx, userID, vaultID, acked)
+}
+
+// PurgeExpired permanently removes every user's expired entries and returns how many were removed.
+func (s *VaultService) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.repo.PurgeExpired(ctx, time.Now().UTC())
+}
+
+// RunExpiryPurge calls PurgeExpired every interval until ctx is cancelled.
+func (s *VaultService) RunExpiryPurge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-		for _, re := range req.Entries {
-			data, err := base64.StdEncoding.DecodeString(re.EncryptedData)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.PurgeExpired(ctx)
 			if err != nil {
-				slog.Warn("skipping entry: base64 decode failed", "entry_id", re.EntryID, "error", err)
-				skipped++
+				slog.Error("expired entry purge failed", "error", err)
 				continue
 			}
-
-			version := re.Version
-			if version < 1 {
-				version = 1
+			if n > 0 {
+				slog.Info("purged expired vault entries", "count", n)
 			}
+		}
+	}
+}
 
-			entry := model.VaultEntry{
-				UserID:        userID,
-				EntryID:       re.EntryID,
-				EncryptedData: data,
-				Version:       version,
-				Deleted:       re.Deleted,
-			}
+// upsertManual applies entry when it is newer than the stored entry. Otherwise, rather
+// than discarding it as LWW would, a write whose content differs is staged as a conflict.
+// A winning write resolves any conflicts staged earlier for the same entry. The stored
+// entry is read and locked within tx, so concurrent syncs can't both decide to win.
+func (s *VaultService) upsertManual(ctx context.Context, tx repository.Tx, entry *model.VaultEntry) error {
+	existing, err := s.repo.GetByEntryIDForUpdateTx(ctx, tx, entry.UserID, entry.VaultID, entry.EntryID)
+	if err != nil && !errors.Is(err, repository.ErrEntryNotFound) {
+		return err
+	}
 
-			if err := s.repo.UpsertTx(ctx, tx, &entry); err != nil {
-				slog.Warn("skipping entry: upsert failed", "entry_id", re.EntryID, "error", err)
-				skipped++
-				continue
-			}
+	if existing == nil || entry.Version > existing.Version {
+		if err := s.repo.UpsertTx(ctx, tx, entry); err != nil {
+			return err
 		}
+		return s.repo.ClearConflictsTx(ctx, tx, entry.UserID, entry.VaultID, entry.EntryID)
+	}
 
-		if err := tx.Commit(); err != nil {
-			return model.SyncResponse{}, err
+	if bytes.Equal(existing.EncryptedData, entry.EncryptedData) &&
+		existing.Deleted == entry.Deleted && existing.Favorite == entry.Favorite {
+		return nil
+	}
+
+	return s.repo.StageConflictTx(ctx, tx, &model.VaultConflict{
+		UserID:        entry.UserID,
+		VaultID:       entry.VaultID,
+		EntryID:       entry.EntryID,
+		EncryptedData: entry.EncryptedData,
+		Version:       entry.Version,
+		Favorite:      entry.Favorite,
+		Deleted:       entry.Deleted,
+		DeviceID:      entry.LastDeviceID,
+	})
+}
+
+// listConflicts returns the vault's staged conflicts alongside the entries they lost to.
+func (s *VaultService) listConflicts(ctx context.Context, userID, vaultID int64) ([]model.VaultConflictResponse, error) {
+	conflicts, err := s.repo.ListConflicts(ctx, userID, vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]model.VaultConflictResponse, 0, len(conflicts))
+	for _, c := range conflicts {
+		cr := model.VaultConflictResponse{
+			ID:      c.ID,
+			EntryID: c.EntryID,
+			Incoming: model.VaultEntryResponse{
+				EntryID:       c.EntryID,
+				EncryptedData: base64.StdEncoding.EncodeToString(c.EncryptedData),
+				Version:       c.Version,
+				Favorite:      c.Favorite,
+				LastDeviceID:  c.DeviceID,
+				UpdatedAt:     c.CreatedAt,
+				Deleted:       c.Deleted,
+			},
 		}
+
+		existing, err := s.repo.GetByEntryID(ctx, userID, vaultID, c.EntryID)
+		switch {
+		case err == nil:
+			cr.Existing = &entriesToResponse([]model.VaultEntry{*existing})[0]
+		case !errors.Is(err, repository.ErrEntryNotFound):
+			return nil, err
+		}
+
+		result = append(result, cr)
 	}
+	return result, nil
+}
 
-	// Get server-side changes to send back to the client.
-	var serverEntries []model.VaultEntry
-	var err error
+// blobEncodings are the base64 variants accepted for encrypted_data, standard
+// first. The alphabets differ only in "+/" versus "-_", and padding is either
+// required or forbidden, so at most one distinct decoding exists for any input.
+var blobEncodings = []*base64.Encoding{
+	base64.StdEncoding,
+	base64.RawStdEncoding,
+	base64.URLEncoding,
+	base64.RawURLEncoding,
+}
 
-	if req.LastSyncedAt == nil {
-		// First sync: return all entries including deleted.
-		serverEntries, err = s.repo.GetChangedSince(ctx, userID, time.Time{})
-	} else {
-		serverEntries, err = s.repo.GetChangedSince(ctx, userID, *req.LastSyncedAt)
+// decodeEncryptedData decodes a base64 blob in the standard or URL-safe
+// alphabet, padded or not. Blobs are always returned in standard base64; the
+// decoded bytes are the same either way, so nothing about the client's choice
+// needs to be stored. The decoder's error is replaced with ErrInvalidEncoding
+// so no part of the payload can leak into logs or responses.
+func decodeEncryptedData(s string) ([]byte, error) {
+	for _, enc := range blobEncodings {
+		if data, err := enc.DecodeString(s); err == nil {
+			return data, nil
+		}
 	}
+	return nil, ErrInvalidEncoding
+}
+
+// decodeEntryData decodes a client blob and checks it against the entry size limit.
+func (s *VaultService) decodeEntryData(encoded string) ([]byte, error) {
+	data, err := decodeEncryptedData(encoded)
 	if err != nil {
-		return model.SyncResponse{}, err
+		return nil, err
 	}
+	if s.maxEntryBytes > 0 && len(data) > s.maxEntryBytes {
+		return nil, ErrEntryTooLarge
+	}
+	return data, nil
+}
 
-	return model.SyncResponse{
-		SyncedAt: syncedAt,
-		Entries:  entriesToResponse(serverEntries),
-		Skipped:  skipped,
-	}, nil
+// checkEntryLimit returns ErrEntryLimit if adding n entries would take the
+// user past the entry limit. The count is read outside any write transaction,
+// so concurrent requests that each fit can together overshoot the limit
+// slightly; it is a quota, not an invariant.
+func (s *VaultService) checkEntryLimit(ctx context.Context, userID int64, n int) error {
+	if s.maxEntries <= 0 || n == 0 {
+		return nil
+	}
+	usage, err := s.repo.UsageStats(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if usage.Entries+int64(n) > int64(s.maxEntries) {
+		return ErrEntryLimit
+	}
+	return nil
+}
+
+// validDeviceID reports whether id is empty or a short token of [A-Za-z0-9-_].
+func validDeviceID(id string) bool {
+	if len(id) > maxDeviceIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validDeleteReason reports whether reason is empty or one of the model's delete reasons.
+func validDeleteReason(reason string) bool {
+	switch reason {
+	case "", model.DeleteReasonUser, model.DeleteReasonSuperseded, model.DeleteReasonDuplicate:
+		return true
+	}
+	return false
 }
 
 // entriesToResponse converts a slice of VaultEntry to a slice of VaultEntryResponse.
+// Expired entries are reported as deleted.
 func entriesToResponse(entries []model.VaultEntry) []model.VaultEntryResponse {
+	now := time.Now().UTC()
 	result := make([]model.VaultEntryResponse, len(entries))
 	for i, e := range entries {
+		var createdAt *time.Time
+		if !e.CreatedAt.IsZero() {
+			createdAt = &e.CreatedAt
+		}
 		result[i] = model.VaultEntryResponse{
 			EntryID:       e.EntryID,
 			EncryptedData: base64.StdEncoding.EncodeToString(e.EncryptedData),
 			Version:       e.Version,
+			Favorite:      e.Favorite,
+			LastDeviceID:  e.LastDeviceID,
+			CreatedAt:     createdAt,
 			UpdatedAt:     e.UpdatedAt,
-			Deleted:       e.Deleted,
+			Deleted:       e.Deleted || e.Expired(now),
+			ExpiresAt:     e.ExpiresAt,
+
+			LastAccessedAt: e.LastAccessedAt,
+		}
+		if e.Deleted {
+			result[i].DeleteReason = e.DeleteReason
 		}
 	}
 	return result
@@ -1,35 +1,104 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/vaultpass/vaultpass-go/internal/model"
 	"github.com/vaultpass/vaultpass-go/internal/repository"
 )
 
 func newTestVaultService() *VaultService {
-	return NewVaultService(repository.NewVaultRepository(nil))
+	return NewVaultService(repository.NewVaultRepository(nil), repository.NewMemoryCollectionRepository())
 }
 
-func TestCreateEntry_EmptyEntryID(t *testing.T) {
-	svc := newTestVaultService()
+func TestCreateEntry_ServerGeneratesEntryID(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	first, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EncryptedData: "dGVzdA=="})
+	if err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+	second, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EncryptedData: "dGVzdA=="})
+	if err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+
+	if len(first.EntryID) != 36 || strings.Count(first.EntryID, "-") != 4 {
+		t.Errorf("expected a 36-character UUID, got %q", first.EntryID)
+	}
+	if first.EntryID == second.EntryID {
+		t.Errorf("expected unique entry IDs, got %q twice", first.EntryID)
+	}
+	if got, err := svc.BatchGet(ctx, 1, 0, []string{first.EntryID}); err != nil || len(got) != 1 {
+		t.Errorf("generated entry not stored under its ID: %v %v", got, err)
+	}
+}
+
+func TestCreateEntry_ClientEntryIDHonored(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
 
-	_, err := svc.CreateEntry(context.Background(), 1, model.VaultEntryRequest{
-		EntryID:       "",
+	resp, err := svc.CreateEntry(context.Background(), 1, 0, model.VaultEntryRequest{
+		EntryID:       "client-chosen-id",
 		EncryptedData: "dGVzdA==",
 	})
+	if err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+	if resp.EntryID != "client-chosen-id" {
+		t.Errorf("expected the client entry ID, got %q", resp.EntryID)
+	}
+}
 
-	if err != ErrEntryIDRequired {
-		t.Errorf("expected ErrEntryIDRequired, got %v", err)
+func TestCreateEntry_ReplacesDeletedAndExpiredIDs(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+	past := time.Now().Add(-time.Minute)
+
+	for _, req := range []model.VaultEntryRequest{
+		{EntryID: "gone", EncryptedData: b64("old")},
+		{EntryID: "expired", EncryptedData: b64("old"), ExpiresAt: &past},
+	} {
+		if _, err := svc.CreateEntry(ctx, 1, 0, req); err != nil {
+			t.Fatalf("CreateEntry(%q) unexpected error: %v", req.EntryID, err)
+		}
+	}
+	if err := svc.DeleteEntry(ctx, 1, 0, "gone"); err != nil {
+		t.Fatalf("DeleteEntry() unexpected error: %v", err)
+	}
+
+	for _, id := range []string{"gone", "expired"} {
+		created, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: id, EncryptedData: b64("new")})
+		if err != nil {
+			t.Fatalf("CreateEntry(%q) over a dead entry: unexpected error: %v", id, err)
+		}
+		got, err := svc.GetEntry(ctx, 1, 0, id)
+		if err != nil {
+			t.Fatalf("GetEntry(%q) unexpected error: %v", id, err)
+		}
+		if got.EncryptedData != b64("new") || got.Deleted || got.Version != created.Version {
+			t.Errorf("GetEntry(%q) = %+v; want the new blob at version %d", id, got, created.Version)
+		}
+	}
+
+	if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: "gone", EncryptedData: b64("again")}); !errors.Is(err, ErrEntryExists) {
+		t.Errorf("expected ErrEntryExists once the ID is live again, got %v", err)
 	}
 }
 
 func TestCreateEntry_EmptyEncryptedData(t *testing.T) {
 	svc := newTestVaultService()
 
-	_, err := svc.CreateEntry(context.Background(), 1, model.VaultEntryRequest{
+	_, err := svc.CreateEntry(context.Background(), 1, 0, model.VaultEntryRequest{
 		EntryID:       "entry-1",
 		EncryptedData: "",
 	})
@@ -42,7 +111,7 @@ func TestCreateEntry_EmptyEncryptedData(t *testing.T) {
 func TestUpdateEntry_EmptyEncryptedData(t *testing.T) {
 	svc := newTestVaultService()
 
-	_, err := svc.UpdateEntry(context.Background(), 1, "entry-1", model.VaultEntryRequest{
+	_, err := svc.UpdateEntry(context.Background(), 1, 0, "entry-1", model.VaultEntryRequest{
 		EncryptedData: "",
 	})
 
@@ -95,3 +164,1335 @@ func TestEntriesToResponse_Base64Encoding(t *testing.T) {
 		t.Errorf("expected version 3, got %d", result[0].Version)
 	}
 }
+
+const secretPayload = "c2VjcmV0LXBheWxvYWQ***not-base64***"
+
+func TestCreateEntry_InvalidBase64DoesNotEchoPayload(t *testing.T) {
+	svc := newTestVaultService()
+
+	_, err := svc.CreateEntry(context.Background(), 1, 0, model.VaultEntryRequest{
+		EntryID:       "entry-1",
+		EncryptedData: secretPayload,
+	})
+
+	if !errors.Is(err, ErrInvalidEncoding) {
+		t.Fatalf("expected ErrInvalidEncoding, got %v", err)
+	}
+	if strings.Contains(err.Error(), "c2VjcmV0") {
+		t.Errorf("error message leaks payload: %q", err.Error())
+	}
+}
+
+func TestDecodeEncryptedData_AcceptsBase64Variants(t *testing.T) {
+	// 0xfb 0xff 0xbf encodes to "+/+/" in the standard alphabet and "-_-_" in the URL-safe one.
+	want := []byte{0xfb, 0xff, 0xbf, 0x01}
+
+	for _, in := range []string{
+		"+/+/AQ==", // standard, padded
+		"+/+/AQ",   // standard, unpadded
+		"-_-_AQ==", // URL-safe, padded
+		"-_-_AQ",   // URL-safe, unpadded
+	} {
+		got, err := decodeEncryptedData(in)
+		if err != nil {
+			t.Errorf("decodeEncryptedData(%q) unexpected error: %v", in, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("decodeEncryptedData(%q) = %x, want %x", in, got, want)
+		}
+	}
+
+	for _, in := range []string{
+		"+/-_AQ==", // mixed alphabets
+		"+/+/AQ=",  // short padding
+		"+/+/A",    // truncated
+		"+/+/AQ==AQ",
+		"not base64!",
+	} {
+		if _, err := decodeEncryptedData(in); !errors.Is(err, ErrInvalidEncoding) {
+			t.Errorf("decodeEncryptedData(%q): expected ErrInvalidEncoding, got %v", in, err)
+		}
+	}
+}
+
+func TestCreateEntry_URLSafeBlobRoundTripsAsStandard(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+
+	resp, err := svc.CreateEntry(context.Background(), 1, 0, model.VaultEntryRequest{EntryID: "e1", EncryptedData: "-_-_AQ"})
+	if err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+	if resp.EncryptedData != "+/+/AQ==" {
+		t.Errorf("expected the blob back in standard base64, got %q", resp.EncryptedData)
+	}
+}
+
+func TestLogSkippedEntry_RedactsPayload(t *testing.T) {
+	var buf bytes.Buffer
+	original := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer slog.SetDefault(original)
+
+	re := model.VaultEntryRequest{EntryID: "entry-1", EncryptedData: secretPayload, Version: 2}
+	_, err := decodeEncryptedData(re.EncryptedData)
+	logSkippedEntry(re, "base64 decode failed", err)
+
+	out := buf.String()
+	if strings.Contains(out, "c2VjcmV0") || strings.Contains(out, "not-base64") {
+		t.Errorf("log line leaks payload: %q", out)
+	}
+	if !strings.Contains(out, "entry_id=entry-1") {
+		t.Errorf("log line missing entry_id: %q", out)
+	}
+	if !strings.Contains(out, "encoded_size=") {
+		t.Errorf("log line missing encoded_size: %q", out)
+	}
+}
+
+func b64(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+
+func TestVaultService_CreateAndList(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	created, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: "entry-1", EncryptedData: b64("blob-1")})
+	if err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+	if created.Version != 1 {
+		t.Errorf("expected version 1, got %d", created.Version)
+	}
+	if _, err := svc.CreateEntry(ctx, 2, 0, model.VaultEntryRequest{EntryID: "entry-2", EncryptedData: b64("other-user")}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+
+	entries, err := svc.ListEntries(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("ListEntries() unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].EntryID != "entry-1" {
+		t.Fatalf("expected only user 1's entry, got %+v", entries)
+	}
+}
+
+func TestVaultService_UpdateAndDelete(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: "entry-1", EncryptedData: b64("v1")}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+	updated, err := svc.UpdateEntry(ctx, 1, 0, "entry-1", model.VaultEntryRequest{EncryptedData: b64("v2")})
+	if err != nil {
+		t.Fatalf("UpdateEntry() unexpected error: %v", err)
+	}
+	if updated.Version != 2 {
+		t.Errorf("expected version 2 after update, got %d", updated.Version)
+	}
+
+	if _, err := svc.UpdateEntry(ctx, 1, 0, "missing", model.VaultEntryRequest{EncryptedData: b64("x")}); err != ErrEntryNotFound {
+		t.Errorf("expected ErrEntryNotFound, got %v", err)
+	}
+
+	if err := svc.DeleteEntry(ctx, 1, 0, "entry-1"); err != nil {
+		t.Fatalf("DeleteEntry() unexpected error: %v", err)
+	}
+	entries, _ := svc.ListEntries(ctx, 1, 0)
+	if len(entries) != 0 {
+		t.Errorf("expected deleted entry to be hidden from list, got %d entries", len(entries))
+	}
+}
+
+func TestVaultService_SyncLastWriteWins(t *testing.T) {
+	store := repository.NewMemoryVaultRepository()
+	svc := NewVaultService(store, repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: "entry-1", EncryptedData: b64("server-v1")}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+
+	resp, err := svc.Sync(ctx, 1, 0, model.SyncRequest{
+		Entries: []model.VaultEntryRequest{
+			{EntryID: "entry-1", EncryptedData: b64("client-v3"), Version: 3},
+			{EntryID: "entry-2", EncryptedData: b64("client-new"), Version: 1},
+			{EntryID: "entry-3", EncryptedData: "%%%", Version: 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	if len(resp.Skipped) != 1 || resp.Skipped[0] != (model.SkippedEntry{EntryID: "entry-3", Reason: "invalid_encoding"}) {
+		t.Errorf("expected entry-3 skipped as invalid_encoding, got %+v", resp.Skipped)
+	}
+	if len(resp.Entries) != 2 {
+		t.Fatalf("expected 2 entries on first sync, got %d", len(resp.Entries))
+	}
+
+	// A stale write from another device must not clobber the newer version.
+	if _, err := svc.Sync(ctx, 1, 0, model.SyncRequest{
+		Entries: []model.VaultEntryRequest{{EntryID: "entry-1", EncryptedData: b64("stale-v2"), Version: 2}},
+	}); err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+
+	got, _ := store.GetByEntryID(ctx, 1, 1, "entry-1") // user 1's default vault is the first one created
+	if string(got.EncryptedData) != "client-v3" || got.Version != 3 {
+		t.Errorf("expected LWW to keep client-v3 at version 3, got %q v%d", got.EncryptedData, got.Version)
+	}
+}
+
+func TestVaultService_SyncReportsOversizedEntries(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository(), WithEntryLimits(0, 8))
+	ctx := context.Background()
+
+	resp, err := svc.Sync(ctx, 1, 0, model.SyncRequest{
+		Entries: []model.VaultEntryRequest{
+			{EntryID: "small", EncryptedData: b64("12345678"), Version: 1},
+			{EntryID: "big-1", EncryptedData: b64("123456789"), Version: 1},
+			{EntryID: "bad-data", EncryptedData: "%%%", Version: 1},
+			{EntryID: "big-2", EncryptedData: b64(strings.Repeat("x", 64)), Version: 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+
+	want := []model.SkippedEntry{
+		{EntryID: "big-1", Reason: "entry_too_large"},
+		{EntryID: "bad-data", Reason: "invalid_encoding"},
+		{EntryID: "big-2", Reason: "entry_too_large"},
+	}
+	if !slices.Equal(resp.Skipped, want) {
+		t.Errorf("Skipped = %+v, want %+v", resp.Skipped, want)
+	}
+	if len(resp.Entries) != 1 || resp.Entries[0].EntryID != "small" {
+		t.Errorf("expected only the entry within the limit to be stored, got %+v", resp.Entries)
+	}
+}
+
+func TestVaultService_SyncDeltaSinceLastSync(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	first, err := svc.Sync(ctx, 1, 0, model.SyncRequest{
+		Entries: []model.VaultEntryRequest{{EntryID: "entry-1", EncryptedData: b64("a"), Version: 1}},
+	})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+
+	time.Sleep(time.Millisecond)
+	if err := svc.DeleteEntry(ctx, 1, 0, "entry-1"); err != nil {
+		t.Fatalf("DeleteEntry() unexpected error: %v", err)
+	}
+
+	second, err := svc.Sync(ctx, 1, 0, model.SyncRequest{LastSyncedAt: &first.SyncedAt})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	if len(second.Entries) != 1 || !second.Entries[0].Deleted {
+		t.Fatalf("expected the deletion tombstone in the delta, got %+v", second.Entries)
+	}
+}
+
+func TestVaultService_SyncPagesWithLimitAndCursor(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	var uploads []model.VaultEntryRequest
+	for _, id := range []string{"e1", "e2", "e3", "e4", "e5"} {
+		uploads = append(uploads, model.VaultEntryRequest{EntryID: id, EncryptedData: b64(id), Version: 1})
+	}
+	if _, err := svc.Sync(ctx, 1, 0, model.SyncRequest{Entries: uploads}); err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+
+	seen := map[string]bool{}
+	var cursor string
+	var pages []int
+	for range 5 {
+		resp, err := svc.Sync(ctx, 1, 0, model.SyncRequest{Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("Sync() unexpected error: %v", err)
+		}
+		pages = append(pages, len(resp.Entries))
+		for _, e := range resp.Entries {
+			if seen[e.EntryID] {
+				t.Errorf("entry %s returned twice", e.EntryID)
+			}
+			seen[e.EntryID] = true
+		}
+		if resp.HasMore != (resp.NextCursor != "") {
+			t.Fatalf("expected next_cursor exactly when has_more, got %v %q", resp.HasMore, resp.NextCursor)
+		}
+		if !resp.HasMore {
+			break
+		}
+		cursor = resp.NextCursor
+	}
+
+	if !slices.Equal(pages, []int{2, 2, 1}) {
+		t.Errorf("expected pages of 2, 2 and 1 entries, got %v", pages)
+	}
+	if len(seen) != 5 {
+		t.Errorf("expected every entry across the pages, got %v", seen)
+	}
+}
+
+func TestVaultService_SyncLimitDefaultsAndCap(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	uploads := make([]model.VaultEntryRequest, MaxSyncPageSize+1)
+	for i := range uploads {
+		id := fmt.Sprintf("e%d", i)
+		uploads[i] = model.VaultEntryRequest{EntryID: id, EncryptedData: b64(id), Version: 1}
+	}
+	if _, err := svc.Sync(ctx, 1, 0, model.SyncRequest{Entries: uploads}); err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+
+	// Clients that don't page get every change.
+	all, err := svc.Sync(ctx, 1, 0, model.SyncRequest{})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	if len(all.Entries) != len(uploads) || all.HasMore {
+		t.Errorf("expected all %d entries without a limit, got %d, has_more=%v", len(uploads), len(all.Entries), all.HasMore)
+	}
+
+	capped, err := svc.Sync(ctx, 1, 0, model.SyncRequest{Limit: MaxSyncPageSize * 2})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	if len(capped.Entries) != MaxSyncPageSize || !capped.HasMore {
+		t.Errorf("expected a limit past the maximum capped at %d, got %d, has_more=%v", MaxSyncPageSize, len(capped.Entries), capped.HasMore)
+	}
+}
+
+func TestVaultService_SyncRejectsBadLimitAndCursor(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	if _, err := svc.Sync(ctx, 1, 0, model.SyncRequest{Limit: -1}); !errors.Is(err, ErrInvalidSyncLimit) {
+		t.Errorf("expected ErrInvalidSyncLimit, got %v", err)
+	}
+	// The last is a valid cursor from another list, keyed by a numeric ID.
+	idCursor := repository.Cursor{At: time.Now(), ID: 7}.Encode()
+	for _, c := range []string{"not base64!", "bm8tY29sb24", "eDpl", idCursor} {
+		if _, err := svc.Sync(ctx, 1, 0, model.SyncRequest{Cursor: c}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("cursor %q: expected ErrInvalidCursor, got %v", c, err)
+		}
+	}
+}
+
+func TestVaultService_RekeyVersionReachesSync(t *testing.T) {
+	users := repository.NewMemoryUserRepository()
+	user := &model.User{Email: "user@example.com", AuthHash: "hash"}
+	if err := users.Create(context.Background(), user); err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository(), WithUserStore(users))
+	ctx := context.Background()
+
+	before, err := svc.Sync(ctx, user.ID, 0, model.SyncRequest{})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	if before.RekeyVersion != 0 {
+		t.Fatalf("expected rekey version 0 before any rekey, got %d", before.RekeyVersion)
+	}
+
+	for want := int64(1); want <= 2; want++ {
+		resp, err := svc.Rekey(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("Rekey() unexpected error: %v", err)
+		}
+		if resp.RekeyVersion != want {
+			t.Errorf("Rekey() = %d, want %d", resp.RekeyVersion, want)
+		}
+	}
+
+	after, err := svc.Sync(ctx, user.ID, 0, model.SyncRequest{LastSyncedAt: &before.SyncedAt})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	if after.RekeyVersion != 2 {
+		t.Errorf("expected later syncs to report rekey version 2, got %d", after.RekeyVersion)
+	}
+}
+
+func TestVaultService_RekeyUnavailableWithoutUserStore(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	if _, err := svc.Rekey(context.Background(), 1); !errors.Is(err, ErrRekeyUnavailable) {
+		t.Errorf("expected ErrRekeyUnavailable, got %v", err)
+	}
+}
+
+func TestVaultService_SyncDeleteReasonRoundTrip(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	for _, id := range []string{"deleted-here", "restored"} {
+		if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: id, EncryptedData: b64(id)}); err != nil {
+			t.Fatalf("CreateEntry() unexpected error: %v", err)
+		}
+	}
+	if err := svc.DeleteEntryWithReason(ctx, 1, 0, "deleted-here", model.DeleteReasonDuplicate); err != nil {
+		t.Fatalf("DeleteEntryWithReason() unexpected error: %v", err)
+	}
+	if err := svc.DeleteEntryWithReason(ctx, 1, 0, "restored", model.DeleteReasonUser); err != nil {
+		t.Fatalf("DeleteEntryWithReason() unexpected error: %v", err)
+	}
+	if _, err := svc.RestoreEntry(ctx, 1, 0, "restored"); err != nil {
+		t.Fatalf("RestoreEntry() unexpected error: %v", err)
+	}
+	if err := svc.DeleteEntryWithReason(ctx, 1, 0, "restored", "spam"); !errors.Is(err, ErrInvalidDeleteReason) {
+		t.Errorf("expected ErrInvalidDeleteReason, got %v", err)
+	}
+
+	resp, err := svc.Sync(ctx, 1, 0, model.SyncRequest{
+		Entries: []model.VaultEntryRequest{
+			{EntryID: "deleted-there", EncryptedData: b64("x"), Version: 2, Deleted: true, DeleteReason: model.DeleteReasonSuperseded},
+			{EntryID: "live", EncryptedData: b64("x"), Version: 1, DeleteReason: model.DeleteReasonUser},
+			{EntryID: "bad-reason", EncryptedData: b64("x"), Version: 1, Deleted: true, DeleteReason: "spam"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	if len(resp.Skipped) != 1 || resp.Skipped[0] != (model.SkippedEntry{EntryID: "bad-reason", Reason: "invalid_delete_reason"}) {
+		t.Errorf("expected the invalid reason to be skipped, got %+v", resp.Skipped)
+	}
+
+	want := map[string]string{
+		"deleted-here":  model.DeleteReasonDuplicate,
+		"deleted-there": model.DeleteReasonSuperseded,
+		"restored":      "",
+		"live":          "",
+	}
+	if len(resp.Entries) != len(want) {
+		t.Fatalf("expected %d entries, got %+v", len(want), resp.Entries)
+	}
+	for _, e := range resp.Entries {
+		if e.DeleteReason != want[e.EntryID] {
+			t.Errorf("%s: expected delete_reason %q, got %q", e.EntryID, want[e.EntryID], e.DeleteReason)
+		}
+	}
+}
+
+func TestVaultService_ToggleFavoriteOnUpdate(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: "entry-1", EncryptedData: b64("v1")}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+
+	starred, err := svc.UpdateEntry(ctx, 1, 0, "entry-1", model.VaultEntryRequest{EncryptedData: b64("v1"), Favorite: true})
+	if err != nil {
+		t.Fatalf("UpdateEntry() unexpected error: %v", err)
+	}
+	if !starred.Favorite || starred.Version != 2 {
+		t.Errorf("expected favorite at version 2, got %+v", starred)
+	}
+
+	unstarred, err := svc.UpdateEntry(ctx, 1, 0, "entry-1", model.VaultEntryRequest{EncryptedData: b64("v1")})
+	if err != nil {
+		t.Fatalf("UpdateEntry() unexpected error: %v", err)
+	}
+	if unstarred.Favorite {
+		t.Error("expected favorite to be cleared by update")
+	}
+
+	// The toggle must reach other devices through sync.
+	resp, err := svc.Sync(ctx, 1, 0, model.SyncRequest{})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	if len(resp.Entries) != 1 || resp.Entries[0].Favorite || resp.Entries[0].Version != 3 {
+		t.Errorf("unexpected synced entry: %+v", resp.Entries)
+	}
+}
+
+func TestVaultService_ListFavorites(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	reqs := []model.VaultEntryRequest{
+		{EntryID: "starred", EncryptedData: b64("a"), Favorite: true},
+		{EntryID: "plain", EncryptedData: b64("b")},
+		{EntryID: "starred-deleted", EncryptedData: b64("c"), Favorite: true},
+	}
+	for _, req := range reqs {
+		if _, err := svc.CreateEntry(ctx, 1, 0, req); err != nil {
+			t.Fatalf("CreateEntry() unexpected error: %v", err)
+		}
+	}
+	if _, err := svc.CreateEntry(ctx, 2, 0, model.VaultEntryRequest{EntryID: "other", EncryptedData: b64("d"), Favorite: true}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+	if err := svc.DeleteEntry(ctx, 1, 0, "starred-deleted"); err != nil {
+		t.Fatalf("DeleteEntry() unexpected error: %v", err)
+	}
+
+	favorites, err := svc.ListFavorites(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("ListFavorites() unexpected error: %v", err)
+	}
+	if len(favorites) != 1 || favorites[0].EntryID != "starred" || !favorites[0].Favorite {
+		t.Errorf("expected only the live starred entry, got %+v", favorites)
+	}
+}
+
+func TestVaultService_TrashAndRestore(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	for _, id := range []string{"active", "first-deleted", "second-deleted"} {
+		if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: id, EncryptedData: b64(id)}); err != nil {
+			t.Fatalf("CreateEntry() unexpected error: %v", err)
+		}
+	}
+	for _, id := range []string{"first-deleted", "second-deleted"} {
+		if err := svc.DeleteEntry(ctx, 1, 0, id); err != nil {
+			t.Fatalf("DeleteEntry() unexpected error: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	trash, err := svc.Trash(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("Trash() unexpected error: %v", err)
+	}
+	if len(trash) != 2 || trash[0].EntryID != "second-deleted" || trash[1].EntryID != "first-deleted" {
+		t.Fatalf("expected deleted entries, most recent first, got %+v", trash)
+	}
+	for _, e := range trash {
+		if !e.Deleted {
+			t.Errorf("trash entry %s should be marked deleted", e.EntryID)
+		}
+	}
+
+	restored, err := svc.RestoreEntry(ctx, 1, 0, "first-deleted")
+	if err != nil {
+		t.Fatalf("RestoreEntry() unexpected error: %v", err)
+	}
+	if restored.Deleted || restored.Version != 3 {
+		t.Errorf("expected live entry at version 3, got %+v", restored)
+	}
+
+	if _, err := svc.RestoreEntry(ctx, 1, 0, "active"); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("restoring a live entry: expected ErrEntryNotFound, got %v", err)
+	}
+
+	trash, _ = svc.Trash(ctx, 1, 0)
+	if len(trash) != 1 || trash[0].EntryID != "second-deleted" {
+		t.Errorf("expected only second-deleted left in trash, got %+v", trash)
+	}
+	entries, _ := svc.ListEntries(ctx, 1, 0)
+	if len(entries) != 2 {
+		t.Errorf("expected restored entry back in the list, got %+v", entries)
+	}
+}
+
+func TestVaultService_ImportPreservesCreatedAt(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: "existing", EncryptedData: b64("mine")}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+
+	created := time.Date(2014, 3, 9, 8, 30, 0, 0, time.UTC)
+	before := time.Now().UTC()
+	resp, err := svc.ImportEntries(ctx, 1, 0, model.VaultImport{Entries: []model.ImportEntry{
+		{EntryID: "old", EncryptedData: b64("a"), CreatedAt: &created},
+		{EncryptedData: b64("b")},
+		{EntryID: "existing", EncryptedData: b64("theirs")},
+	}})
+	if err != nil {
+		t.Fatalf("ImportEntries() unexpected error: %v", err)
+	}
+	if resp.Imported != 2 || len(resp.Skipped) != 1 || resp.Skipped[0] != "existing" {
+		t.Fatalf("expected 2 imported and existing skipped, got %+v", resp)
+	}
+
+	entries, _ := svc.ListEntries(ctx, 1, 0)
+	byID := make(map[string]model.VaultEntryResponse, len(entries))
+	for _, e := range entries {
+		byID[e.EntryID] = e
+	}
+	old := byID["old"]
+	if old.CreatedAt == nil || !old.CreatedAt.Equal(created) {
+		t.Errorf("expected created_at %v preserved, got %v", created, old.CreatedAt)
+	}
+	if old.UpdatedAt.Before(before) {
+		t.Errorf("expected updated_at to be the import time, got %v", old.UpdatedAt)
+	}
+	if byID["existing"].EncryptedData != b64("mine") {
+		t.Error("import must not overwrite an existing entry")
+	}
+	if len(entries) != 3 {
+		t.Errorf("expected the entry without an ID to get one, got %d entries", len(entries))
+	}
+}
+
+func TestVaultService_ImportRejectsInvalidCreatedAt(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	future := time.Now().Add(24 * time.Hour)
+	ancient := time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	for name, ts := range map[string]time.Time{"future": future, "ancient": ancient} {
+		_, err := svc.ImportEntries(ctx, 1, 0, model.VaultImport{Entries: []model.ImportEntry{
+			{EntryID: "ok", EncryptedData: b64("a")},
+			{EntryID: name, EncryptedData: b64("b"), CreatedAt: &ts},
+		}})
+		if !errors.Is(err, ErrInvalidCreatedAt) {
+			t.Errorf("%s: expected ErrInvalidCreatedAt, got %v", name, err)
+		}
+	}
+
+	// Validation happens before any write, so the valid entry wasn't imported either.
+	if entries, _ := svc.ListEntries(ctx, 1, 0); len(entries) != 0 {
+		t.Errorf("expected nothing imported, got %+v", entries)
+	}
+	if _, err := svc.ImportEntries(ctx, 1, 0, model.VaultImport{}); !errors.Is(err, ErrImportEmpty) {
+		t.Errorf("expected ErrImportEmpty, got %v", err)
+	}
+}
+
+func TestVaultService_LastDeviceFollowsWinningWrite(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	// Two devices upload concurrently; the phone's write has the higher version.
+	_, err := svc.Sync(ctx, 1, 0, model.SyncRequest{Entries: []model.VaultEntryRequest{
+		{EntryID: "entry-1", EncryptedData: b64("phone"), Version: 3, DeviceID: "phone"},
+	}})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	resp, err := svc.Sync(ctx, 1, 0, model.SyncRequest{Entries: []model.VaultEntryRequest{
+		{EntryID: "entry-1", EncryptedData: b64("laptop"), Version: 2, DeviceID: "laptop"},
+	}})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+
+	if len(resp.Entries) != 1 || resp.Entries[0].LastDeviceID != "phone" {
+		t.Fatalf("expected the highest-version write's device, got %+v", resp.Entries)
+	}
+
+	updated, err := svc.UpdateEntry(ctx, 1, 0, "entry-1", model.VaultEntryRequest{EncryptedData: b64("v4"), DeviceID: "laptop"})
+	if err != nil {
+		t.Fatalf("UpdateEntry() unexpected error: %v", err)
+	}
+	if updated.LastDeviceID != "laptop" {
+		t.Errorf("expected laptop after winning update, got %q", updated.LastDeviceID)
+	}
+}
+
+func TestVaultService_InvalidDeviceID(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	for _, id := range []string{"has space", "semi;colon", strings.Repeat("a", 65)} {
+		_, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: "e", EncryptedData: b64("x"), DeviceID: id})
+		if !errors.Is(err, ErrInvalidDeviceID) {
+			t.Errorf("device_id %q: expected ErrInvalidDeviceID, got %v", id, err)
+		}
+	}
+
+	resp, err := svc.Sync(ctx, 1, 0, model.SyncRequest{Entries: []model.VaultEntryRequest{
+		{EntryID: "e", EncryptedData: b64("x"), Version: 1, DeviceID: "bad id"},
+	}})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	if len(resp.Skipped) != 1 || resp.Skipped[0].Reason != "invalid_device_id" || len(resp.Entries) != 0 {
+		t.Errorf("expected entry with invalid device_id to be skipped, got %+v", resp)
+	}
+}
+
+// concurrentEdit seeds entry-1 at version 2, then uploads a laptop edit that was
+// also based on version 1 and therefore carries the same version number.
+func concurrentEdit(t *testing.T, svc *VaultService, strategy string) model.SyncResponse {
+	t.Helper()
+	ctx := context.Background()
+
+	for _, v := range []int{1, 2} {
+		_, err := svc.Sync(ctx, 1, 0, model.SyncRequest{Entries: []model.VaultEntryRequest{
+			{EntryID: "entry-1", EncryptedData: b64(fmt.Sprintf("phone-v%d", v)), Version: v, DeviceID: "phone"},
+		}})
+		if err != nil {
+			t.Fatalf("Sync() unexpected error: %v", err)
+		}
+	}
+
+	resp, err := svc.Sync(ctx, 1, 0, model.SyncRequest{
+		ConflictStrategy: strategy,
+		Entries: []model.VaultEntryRequest{
+			{EntryID: "entry-1", EncryptedData: b64("laptop-v2"), Version: 2, DeviceID: "laptop"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	return resp
+}
+
+func TestVaultService_SyncConcurrentEditLWW(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+
+	resp := concurrentEdit(t, svc, "")
+
+	if len(resp.Conflicts) != 0 {
+		t.Errorf("LWW must not report conflicts, got %+v", resp.Conflicts)
+	}
+	if len(resp.Entries) != 1 || resp.Entries[0].EncryptedData != b64("phone-v2") {
+		t.Errorf("expected stored entry to be kept, got %+v", resp.Entries)
+	}
+}
+
+func TestVaultService_SyncConcurrentEditManual(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	resp := concurrentEdit(t, svc, model.ConflictManual)
+
+	if len(resp.Entries) != 1 || resp.Entries[0].EncryptedData != b64("phone-v2") {
+		t.Errorf("manual must not clobber the stored entry, got %+v", resp.Entries)
+	}
+	if len(resp.Conflicts) != 1 {
+		t.Fatalf("expected one staged conflict, got %+v", resp.Conflicts)
+	}
+	c := resp.Conflicts[0]
+	if c.Incoming.EncryptedData != b64("laptop-v2") || c.Incoming.LastDeviceID != "laptop" {
+		t.Errorf("unexpected incoming side: %+v", c.Incoming)
+	}
+	if c.Existing == nil || c.Existing.EncryptedData != b64("phone-v2") {
+		t.Errorf("unexpected existing side: %+v", c.Existing)
+	}
+
+	// The client resolves by uploading a merged, newer version.
+	resolved, err := svc.Sync(ctx, 1, 0, model.SyncRequest{
+		ConflictStrategy: model.ConflictManual,
+		Entries: []model.VaultEntryRequest{
+			{EntryID: "entry-1", EncryptedData: b64("merged"), Version: 3, DeviceID: "laptop"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	if len(resolved.Conflicts) != 0 {
+		t.Errorf("expected conflict to be cleared by newer write, got %+v", resolved.Conflicts)
+	}
+}
+
+func TestVaultService_SyncManualIgnoresIdenticalReplay(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	req := model.SyncRequest{
+		ConflictStrategy: model.ConflictManual,
+		Entries:          []model.VaultEntryRequest{{EntryID: "entry-1", EncryptedData: b64("same"), Version: 1}},
+	}
+	for range 2 {
+		resp, err := svc.Sync(ctx, 1, 0, req)
+		if err != nil {
+			t.Fatalf("Sync() unexpected error: %v", err)
+		}
+		if len(resp.Conflicts) != 0 {
+			t.Fatalf("retrying an identical write must not conflict, got %+v", resp.Conflicts)
+		}
+	}
+}
+
+func TestVaultService_SyncManualStagesReplayedLoserOnce(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	concurrentEdit(t, svc, model.ConflictManual)
+
+	// The laptop retries its losing upload, e.g. after missing the response.
+	resp, err := svc.Sync(ctx, 1, 0, model.SyncRequest{
+		ConflictStrategy: model.ConflictManual,
+		Entries: []model.VaultEntryRequest{
+			{EntryID: "entry-1", EncryptedData: b64("laptop-v2"), Version: 2, DeviceID: "laptop"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	if len(resp.Conflicts) != 1 {
+		t.Errorf("expected the replayed conflict to be staged once, got %+v", resp.Conflicts)
+	}
+}
+
+func TestVaultService_SyncInvalidStrategy(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+
+	_, err := svc.Sync(context.Background(), 1, 0, model.SyncRequest{ConflictStrategy: "merge"})
+	if !errors.Is(err, ErrInvalidStrategy) {
+		t.Errorf("expected ErrInvalidStrategy, got %v", err)
+	}
+}
+
+func TestSyncUpload_TransactionTimeout(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository(),
+		WithSyncTxTimeout(20*time.Millisecond))
+	ctx := context.Background()
+
+	upload, err := svc.BeginSync(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("BeginSync() unexpected error: %v", err)
+	}
+	defer upload.Close()
+	if err := upload.Apply(model.VaultEntryRequest{EntryID: "a", EncryptedData: b64("a")}); err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+
+	// A client that stalls mid-upload loses its transaction at the deadline.
+	time.Sleep(40 * time.Millisecond)
+	if err := upload.Apply(model.VaultEntryRequest{EntryID: "b", EncryptedData: b64("b")}); !errors.Is(err, ErrSyncTimeout) {
+		t.Fatalf("expected ErrSyncTimeout from Apply, got %v", err)
+	}
+	if _, err := upload.Finish(model.SyncRequest{}); !errors.Is(err, ErrSyncTimeout) {
+		t.Fatalf("expected ErrSyncTimeout from Finish, got %v", err)
+	}
+	upload.Close()
+
+	if entries, _ := svc.ListEntries(ctx, 1, 0); len(entries) != 0 {
+		t.Errorf("expected the timed-out upload to be rolled back, got %d entries", len(entries))
+	}
+}
+
+func TestVaultService_BatchGet(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	for _, id := range []string{"a", "b", "c", "gone"} {
+		if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: id, EncryptedData: b64(id)}); err != nil {
+			t.Fatalf("CreateEntry() unexpected error: %v", err)
+		}
+	}
+	if _, err := svc.CreateEntry(ctx, 2, 0, model.VaultEntryRequest{EntryID: "other-user", EncryptedData: b64("x")}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+	if err := svc.DeleteEntry(ctx, 1, 0, "gone"); err != nil {
+		t.Fatalf("DeleteEntry() unexpected error: %v", err)
+	}
+
+	entries, err := svc.BatchGet(ctx, 1, 0, []string{"c", "missing", "a", "c", "gone", "other-user"})
+	if err != nil {
+		t.Fatalf("BatchGet() unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.EntryID)
+	}
+	if strings.Join(got, ",") != "c,a" {
+		t.Errorf("expected present entries in request order without duplicates, got %v", got)
+	}
+}
+
+func TestVaultService_GetEntryRecordsAccess(t *testing.T) {
+	repo := repository.NewMemoryVaultRepository()
+	vaults := repository.NewMemoryCollectionRepository()
+	svc := NewVaultService(repo, vaults)
+	ctx := context.Background()
+
+	for _, id := range []string{"read", "batch", "gone"} {
+		if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: id, EncryptedData: b64(id)}); err != nil {
+			t.Fatalf("CreateEntry() unexpected error: %v", err)
+		}
+	}
+	if err := svc.DeleteEntry(ctx, 1, 0, "gone"); err != nil {
+		t.Fatalf("DeleteEntry() unexpected error: %v", err)
+	}
+	created, err := svc.Sync(ctx, 1, 0, model.SyncRequest{})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+
+	got, err := svc.GetEntry(ctx, 1, 0, "read")
+	if err != nil {
+		t.Fatalf("GetEntry() unexpected error: %v", err)
+	}
+	if got.LastAccessedAt == nil {
+		t.Fatal("expected GetEntry to report last_accessed_at")
+	}
+	if _, err := svc.BatchGet(ctx, 1, 0, []string{"batch"}); err != nil {
+		t.Fatalf("BatchGet() unexpected error: %v", err)
+	}
+	if _, err := svc.GetEntry(ctx, 1, 0, "gone"); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("expected ErrEntryNotFound for a deleted entry, got %v", err)
+	}
+
+	list, err := svc.ListEntries(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("ListEntries() unexpected error: %v", err)
+	}
+	for _, e := range list {
+		if e.LastAccessedAt == nil {
+			t.Errorf("%s: expected last_accessed_at after a fetch", e.EntryID)
+		}
+	}
+
+	personal, err := vaults.EnsureDefault(ctx, 1)
+	if err != nil {
+		t.Fatalf("EnsureDefault() unexpected error: %v", err)
+	}
+	stored, _ := repo.GetByEntryID(ctx, 1, personal.ID, "read")
+	if stored.LastAccessedAt == nil || !stored.LastAccessedAt.Equal(*got.LastAccessedAt) {
+		t.Errorf("expected the stored access time %v, got %v", got.LastAccessedAt, stored.LastAccessedAt)
+	}
+
+	// Reads are not changes: the next delta stays empty.
+	delta, err := svc.Sync(ctx, 1, 0, model.SyncRequest{LastSyncedAt: &created.SyncedAt})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	if len(delta.Entries) != 0 {
+		t.Errorf("expected no sync changes from reads, got %+v", delta.Entries)
+	}
+}
+
+func TestVaultService_BatchGetCap(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	ids := make([]string, maxBatchGetIDs+1)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("entry-%d", i)
+	}
+	if _, err := svc.BatchGet(ctx, 1, 0, ids); !errors.Is(err, ErrTooManyEntryIDs) {
+		t.Errorf("expected ErrTooManyEntryIDs, got %v", err)
+	}
+
+	// Duplicates don't count toward the cap.
+	dupes := make([]string, maxBatchGetIDs*2)
+	for i := range dupes {
+		dupes[i] = "same"
+	}
+	entries, err := svc.BatchGet(ctx, 1, 0, dupes)
+	if err != nil {
+		t.Fatalf("BatchGet() unexpected error for duplicates: %v", err)
+	}
+	if entries == nil || len(entries) != 0 {
+		t.Errorf("expected empty non-nil result, got %v", entries)
+	}
+}
+
+func TestVaultService_UpdateEntryExpectedVersion(t *testing.T) {
+	store := repository.NewMemoryVaultRepository()
+	svc := NewVaultService(store, repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: "entry-1", EncryptedData: b64("v1")}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+
+	one := 1
+	updated, err := svc.UpdateEntry(ctx, 1, 0, "entry-1", model.VaultEntryRequest{EncryptedData: b64("laptop"), ExpectedVersion: &one})
+	if err != nil {
+		t.Fatalf("UpdateEntry() with matching version unexpected error: %v", err)
+	}
+	if updated.Version != 2 {
+		t.Errorf("expected version 2, got %d", updated.Version)
+	}
+
+	_, err = svc.UpdateEntry(ctx, 1, 0, "entry-1", model.VaultEntryRequest{EncryptedData: b64("phone"), ExpectedVersion: &one})
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict for stale version, got %v", err)
+	}
+
+	stored, _ := store.GetByEntryID(ctx, 1, 1, "entry-1") // user 1's default vault is the first one created
+	if string(stored.EncryptedData) != "laptop" || stored.Version != 2 {
+		t.Errorf("stale update must not apply, got %q v%d", stored.EncryptedData, stored.Version)
+	}
+}
+
+func TestVaultService_UpdateEntryExpectedVersionDeleted(t *testing.T) {
+	store := repository.NewMemoryVaultRepository()
+	svc := NewVaultService(store, repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: "entry-1", EncryptedData: b64("v1")}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+	if err := svc.DeleteEntry(ctx, 1, 0, "entry-1"); err != nil {
+		t.Fatalf("DeleteEntry() unexpected error: %v", err)
+	}
+
+	// The tombstone is at version 2; a conditional update must 404 like an unconditional one.
+	two := 2
+	for _, expected := range []*int{nil, &two} {
+		_, err := svc.UpdateEntry(ctx, 1, 0, "entry-1", model.VaultEntryRequest{EncryptedData: b64("revived"), ExpectedVersion: expected})
+		if !errors.Is(err, ErrEntryNotFound) {
+			t.Errorf("expected_version=%v: expected ErrEntryNotFound, got %v", expected != nil, err)
+		}
+	}
+
+	stored, _ := store.GetByEntryID(ctx, 1, 1, "entry-1")
+	if !stored.Deleted || string(stored.EncryptedData) != "v1" {
+		t.Errorf("tombstone must be left alone, got deleted=%v %q", stored.Deleted, stored.EncryptedData)
+	}
+}
+
+func TestVaultService_AckCursorAllowsTombstonePurge(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	for _, id := range []string{"acked", "unacked", "live"} {
+		if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: id, EncryptedData: b64(id)}); err != nil {
+			t.Fatalf("CreateEntry() unexpected error: %v", err)
+		}
+	}
+	if err := svc.DeleteEntry(ctx, 1, 0, "acked"); err != nil {
+		t.Fatalf("DeleteEntry() unexpected error: %v", err)
+	}
+
+	// Nothing is purged until a client acknowledges a cursor.
+	if n, err := svc.PurgeTombstones(ctx, 1, 0); err != nil || n != 0 {
+		t.Fatalf("PurgeTombstones() before any ack = %d, %v; want 0", n, err)
+	}
+
+	time.Sleep(time.Millisecond)
+	first, err := svc.Sync(ctx, 1, 0, model.SyncRequest{})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+
+	// This tombstone is written after the cursor the client will acknowledge.
+	time.Sleep(time.Millisecond)
+	if err := svc.DeleteEntry(ctx, 1, 0, "unacked"); err != nil {
+		t.Fatalf("DeleteEntry() unexpected error: %v", err)
+	}
+	// The acknowledgment itself purges what it covers.
+	if _, err := svc.Sync(ctx, 1, 0, model.SyncRequest{LastSyncedAt: &first.SyncedAt, AckCursor: &first.SyncedAt}); err != nil {
+		t.Fatalf("Sync() with ack_cursor unexpected error: %v", err)
+	}
+	if n, err := svc.PurgeTombstones(ctx, 1, 0); err != nil || n != 0 {
+		t.Fatalf("PurgeTombstones() after the ack = %d, %v; want 0 left to purge", n, err)
+	}
+
+	all, _ := svc.Sync(ctx, 1, 0, model.SyncRequest{})
+	got := map[string]bool{}
+	for _, e := range all.Entries {
+		got[e.EntryID] = e.Deleted
+	}
+	if _, ok := got["acked"]; ok {
+		t.Error("expected the acknowledged tombstone to be gone")
+	}
+	if deleted, ok := got["unacked"]; !ok || !deleted {
+		t.Error("expected the unacknowledged tombstone to be kept")
+	}
+	if deleted, ok := got["live"]; !ok || deleted {
+		t.Error("expected the live entry to be untouched")
+	}
+}
+
+func TestVaultService_TombstonePurgeWaitsForEveryDevice(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: "gone", EncryptedData: b64("gone")}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+	before, err := svc.Sync(ctx, 1, 0, model.SyncRequest{DeviceID: "phone"})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	ack := func(device string, at time.Time) {
+		t.Helper()
+		if _, err := svc.Sync(ctx, 1, 0, model.SyncRequest{LastSyncedAt: &at, AckCursor: &at, DeviceID: device}); err != nil {
+			t.Fatalf("Sync() ack for %s unexpected error: %v", device, err)
+		}
+	}
+	ack("phone", before.SyncedAt)
+
+	time.Sleep(time.Millisecond)
+	if err := svc.DeleteEntry(ctx, 1, 0, "gone"); err != nil {
+		t.Fatalf("DeleteEntry() unexpected error: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	after, err := svc.Sync(ctx, 1, 0, model.SyncRequest{DeviceID: "laptop"})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+
+	// The laptop has seen the delete, but the phone's ack predates it.
+	ack("laptop", after.SyncedAt)
+	if resp, _ := svc.Sync(ctx, 1, 0, model.SyncRequest{DeviceID: "laptop"}); len(resp.Entries) != 1 {
+		t.Fatalf("expected the tombstone to be kept for the phone, got %+v", resp.Entries)
+	}
+
+	ack("phone", after.SyncedAt)
+	if resp, _ := svc.Sync(ctx, 1, 0, model.SyncRequest{DeviceID: "laptop"}); len(resp.Entries) != 0 {
+		t.Errorf("expected the tombstone purged once every device acked, got %+v", resp.Entries)
+	}
+}
+
+func TestVaultService_TombstonePurgeWaitsForSilentDevice(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: "gone", EncryptedData: b64("gone")}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+	// The tablet syncs but never acknowledges a cursor.
+	if _, err := svc.Sync(ctx, 1, 0, model.SyncRequest{DeviceID: "tablet"}); err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	if err := svc.DeleteEntry(ctx, 1, 0, "gone"); err != nil {
+		t.Fatalf("DeleteEntry() unexpected error: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+
+	resp, err := svc.Sync(ctx, 1, 0, model.SyncRequest{DeviceID: "phone"})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	if _, err := svc.Sync(ctx, 1, 0, model.SyncRequest{AckCursor: &resp.SyncedAt, DeviceID: "phone"}); err != nil {
+		t.Fatalf("Sync() ack unexpected error: %v", err)
+	}
+
+	stored, err := svc.repo.GetByEntryID(ctx, 1, 1, "gone")
+	if err != nil || !stored.Deleted {
+		t.Errorf("expected the tombstone to be kept for the tablet, got %+v, %v", stored, err)
+	}
+}
+
+func TestVaultService_AckCursorInFuture(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+
+	future := time.Now().Add(time.Hour)
+	_, err := svc.Sync(context.Background(), 1, 0, model.SyncRequest{AckCursor: &future})
+	if !errors.Is(err, ErrInvalidAckCursor) {
+		t.Errorf("expected ErrInvalidAckCursor, got %v", err)
+	}
+}
+
+func TestVaultService_ExpiredEntries(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	past := time.Now().Add(-time.Minute)
+	soon := time.Now().Add(50 * time.Millisecond)
+	later := time.Now().Add(time.Hour)
+	for id, expires := range map[string]*time.Time{"expired": &past, "expiring": &soon, "later": &later, "forever": nil} {
+		if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: id, EncryptedData: b64(id), ExpiresAt: expires}); err != nil {
+			t.Fatalf("CreateEntry(%s) unexpected error: %v", id, err)
+		}
+	}
+
+	first, err := svc.Sync(ctx, 1, 0, model.SyncRequest{})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	for _, e := range first.Entries {
+		if want := e.EntryID == "expired"; e.Deleted != want {
+			t.Errorf("%s: expected deleted=%v in full sync, got %v", e.EntryID, want, e.Deleted)
+		}
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	entries, err := svc.ListEntries(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("ListEntries() unexpected error: %v", err)
+	}
+	var ids []string
+	for _, e := range entries {
+		ids = append(ids, e.EntryID)
+	}
+	if len(ids) != 2 || slices.Contains(ids, "expired") || slices.Contains(ids, "expiring") {
+		t.Errorf("expected only unexpired entries to be listed, got %v", ids)
+	}
+
+	// An entry that expires between syncs is sent as a tombstone even though it wasn't written.
+	delta, err := svc.Sync(ctx, 1, 0, model.SyncRequest{LastSyncedAt: &first.SyncedAt})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	if len(delta.Entries) != 1 || delta.Entries[0].EntryID != "expiring" || !delta.Entries[0].Deleted {
+		t.Errorf("expected the newly expired entry as deleted in the delta, got %+v", delta.Entries)
+	}
+
+	n, err := svc.PurgeExpired(ctx)
+	if err != nil || n != 2 {
+		t.Errorf("PurgeExpired() = %d, %v; want 2", n, err)
+	}
+}
+
+func TestVaultService_ExpiryFollowsLWW(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	later := time.Now().Add(time.Hour).UTC()
+	if _, err := svc.Sync(ctx, 1, 0, model.SyncRequest{Entries: []model.VaultEntryRequest{
+		{EntryID: "e1", EncryptedData: b64("v2"), Version: 2, ExpiresAt: &later},
+	}}); err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+
+	// A stale write can't change the expiry; a newer one can clear it.
+	past := time.Now().Add(-time.Hour)
+	resp, err := svc.Sync(ctx, 1, 0, model.SyncRequest{Entries: []model.VaultEntryRequest{
+		{EntryID: "e1", EncryptedData: b64("v1"), Version: 1, ExpiresAt: &past},
+	}})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	if got := resp.Entries[0]; got.Deleted || got.ExpiresAt == nil || !got.ExpiresAt.Equal(later) {
+		t.Errorf("expected stale write to leave expiry untouched, got %+v", got)
+	}
+
+	resp, err = svc.Sync(ctx, 1, 0, model.SyncRequest{Entries: []model.VaultEntryRequest{
+		{EntryID: "e1", EncryptedData: b64("v3"), Version: 3},
+	}})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	if got := resp.Entries[0]; got.ExpiresAt != nil {
+		t.Errorf("expected newer write to clear the expiry, got %v", got.ExpiresAt)
+	}
+}
+
+func TestVaultService_WipeRequiresConfirmationAndSyncs(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository())
+	ctx := context.Background()
+
+	for _, id := range []string{"entry-1", "entry-2"} {
+		if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: id, EncryptedData: b64("v1")}); err != nil {
+			t.Fatalf("CreateEntry() unexpected error: %v", err)
+		}
+	}
+	before := time.Now().UTC()
+	time.Sleep(10 * time.Millisecond)
+
+	if _, err := svc.Wipe(ctx, 1, model.WipeRequest{Confirm: "delete all entries"}); err != ErrWipeNotConfirmed {
+		t.Fatalf("expected ErrWipeNotConfirmed for a wrong phrase, got %v", err)
+	}
+
+	resp, err := svc.Wipe(ctx, 1, model.WipeRequest{Confirm: model.WipeConfirmation})
+	if err != nil {
+		t.Fatalf("Wipe() unexpected error: %v", err)
+	}
+	if resp.Wiped != 2 {
+		t.Errorf("expected 2 entries wiped, got %d", resp.Wiped)
+	}
+
+	// Other devices learn about the wipe through their next delta sync.
+	delta, err := svc.Sync(ctx, 1, 0, model.SyncRequest{LastSyncedAt: &before})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	if len(delta.Entries) != 2 {
+		t.Fatalf("expected both wiped entries in the delta, got %+v", delta.Entries)
+	}
+	for _, e := range delta.Entries {
+		if !e.Deleted || e.Version != 2 {
+			t.Errorf("expected %s deleted at version 2, got deleted=%v v%d", e.EntryID, e.Deleted, e.Version)
+		}
+	}
+}
+
+func TestVaultService_EntryLimitIgnoresExpiredEntries(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository(),
+		WithEntryLimits(1, 0))
+	ctx := context.Background()
+
+	soon := time.Now().Add(20 * time.Millisecond)
+	if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: "temp", EncryptedData: b64("1"), ExpiresAt: &soon}); err != nil {
+		t.Fatalf("CreateEntry() unexpected error: %v", err)
+	}
+	if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: "next", EncryptedData: b64("1")}); !errors.Is(err, ErrEntryLimit) {
+		t.Fatalf("expected ErrEntryLimit while the first entry is live, got %v", err)
+	}
+
+	// Once it expires the entry no longer counts, even before it is purged.
+	time.Sleep(30 * time.Millisecond)
+	quota, err := svc.Quota(ctx, 1)
+	if err != nil {
+		t.Fatalf("Quota() unexpected error: %v", err)
+	}
+	if quota.Entries != 0 || quota.Bytes != 0 {
+		t.Errorf("expected the expired entry left out of the quota, got %+v", quota)
+	}
+	if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: "next", EncryptedData: b64("1")}); err != nil {
+		t.Errorf("expected the expired entry not to count against the limit, got %v", err)
+	}
+}
+
+func TestVaultService_EntryLimitsAndQuota(t *testing.T) {
+	svc := NewVaultService(repository.NewMemoryVaultRepository(), repository.NewMemoryCollectionRepository(),
+		WithEntryLimits(3, 8))
+	ctx := context.Background()
+
+	if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: "big", EncryptedData: b64("123456789")}); !errors.Is(err, ErrEntryTooLarge) {
+		t.Errorf("expected ErrEntryTooLarge for a 9-byte blob, got %v", err)
+	}
+	for _, id := range []string{"a", "gone"} {
+		if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EntryID: id, EncryptedData: b64("1234")}); err != nil {
+			t.Fatalf("CreateEntry(%s) unexpected error: %v", id, err)
+		}
+	}
+	if err := svc.DeleteEntry(ctx, 1, 0, "gone"); err != nil {
+		t.Fatalf("DeleteEntry() unexpected error: %v", err)
+	}
+	if _, err := svc.UpdateEntry(ctx, 1, 0, "a", model.VaultEntryRequest{EncryptedData: b64("123456789")}); !errors.Is(err, ErrEntryTooLarge) {
+		t.Errorf("expected ErrEntryTooLarge on update, got %v", err)
+	}
+
+	// One live entry; importing three would exceed the limit of three, so nothing is written.
+	imp := model.VaultImport{Entries: []model.ImportEntry{
+		{EntryID: "i1", EncryptedData: b64("12345678")},
+		{EntryID: "i2", EncryptedData: b64("1")},
+		{EntryID: "i3", EncryptedData: b64("1")},
+	}}
+	if _, err := svc.ImportEntries(ctx, 1, 0, imp); !errors.Is(err, ErrEntryLimit) {
+		t.Errorf("expected ErrEntryLimit for an import past the limit, got %v", err)
+	}
+	imp.Entries = imp.Entries[:2]
+	if resp, err := svc.ImportEntries(ctx, 1, 0, imp); err != nil || resp.Imported != 2 {
+		t.Fatalf("ImportEntries() = %+v, %v; expected 2 imported", resp, err)
+	}
+	if _, err := svc.CreateEntry(ctx, 1, 0, model.VaultEntryRequest{EncryptedData: b64("1")}); !errors.Is(err, ErrEntryLimit) {
+		t.Errorf("expected ErrEntryLimit once the limit is reached, got %v", err)
+	}
+	if _, err := svc.CreateEntry(ctx, 2, 0, model.VaultEntryRequest{EncryptedData: b64("1")}); err != nil {
+		t.Errorf("expected the limit to be per user, got %v", err)
+	}
+	if _, err := svc.RestoreEntry(ctx, 1, 0, "gone"); !errors.Is(err, ErrEntryLimit) {
+		t.Errorf("expected ErrEntryLimit restoring past the limit, got %v", err)
+	}
+	resp, err := svc.Sync(ctx, 1, 0, model.SyncRequest{Entries: []model.VaultEntryRequest{
+		{EntryID: "a", EncryptedData: b64("5678"), Version: 2},
+		{EntryID: "synced", EncryptedData: b64("1"), Version: 1},
+		{EntryID: "gone", EncryptedData: b64("1"), Version: 3},
+	}})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+	want := []model.SkippedEntry{{EntryID: "synced", Reason: "entry_limit"}, {EntryID: "gone", Reason: "entry_limit"}}
+	if !slices.Equal(resp.Skipped, want) {
+		t.Errorf("expected new and revived entries skipped with entry_limit, got %+v", resp.Skipped)
+	}
+
+	quota, err := svc.Quota(ctx, 1)
+	if err != nil {
+		t.Fatalf("Quota() unexpected error: %v", err)
+	}
+	if want := (model.VaultQuota{Entries: 3, Bytes: 13, MaxEntries: 3, MaxEntryBytes: 8}); quota != want {
+		t.Errorf("expected %+v, got %+v", want, quota)
+	}
+}
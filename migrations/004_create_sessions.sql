@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    id           VARCHAR(64) PRIMARY KEY,
+    user_id      BIGINT NOT NULL,
+    ip_address   VARCHAR(45) NOT NULL,
+    user_agent   VARCHAR(255) NOT NULL,
+    created_at   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at   TIMESTAMP NOT NULL,
+    revoked_at   TIMESTAMP NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_user_expires (user_id, expires_at)
+);
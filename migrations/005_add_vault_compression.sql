@@ -0,0 +1,2 @@
+ALTER TABLE vault_entries
+    ADD COLUMN compressed BOOLEAN NOT NULL DEFAULT FALSE AFTER encrypted_data;
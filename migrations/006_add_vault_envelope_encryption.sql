@@ -0,0 +1,3 @@
+ALTER TABLE vault_entries
+    ADD COLUMN server_encrypted BOOLEAN NOT NULL DEFAULT FALSE AFTER compressed,
+    ADD COLUMN nonce VARBINARY(12) NULL AFTER server_encrypted;
@@ -0,0 +1,3 @@
+ALTER TABLE vault_entries
+    ADD COLUMN favorite BOOLEAN NOT NULL DEFAULT FALSE AFTER version,
+    ADD INDEX idx_user_favorite (user_id, favorite, deleted);
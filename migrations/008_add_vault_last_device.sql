@@ -0,0 +1,2 @@
+ALTER TABLE vault_entries
+    ADD COLUMN last_device_id VARCHAR(64) NOT NULL DEFAULT '' AFTER favorite;
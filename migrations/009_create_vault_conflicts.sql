@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS vault_conflicts (
+    id               BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id          BIGINT NOT NULL,
+    entry_id         VARCHAR(36) NOT NULL,
+    encrypted_data   MEDIUMBLOB NOT NULL,
+    compressed       BOOLEAN NOT NULL DEFAULT FALSE,
+    server_encrypted BOOLEAN NOT NULL DEFAULT FALSE,
+    nonce            VARBINARY(12) NULL,
+    version          INT NOT NULL,
+    favorite         BOOLEAN NOT NULL DEFAULT FALSE,
+    deleted          BOOLEAN NOT NULL DEFAULT FALSE,
+    device_id        VARCHAR(64) NOT NULL DEFAULT '',
+    created_at       TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_user_entry (user_id, entry_id)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE login_events
+    ADD INDEX idx_user_success_created (user_id, success, created_at, id);
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ADD COLUMN deactivated_at TIMESTAMP NULL DEFAULT NULL AFTER updated_at,
+    ADD INDEX idx_deactivated_at (deactivated_at);
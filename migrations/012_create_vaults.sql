@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS vaults (
+    id         BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id    BIGINT NOT NULL,
+    name       VARCHAR(100) NOT NULL,
+    is_default BOOLEAN NULL DEFAULT NULL, -- TRUE for the user's default vault, NULL otherwise
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE INDEX idx_user_default (user_id, is_default)
+);
+
+-- Every existing user gets a default vault that takes over their entries.
+INSERT INTO vaults (user_id, name, is_default)
+SELECT id, 'Personal', TRUE FROM users;
+
+ALTER TABLE vault_entries ADD COLUMN vault_id BIGINT NULL AFTER user_id;
+
+UPDATE vault_entries e
+JOIN vaults v ON v.user_id = e.user_id AND v.is_default = TRUE
+SET e.vault_id = v.id;
+
+ALTER TABLE vault_entries
+    MODIFY vault_id BIGINT NOT NULL,
+    ADD FOREIGN KEY (vault_id) REFERENCES vaults(id) ON DELETE CASCADE,
+    ADD UNIQUE INDEX idx_vault_entry (user_id, vault_id, entry_id),
+    DROP INDEX idx_user_entry,
+    ADD INDEX idx_vault_updated (vault_id, updated_at);
+
+ALTER TABLE vault_conflicts ADD COLUMN vault_id BIGINT NULL AFTER user_id;
+
+UPDATE vault_conflicts c
+JOIN vaults v ON v.user_id = c.user_id AND v.is_default = TRUE
+SET c.vault_id = v.id;
+
+ALTER TABLE vault_conflicts
+    MODIFY vault_id BIGINT NOT NULL,
+    ADD FOREIGN KEY (vault_id) REFERENCES vaults(id) ON DELETE CASCADE,
+    ADD INDEX idx_vault_entry (user_id, vault_id, entry_id),
+    DROP INDEX idx_user_entry;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS sync_acks (
+    user_id    BIGINT NOT NULL,
+    vault_id   BIGINT NOT NULL,
+    acked_at   TIMESTAMP(6) NOT NULL, -- the client has applied every change up to this point
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, vault_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (vault_id) REFERENCES vaults(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+ALTER TABLE vault_entries
+    ADD COLUMN expires_at TIMESTAMP NULL DEFAULT NULL AFTER deleted,
+    ADD INDEX idx_expires_at (expires_at);
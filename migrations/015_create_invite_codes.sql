@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS invite_codes (
+    code_hash  CHAR(64) PRIMARY KEY,        -- hex SHA-256 of the code; the code itself is never stored
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NULL,              -- NULL means the code never expires
+    used_at    TIMESTAMP NULL,
+    used_by    VARCHAR(255) NULL             -- email of the account registered with the code
+);
@@ -0,0 +1,3 @@
+ALTER TABLE vault_entries
+    ADD COLUMN content_hash CHAR(64) NULL DEFAULT NULL AFTER nonce,
+    ADD INDEX idx_user_content_hash (user_id, content_hash);
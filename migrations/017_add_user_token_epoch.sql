@@ -0,0 +1,2 @@
+ALTER TABLE users
+    ADD COLUMN token_epoch BIGINT UNSIGNED NOT NULL DEFAULT 0 AFTER deactivated_at;
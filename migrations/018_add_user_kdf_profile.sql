@@ -0,0 +1,2 @@
+ALTER TABLE users
+    ADD COLUMN kdf_profile JSON NULL DEFAULT NULL AFTER token_epoch;
@@ -0,0 +1,2 @@
+ALTER TABLE vault_entries
+    ADD COLUMN delete_reason VARCHAR(16) NOT NULL DEFAULT '' AFTER deleted;
@@ -0,0 +1,2 @@
+ALTER TABLE vault_entries
+    ADD COLUMN last_accessed_at TIMESTAMP NULL DEFAULT NULL AFTER updated_at;
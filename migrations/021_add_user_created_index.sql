@@ -0,0 +1,2 @@
+ALTER TABLE users
+    ADD INDEX idx_created_id (created_at, id);
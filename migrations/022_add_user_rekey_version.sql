@@ -0,0 +1,2 @@
+ALTER TABLE users
+    ADD COLUMN rekey_version BIGINT UNSIGNED NOT NULL DEFAULT 0 AFTER token_epoch;
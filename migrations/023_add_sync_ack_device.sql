@@ -0,0 +1,4 @@
+ALTER TABLE sync_acks
+    ADD COLUMN device_id VARCHAR(64) NOT NULL DEFAULT '' AFTER vault_id,
+    DROP PRIMARY KEY,
+    ADD PRIMARY KEY (user_id, vault_id, device_id);
@@ -0,0 +1,2 @@
+ALTER TABLE sync_acks
+    MODIFY COLUMN acked_at TIMESTAMP(6) NULL DEFAULT NULL; -- NULL until the device acknowledges a cursor